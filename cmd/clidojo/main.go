@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"clidojo/internal/app"
+	"clidojo/internal/cli"
+)
+
+func main() {
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		code := cli.Run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+		stop()
+		os.Exit(code)
+	}
+
+	cfg := app.DefaultConfig()
+	flag.BoolVar(&cfg.Dev, "dev", cfg.Dev, "enable dev mode (dev HTTP API, demo scenarios and pack hot reload)")
+	flag.StringVar(&cfg.DevHTTP, "dev-http", cfg.DevHTTP, "dev HTTP API listen address")
+	flag.StringVar(&cfg.LogPath, "log", cfg.LogPath, "write JSONL telemetry to this file")
+	flag.BoolVar(&cfg.DebugLayout, "debug-layout", cfg.DebugLayout, "render layout debug information")
+	flag.StringVar(&cfg.SandboxMode, "sandbox", cfg.SandboxMode, "sandbox mode: auto, mock, docker, podman")
+	flag.StringVar(&cfg.DemoScenario, "demo", cfg.DemoScenario, "apply a deterministic demo scenario (dev mode)")
+	flag.StringVar(&cfg.EngineOverride, "engine", cfg.EngineOverride, "force container engine: docker or podman")
+	flag.BoolVar(&cfg.ASCIIOnly, "ascii", cfg.ASCIIOnly, "use ASCII-only glyphs")
+	flag.StringVar(&cfg.DataDir, "data-dir", cfg.DataDir, "state/work directory (default ~/.local/share/clidojo)")
+	flag.Var((*stringList)(&cfg.PacksDirs), "packs-dir", "additional pack root, searched before the default roots (repeatable)")
+	flag.StringVar(&cfg.TrustPolicy, "trust-policy", cfg.TrustPolicy, "packs without a trusted signature: off, sandbox-only, refuse")
+	flag.BoolVar(&cfg.KeepArtifacts, "keep-artifacts", cfg.KeepArtifacts, "keep staged work directories after exit")
+	flag.Parse()
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "clidojo:", err)
+		os.Exit(2)
+	}
+
+	a, err := app.New(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "clidojo:", err)
+		os.Exit(1)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	err = a.Run(ctx)
+	stop()
+	a.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "clidojo:", err)
+		os.Exit(1)
+	}
+}
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ",") }
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
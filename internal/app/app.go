@@ -1,15 +1,16 @@
 package app
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
 	"math/rand"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -53,9 +54,26 @@ type App struct {
 	dailyDay    string
 	dailyPlan   []dailyLevelRef
 	dailyIndex  int
+	// toolPlan is the Tools screen playlist for toolID, at toolIndex.
+	toolID    string
+	toolName  string
+	toolPlan  []dailyLevelRef
+	toolIndex int
+	// nextVariant is the variant_id requested for the next new run, e.g. by
+	// Continue or a Daily Drill playlist.
+	nextVariant string
+	// nextSeed is the dataset seed Continue replays for seed: per_run levels.
+	nextSeed *int64
+	// runDataset is the per-run dataset dir of the current run, removed when
+	// the run ends.
+	runDataset string
 
 	handle sandbox.Handle
 	runID  int64
+	// teardown holds the hooks to run in handle before it is stopped, from
+	// the level it was started for; hookCWD is their working directory.
+	teardown levels.HooksSpec
+	hookCWD  string
 
 	startTime    time.Time
 	hintsUsed    int
@@ -74,6 +92,20 @@ type App struct {
 	lastResult      grading.Result
 	elapsedOverride string
 
+	// stage is the current stage of a multi-stage level (0 otherwise),
+	// started at stageStart. The bases are the run's hint and reset counts
+	// when it began; stageResults holds the stages passed so far.
+	stage          int
+	stageStart     time.Time
+	stageHintBase  int
+	stageResetBase int
+	stageResults   []grading.Result
+
+	// packWatchStop stops the --dev pack watcher; levelSig is the active
+	// level's file signature, used to offer a restage when it changes.
+	packWatchStop context.CancelFunc
+	levelSig      string
+
 	devMu     sync.Mutex
 	devServer *http.Server
 	demoMu    sync.Mutex
@@ -106,8 +138,9 @@ type App struct {
 }
 
 type dailyLevelRef struct {
-	PackID  string `json:"pack_id"`
-	LevelID string `json:"level_id"`
+	PackID    string `json:"pack_id"`
+	LevelID   string `json:"level_id"`
+	VariantID string `json:"variant_id,omitempty"`
 }
 
 func New(cfg Config) (*App, error) {
@@ -132,14 +165,51 @@ func New(cfg Config) (*App, error) {
 	}
 
 	loader := levels.NewLoader()
-	packs, err := loader.LoadPacks(context.Background(), "packs")
+	trustStore, err := levels.LoadTrustStore(levels.TrustStoreDir(cfg.DataDir))
+	if err != nil {
+		logger.Error("trust.store_load_failed", map[string]any{"dir": trustStore.Dir, "error": err.Error()})
+	}
+	loader.TrustStore = trustStore
+	loader.TrustPolicy = cfg.TrustPolicy
+	loader.OnRefused = func(path string, err error) {
+		logger.Info("pack.refused", map[string]any{"path": path, "error": err.Error()})
+	}
+	// One broken pack or level must not keep the rest from loading.
+	loader.IsolateErrors = true
+	roots := packSearchRoots(cfg)
+	packs, err := loader.LoadPackRoots(context.Background(), roots)
 	if err != nil {
 		_ = store.Close()
 		_ = logger.Close()
 		return nil, err
 	}
-	if len(packs) == 0 || len(packs[0].LoadedLevels) == 0 {
-		return nil, fmt.Errorf("no packs/levels available under packs/")
+	first := firstPlayablePack(packs)
+	if first < 0 {
+		_ = store.Close()
+		_ = logger.Close()
+		return nil, noPlayableLevelsError(packs, roots)
+	}
+	for _, p := range packs {
+		if p.LoadError != "" {
+			logger.Error("pack.broken", map[string]any{"pack": p.PackID, "path": firstNonEmpty(p.Archive, p.Path), "error": p.LoadError})
+		}
+		for _, b := range p.BrokenLevels {
+			logger.Error("level.broken", map[string]any{"pack": p.PackID, "level": b.LevelID, "path": b.Path, "error": b.Error})
+		}
+		for _, shadowed := range p.Shadows {
+			logger.Info("pack.shadowed", map[string]any{"pack": p.PackID, "used": p.Path, "ignored": shadowed})
+		}
+		for _, msg := range p.DependencyErrors {
+			logger.Error("pack.dependency_unsatisfied", map[string]any{"pack": p.PackID, "error": msg})
+		}
+		for _, lv := range p.LoadedLevels {
+			for _, msg := range lv.DependencyErrors {
+				logger.Error("level.dependency_unsatisfied", map[string]any{"pack": p.PackID, "level": lv.LevelID, "error": msg})
+			}
+		}
+		if p.Trust.Restricted {
+			logger.Info("pack.restricted", map[string]any{"pack": p.PackID, "trust": p.Trust.Status, "detail": p.Trust.Detail})
+		}
 	}
 
 	termPane := term.NewTerminalPane(nil)
@@ -151,6 +221,7 @@ func New(cfg Config) (*App, error) {
 		StyleVariant: cfg.UI.StyleVariant,
 		MotionLevel:  cfg.UI.MotionLevel,
 		MouseScope:   cfg.UI.MouseScope,
+		Locale:       ui.ResolveLocale(cfg.UI.Locale),
 	})
 	termPane.SetDirty(view.RequestDraw)
 
@@ -166,13 +237,19 @@ func New(cfg Config) (*App, error) {
 		term:         termPane,
 		sessionID:    uuid.NewString(),
 		packs:        packs,
-		pack:         packs[0],
-		level:        packs[0].LoadedLevels[0],
+		pack:         packs[first],
+		level:        packs[first].LoadedLevels[0],
 		checkStatus:  map[string]string{},
 		screen:       ui.ScreenMainMenu,
 		mode:         ModeFreePlay,
 		ensuredImage: map[string]bool{},
 	}
+	loader.RunGenerator = func(ctx context.Context, job levels.GeneratorJob) error {
+		job.SessionID = a.sessionID
+		a.logger.Info("dataset.generate", map[string]any{"pack": job.PackID, "level": job.LevelID, "output": job.OutputDir})
+		return a.sandbox.RunGenerator(ctx, sandbox.GeneratorSpec(job))
+	}
+	loader.ImageID = a.sandbox.ImageID
 	view.SetController(a)
 	view.SetCatalog(a.catalog())
 	return a, nil
@@ -181,6 +258,7 @@ func New(cfg Config) (*App, error) {
 func (a *App) Run(ctx context.Context) error {
 	a.logger.Info("app.start", map[string]any{"session": a.sessionID, "sandbox": a.cfg.SandboxMode})
 	a.loadPersistedSettings(ctx)
+	a.view.SetLocale(a.locale())
 	a.refreshCatalog()
 
 	engine, err := a.sandbox.Detect(ctx, a.cfg.EngineOverride)
@@ -197,6 +275,9 @@ func (a *App) Run(ctx context.Context) error {
 		a.engine = engine
 		a.logger.Info("engine.detected", map[string]any{"engine": engine.Name, "version": engine.Version})
 		_ = a.sandbox.CleanupOrphans(ctx, a.sessionID)
+		if err := removeStaleRunDatasets(a.cfg.DataDir, a.sessionID); err != nil {
+			a.logger.Error("dataset.cleanup_failed", map[string]any{"error": err.Error()})
+		}
 	}
 
 	a.view.SetMainMenuState(a.mainMenuState())
@@ -208,6 +289,7 @@ func (a *App) Run(ctx context.Context) error {
 		if err := a.startDevHTTP(); err != nil {
 			return err
 		}
+		a.startPackWatch()
 		if a.cfg.DemoScenario != "" {
 			_, err := a.runDemoScenario(context.Background(), a.cfg.DemoScenario, 30*time.Second)
 			if err != nil {
@@ -228,7 +310,10 @@ func (a *App) Close() {
 	if a.devServer != nil {
 		_ = a.devServer.Shutdown(ctx)
 	}
-	a.stopLevelRuntime(ctx)
+	if a.packWatchStop != nil {
+		a.packWatchStop()
+	}
+	a.exitLevel(ctx)
 	_ = a.store.Close()
 	_ = a.logger.Close()
 }
@@ -236,6 +321,7 @@ func (a *App) Close() {
 func (a *App) stopLevelRuntime(ctx context.Context) {
 	a.stopAutoCheckLoop()
 	if a.handle != nil {
+		a.runTeardown()
 		_ = a.handle.Stop(ctx)
 		a.handle = nil
 	}
@@ -243,11 +329,51 @@ func (a *App) stopLevelRuntime(ctx context.Context) {
 	a.activeLevel = false
 }
 
+// exitLevel stops the level and drops its per-run dataset. Resets keep the
+// dataset, so they only stop the runtime.
+func (a *App) exitLevel(ctx context.Context) {
+	a.stopLevelRuntime(ctx)
+	a.removeRunDataset()
+}
+
+// runTeardown runs the teardown hooks of the level whose container is up.
+// Failures are logged; the container is removed either way.
+func (a *App) runTeardown() {
+	hooks := a.teardown
+	a.teardown = levels.HooksSpec{}
+	if len(hooks.InContainer) == 0 || a.handle.IsMock() {
+		return
+	}
+	// Hooks carry their own timeouts.
+	if err := hooks.RunHooks(context.Background(), "teardown", a.hookExec(a.handle, a.hookCWD), a.logHook("teardown")); err != nil {
+		a.logger.Error("level.teardown_failed", map[string]any{"level": a.level.LevelID, "error": err.Error()})
+	}
+}
+
+func (a *App) hookExec(h sandbox.Handle, cwd string) levels.HookExecFunc {
+	return func(ctx context.Context, script string) ([]byte, error) {
+		return a.sandbox.ExecScript(ctx, h, cwd, script)
+	}
+}
+
+func (a *App) logHook(phase string) levels.HookLogFunc {
+	return func(h levels.HookSpec, out []byte, err error) {
+		fields := map[string]any{"phase": phase, "hook": h.HookID, "output": strings.TrimSpace(string(out))}
+		if err != nil {
+			fields["error"] = err.Error()
+		}
+		a.logger.Info("level.hook", fields)
+	}
+}
+
 func (a *App) startLevel(ctx context.Context, newRun bool) error {
-	setLoading := func(step string) {
-		a.view.SetInfo("Loading level", step, true)
+	// Progress counts the steps below; the dataset step is split further by
+	// PrepareDataset.
+	const loadSteps = 7
+	setLoading := func(step string, done float64) {
+		a.view.SetLoading(step, done/loadSteps)
 	}
-	setLoading("Preparing runtime...")
+	setLoading("Preparing runtime...", 0)
 	defer a.view.SetInfo("", "", false)
 
 	a.stopLevelRuntime(ctx)
@@ -256,20 +382,38 @@ func (a *App) startLevel(ctx context.Context, newRun bool) error {
 	a.view.SetReferenceText("", false)
 	a.view.SetDiffText("", false)
 
-	workDir := filepath.Join(a.cfg.DataDir, "work", a.sessionID, a.level.LevelID)
-	setLoading("Staging workspace...")
-	a.logger.Info("level.stage_workdir", map[string]any{"workdir": workDir})
-	if err := a.loader.StageWorkdir(a.level, workDir); err != nil {
-		return err
+	if newRun {
+		a.removeRunDataset()
+		setLoading("Preparing variant...", 0)
+		if err := a.applyVariant(ctx); err != nil {
+			return err
+		}
+		a.applyRunSeed()
 	}
+	a.level = a.level.Localized(a.locale())
 
 	ensureCtx, ensureCancel := context.WithTimeout(context.Background(), 10*time.Minute)
-	setLoading("Resolving container image...")
+	setLoading("Resolving container image...", 1)
 	image, err := a.ensureLevelImage(ensureCtx)
+	if err == nil {
+		a.level, err = a.loader.PrepareDataset(ensureCtx, a.pack, a.level, func(p levels.DatasetProgress) {
+			setLoading(p.Step+"...", 2+float64(p.Done)/float64(p.Total))
+		})
+	}
 	ensureCancel()
 	if err != nil {
 		return err
 	}
+
+	if a.cfg.Dev {
+		a.levelSig = levels.DirSignature(a.level.Path)
+	}
+	workDir := filepath.Join(a.cfg.DataDir, "work", a.sessionID, a.level.LevelID)
+	setLoading("Staging workspace...", 3)
+	a.logger.Info("level.stage_workdir", map[string]any{"workdir": workDir})
+	if err := a.loader.StageWorkdir(a.level, workDir); err != nil {
+		return err
+	}
 	readOnly := true
 	if a.level.Sandbox.ReadOnlyRoot != nil {
 		readOnly = *a.level.Sandbox.ReadOnlyRoot
@@ -279,7 +423,7 @@ func (a *App) startLevel(ctx context.Context, newRun bool) error {
 		tmpfs = append(tmpfs, sandbox.TmpfsMount{Mount: tm.Mount, Options: tm.Options})
 	}
 
-	setLoading("Starting sandbox...")
+	setLoading("Starting sandbox...", 4)
 	handle, err := a.sandbox.StartLevel(ctx, sandbox.StartSpec{
 		SessionID:     a.sessionID,
 		PackID:        a.pack.PackID,
@@ -300,6 +444,7 @@ func (a *App) startLevel(ctx context.Context, newRun bool) error {
 		MemoryMB:      a.level.Sandbox.MemoryMB,
 		PidsLimit:     a.level.Sandbox.PidsLimit,
 		Tmpfs:         tmpfs,
+		Services:      serviceSpecs(a.level),
 	})
 	if err != nil {
 		return err
@@ -309,14 +454,26 @@ func (a *App) startLevel(ctx context.Context, newRun bool) error {
 	if current := a.sandbox.CurrentEngine(); current != "" {
 		a.engine.Name = current
 	}
+	if !handle.IsMock() && len(a.level.Setup.InContainer) > 0 {
+		setLoading("Running setup hooks...", 5)
+		// Hooks carry their own timeouts.
+		if err := a.level.Setup.RunHooks(context.Background(), "setup", a.hookExec(handle, a.level.Shell.CWD), a.logHook("setup")); err != nil {
+			// Leave no half-built sandbox behind.
+			a.stopLevelRuntime(ctx)
+			return err
+		}
+	}
+	a.teardown, a.hookCWD = a.level.Teardown, a.level.Shell.CWD
 
 	if newRun {
 		runID, err := a.store.StartLevelRun(ctx, state.LevelRun{
-			SessionID: a.sessionID,
-			PackID:    a.pack.PackID,
-			LevelID:   a.level.LevelID,
-			Mode:      string(a.mode),
-			StartTS:   time.Now().UTC(),
+			SessionID:   a.sessionID,
+			PackID:      a.pack.PackID,
+			LevelID:     a.level.LevelID,
+			Mode:        string(a.mode),
+			Variant:     a.level.Variant,
+			DatasetSeed: a.level.RunSeed,
+			StartTS:     time.Now().UTC(),
 		})
 		if err != nil {
 			return err
@@ -328,16 +485,22 @@ func (a *App) startLevel(ctx context.Context, newRun bool) error {
 		a.resetCount = 0
 		a.checkFails = 0
 		a.checkAttempt = 0
+		a.stageResults = nil
+		a.enterStage(0, a.startTime)
+	} else if a.stage > 0 {
+		// The reset restaged the workdir, so a multi-stage level starts over.
+		a.stageResults = nil
+		a.enterStage(0, time.Now())
 	}
 	a.lastResult = grading.Result{}
 	a.elapsedOverride = ""
 	a.checkStatus = map[string]string{}
-	for _, c := range a.level.Checks {
+	for _, c := range a.stageLevel().Checks {
 		a.checkStatus[c.ID] = "pending"
 	}
 
 	if handle.IsMock() {
-		setLoading("Starting demo terminal...")
+		setLoading("Starting demo terminal...", 6)
 		a.logger.Info("term.mode", map[string]any{"mode": "playback"})
 		if err := os.WriteFile(filepath.Join(workDir, ".dojo_cmdlog"), []byte(a.demo.MockCmdLog(a.level.LevelID)), 0o644); err != nil {
 			return err
@@ -349,7 +512,7 @@ func (a *App) startLevel(ctx context.Context, newRun bool) error {
 		}
 		a.logger.Info("term.playback.started", map[string]any{"level": a.level.LevelID})
 	} else {
-		setLoading("Starting interactive shell...")
+		setLoading("Starting interactive shell...", 6)
 		a.logger.Info("term.mode", map[string]any{"mode": "pty"})
 		// Keep interactive shell lifecycle tied to explicit Stop() calls rather
 		// than short-lived handler contexts.
@@ -366,7 +529,11 @@ func (a *App) startLevel(ctx context.Context, newRun bool) error {
 	a.view.SetScreen(ui.ScreenPlaying)
 	a.activeLevel = true
 	a.logger.Info("level.start.flash", map[string]any{"level": a.level.LevelID})
-	a.view.FlashStatus("Level ready")
+	if a.level.Variant != "" {
+		a.view.FlashStatus("Level ready (variant " + a.level.Variant + ")")
+	} else {
+		a.view.FlashStatus("Level ready")
+	}
 	a.setDevState("playing", "playing")
 	a.logger.Info("level.start.persist_state", map[string]any{"level": a.level.LevelID})
 	if err := a.demo.SetState(ctx, "", "playing", true); err != nil {
@@ -381,17 +548,18 @@ func (a *App) syncPlayingState(score int, badges []string) {
 	if badges == nil {
 		badges = a.badgesFor(a.lastResult.Passed)
 	}
-	checks := make([]ui.CheckRow, 0, len(a.level.Checks))
-	for _, c := range a.level.Checks {
+	level := a.stageLevel()
+	checks := make([]ui.CheckRow, 0, len(level.Checks))
+	for _, c := range level.Checks {
 		checks = append(checks, ui.CheckRow{ID: c.ID, Description: c.Description, Status: a.checkStatus[c.ID]})
 	}
-	a.view.SetPlayingState(ui.PlayingState{
+	st := ui.PlayingState{
 		ModeLabel:    a.modeLabel(),
 		PackID:       a.pack.PackID,
 		LevelID:      a.level.LevelID,
 		ElapsedLabel: a.elapsedOverride,
 		HudWidth:     a.hudWidth(),
-		Objective:    a.level.Objective.Bullets,
+		Objective:    level.Objective.Bullets,
 		Checks:       checks,
 		Hints:        a.buildHintRows(),
 		Engine:       a.engine.Name,
@@ -402,15 +570,84 @@ func (a *App) syncPlayingState(score int, badges []string) {
 		Streak:       a.passStreak,
 		Badges:       badges,
 		SessionGoals: a.sessionGoalsForLevel(),
-	})
+	}
+	if n := len(a.level.Stages); n > 0 {
+		st.Stage, st.StageCount, st.StageTitle = a.stage+1, n, a.level.Stages[a.stage].Title
+	}
+	a.view.SetPlayingState(st)
+}
+
+// stageLevel is the level as played right now: the current stage of a
+// multi-stage level, else the level itself.
+func (a *App) stageLevel() levels.Level {
+	return a.level.AtStage(a.stage)
+}
+
+// enterStage makes stage idx current with its clock started at start. Hints
+// and check state are per stage; levels without stages only have stage 0.
+func (a *App) enterStage(idx int, start time.Time) {
+	a.stage = idx
+	a.stageStart = start
+	a.stageHintBase, a.stageResetBase = 0, 0
+	if idx > 0 {
+		a.stageHintBase, a.stageResetBase = a.hintsUsed, a.resetCount
+	}
+	a.hintRevealed = 0
+	a.checkFails = 0
+	a.checkStatus = map[string]string{}
+	for _, c := range a.stageLevel().Checks {
+		a.checkStatus[c.ID] = "pending"
+	}
+}
+
+// passStage records a passed stage of a multi-stage level and, unless it was
+// the final one, reveals the next stage in the same sandbox. It reports
+// whether the level goes on.
+func (a *App) passStage(ctx context.Context, result grading.Result) bool {
+	stage := a.level.Stages[a.stage]
+	result.Stages = []grading.StageResult{{
+		StageID:    stage.StageID,
+		Title:      stage.Title,
+		DurationMS: time.Since(a.stageStart).Milliseconds(),
+		Points:     result.Score.TotalPoints,
+	}}
+	a.stageResults = append(a.stageResults, result)
+	if a.stage == len(a.level.Stages)-1 {
+		return false
+	}
+	a.lastResult = grading.Result{}
+	a.resultOpen = false
+	a.view.SetResult(ui.ResultState{})
+	a.enterStage(a.stage+1, time.Now())
+	// The next stage's checks may watch other paths.
+	a.startAutoCheckLoop()
+	a.syncPlayingState(currentScore(a), nil)
+	next := a.level.Stages[a.stage]
+	a.view.FlashStatus(fmt.Sprintf("Stage %d passed • Stage %d/%d: %s", a.stage, a.stage+1, len(a.level.Stages), cmp.Or(next.Title, next.StageID)))
+	return true
+}
+
+// projectedScore is the level score shown for a current stage score: for a
+// multi-stage level it adds the points of the passed stages and the base
+// share of the stages still ahead.
+func (a *App) projectedScore(current int) int {
+	score := current
+	for _, r := range a.stageResults {
+		score += r.Score.TotalPoints
+	}
+	for i := a.stage + 1; i < len(a.level.Stages); i++ {
+		score += a.level.AtStage(i).Scoring.BasePoints
+	}
+	return score
 }
 
 func (a *App) buildHintRows() []ui.HintRow {
-	if len(a.level.Hints) == 0 {
+	hints := a.stageLevel().Hints
+	if len(hints) == 0 {
 		return []ui.HintRow{{Text: "Use F5 to run checks.", Revealed: true}}
 	}
-	rows := make([]ui.HintRow, 0, len(a.level.Hints))
-	for i, h := range a.level.Hints {
+	rows := make([]ui.HintRow, 0, len(hints))
+	for i, h := range hints {
 		revealed := i < a.hintRevealed
 		unlocked, reason := a.hintUnlocked(i)
 		rows = append(rows, ui.HintRow{
@@ -427,11 +664,12 @@ func (a *App) hintUnlocked(idx int) (bool, string) {
 	if idx <= 0 {
 		return true, ""
 	}
-	if idx >= len(a.level.Hints) {
+	hints := a.stageLevel().Hints
+	if idx >= len(hints) {
 		return false, ""
 	}
-	h := a.level.Hints[idx]
-	elapsed := int(time.Since(a.startTime).Seconds())
+	h := hints[idx]
+	elapsed := int(time.Since(a.stageStart).Seconds())
 	if h.Unlock.AfterSeconds > 0 && elapsed >= h.Unlock.AfterSeconds {
 		return true, ""
 	}
@@ -463,6 +701,8 @@ func (a *App) modeLabel() string {
 		return "Daily Drill"
 	case ModeCampaign:
 		return "Campaign"
+	case ModeToolDrill:
+		return "Tool: " + a.toolName
 	default:
 		return "Free Play"
 	}
@@ -477,6 +717,8 @@ func (a *App) OnContinue() {
 		if pack, level, findErr := a.loader.FindLevel(a.packs, last.PackID, last.LevelID); findErr == nil {
 			a.pack = pack
 			a.level = level
+			a.nextVariant = last.Variant
+			a.nextSeed = last.DatasetSeed
 		}
 	}
 	if a.mode == ModeDailyDrill {
@@ -492,8 +734,12 @@ func (a *App) OnContinue() {
 			}
 			a.dailyIndex = idx
 			if pack, level, findErr := a.loader.FindLevel(a.packs, plan[idx].PackID, plan[idx].LevelID); findErr == nil {
+				if pack.PackID != a.pack.PackID || level.LevelID != a.level.LevelID {
+					a.nextSeed = nil
+				}
 				a.pack = pack
 				a.level = level
+				a.nextVariant = plan[idx].VariantID
 			}
 		}
 	} else {
@@ -542,6 +788,7 @@ func (a *App) OnStartDailyDrill() {
 	a.dailyIndex = idx
 	a.pack = pack
 	a.level = level
+	a.nextVariant = selected.VariantID
 	a.refreshCatalog()
 	a.view.SetMainMenuState(a.mainMenuState())
 	a.view.SetLevelSelection(a.pack.PackID, a.level.LevelID)
@@ -578,7 +825,7 @@ func (a *App) OnOpenLevelSelect() {
 	defer cancel()
 	if a.activeLevel {
 		a.logger.Info("ui.level_select.stop_runtime", map[string]any{})
-		a.stopLevelRuntime(ctx)
+		a.exitLevel(ctx)
 	}
 	a.logger.Info("ui.level_select.clear_overlays", map[string]any{})
 	a.view.SetMenuOpen(false)
@@ -605,7 +852,7 @@ func (a *App) OnStartLevel(packID, levelID string) {
 		return
 	}
 	if a.mode == ModeCampaign {
-		if locked, reason := a.levelLocked(level); locked {
+		if locked, reason := a.levelLocked(pack, level); locked {
 			a.view.FlashStatus(reason)
 			return
 		}
@@ -622,7 +869,7 @@ func (a *App) OnBackToMainMenu() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	if a.activeLevel {
-		a.stopLevelRuntime(ctx)
+		a.exitLevel(ctx)
 	}
 	a.menuOpen = false
 	a.hintsOpen = false
@@ -713,17 +960,8 @@ func (a *App) executeCheck(manual bool, source string) {
 	}
 	a.checkAttempt++
 
-	checks := a.levelChecksForGrading()
-	for _, bonus := range a.level.Scoring.CmdlogBonuses {
-		checks = append(checks, grading.CheckSpec{
-			ID:       bonus.ID,
-			Type:     "cmdlog_contains_regex",
-			Required: false,
-			Points:   bonus.Points,
-			Pattern:  bonus.Pattern,
-			MinCount: 1,
-		})
-	}
+	level := a.stageLevel()
+	checks := grading.ChecksForLevel(level)
 
 	started := time.Now()
 	var (
@@ -735,14 +973,14 @@ func (a *App) executeCheck(manual bool, source string) {
 			LevelID:        a.level.LevelID,
 			Checks:         checks,
 			Attempt:        a.checkAttempt,
-			BasePoints:     a.level.Scoring.BasePoints,
-			HintsUsed:      a.hintsUsed,
-			Resets:         a.resetCount,
-			TimePenalty:    a.level.Scoring.TimePenaltyPerSecond,
-			HintPenalty:    a.level.Scoring.HintPenaltyPoints,
-			ResetPenalty:   a.level.Scoring.ResetPenaltyPoints,
-			GraceSeconds:   a.level.Scoring.TimeGraceSeconds,
-			ElapsedSeconds: int(time.Since(a.startTime).Seconds()),
+			BasePoints:     level.Scoring.BasePoints,
+			HintsUsed:      a.hintsUsed - a.stageHintBase,
+			Resets:         a.resetCount - a.stageResetBase,
+			TimePenalty:    level.Scoring.TimePenaltyPerSecond,
+			HintPenalty:    level.Scoring.HintPenaltyPoints,
+			ResetPenalty:   level.Scoring.ResetPenaltyPoints,
+			GraceSeconds:   level.Scoring.TimeGraceSeconds,
+			ElapsedSeconds: int(time.Since(a.stageStart).Seconds()),
 			PackID:         a.pack.PackID,
 			PackVersion:    a.pack.Version,
 		})
@@ -760,14 +998,16 @@ func (a *App) executeCheck(manual bool, source string) {
 			Container:            a.handle.ContainerName(),
 			ImageRef:             ifThenElse(a.level.Image.Ref != "", a.level.Image.Ref, a.pack.Image.Ref),
 			WorkDir:              a.handle.WorkDir(),
+			Services:             a.handle.Services(),
+			Restricted:           a.pack.Trust.Restricted,
 			Checks:               checks,
-			BasePoints:           a.level.Scoring.BasePoints,
-			TimeGraceSeconds:     a.level.Scoring.TimeGraceSeconds,
-			TimePenaltyPerSecond: a.level.Scoring.TimePenaltyPerSecond,
-			HintPenaltyPoints:    a.level.Scoring.HintPenaltyPoints,
-			ResetPenaltyPoints:   a.level.Scoring.ResetPenaltyPoints,
-			HintsUsed:            a.hintsUsed,
-			Resets:               a.resetCount,
+			BasePoints:           level.Scoring.BasePoints,
+			TimeGraceSeconds:     level.Scoring.TimeGraceSeconds,
+			TimePenaltyPerSecond: level.Scoring.TimePenaltyPerSecond,
+			HintPenaltyPoints:    level.Scoring.HintPenaltyPoints,
+			ResetPenaltyPoints:   level.Scoring.ResetPenaltyPoints,
+			HintsUsed:            a.hintsUsed - a.stageHintBase,
+			Resets:               a.resetCount - a.stageResetBase,
 		})
 	}
 	if err != nil {
@@ -803,6 +1043,14 @@ func (a *App) executeCheck(manual bool, source string) {
 		}
 	}
 
+	if len(a.level.Stages) > 0 && result.Passed {
+		if a.passStage(ctx, result) {
+			return
+		}
+		result = grading.MergeStages(a.stageResults)
+		a.stageResults = nil
+	}
+
 	a.lastResult = result
 	_ = a.store.RecordCheckAttempt(ctx, a.runID, result.Passed)
 
@@ -824,7 +1072,11 @@ func (a *App) executeCheck(manual bool, source string) {
 		a.checkFails++
 	}
 
-	breakdown := make([]ui.BreakdownRow, 0, len(result.Score.Breakdown)+1)
+	breakdown := make([]ui.BreakdownRow, 0, len(result.Stages)+len(result.Score.Breakdown)+1)
+	for _, st := range result.Stages {
+		label := "stage " + cmp.Or(st.Title, st.StageID)
+		breakdown = append(breakdown, ui.BreakdownRow{Label: label, Value: fmt.Sprintf("%s • %d", (time.Duration(st.DurationMS) * time.Millisecond).Round(time.Second), st.Points)})
+	}
 	for _, row := range result.Score.Breakdown {
 		breakdown = append(breakdown, ui.BreakdownRow{Label: row.Kind, Value: fmt.Sprintf("%d", row.Points)})
 	}
@@ -832,6 +1084,7 @@ func (a *App) executeCheck(manual bool, source string) {
 
 	a.applyResultStreak(result.Passed, failedRun)
 	_ = a.store.UpsertLevelProgress(context.Background(), state.LevelProgressUpdate{
+		PackID:       a.pack.PackID,
 		LevelID:      a.level.LevelID,
 		Passed:       result.Passed,
 		Score:        result.Score.TotalPoints,
@@ -839,7 +1092,7 @@ func (a *App) executeCheck(manual bool, source string) {
 		LastPlayedTS: time.Now().UTC(),
 	})
 	a.refreshCatalog()
-	a.syncPlayingState(result.Score.TotalPoints, a.badgesFor(result.Passed))
+	a.syncPlayingState(a.projectedScore(result.Score.TotalPoints), a.badgesFor(result.Passed))
 	quietFail := a.autoCheckQuietFail
 	if manual || result.Passed || !quietFail {
 		a.resultOpen = true
@@ -988,14 +1241,14 @@ func (a *App) levelAutoCheckConfig() (string, time.Duration, bool) {
 	// enabled auto-check globally. This keeps gameplay deterministic and avoids
 	// background grading in the default manual-check mode.
 	if mode != "off" {
-		if raw := strings.TrimSpace(a.level.XAutoCheck.Mode); raw != "" {
+		if raw := strings.TrimSpace(a.level.AutoCheck.Mode); raw != "" {
 			mode = normalizeAutoCheckMode(raw)
 		}
-		if a.level.XAutoCheck.DebounceMS > 0 {
-			debounceMS = a.level.XAutoCheck.DebounceMS
+		if a.level.AutoCheck.DebounceMS > 0 {
+			debounceMS = a.level.AutoCheck.DebounceMS
 		}
-		if a.level.XAutoCheck.QuietFail != nil {
-			quietFail = *a.level.XAutoCheck.QuietFail
+		if a.level.AutoCheck.QuietFail != nil {
+			quietFail = *a.level.AutoCheck.QuietFail
 		}
 	}
 	return mode, time.Duration(debounceMS) * time.Millisecond, quietFail
@@ -1048,8 +1301,9 @@ func (a *App) autoCheckWatchPaths() []string {
 	}
 	workDir := a.handle.WorkDir()
 	seen := map[string]struct{}{}
-	out := make([]string, 0, len(a.level.Checks))
-	for _, check := range a.level.Checks {
+	checks := a.stageLevel().Checks
+	out := make([]string, 0, len(checks))
+	for _, check := range checks {
 		for _, raw := range []string{check.Path, check.CompareToPath} {
 			path := strings.TrimSpace(raw)
 			if path == "" {
@@ -1108,42 +1362,6 @@ func (a *App) autoCheckFilesSignature(paths []string) string {
 	return b.String()
 }
 
-func (a *App) levelChecksForGrading() []grading.CheckSpec {
-	out := make([]grading.CheckSpec, 0, len(a.level.Checks))
-	for _, c := range a.level.Checks {
-		required := c.Required == nil || *c.Required
-		out = append(out, grading.CheckSpec{
-			ID:             c.ID,
-			Type:           c.Type,
-			Description:    c.Description,
-			Required:       required,
-			Points:         c.Points,
-			OnFailMessage:  c.OnFailMessage,
-			OnPassMessage:  c.OnPassMessage,
-			Path:           c.Path,
-			Expected:       c.Expected,
-			Normalize:      grading.NormalizeSpec(c.Normalize),
-			Equals:         c.Equals,
-			Min:            c.Min,
-			Max:            c.Max,
-			Pattern:        c.Pattern,
-			Mode:           c.Mode,
-			MinMatches:     c.MinMatches,
-			Order:          c.Order,
-			Key:            c.Key,
-			Unique:         c.Unique,
-			IgnoreCase:     c.IgnoreCase,
-			Split:          grading.FileSplitSpec(c.Split),
-			Column:         c.Column,
-			Command:        c.Command,
-			CompareToPath:  c.CompareToPath,
-			TimeoutSeconds: c.TimeoutSeconds,
-			MinCount:       c.MinCount,
-		})
-	}
-	return out
-}
-
 func (a *App) OnReset() {
 	if !a.activeLevel {
 		a.view.FlashStatus("start a level first")
@@ -1193,7 +1411,7 @@ func (a *App) OnRevealHint() {
 	if !a.activeLevel {
 		return
 	}
-	for idx := a.hintRevealed; idx < len(a.level.Hints); idx++ {
+	for idx := a.hintRevealed; idx < len(a.stageLevel().Hints); idx++ {
 		if unlocked, reason := a.hintUnlocked(idx); unlocked {
 			a.hintRevealed = idx + 1
 			a.hintsUsed++
@@ -1238,7 +1456,7 @@ func (a *App) OnJournalExplainAI() {
 		return
 	}
 	latest := entries[len(entries)-1]
-	explainerText := buildJournalExplainText(latest.Command, a.level, a.checkStatus, a.lastResult.Passed)
+	explainerText := buildJournalExplainText(latest.Command, a.stageLevel(), a.checkStatus, a.lastResult.Passed)
 	a.view.SetInfo("AI Explain", explainerText, true)
 }
 
@@ -1263,6 +1481,7 @@ func (a *App) OnOpenSettings() {
 		StyleVariant:        a.cfg.UI.StyleVariant,
 		MotionLevel:         a.cfg.UI.MotionLevel,
 		MouseScope:          a.cfg.UI.MouseScope,
+		Locale:              a.cfg.UI.Locale,
 	}, true)
 }
 
@@ -1309,6 +1528,7 @@ func (a *App) OnApplySettings(update ui.SettingsState) {
 	updated.UI.StyleVariant = update.StyleVariant
 	updated.UI.MotionLevel = update.MotionLevel
 	updated.UI.MouseScope = update.MouseScope
+	updated.UI.Locale = update.Locale
 	if err := updated.Validate(); err != nil {
 		a.view.FlashStatus("settings rejected: " + err.Error())
 		return
@@ -1320,7 +1540,10 @@ func (a *App) OnApplySettings(update ui.SettingsState) {
 		"style_variant":          a.cfg.UI.StyleVariant,
 		"motion_level":           a.cfg.UI.MotionLevel,
 		"mouse_scope":            a.cfg.UI.MouseScope,
+		"locale":                 a.cfg.UI.Locale,
 	})
+	a.view.SetLocale(a.locale())
+	a.refreshCatalog()
 	if a.activeLevel {
 		a.startAutoCheckLoop()
 	}
@@ -1347,6 +1570,14 @@ func (a *App) OnNextLevel() {
 		}
 		return
 	}
+	if a.mode == ModeToolDrill {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+		if err := a.advanceToolDrill(ctx); err != nil {
+			a.view.FlashStatus("tool playlist failed: " + err.Error())
+		}
+		return
+	}
 
 	a.advanceLevel()
 	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
@@ -1488,7 +1719,7 @@ func (a *App) applyDemoScenario(ctx context.Context, scenario string) error {
 	a.journalOpen = s.JournalOpen
 	a.elapsedOverride = "10s"
 	if a.hintsOpen {
-		a.hintRevealed = min(1, len(a.level.Hints))
+		a.hintRevealed = min(1, len(a.stageLevel().Hints))
 	}
 
 	if a.handle != nil && a.handle.IsMock() && s.Name != "pause_menu" {
@@ -1742,31 +1973,38 @@ func (a *App) startDevHTTP() error {
 
 func (a *App) catalog() []ui.PackSummary {
 	progressMap, _ := a.store.GetLevelProgressMap(context.Background())
+	passed, _ := a.store.GetPassedLevels(context.Background())
+	locale := a.locale()
 	out := make([]ui.PackSummary, 0, len(a.packs))
 	for _, p := range a.packs {
 		ps := ui.PackSummary{
-			PackID: p.PackID,
-			Name:   p.Name,
-			Levels: make([]ui.LevelSummary, 0, len(p.LoadedLevels)),
-		}
-		passedByLevel := map[string]bool{}
-		for levelID, progress := range progressMap {
-			passedByLevel[levelID] = progress.PassedCount > 0
+			PackID:     p.PackID,
+			Name:       p.Name,
+			Source:     p.Root.Source,
+			SourcePath: firstNonEmpty(p.Archive, p.Path),
+			Trust:      p.Trust.Status,
+			TrustKeyID: p.Trust.KeyID,
+			Restricted: p.Trust.Restricted,
+			Error:      p.LoadError,
+			Levels:     make([]ui.LevelSummary, 0, len(p.LoadedLevels)+len(p.BrokenLevels)),
 		}
 		for _, lv := range p.LoadedLevels {
-			progress := progressMap[lv.LevelID]
+			lv = lv.Localized(locale)
+			progress := progressMap[p.PackID+"/"+lv.LevelID]
 			locked := false
 			lockReason := ""
-			if a.mode == ModeCampaign && len(lv.XProgression.Prerequisites) > 0 {
-				missing := make([]string, 0, len(lv.XProgression.Prerequisites))
-				for _, prereq := range lv.XProgression.Prerequisites {
-					if !passedByLevel[prereq] {
-						missing = append(missing, prereq)
-					}
-				}
-				if len(missing) > 0 {
+			switch {
+			case a.mode != ModeCampaign:
+			case len(p.DependencyErrors) > 0:
+				locked = true
+				lockReason = "Pack dependencies: " + strings.Join(p.DependencyErrors, "; ")
+			case len(lv.DependencyErrors) > 0:
+				locked = true
+				lockReason = "Broken prerequisites: " + strings.Join(lv.DependencyErrors, "; ")
+			case len(lv.Progression.Prerequisites) > 0:
+				if missing := missingPrerequisites(a.packs, p, lv, passed, progressMap); len(missing) > 0 {
 					locked = true
-					lockReason = "Prerequisites: " + strings.Join(missing, ", ")
+					lockReason = "Prerequisites (master those from other packs): " + strings.Join(missing, ", ")
 				}
 			}
 			ps.Levels = append(ps.Levels, ui.LevelSummary{
@@ -1776,23 +2014,38 @@ func (a *App) catalog() []ui.PackSummary {
 				EstimatedMinutes: lv.EstimatedMinutes,
 				SummaryMD:        lv.SummaryMD,
 				ToolFocus:        append([]string(nil), lv.ToolFocus...),
-				ObjectiveBullets: append([]string(nil), lv.Objective.Bullets...),
-				Concepts:         append([]string(nil), lv.XTeaching.Concepts...),
-				Tier:             lv.XProgression.Tier,
-				Prerequisites:    append([]string(nil), lv.XProgression.Prerequisites...),
+				ObjectiveBullets: append([]string(nil), lv.AtStage(0).Objective.Bullets...),
+				Concepts:         append([]string(nil), lv.Teaching.Concepts...),
+				Tier:             lv.Progression.Tier,
+				Prerequisites:    append([]string(nil), lv.Progression.Prerequisites...),
 				Locked:           locked,
 				LockReason:       lockReason,
 				PassedCount:      progress.PassedCount,
 				BestScore:        progress.BestScore,
 			})
 		}
+		for _, b := range p.BrokenLevels {
+			ps.Levels = append(ps.Levels, ui.LevelSummary{
+				LevelID:    b.LevelID,
+				Title:      b.LevelID,
+				Locked:     true,
+				LockReason: b.Error,
+				Broken:     true,
+			})
+		}
 		out = append(out, ps)
 	}
 	return out
 }
 
+// locale is the locale for UI strings and level translations.
+func (a *App) locale() string {
+	return ui.ResolveLocale(a.cfg.UI.Locale)
+}
+
 func (a *App) refreshCatalog() {
 	a.view.SetCatalog(a.catalog())
+	a.view.SetTools(a.tools())
 }
 
 func (a *App) loadPersistedSettings(ctx context.Context) {
@@ -1817,6 +2070,9 @@ func (a *App) loadPersistedSettings(ctx context.Context) {
 	if v, ok := values["mouse_scope"]; ok {
 		a.cfg.UI.MouseScope = strings.TrimSpace(v)
 	}
+	if v, ok := values["locale"]; ok {
+		a.cfg.UI.Locale = strings.TrimSpace(v)
+	}
 	_ = a.cfg.Validate()
 }
 
@@ -1883,10 +2139,10 @@ func (a *App) resultSummary(passed bool) string {
 
 func currentScore(a *App) int {
 	if a.lastResult.Score.TotalPoints > 0 {
-		return a.lastResult.Score.TotalPoints
+		return a.projectedScore(a.lastResult.Score.TotalPoints)
 	}
-	if a.level.Scoring.BasePoints > 0 {
-		return a.level.Scoring.BasePoints
+	if base := a.stageLevel().Scoring.BasePoints; base > 0 {
+		return a.projectedScore(base)
 	}
 	return 1000
 }
@@ -2019,6 +2275,11 @@ func (a *App) loadOrCreateDailyPlan(ctx context.Context, day time.Time) ([]daily
 		if len(plan) == 0 {
 			return nil, 0, fmt.Errorf("no levels available")
 		}
+		for i, entry := range plan {
+			if pack, level, err := a.loader.FindLevel(a.packs, entry.PackID, entry.LevelID); err == nil {
+				plan[i].VariantID = a.unseenVariant(ctx, pack, level)
+			}
+		}
 		body, _ := json.Marshal(plan)
 		if err := a.store.UpsertDailyDrill(ctx, state.DailyDrill{
 			Day:            key,
@@ -2092,6 +2353,7 @@ func (a *App) advanceDailyDrill(ctx context.Context) error {
 	}
 	a.pack = pack
 	a.level = level
+	a.nextVariant = next.VariantID
 	a.dailyIndex = completed
 	a.refreshCatalog()
 	a.view.SetMainMenuState(a.mainMenuState())
@@ -2103,29 +2365,187 @@ func (a *App) advanceDailyDrill(ctx context.Context) error {
 	return nil
 }
 
-func (a *App) levelLocked(level levels.Level) (bool, string) {
-	if len(level.XProgression.Prerequisites) == 0 {
+// applyVariant applies the variant for a new run of a.level: the one
+// requested in nextVariant, else an unseen one for Daily Drills and replays
+// of passed levels, else the level's default.
+func (a *App) applyVariant(ctx context.Context) error {
+	id := a.nextVariant
+	a.nextVariant = ""
+	if len(a.level.Variants) == 0 {
+		return nil
+	}
+	if id != "" && !slices.Contains(a.level.VariantIDs(), id) {
+		// The level's variants changed since the run was recorded.
+		id = ""
+	}
+	if id == "" && (a.mode == ModeDailyDrill || a.levelPassed(ctx, a.pack, a.level)) {
+		id = a.unseenVariant(ctx, a.pack, a.level)
+	}
+	level, err := a.loader.PrepareVariant(ctx, a.pack, a.level, id)
+	if err != nil {
+		return err
+	}
+	a.level = level
+	a.logger.Info("level.variant", map[string]any{"level": a.level.LevelID, "variant": a.level.Variant})
+	return nil
+}
+
+func (a *App) runDatasetDir(levelID string, seed int64) string {
+	return filepath.Join(a.cfg.DataDir, "datasets", a.sessionID, fmt.Sprintf("%s-%d", levelID, seed))
+}
+
+// applyRunSeed gives seed: per_run levels the seed requested in nextSeed, or
+// a fresh one, and a dataset dir private to that seed.
+func (a *App) applyRunSeed() {
+	seed := a.nextSeed
+	a.nextSeed = nil
+	if !a.level.PerRunSeed() {
+		return
+	}
+	if seed == nil {
+		fresh := rand.Int63()
+		seed = &fresh
+	}
+	a.level = a.level.WithRunSeed(*seed, a.runDatasetDir(a.level.LevelID, *seed))
+	a.runDataset = a.level.DatasetHostPath
+	a.logger.Info("level.run_seed", map[string]any{"level": a.level.LevelID, "seed": *seed})
+}
+
+func (a *App) removeRunDataset() {
+	if a.runDataset == "" {
+		return
+	}
+	if err := os.RemoveAll(a.runDataset); err != nil {
+		a.logger.Error("dataset.remove_failed", map[string]any{"dir": a.runDataset, "error": err.Error()})
+	}
+	a.runDataset = ""
+}
+
+// removeStaleRunDatasets removes the per-run dataset dirs of every session
+// but activeSession, left behind by sessions that did not exit cleanly.
+func removeStaleRunDatasets(dataDir, activeSession string) error {
+	root := filepath.Join(dataDir, "datasets")
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, e := range entries {
+		// Session dirs are named by session UUID; leave anything else alone.
+		if _, err := uuid.Parse(e.Name()); err != nil || !e.IsDir() || e.Name() == activeSession {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(root, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *App) levelPassed(ctx context.Context, pack levels.Pack, level levels.Level) bool {
+	if passed, err := a.store.GetPassedLevels(ctx); err == nil && passed[pack.PackID+"/"+level.LevelID] > 0 {
+		return true
+	}
+	progress, err := a.store.GetLevelProgressMap(ctx)
+	return err == nil && progress[pack.PackID+"/"+level.LevelID].PassedCount > 0
+}
+
+func (a *App) unseenVariant(ctx context.Context, pack levels.Pack, level levels.Level) string {
+	if len(level.Variants) == 0 {
+		return ""
+	}
+	runs, err := a.store.GetVariantRuns(ctx, pack.PackID, level.LevelID)
+	if err != nil {
+		a.logger.Error("level.variant_runs_failed", map[string]any{"level": level.LevelID, "error": err.Error()})
+	}
+	return leastPlayedVariant(level, runs)
+}
+
+// leastPlayedVariant returns the variant with the fewest runs, earliest
+// declared first on ties. Runs from before the level had variants count
+// towards the default (first) variant.
+func leastPlayedVariant(level levels.Level, runs map[string]int) string {
+	best, bestRuns := "", -1
+	for i, v := range level.Variants {
+		n := runs[v.VariantID]
+		if i == 0 {
+			n += runs[""]
+		}
+		if bestRuns < 0 || n < bestRuns {
+			best, bestRuns = v.VariantID, n
+		}
+	}
+	return best
+}
+
+// levelLocked reports whether Campaign keeps level locked: its pack has
+// unsatisfied requires, one of its prerequisites is broken, or one is not met.
+func (a *App) levelLocked(pack levels.Pack, level levels.Level) (bool, string) {
+	if len(pack.DependencyErrors) > 0 {
+		return true, "Pack " + pack.PackID + " has unsatisfied dependencies: " + strings.Join(pack.DependencyErrors, "; ")
+	}
+	if len(level.DependencyErrors) > 0 {
+		return true, "Level " + level.LevelID + " has broken prerequisites: " + strings.Join(level.DependencyErrors, "; ")
+	}
+	if len(level.Progression.Prerequisites) == 0 {
 		return false, ""
 	}
 	progressMap, err := a.store.GetLevelProgressMap(context.Background())
 	if err != nil {
 		return false, ""
 	}
-	missing := make([]string, 0, len(level.XProgression.Prerequisites))
-	for _, prereq := range level.XProgression.Prerequisites {
-		if progressMap[prereq].PassedCount <= 0 {
-			missing = append(missing, prereq)
-		}
+	passed, err := a.store.GetPassedLevels(context.Background())
+	if err != nil {
+		return false, ""
 	}
+	missing := missingPrerequisites(a.packs, pack, level, passed, progressMap)
 	if len(missing) == 0 {
 		return false, ""
 	}
-	return true, "Level locked. Complete prerequisites: " + strings.Join(missing, ", ")
+	return true, "Level locked. Pass prerequisites (master those from other packs): " + strings.Join(missing, ", ")
+}
+
+// missingPrerequisites lists the prerequisites of level that are not met. A
+// prerequisite in the same pack needs a passed run; one in another pack must
+// be mastered (see levelMastered), which needs that pack loaded from packs.
+// passed and progress are keyed by "pack_id/level_id".
+func missingPrerequisites(packs []levels.Pack, pack levels.Pack, level levels.Level, passed map[string]int, progress map[string]state.LevelProgress) []string {
+	missing := []string{}
+	for _, ref := range level.PrerequisiteRefs(pack) {
+		if ref.PackID == pack.PackID {
+			if passed[ref.String()] > 0 {
+				continue
+			}
+			missing = append(missing, ref.LevelID)
+			continue
+		}
+		if refLevel, ok := findLoadedLevel(packs, ref); ok && passed[ref.String()] > 0 && levelMastered(refLevel, progress[ref.String()]) {
+			continue
+		}
+		missing = append(missing, ref.String())
+	}
+	return missing
+}
+
+func findLoadedLevel(packs []levels.Pack, ref levels.LevelRef) (levels.Level, bool) {
+	for _, p := range packs {
+		if p.PackID != ref.PackID {
+			continue
+		}
+		for _, lv := range p.LoadedLevels {
+			if lv.LevelID == ref.LevelID {
+				return lv, true
+			}
+		}
+	}
+	return levels.Level{}, false
 }
 
 func (a *App) sessionGoalsForLevel() []string {
 	goals := []string{"Finish the level"}
-	mastery := a.level.XProgression.Mastery
+	mastery := a.level.Progression.Mastery
 	if mastery.MaxHints > 0 {
 		goals = append(goals, fmt.Sprintf("Use at most %d hint(s)", mastery.MaxHints))
 	} else {
@@ -2143,11 +2563,8 @@ func (a *App) sessionGoalsForLevel() []string {
 }
 
 func (a *App) ensureLevelImage(ctx context.Context) (string, error) {
-	image := a.pack.Image.Ref
-	if strings.TrimSpace(a.level.Image.Ref) != "" {
-		image = strings.TrimSpace(a.level.Image.Ref)
-	}
-	if strings.TrimSpace(image) == "" {
+	image := a.level.ImageRef(a.pack)
+	if image == "" {
 		return "", fmt.Errorf("no image configured for %s/%s", a.pack.PackID, a.level.LevelID)
 	}
 	if a.cfg.SandboxMode == "mock" || a.engine.Name == "mock" {
@@ -2213,36 +2630,15 @@ func (a *App) buildPackImage(ctx context.Context, engine string, pack levels.Pac
 	if pack.Image.Build == nil {
 		return fmt.Errorf("pack %s has no image.build config", pack.PackID)
 	}
-	build := pack.Image.Build
-	contextDir := filepath.Join(pack.Path, build.ContextDir)
-	dockerfile := strings.TrimSpace(build.Dockerfile)
-	if dockerfile == "" {
-		dockerfile = "Dockerfile"
+	if pack.Trust.Restricted {
+		return fmt.Errorf("pack %s is %s: image.build is disabled: %w (trust its signing key or use --trust-policy off)", pack.PackID, pack.Trust.Status, levels.ErrSandboxOnly)
 	}
-	dockerfilePath := filepath.Join(contextDir, dockerfile)
-	args := []string{"build", "-t", pack.Image.Ref, "-f", dockerfilePath}
-	if strings.TrimSpace(build.Target) != "" {
-		args = append(args, "--target", strings.TrimSpace(build.Target))
-	}
-	keys := make([]string, 0, len(build.Args))
-	for k := range build.Args {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-	for _, k := range keys {
-		args = append(args, "--build-arg", fmt.Sprintf("%s=%s", k, build.Args[k]))
-	}
-	args = append(args, contextDir)
 	a.logger.Info("image.ensure.build", map[string]any{
 		"engine": engine,
 		"pack":   pack.PackID,
 		"image":  pack.Image.Ref,
 	})
-	out, err := exec.CommandContext(ctx, engine, args...).CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("%s build failed for %s: %s", engine, pack.Image.Ref, strings.TrimSpace(string(out)))
-	}
-	return nil
+	return sandbox.BuildImage(ctx, engine, packImageBuild(pack))
 }
 
 func (a *App) pullImage(ctx context.Context, engine, image string) error {
@@ -2250,28 +2646,26 @@ func (a *App) pullImage(ctx context.Context, engine, image string) error {
 		"engine": engine,
 		"image":  image,
 	})
-	out, err := exec.CommandContext(ctx, engine, "pull", image).CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("%s pull failed for %s: %s", engine, image, strings.TrimSpace(string(out)))
-	}
-	return nil
+	return sandbox.PullImage(ctx, engine, image)
 }
 
 func (a *App) imageExists(ctx context.Context, engine, image string) (bool, error) {
-	out, err := exec.CommandContext(ctx, engine, "image", "inspect", image).CombinedOutput()
-	if err != nil {
-		msg := strings.ToLower(strings.TrimSpace(string(out)))
-		// Normal "missing image" output from docker/podman.
-		if strings.Contains(msg, "no such image") || strings.Contains(msg, "not found") || strings.Contains(msg, "unable to find") {
-			return false, nil
-		}
-		return false, fmt.Errorf("%s image inspect failed for %s: %s", engine, image, strings.TrimSpace(string(out)))
+	return sandbox.ImageExists(ctx, engine, image)
+}
+
+func packImageBuild(pack levels.Pack) sandbox.ImageBuild {
+	build := pack.Image.Build
+	return sandbox.ImageBuild{
+		Ref:        pack.Image.Ref,
+		ContextDir: filepath.Join(pack.Path, build.ContextDir),
+		Dockerfile: build.Dockerfile,
+		Target:     build.Target,
+		Args:       build.Args,
 	}
-	return len(out) > 0, nil
 }
 
 func (a *App) reviewDaysForLevel() []int {
-	days := append([]int(nil), a.level.XTeaching.ReviewDays...)
+	days := append([]int(nil), a.level.Teaching.ReviewDays...)
 	if len(days) == 0 {
 		return []int{1, 3, 7}
 	}
@@ -2288,8 +2682,8 @@ func (a *App) reviewDaysForLevel() []int {
 }
 
 func (a *App) enqueueSpacedReviews(ctx context.Context) error {
-	concepts := make([]string, 0, len(a.level.XTeaching.Concepts))
-	for _, concept := range a.level.XTeaching.Concepts {
+	concepts := make([]string, 0, len(a.level.Teaching.Concepts))
+	for _, concept := range a.level.Teaching.Concepts {
 		concept = strings.TrimSpace(concept)
 		if concept != "" {
 			concepts = append(concepts, concept)
@@ -2301,6 +2695,16 @@ func (a *App) enqueueSpacedReviews(ctx context.Context) error {
 	return a.store.EnqueueReviewConcepts(ctx, a.level.LevelID, concepts, a.reviewDaysForLevel(), time.Now())
 }
 
+// serviceSpecs converts the level's services for sandbox.StartSpec.
+func serviceSpecs(level levels.Level) []sandbox.ServiceSpec {
+	runs := level.ServiceRuns()
+	out := make([]sandbox.ServiceSpec, 0, len(runs))
+	for _, run := range runs {
+		out = append(out, sandbox.ServiceSpec(run))
+	}
+	return out
+}
+
 func containerName(sessionID, levelID string) string {
 	safe := regexp.MustCompile(`[^a-zA-Z0-9_.-]`).ReplaceAllString(levelID, "_")
 	short := sessionID
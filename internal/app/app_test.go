@@ -8,18 +8,23 @@ import (
 	"testing"
 	"time"
 
+	"clidojo/internal/grading"
 	"clidojo/internal/levels"
+	"clidojo/internal/state"
+	"clidojo/internal/term"
+	"clidojo/internal/ui"
 )
 
 type fakeHandle struct{ work string }
 
-func (f fakeHandle) ShellCommand() []string     { return nil }
-func (f fakeHandle) Stop(context.Context) error { return nil }
-func (f fakeHandle) WorkDir() string            { return f.work }
-func (f fakeHandle) ContainerName() string      { return "mock" }
-func (f fakeHandle) Cwd() string                { return "" }
-func (f fakeHandle) Env() []string              { return nil }
-func (f fakeHandle) IsMock() bool               { return true }
+func (f fakeHandle) ShellCommand() []string      { return nil }
+func (f fakeHandle) Stop(context.Context) error  { return nil }
+func (f fakeHandle) WorkDir() string             { return f.work }
+func (f fakeHandle) ContainerName() string       { return "mock" }
+func (f fakeHandle) Cwd() string                 { return "" }
+func (f fakeHandle) Env() []string               { return nil }
+func (f fakeHandle) IsMock() bool                { return true }
+func (f fakeHandle) Services() map[string]string { return nil }
 
 func TestTagsForCommand(t *testing.T) {
 	tags := tagsForCommand("find . -type f -print0 | xargs -0 sha1sum")
@@ -78,7 +83,7 @@ func TestLevelAutoCheckConfigDefaultsAndOverride(t *testing.T) {
 	}
 
 	q := false
-	a.level.XAutoCheck = levels.AutoCheckExtension{
+	a.level.AutoCheck = levels.AutoCheckSpec{
 		Mode:       "command_debounce",
 		DebounceMS: 250,
 		QuietFail:  &q,
@@ -117,7 +122,7 @@ func TestLevelAutoCheckConfigIgnoresLevelOverrideWhenGlobalOff(t *testing.T) {
 			},
 		},
 		level: levels.Level{
-			XAutoCheck: levels.AutoCheckExtension{
+			AutoCheck: levels.AutoCheckSpec{
 				Mode:       "command_and_fs_debounce",
 				DebounceMS: 1200,
 				QuietFail:  &q,
@@ -294,3 +299,339 @@ func TestResultSummaryIncludesNextChallengeOnPass(t *testing.T) {
 		t.Fatalf("unexpected fail summary: %q", gotFail)
 	}
 }
+
+func TestPackSearchRootsPrecedence(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "/xdg")
+	t.Setenv("CLIDOJO_PACKS_PATH", "/env/a"+string(os.PathListSeparator)+"/env/b")
+	roots := packSearchRoots(Config{PacksDirs: []string{"/flag/one", "/flag/two"}})
+	got := make([]string, 0, len(roots))
+	for _, r := range roots {
+		got = append(got, r.Source+":"+r.Path)
+	}
+	want := []string{
+		"flag:/flag/one",
+		"flag:/flag/two",
+		"env:/env/a",
+		"env:/env/b",
+		"user:/xdg/clidojo/packs",
+		"system:/usr/share/clidojo/packs",
+	}
+	if len(got) != len(want)+1 || got[len(got)-1] != "builtin:"+builtinPacksDir() {
+		t.Fatalf("unexpected roots %v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("root %d: got %q want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestMissingPrerequisitesResolvesQualifiedRefs(t *testing.T) {
+	core := levels.Pack{PackID: "builtin-core", LoadedLevels: []levels.Level{
+		{LevelID: "level-001-pipes-101"},
+		{LevelID: "level-003-top-ips"},
+	}}
+	pack := levels.Pack{PackID: "incident-response"}
+	level := levels.Level{Progression: levels.ProgressionSpec{
+		Prerequisites: []string{"builtin-core/level-003-top-ips", "ir-001-triage", "builtin-core/level-001-pipes-101"},
+	}}
+	passed := map[string]int{"builtin-core/level-001-pipes-101": 1}
+	// Same level_id passed in another pack must not satisfy the qualified ref.
+	progress := map[string]state.LevelProgress{
+		"incident-response/level-003-top-ips": {PassedCount: 1},
+		"builtin-core/level-001-pipes-101":    {PassedCount: 1},
+	}
+	got := missingPrerequisites([]levels.Pack{core, pack}, pack, level, passed, progress)
+	want := []string{"builtin-core/level-003-top-ips", "ir-001-triage"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestMissingPrerequisitesRequiresMasteryAcrossPacks(t *testing.T) {
+	core := levels.Pack{PackID: "builtin-core", LoadedLevels: []levels.Level{{
+		LevelID:     "level-003-top-ips",
+		Progression: levels.ProgressionSpec{Mastery: levels.ProgressionMastery{MinScore: 800}},
+	}}}
+	pack := levels.Pack{PackID: "incident-response", LoadedLevels: []levels.Level{{LevelID: "ir-001-triage"}}}
+	level := levels.Level{Progression: levels.ProgressionSpec{
+		Prerequisites: []string{"builtin-core/level-003-top-ips", "ir-001-triage"},
+	}}
+	passed := map[string]int{"builtin-core/level-003-top-ips": 1, "incident-response/ir-001-triage": 1}
+	packs := []levels.Pack{core, pack}
+
+	// A pass below the mastery bar unlocks same-pack prerequisites only.
+	progress := map[string]state.LevelProgress{
+		"builtin-core/level-003-top-ips":  {PassedCount: 1, BestScore: 500},
+		"incident-response/ir-001-triage": {PassedCount: 1, BestScore: 100},
+	}
+	got := missingPrerequisites(packs, pack, level, passed, progress)
+	if strings.Join(got, ",") != "builtin-core/level-003-top-ips" {
+		t.Fatalf("expected the unmastered cross-pack level, got %v", got)
+	}
+
+	progress["builtin-core/level-003-top-ips"] = state.LevelProgress{PassedCount: 2, BestScore: 900}
+	if got := missingPrerequisites(packs, pack, level, passed, progress); len(got) != 0 {
+		t.Fatalf("expected mastered prerequisites to unlock, got %v", got)
+	}
+	// The referenced pack must be loaded for its mastery bar to be checked.
+	if got := missingPrerequisites([]levels.Pack{pack}, pack, level, passed, progress); strings.Join(got, ",") != "builtin-core/level-003-top-ips" {
+		t.Fatalf("expected a missing pack to keep the level locked, got %v", got)
+	}
+}
+
+func TestMissingPrerequisitesKeepsPacksSharingALevelIDApart(t *testing.T) {
+	mastery := levels.ProgressionSpec{Mastery: levels.ProgressionMastery{MinScore: 800}}
+	core := levels.Pack{PackID: "core", LoadedLevels: []levels.Level{{LevelID: "intro", Progression: mastery}}}
+	extra := levels.Pack{PackID: "extra", LoadedLevels: []levels.Level{{LevelID: "intro", Progression: mastery}}}
+	packs := []levels.Pack{core, extra}
+	// Only extra/intro is passed and mastered.
+	passed := map[string]int{"extra/intro": 1}
+	progress := map[string]state.LevelProgress{"extra/intro": {PackID: "extra", LevelID: "intro", PassedCount: 1, BestScore: 900}}
+
+	sameCore := levels.Level{Progression: levels.ProgressionSpec{Prerequisites: []string{"intro"}}}
+	if got := missingPrerequisites(packs, core, sameCore, passed, progress); strings.Join(got, ",") != "intro" {
+		t.Fatalf("a pass in extra should not unlock core's intro, got %v", got)
+	}
+	if got := missingPrerequisites(packs, extra, sameCore, passed, progress); len(got) != 0 {
+		t.Fatalf("extra's own intro is passed, got %v", got)
+	}
+	crossCore := levels.Level{Progression: levels.ProgressionSpec{Prerequisites: []string{"core/intro"}}}
+	if got := missingPrerequisites(packs, extra, crossCore, passed, progress); strings.Join(got, ",") != "core/intro" {
+		t.Fatalf("mastering extra/intro should not satisfy core/intro, got %v", got)
+	}
+}
+
+func TestLeastPlayedVariantPrefersUnseen(t *testing.T) {
+	level := levels.Level{Variants: []levels.VariantSpec{{VariantID: "top5"}, {VariantID: "top3"}, {VariantID: "top4"}}}
+	cases := []struct {
+		runs map[string]int
+		want string
+	}{
+		{nil, "top5"},
+		// Runs from before the level had variants count as the default.
+		{map[string]int{"": 2}, "top3"},
+		{map[string]int{"top5": 1, "top3": 1}, "top4"},
+		{map[string]int{"top5": 2, "top3": 1, "top4": 1}, "top3"},
+	}
+	for _, tc := range cases {
+		if got := leastPlayedVariant(level, tc.runs); got != tc.want {
+			t.Fatalf("runs %v: got %q want %q", tc.runs, got, tc.want)
+		}
+	}
+}
+
+func TestApplyRunSeedReplaysContinueSeed(t *testing.T) {
+	perRun := levels.Level{LevelID: "level-gen"}
+	perRun.Filesystem.Dataset.Source = "generator"
+	perRun.Filesystem.Dataset.Generator = &levels.GeneratorSpec{Command: "./gen.sh", Seed: &levels.DatasetSeed{PerRun: true}}
+	a := &App{cfg: Config{DataDir: t.TempDir()}, sessionID: "s1", level: perRun}
+
+	a.applyRunSeed()
+	if a.level.RunSeed == nil || !strings.Contains(a.level.DatasetHostPath, filepath.Join("datasets", "s1")) {
+		t.Fatalf("expected a fresh seed and run dataset dir, got seed=%v dir=%q", a.level.RunSeed, a.level.DatasetHostPath)
+	}
+
+	seed := int64(99)
+	a.level = perRun
+	a.nextSeed = &seed
+	a.applyRunSeed()
+	if a.level.RunSeed == nil || *a.level.RunSeed != 99 || a.nextSeed != nil {
+		t.Fatalf("expected Continue seed 99 to be replayed, got %v", a.level.RunSeed)
+	}
+
+	a.level = levels.Level{LevelID: "level-dir"}
+	a.nextSeed = &seed
+	a.applyRunSeed()
+	if a.level.RunSeed != nil || a.nextSeed != nil {
+		t.Fatalf("fixed-dataset levels must not get a run seed")
+	}
+}
+
+func TestPackSourcesDetectsLevelEdits(t *testing.T) {
+	root := t.TempDir()
+	levelDir := filepath.Join(root, "my-pack", "levels", "level-one")
+	if err := os.MkdirAll(levelDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{filepath.Join(root, "my-pack", "pack.yaml"), filepath.Join(levelDir, "level.yaml")} {
+		if err := os.WriteFile(f, []byte("kind: x\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	roots := []levels.PackRoot{{Path: root}}
+	before := packSources(roots)
+	if len(before) != 1 {
+		t.Fatalf("expected one pack source, got %v", before)
+	}
+	if changed := changedSources(before, packSources(roots)); len(changed) != 0 {
+		t.Fatalf("expected no changes, got %v", changed)
+	}
+	if err := os.WriteFile(filepath.Join(levelDir, "level.yaml"), []byte("kind: level\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	changed := changedSources(before, packSources(roots))
+	if len(changed) != 1 || changed[0] != filepath.Join(root, "my-pack") {
+		t.Fatalf("expected my-pack to change, got %v", changed)
+	}
+	if err := os.RemoveAll(filepath.Join(root, "my-pack")); err != nil {
+		t.Fatal(err)
+	}
+	if changed := changedSources(before, packSources(roots)); len(changed) != 1 {
+		t.Fatalf("expected the removed pack to be reported, got %v", changed)
+	}
+}
+
+func TestToolSummariesAggregateMastery(t *testing.T) {
+	mastery := levels.ProgressionSpec{Mastery: levels.ProgressionMastery{MinScore: 800}}
+	packs := []levels.Pack{
+		{PackID: "core", Tools: []levels.PackTool{{ToolID: "pipes", Name: "Pipes"}, {ToolID: "awk", Name: "awk", SummaryMD: "Fields."}}, LoadedLevels: []levels.Level{
+			{LevelID: "l1", Title: "One", ToolFocus: []string{"awk", "sort"}, Progression: mastery},
+			{LevelID: "l2", Title: "Two", ToolFocus: []string{"awk"}, Progression: mastery},
+			{LevelID: "l3", Title: "Three", ToolFocus: []string{"awk"}},
+		}},
+		{PackID: "extra", Tools: []levels.PackTool{{ToolID: "awk", Name: "AWK (dup)"}}, DependencyErrors: []string{"missing core"}, LoadedLevels: []levels.Level{
+			{LevelID: "x1", Title: "X", ToolFocus: []string{"awk"}},
+		}},
+	}
+	progress := map[string]state.LevelProgress{
+		"core/l1": {PassedCount: 1, BestScore: 900},
+		"core/l2": {PassedCount: 2, BestScore: 700},
+		// A same-named level in another pack does not count for extra/x1.
+		"core/x1": {PassedCount: 1, BestScore: 900},
+	}
+	tools := toolSummaries(packs, progress, "en")
+	ids := []string{}
+	for _, tool := range tools {
+		ids = append(ids, tool.ToolID)
+	}
+	if strings.Join(ids, ",") != "pipes,awk,sort" {
+		t.Fatalf("unexpected tool order %v", ids)
+	}
+	awk := tools[1]
+	if awk.Name != "awk" || len(awk.Levels) != 4 || awk.Passed != 2 || awk.Mastered != 1 {
+		t.Fatalf("unexpected awk summary %+v", awk)
+	}
+	if !awk.Levels[3].Locked {
+		t.Fatalf("levels of packs with dependency errors should be locked")
+	}
+
+	plan := toolPlaylist(awk)
+	if len(plan) != 2 || plan[0].LevelID != "l2" || plan[1].LevelID != "l3" {
+		t.Fatalf("expected the unmastered unlocked levels, got %+v", plan)
+	}
+	if plan := toolPlaylist(tools[2]); len(plan) != 1 || plan[0].LevelID != "l1" {
+		t.Fatalf("a fully mastered tool should replay its levels, got %+v", plan)
+	}
+}
+
+func TestStagesScopeHintsChecksAndScore(t *testing.T) {
+	level := levels.Level{LevelID: "level-incident", Scoring: levels.ScoringSpec{BasePoints: 1000}}
+	level.Stages = []levels.StageSpec{
+		{StageID: "find", Hints: []levels.HintSpec{{HintID: "h1", TextMD: "look"}}, Checks: []levels.CheckSpec{{ID: "bad_log"}}},
+		{StageID: "extract", Hints: []levels.HintSpec{{HintID: "h2", TextMD: "grep"}}, Checks: []levels.CheckSpec{{ID: "ips"}}},
+	}
+	a := &App{level: level}
+	a.enterStage(0, time.Now())
+	if _, ok := a.checkStatus["ips"]; ok || a.checkStatus["bad_log"] != "pending" {
+		t.Fatalf("stage 0 should only track its own checks: %v", a.checkStatus)
+	}
+	if got := currentScore(a); got != 1000 {
+		t.Fatalf("expected the full base before any stage passed, got %d", got)
+	}
+
+	a.hintsUsed, a.hintRevealed = 1, 1
+	a.stageResults = append(a.stageResults, grading.Result{Passed: true, Score: grading.Score{TotalPoints: 420}})
+	a.enterStage(1, time.Now())
+	if a.hintRevealed != 0 || a.stageHintBase != 1 || a.checkStatus["ips"] != "pending" {
+		t.Fatalf("stage 1 should start with its own hints and checks: revealed=%d base=%d %v", a.hintRevealed, a.stageHintBase, a.checkStatus)
+	}
+	if rows := a.buildHintRows(); len(rows) != 1 || rows[0].Text != "grep" {
+		t.Fatalf("expected stage 1 hints, got %+v", rows)
+	}
+	if got := currentScore(a); got != 420+500 {
+		t.Fatalf("expected passed stage points plus the open stage's share, got %d", got)
+	}
+}
+
+func TestPassingAnIntermediateStageRecordsNoAttempt(t *testing.T) {
+	ctx := context.Background()
+	store, err := state.NewSQLite(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if err := store.EnsureSchema(ctx); err != nil {
+		t.Fatal(err)
+	}
+	runID, err := store.StartLevelRun(ctx, state.LevelRun{SessionID: "s", PackID: "p", LevelID: "level-incident", StartTS: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	level := levels.Level{LevelID: "level-incident", Scoring: levels.ScoringSpec{BasePoints: 1000}}
+	level.Stages = []levels.StageSpec{{StageID: "find"}, {StageID: "extract"}}
+	a := &App{store: store, view: ui.New(ui.Options{}), level: level, runID: runID}
+	a.enterStage(0, time.Now())
+
+	if !a.passStage(ctx, grading.Result{Passed: true}) || a.stage != 1 {
+		t.Fatalf("expected to advance to stage 1, at %d", a.stage)
+	}
+	last, err := store.GetLastRun(ctx)
+	if err != nil || last == nil {
+		t.Fatalf("last run: %v", err)
+	}
+	if last.Attempts != 0 || last.LastPassed {
+		t.Fatalf("an intermediate stage pass should not be recorded as an attempt: %+v", last)
+	}
+}
+
+func TestRunDatasetsAreRemoved(t *testing.T) {
+	dataDir := t.TempDir()
+	session := "6f1d8e1c-3f7a-4c2e-9b1a-0d2f5e6a7b8c"
+	stale := "0b9c6a4e-1d2f-4e3a-8b7c-5d6e7f8a9b0c"
+	level := levels.Level{LevelID: "level-gen"}
+	level.Filesystem.Dataset.Source = "generator"
+	level.Filesystem.Dataset.Generator = &levels.GeneratorSpec{Command: "./gen.sh", Seed: &levels.DatasetSeed{PerRun: true}}
+	a := &App{cfg: Config{DataDir: dataDir}, sessionID: session, level: level, term: term.NewTerminalPane(nil)}
+	a.applyRunSeed()
+	run := a.level.DatasetHostPath
+	if filepath.Dir(filepath.Dir(run)) != filepath.Join(dataDir, "datasets") {
+		t.Fatalf("unexpected run dataset dir %s", run)
+	}
+	keep := filepath.Join(dataDir, "datasets", "not-a-session")
+	for _, dir := range []string{run, filepath.Join(dataDir, "datasets", stale, "level-gen-1"), keep} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := removeStaleRunDatasets(dataDir, session); err != nil {
+		t.Fatalf("sweep: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "datasets", stale)); !os.IsNotExist(err) {
+		t.Fatalf("expected the stale session dir to be removed, got %v", err)
+	}
+	for _, dir := range []string{run, keep} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Fatalf("sweep removed %s: %v", dir, err)
+		}
+	}
+
+	a.exitLevel(context.Background())
+	if _, err := os.Stat(run); !os.IsNotExist(err) {
+		t.Fatalf("expected exiting the level to remove its dataset, got %v", err)
+	}
+}
+
+func TestBrokenPrerequisitesLockOnlyTheirLevel(t *testing.T) {
+	broken := levels.Level{LevelID: "b", DependencyErrors: []string{"prerequisite p/nope: no such level in pack p"}}
+	fine := levels.Level{LevelID: "a"}
+	pack := levels.Pack{PackID: "p", LoadedLevels: []levels.Level{fine, broken}}
+	a := &App{mode: ModeCampaign, packs: []levels.Pack{pack}}
+	if locked, reason := a.levelLocked(pack, broken); !locked || !strings.Contains(reason, "no such level") {
+		t.Fatalf("expected the broken level to be locked, got %v %q", locked, reason)
+	}
+	if locked, reason := a.levelLocked(pack, fine); locked {
+		t.Fatalf("a broken prerequisite elsewhere should not lock the pack: %q", reason)
+	}
+}
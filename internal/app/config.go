@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"clidojo/internal/levels"
 )
 
 // Config controls runtime behavior for the TUI app.
@@ -18,6 +21,8 @@ type Config struct {
 	EngineOverride string
 	ASCIIOnly      bool
 	DataDir        string
+	PacksDirs      []string
+	TrustPolicy    string
 	KeepArtifacts  bool
 	Gameplay       GameplayConfig
 	UI             UIConfig
@@ -32,12 +37,16 @@ type UIConfig struct {
 	StyleVariant string
 	MotionLevel  string
 	MouseScope   string
+	// Locale is "auto" (LC_ALL, LC_MESSAGES or LANG) or a language such as
+	// "de"; it picks UI strings and level translations.
+	Locale string
 }
 
 func DefaultConfig() Config {
 	return Config{
 		SandboxMode: "auto",
 		DevHTTP:     "127.0.0.1:17321",
+		TrustPolicy: levels.TrustPolicySandboxOnly,
 		Gameplay: GameplayConfig{
 			AutoCheckDefault:    "off",
 			AutoCheckDebounceMS: 800,
@@ -46,6 +55,7 @@ func DefaultConfig() Config {
 			StyleVariant: "modern_arcade",
 			MotionLevel:  "full",
 			MouseScope:   "scoped",
+			Locale:       "auto",
 		},
 	}
 }
@@ -60,6 +70,13 @@ func (c *Config) Validate() error {
 	if c.EngineOverride != "" && c.EngineOverride != "docker" && c.EngineOverride != "podman" {
 		return fmt.Errorf("invalid engine override %q", c.EngineOverride)
 	}
+	switch c.TrustPolicy {
+	case "":
+		c.TrustPolicy = levels.TrustPolicySandboxOnly
+	case levels.TrustPolicyOff, levels.TrustPolicySandboxOnly, levels.TrustPolicyRefuse:
+	default:
+		return fmt.Errorf("invalid trust policy %q", c.TrustPolicy)
+	}
 	switch c.Gameplay.AutoCheckDefault {
 	case "", "off", "manual", "command_debounce", "command_and_fs_debounce":
 	default:
@@ -95,6 +112,9 @@ func (c *Config) Validate() error {
 	if c.UI.MouseScope == "" {
 		c.UI.MouseScope = "scoped"
 	}
+	if strings.TrimSpace(c.UI.Locale) == "" {
+		c.UI.Locale = "auto"
+	}
 
 	if c.DataDir == "" {
 		home, err := os.UserHomeDir()
@@ -0,0 +1,195 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"clidojo/internal/levels"
+	"clidojo/internal/ui"
+)
+
+// packWatchInterval is how often --dev polls the pack roots for edits.
+const packWatchInterval = time.Second
+
+// startPackWatch polls every pack root and reloads packs whose files change,
+// so levels can be edited without restarting the TUI.
+func (a *App) startPackWatch() {
+	roots := packSearchRoots(a.cfg)
+	last := packSources(roots)
+	ctx, cancel := context.WithCancel(context.Background())
+	a.packWatchStop = cancel
+	go func() {
+		ticker := time.NewTicker(packWatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				cur := packSources(roots)
+				if changed := changedSources(last, cur); len(changed) > 0 {
+					last = cur
+					a.reloadPacks(roots, changed)
+				}
+			}
+		}
+	}()
+}
+
+func (a *App) reloadPacks(roots []levels.PackRoot, changed []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	packs := a.packs
+	var err error
+	for _, path := range changed {
+		if _, statErr := os.Stat(path); statErr != nil || !packLoadedFrom(packs, path) {
+			// Packs were added or removed: rescan everything.
+			packs, err = a.loader.LoadPackRoots(ctx, roots)
+			break
+		}
+		if packs, err = a.loader.ReloadPack(ctx, packs, path); err != nil {
+			break
+		}
+	}
+	if err == nil && firstPlayablePack(packs) < 0 {
+		err = noPlayableLevelsError(packs, roots)
+	}
+	if err != nil {
+		a.logger.Error("dev.reload_failed", map[string]any{"changed": changed, "error": err.Error()})
+		a.view.SetInfo("Pack Reload Failed", err.Error()+"\n\nThe previous packs stay loaded; fix the file and save again.", true)
+		return
+	}
+
+	a.packs = packs
+	if pack, _, findErr := a.loader.FindLevel(packs, a.pack.PackID, a.level.LevelID); findErr == nil {
+		a.pack = pack
+	}
+	a.logger.Info("dev.reloaded", map[string]any{"changed": changed})
+	a.refreshCatalog()
+	a.view.SetMainMenuState(a.mainMenuState())
+	a.view.FlashStatus(fmt.Sprintf("Reloaded %d pack(s)", len(changed)))
+	if problems := reloadProblems(packs, changed); len(problems) > 0 {
+		a.view.SetInfo("Pack Reload Problems", strings.Join(problems, "\n")+"\n\nBroken entries are disabled in level select; fix the file and save again.", true)
+		return
+	}
+
+	if sig := levels.DirSignature(a.level.Path); a.activeLevel && a.levelSig != "" && sig != a.levelSig {
+		a.levelSig = sig
+		a.view.SetInfo(ui.InfoTitleLevelChanged, fmt.Sprintf("%s changed on disk.\n\nr: Restage it in place (keeps this run)", a.level.LevelID), true)
+	}
+}
+
+// OnRestageLevel reloads the active level's definition and restages it
+// without starting a new run.
+func (a *App) OnRestageLevel() {
+	if !a.activeLevel {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	pack, level, err := a.loader.FindLevel(a.packs, a.pack.PackID, a.level.LevelID)
+	if err == nil && len(level.Variants) > 0 {
+		variant := a.level.Variant
+		if !slices.Contains(level.VariantIDs(), variant) {
+			variant = ""
+		}
+		level, err = a.loader.PrepareVariant(ctx, pack, level, variant)
+	}
+	if err != nil {
+		a.view.FlashStatus("restage failed: " + err.Error())
+		return
+	}
+	if seed := a.level.RunSeed; seed != nil && level.PerRunSeed() {
+		// The generator may have changed; regenerate from the same seed.
+		dir := a.runDatasetDir(level.LevelID, *seed)
+		_ = os.RemoveAll(dir)
+		level = level.WithRunSeed(*seed, dir)
+	}
+	a.pack = pack
+	a.level = level
+	if err := a.startLevel(ctx, false); err != nil {
+		a.view.FlashStatus("restage failed: " + err.Error())
+		return
+	}
+	a.view.FlashStatus("Level restaged")
+}
+
+// packSources maps every pack dir and archive in roots to a signature of its
+// files.
+func packSources(roots []levels.PackRoot) map[string]string {
+	out := map[string]string{}
+	for _, root := range roots {
+		abs, err := filepath.Abs(root.Path)
+		if err != nil {
+			continue
+		}
+		entries, err := os.ReadDir(abs)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			path := filepath.Join(abs, entry.Name())
+			switch {
+			case entry.IsDir():
+				if _, err := os.Stat(filepath.Join(path, "pack.yaml")); err == nil {
+					out[path] = levels.DirSignature(path)
+				}
+			case strings.HasSuffix(entry.Name(), levels.ArchiveExt):
+				if info, err := entry.Info(); err == nil {
+					out[path] = strconv.FormatInt(info.Size(), 10) + ":" + strconv.FormatInt(info.ModTime().UnixNano(), 10)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// changedSources lists the paths added, removed or modified between two
+// packSources results, sorted.
+func changedSources(old, cur map[string]string) []string {
+	out := []string{}
+	for path, sig := range cur {
+		if old[path] != sig {
+			out = append(out, path)
+		}
+	}
+	for path := range old {
+		if _, ok := cur[path]; !ok {
+			out = append(out, path)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// reloadProblems lists load errors of the packs loaded from changed paths.
+func reloadProblems(packs []levels.Pack, changed []string) []string {
+	out := []string{}
+	for _, p := range packs {
+		if !slices.Contains(changed, p.Path) && !slices.Contains(changed, p.Archive) {
+			continue
+		}
+		for _, msg := range p.LoadErrors() {
+			out = append(out, p.PackID+": "+msg)
+		}
+	}
+	return out
+}
+
+func packLoadedFrom(packs []levels.Pack, path string) bool {
+	for _, p := range packs {
+		if p.Path == path || (p.Archive != "" && p.Archive == path) {
+			return true
+		}
+	}
+	return false
+}
@@ -8,6 +8,9 @@ const (
 	ModeFreePlay   GameMode = "free"
 	ModeDailyDrill GameMode = "daily"
 	ModeCampaign   GameMode = "campaign"
+	// ModeToolDrill plays a Tools screen playlist. It is not resumable, so
+	// runs recorded in it continue as Free Play.
+	ModeToolDrill GameMode = "tool"
 )
 
 func normalizeGameMode(raw string) GameMode {
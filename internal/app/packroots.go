@@ -0,0 +1,92 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"clidojo/internal/levels"
+)
+
+const systemPacksDir = "/usr/share/clidojo/packs"
+
+// packSearchRoots returns pack roots in precedence order: --packs-dir flags,
+// CLIDOJO_PACKS_PATH entries, the user data dir, the system dir and finally
+// the packs shipped with the binary.
+func packSearchRoots(cfg Config) []levels.PackRoot {
+	roots := make([]levels.PackRoot, 0, len(cfg.PacksDirs)+4)
+	for _, dir := range cfg.PacksDirs {
+		if strings.TrimSpace(dir) != "" {
+			roots = append(roots, levels.PackRoot{Path: dir, Source: levels.RootSourceFlag})
+		}
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("CLIDOJO_PACKS_PATH")) {
+		if strings.TrimSpace(dir) != "" {
+			roots = append(roots, levels.PackRoot{Path: dir, Source: levels.RootSourceEnv})
+		}
+	}
+	if dir := levels.UserPacksDir(); dir != "" {
+		roots = append(roots, levels.PackRoot{Path: dir, Source: levels.RootSourceUser})
+	}
+	roots = append(roots, levels.PackRoot{Path: systemPacksDir, Source: levels.RootSourceSystem})
+	roots = append(roots, levels.PackRoot{Path: builtinPacksDir(), Source: levels.RootSourceBuiltin})
+	return roots
+}
+
+// builtinPacksDir locates the packs shipped next to the binary (bin/clidojo
+// in a checkout, or a packs/ dir beside an installed binary), falling back to
+// ./packs for `go run`.
+func builtinPacksDir() string {
+	if exe, err := os.Executable(); err == nil {
+		if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+			exe = resolved
+		}
+		dir := filepath.Dir(exe)
+		for _, candidate := range []string{filepath.Join(dir, "packs"), filepath.Join(dir, "..", "packs")} {
+			if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+				return filepath.Clean(candidate)
+			}
+		}
+	}
+	return "packs"
+}
+
+func describePackRoots(roots []levels.PackRoot) string {
+	parts := make([]string, 0, len(roots))
+	for _, r := range roots {
+		parts = append(parts, r.Path)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// firstPlayablePack returns the index of the first pack with at least one
+// loaded level, preferring packs whose dependencies are satisfied, or -1.
+// noPlayableLevelsError is the startup error when no pack has a loadable
+// level; it lists what broke so the cause is not hidden in the log.
+func noPlayableLevelsError(packs []levels.Pack, roots []levels.PackRoot) error {
+	msg := fmt.Sprintf("no packs/levels available (searched %s)", describePackRoots(roots))
+	for _, p := range packs {
+		for _, problem := range p.LoadErrors() {
+			msg += "\n  " + p.PackID + ": " + problem
+		}
+	}
+	return errors.New(msg)
+}
+
+func firstPlayablePack(packs []levels.Pack) int {
+	fallback := -1
+	for i, p := range packs {
+		if len(p.LoadedLevels) == 0 {
+			continue
+		}
+		if len(p.DependencyErrors) == 0 {
+			return i
+		}
+		if fallback < 0 {
+			fallback = i
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,193 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"clidojo/internal/levels"
+	"clidojo/internal/state"
+	"clidojo/internal/ui"
+)
+
+// levelMastered reports whether a level's best run meets its mastery bar: a
+// pass scoring at least progression.mastery.min_score.
+func levelMastered(level levels.Level, progress state.LevelProgress) bool {
+	return progress.PassedCount > 0 && progress.BestScore >= level.Progression.Mastery.MinScore
+}
+
+// toolSummaries builds the tool pages: every pack's tool catalog in pack
+// order (the first pack wins a duplicate tool_id), then tool_focus entries
+// no pack describes, sorted. Each lists the levels that focus on it.
+func toolSummaries(packs []levels.Pack, progress map[string]state.LevelProgress, locale string) []ui.ToolSummary {
+	out := []ui.ToolSummary{}
+	index := map[string]int{}
+	for _, p := range packs {
+		for _, tool := range p.Tools {
+			if _, ok := index[tool.ToolID]; ok || tool.ToolID == "" {
+				continue
+			}
+			index[tool.ToolID] = len(out)
+			out = append(out, ui.ToolSummary{ToolID: tool.ToolID, Name: firstNonEmpty(tool.Name, tool.ToolID), SummaryMD: tool.SummaryMD})
+		}
+	}
+	undeclared := []string{}
+	for _, p := range packs {
+		for _, lv := range p.LoadedLevels {
+			for _, id := range lv.ToolFocus {
+				if _, ok := index[id]; !ok && id != "" {
+					index[id] = -1
+					undeclared = append(undeclared, id)
+				}
+			}
+		}
+	}
+	sort.Strings(undeclared)
+	for _, id := range undeclared {
+		index[id] = len(out)
+		out = append(out, ui.ToolSummary{ToolID: id, Name: id})
+	}
+
+	for _, p := range packs {
+		for _, lv := range p.LoadedLevels {
+			lv = lv.Localized(locale)
+			seen := map[string]bool{}
+			for _, id := range lv.ToolFocus {
+				if seen[id] || id == "" {
+					continue
+				}
+				seen[id] = true
+				tool := &out[index[id]]
+				entry := ui.ToolLevel{
+					PackID:     p.PackID,
+					LevelID:    lv.LevelID,
+					Title:      lv.Title,
+					Difficulty: lv.Difficulty,
+					Passed:     progress[p.PackID+"/"+lv.LevelID].PassedCount > 0,
+					Mastered:   levelMastered(lv, progress[p.PackID+"/"+lv.LevelID]),
+					Locked:     len(p.DependencyErrors) > 0,
+				}
+				if entry.Passed {
+					tool.Passed++
+				}
+				if entry.Mastered {
+					tool.Mastered++
+				}
+				tool.Levels = append(tool.Levels, entry)
+			}
+		}
+	}
+	return out
+}
+
+// toolPlaylist lists the unlocked levels of tool that are not mastered yet,
+// in catalog order. Once all are mastered it replays every unlocked level.
+func toolPlaylist(tool ui.ToolSummary) []dailyLevelRef {
+	open := []dailyLevelRef{}
+	all := []dailyLevelRef{}
+	for _, lv := range tool.Levels {
+		if lv.Locked {
+			continue
+		}
+		ref := dailyLevelRef{PackID: lv.PackID, LevelID: lv.LevelID}
+		all = append(all, ref)
+		if !lv.Mastered {
+			open = append(open, ref)
+		}
+	}
+	if len(open) == 0 {
+		return all
+	}
+	return open
+}
+
+func (a *App) tools() []ui.ToolSummary {
+	progress, _ := a.store.GetLevelProgressMap(context.Background())
+	return toolSummaries(a.packs, progress, a.locale())
+}
+
+func (a *App) OnOpenTools() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if a.activeLevel {
+		a.exitLevel(ctx)
+	}
+	a.view.SetMenuOpen(false)
+	a.view.SetHintsOpen(false)
+	a.view.SetJournalOpen(false)
+	a.view.SetGoalOpen(false)
+	a.view.SetResult(ui.ResultState{})
+	a.resultOpen = false
+	a.view.SetTools(a.tools())
+	a.screen = ui.ScreenTools
+	a.view.SetScreen(ui.ScreenTools)
+	a.setDevState("tools", "tools")
+}
+
+// OnStartToolPlaylist trains one tool through its unmastered levels. The
+// playlist lives in memory only; Continue resumes its last level in Free
+// Play.
+func (a *App) OnStartToolPlaylist(toolID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	var tool *ui.ToolSummary
+	all := a.tools()
+	for i := range all {
+		if all[i].ToolID == toolID {
+			tool = &all[i]
+			break
+		}
+	}
+	if tool == nil {
+		a.view.FlashStatus("tool not found: " + toolID)
+		return
+	}
+	plan := toolPlaylist(*tool)
+	if len(plan) == 0 {
+		a.view.FlashStatus("no playable levels for " + tool.Name)
+		return
+	}
+	a.mode = ModeToolDrill
+	a.toolID = tool.ToolID
+	a.toolName = tool.Name
+	a.toolPlan = plan
+	a.dailyDay = ""
+	a.dailyPlan = nil
+	a.dailyIndex = 0
+	if err := a.startToolLevel(ctx, 0); err != nil {
+		a.view.FlashStatus("tool playlist failed: " + err.Error())
+	}
+}
+
+func (a *App) startToolLevel(ctx context.Context, idx int) error {
+	next := a.toolPlan[idx]
+	pack, level, err := a.loader.FindLevel(a.packs, next.PackID, next.LevelID)
+	if err != nil {
+		return err
+	}
+	a.pack = pack
+	a.level = level
+	a.toolIndex = idx
+	a.refreshCatalog()
+	a.view.SetMainMenuState(a.mainMenuState())
+	a.view.SetLevelSelection(a.pack.PackID, a.level.LevelID)
+	if err := a.startLevel(ctx, true); err != nil {
+		return err
+	}
+	a.view.FlashStatus(fmt.Sprintf("%s %d/%d", a.toolName, idx+1, len(a.toolPlan)))
+	return nil
+}
+
+// advanceToolDrill starts the next level of the tool playlist, or returns to
+// the Tools screen when it is done.
+func (a *App) advanceToolDrill(ctx context.Context) error {
+	next := a.toolIndex + 1
+	if next >= len(a.toolPlan) {
+		a.OnOpenTools()
+		a.view.FlashStatus(fmt.Sprintf("%s playlist complete", a.toolName))
+		return nil
+	}
+	return a.startToolLevel(ctx, next)
+}
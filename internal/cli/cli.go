@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	exitOK    = 0
+	exitFail  = 1
+	exitUsage = 2
+)
+
+// IsCommand reports whether name is a CLI subcommand handled by Run rather
+// than a flag for the interactive TUI.
+func IsCommand(name string) bool {
+	switch name {
+	case "pack", "level":
+		return true
+	default:
+		return false
+	}
+}
+
+// Run executes a non-interactive subcommand and returns the process exit code.
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: clidojo <command> [args]")
+		return exitUsage
+	}
+	switch args[0] {
+	case "pack":
+		return runPack(ctx, args[1:], stdout, stderr)
+	case "level":
+		return runLevel(ctx, args[1:], stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n", args[0])
+		return exitUsage
+	}
+}
+
+func runPack(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		packUsage(stderr)
+		return exitUsage
+	}
+	switch args[0] {
+	case "lint":
+		return runPackLint(ctx, args[1:], stdout, stderr)
+	case "test":
+		return runPackTest(ctx, args[1:], stdout, stderr)
+	case "migrate":
+		return runPackMigrate(ctx, args[1:], stdout, stderr)
+	case "build":
+		return runPackBuild(ctx, args[1:], stdout, stderr)
+	case "install":
+		return runPackInstall(ctx, args[1:], stdout, stderr)
+	case "list":
+		return runPackList(ctx, args[1:], stdout, stderr)
+	case "remove":
+		return runPackRemove(ctx, args[1:], stdout, stderr)
+	case "checksum":
+		return runPackChecksum(ctx, args[1:], stdout, stderr)
+	case "keygen":
+		return runPackKeygen(ctx, args[1:], stdout, stderr)
+	case "sign":
+		return runPackSign(ctx, args[1:], stdout, stderr)
+	case "verify":
+		return runPackVerify(ctx, args[1:], stdout, stderr)
+	case "trust":
+		return runPackTrust(ctx, args[1:], stdout, stderr)
+	case "-h", "--help", "help":
+		packUsage(stdout)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "unknown pack command %q\n", args[0])
+		packUsage(stderr)
+		return exitUsage
+	}
+}
+
+func packUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: clidojo pack <command> [args]")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "commands:")
+	fmt.Fprintln(w, "  lint <dir>          validate pack.yaml/level.yaml files against the bundled schemas")
+	fmt.Fprintln(w, "  test <dir>          run reference solutions and negative fixtures against each level's checks")
+	fmt.Fprintln(w, "  migrate <dir>       upgrade pack.yaml/level.yaml files to the latest schema_version (--write)")
+	fmt.Fprintln(w, "  build <dir>         package a pack directory as a .dojopack archive")
+	fmt.Fprintln(w, "  install <file>      verify a .dojopack archive and install it into the user pack root")
+	fmt.Fprintln(w, "  list                list installed packs")
+	fmt.Fprintln(w, "  remove <pack_id>    remove an installed .dojopack archive")
+	fmt.Fprintln(w, "  checksum <dir>      write each level's dataset.sha256sums (--level ID for one level)")
+	fmt.Fprintln(w, "  keygen <name>       create an ed25519 signing key pair")
+	fmt.Fprintln(w, "  sign <dir>          sign a pack directory (--key file.key)")
+	fmt.Fprintln(w, "  verify <dir|file>   check a pack's signature against the trust store")
+	fmt.Fprintln(w, "  trust <add|list|remove>  manage trusted public keys")
+}
+
+// parseInterspersed parses flags that may appear before or after positional
+// arguments (e.g. `pack lint packs --format json`).
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		if rest[0] == "--" {
+			return append(positional, rest[1:]...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+func validFormat(format string) bool {
+	switch strings.TrimSpace(format) {
+	case "human", "json":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,294 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"clidojo/internal/levels"
+)
+
+func TestPackLintBuiltinPacksJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := Run(context.Background(), []string{"pack", "lint", filepath.Join("..", "..", "packs"), "--format", "json"}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("expected exit %d, got %d\nstdout:\n%s\nstderr:\n%s", exitOK, code, stdout.String(), stderr.String())
+	}
+	var report lintReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("decode report: %v\n%s", err, stdout.String())
+	}
+	if report.Errors != 0 {
+		t.Fatalf("expected no errors, got %+v", report)
+	}
+}
+
+func TestPackLintUsageErrors(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := Run(context.Background(), []string{"pack", "lint"}, &stdout, &stderr); code != exitUsage {
+		t.Fatalf("expected usage exit for missing dir, got %d", code)
+	}
+	if code := Run(context.Background(), []string{"pack", "lint", "packs", "--format", "xml"}, &stdout, &stderr); code != exitUsage {
+		t.Fatalf("expected usage exit for bad format, got %d", code)
+	}
+}
+
+func TestPackTestRejectsMockSandbox(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := Run(context.Background(), []string{"pack", "test", "--sandbox", "mock", filepath.Join("..", "..", "packs", "builtin-core")}, &stdout, &stderr)
+	if code != exitFail {
+		t.Fatalf("expected exit %d, got %d (stderr %q)", exitFail, code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "docker or podman") {
+		t.Fatalf("expected engine requirement in stderr, got %q", stderr.String())
+	}
+}
+
+func TestPackBuildInstallListRemoveRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	tmp := t.TempDir()
+	archive := filepath.Join(tmp, "core.dojopack")
+	installDir := filepath.Join(tmp, "installed")
+	var stdout, stderr bytes.Buffer
+	if code := Run(ctx, []string{"pack", "build", filepath.Join("..", "..", "packs", "builtin-core"), "-o", archive}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("build exit %d: %s", code, stderr.String())
+	}
+	if code := Run(ctx, []string{"pack", "install", archive, "--dir", installDir}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("install exit %d: %s", code, stderr.String())
+	}
+	if code := Run(ctx, []string{"pack", "install", archive, "--dir", installDir}, &stdout, &stderr); code != exitFail {
+		t.Fatalf("expected reinstall without --force to fail, got %d", code)
+	}
+
+	stdout.Reset()
+	if code := Run(ctx, []string{"pack", "list", "--dir", installDir, "--format", "json"}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("list exit %d: %s", code, stderr.String())
+	}
+	var listed []installedPack
+	if err := json.Unmarshal(stdout.Bytes(), &listed); err != nil {
+		t.Fatalf("decode list: %v\n%s", err, stdout.String())
+	}
+	if len(listed) != 1 || listed[0].PackID != "builtin-core" || listed[0].Kind != "archive" || listed[0].Version == "" {
+		t.Fatalf("unexpected list %+v", listed)
+	}
+
+	if code := Run(ctx, []string{"pack", "remove", "builtin-core", "--dir", installDir}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("remove exit %d: %s", code, stderr.String())
+	}
+	if code := Run(ctx, []string{"pack", "remove", "builtin-core", "--dir", installDir}, &stdout, &stderr); code != exitFail {
+		t.Fatalf("expected second remove to fail, got %d", code)
+	}
+}
+
+func TestPackKeygenSignTrustVerify(t *testing.T) {
+	ctx := context.Background()
+	tmp := t.TempDir()
+	packDir := filepath.Join(tmp, "pack")
+	if err := os.CopyFS(packDir, os.DirFS(filepath.Join("..", "..", "packs", "builtin-core"))); err != nil {
+		t.Fatal(err)
+	}
+	dataDir := filepath.Join(tmp, "data")
+	key := filepath.Join(tmp, "author")
+	var stdout, stderr bytes.Buffer
+	run := func(args ...string) int {
+		stdout.Reset()
+		stderr.Reset()
+		return Run(ctx, append([]string{"pack"}, args...), &stdout, &stderr)
+	}
+	if code := run("keygen", key); code != exitOK {
+		t.Fatalf("keygen exit %d: %s", code, stderr.String())
+	}
+	if code := run("sign", "--key", key+".key", packDir); code != exitOK {
+		t.Fatalf("sign exit %d: %s", code, stderr.String())
+	}
+	if code := run("verify", "--data-dir", dataDir, packDir); code != exitFail || !strings.HasPrefix(stdout.String(), "unknown_key") {
+		t.Fatalf("expected unknown_key before trusting, got %d %q", code, stdout.String())
+	}
+	if code := run("trust", "--data-dir", dataDir, "add", key+".pub"); code != exitOK {
+		t.Fatalf("trust add exit %d: %s", code, stderr.String())
+	}
+	if code := run("verify", "--data-dir", dataDir, packDir); code != exitOK || !strings.HasPrefix(stdout.String(), "trusted") {
+		t.Fatalf("expected trusted, got %d %q", code, stdout.String())
+	}
+}
+
+func TestPackChecksumWritesManifestsTheLoaderEnforces(t *testing.T) {
+	ctx := context.Background()
+	packDir := filepath.Join(t.TempDir(), "pack")
+	if err := os.CopyFS(packDir, os.DirFS(filepath.Join("..", "..", "packs", "builtin-core"))); err != nil {
+		t.Fatal(err)
+	}
+	log := filepath.Join(packDir, "levels", "level-003-top-ips", "dataset", "access.log")
+	if err := os.WriteFile(log, []byte("10.0.0.1 - - GET /\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := levels.NewLoader().LoadPack(ctx, packDir); err == nil || !strings.Contains(err.Error(), "modified dataset/access.log") {
+		t.Fatalf("expected the edited dataset to fail the shipped manifest, got %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := Run(ctx, []string{"pack", "checksum", "--level", "level-003-top-ips", packDir}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("checksum exit %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "level-003-top-ips/dataset.sha256sums (1 files)") {
+		t.Fatalf("unexpected output %q", stdout.String())
+	}
+	if _, err := levels.NewLoader().LoadPack(ctx, packDir); err != nil {
+		t.Fatalf("rewritten manifest should load: %v", err)
+	}
+}
+
+func TestPackMigrateReportsThenWrites(t *testing.T) {
+	dir := t.TempDir()
+	levelYAML := filepath.Join(dir, "levels", "level-one", "level.yaml")
+	if err := os.MkdirAll(filepath.Dir(levelYAML), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	src := "kind: level\nschema_version: 1\n\n# keep me\nx-teaching:\n  concepts: [grep]\n"
+	if err := os.WriteFile(levelYAML, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := Run(context.Background(), []string{"pack", "migrate", dir}, &stdout, &stderr); code != exitFail {
+		t.Fatalf("expected dry run to fail on outdated files, got %d (stderr %q)", code, stderr.String())
+	}
+	if b, _ := os.ReadFile(levelYAML); string(b) != src {
+		t.Fatalf("dry run modified the file:\n%s", b)
+	}
+	stdout.Reset()
+	if code := Run(context.Background(), []string{"pack", "migrate", "--write", dir}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("expected --write to succeed, got %d (stderr %q)", code, stderr.String())
+	}
+	want := "kind: level\nschema_version: 2\n\n# keep me\nteaching:\n  concepts: [grep]\n"
+	if b, _ := os.ReadFile(levelYAML); string(b) != want {
+		t.Fatalf("unexpected migrated file:\n%s", b)
+	}
+	stdout.Reset()
+	if code := Run(context.Background(), []string{"pack", "migrate", dir}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("expected migrated tree to be current, got %d\n%s", code, stdout.String())
+	}
+}
+
+func TestLevelNewWizardScaffoldsLintCleanLevel(t *testing.T) {
+	ctx := context.Background()
+	root := filepath.Join(t.TempDir(), "packs")
+	if err := os.CopyFS(filepath.Join(root, "core"), os.DirFS(filepath.Join("..", "..", "packs", "builtin-core"))); err != nil {
+		t.Fatal(err)
+	}
+	old := stdin
+	defer func() { stdin = old }()
+	// Title, default ID, a bad then a good difficulty, tools, two bullets.
+	stdin = strings.NewReader("Count Unique Users\n\n9\n3\nawk, sort\nRead /levels/current/users.txt\nWrite the count to /work/output.txt\n\n")
+
+	var stdout, stderr bytes.Buffer
+	if code := Run(ctx, []string{"level", "new", "--pack", "builtin-core", "--packs-dir", root}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("level new exit %d: %s\n%s", code, stderr.String(), stdout.String())
+	}
+	levelDir := filepath.Join(root, "core", "levels", "level-004-count-unique-users")
+	if !strings.Contains(stdout.String(), "difficulty must be a number from 1 to 5") || !strings.Contains(stdout.String(), "created "+filepath.Join(levelDir, "level.yaml")) {
+		t.Fatalf("unexpected output:\n%s", stdout.String())
+	}
+	if _, err := os.Stat(filepath.Join(levelDir, "dataset")); err != nil {
+		t.Fatalf("dataset dir missing: %v", err)
+	}
+	packs, err := levels.NewLoader().LoadPacks(ctx, root)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	_, level, err := levels.NewLoader().FindLevel(packs, "builtin-core", "level-004-count-unique-users")
+	if err != nil {
+		t.Fatalf("level not registered: %v", err)
+	}
+	if level.Difficulty != 3 || strings.Join(level.ToolFocus, ",") != "awk,sort" || len(level.Objective.Bullets) != 2 {
+		t.Fatalf("answers not applied: %+v", level)
+	}
+	diags, err := levels.Lint(root, levels.LintOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range diags {
+		if strings.Contains(d.File, "level-004") || strings.HasSuffix(d.File, "pack.yaml") {
+			t.Fatalf("scaffold is not lint clean: %s", d)
+		}
+	}
+
+	stdin = strings.NewReader("")
+	if code := Run(ctx, []string{"level", "new", "--pack", filepath.Join(root, "core"), "--title", "Count Unique Users", "--id", "level-004-count-unique-users", "--difficulty", "1", "--tools", "awk", "--objective", "o"}, &stdout, &stderr); code != exitFail {
+		t.Fatalf("expected a duplicate level to fail, got %d", code)
+	}
+}
+
+func TestLevelRecordDraftsLintCleanLevelFromWorkdir(t *testing.T) {
+	ctx := context.Background()
+	root := filepath.Join(t.TempDir(), "packs")
+	packDir := filepath.Join(root, "core")
+	if err := os.CopyFS(packDir, os.DirFS(filepath.Join("..", "..", "packs", "builtin-core"))); err != nil {
+		t.Fatal(err)
+	}
+	dataset := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dataset, "names.txt"), []byte("bob\nann\nbob\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// What the sandbox leaves behind: the bashrc's cmdlog and the output.
+	work := t.TempDir()
+	files := map[string]string{
+		".dojo_cmdlog":       "1\tsort -u /levels/current/names.txt > unique.txt\n2\tsort -u /levels/current/names.txt > unique.txt\n3\texit\n",
+		".dojo_bash_history": "sort -u /levels/current/names.txt > unique.txt\n",
+		"unique.txt":         "ann\nbob\n",
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(work, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec, err := readRecording(work)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(rec.Commands, "|") != "sort -u /levels/current/names.txt > unique.txt" || len(rec.Files) != 1 || rec.Files[0].Path != "unique.txt" {
+		t.Fatalf("unexpected recording: %+v", rec)
+	}
+	rec.LevelScaffold = levels.LevelScaffold{LevelID: "level-004-unique-names", Title: "Unique Names", Difficulty: 1, ToolFocus: []string{"sort"}}
+	packSrc, err := os.ReadFile(filepath.Join(packDir, "pack.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := saveRecordedLevel(packDir, packSrc, dataset, rec); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	levelDir := filepath.Join(packDir, "levels", "level-004-unique-names")
+	for _, f := range []string{"dataset/names.txt", "dataset/.expected/unique.txt"} {
+		if _, err := os.Stat(filepath.Join(levelDir, filepath.FromSlash(f))); err != nil {
+			t.Fatalf("%s missing: %v", f, err)
+		}
+	}
+	packs, err := levels.NewLoader().LoadPacks(ctx, root)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	_, level, err := levels.NewLoader().FindLevel(packs, "builtin-core", "level-004-unique-names")
+	if err != nil {
+		t.Fatalf("level not registered: %v", err)
+	}
+	if len(level.Checks) != 3 || level.Checks[1].Type != "file_lines_count" || level.Checks[1].Equals != 2 {
+		t.Fatalf("unexpected checks: %+v", level.Checks)
+	}
+	diags, err := levels.Lint(root, levels.LintOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range diags {
+		if strings.Contains(d.File, "level-004") || strings.HasSuffix(d.File, "pack.yaml") {
+			t.Fatalf("draft is not lint clean: %s", d)
+		}
+	}
+	if err := saveRecordedLevel(packDir, packSrc, dataset, rec); err == nil {
+		t.Fatal("expected saving over an existing level to fail")
+	}
+}
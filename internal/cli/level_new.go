@@ -0,0 +1,256 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"clidojo/internal/levels"
+)
+
+// stdin feeds the interactive prompts of `level new`; tests replace it.
+var stdin io.Reader = os.Stdin
+
+func runLevel(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		levelUsage(stderr)
+		return exitUsage
+	}
+	switch args[0] {
+	case "new":
+		return runLevelNew(ctx, args[1:], stdout, stderr)
+	case "record":
+		return runLevelRecord(ctx, args[1:], stdout, stderr)
+	case "-h", "--help", "help":
+		levelUsage(stdout)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "unknown level command %q\n", args[0])
+		levelUsage(stderr)
+		return exitUsage
+	}
+}
+
+func levelUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: clidojo level <command> [args]")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "commands:")
+	fmt.Fprintln(w, "  new --pack <id|dir>     scaffold a level in a pack directory and register it in pack.yaml")
+	fmt.Fprintln(w, "  record --pack <id|dir>  solve a level once in a sandbox and draft its level.yaml from the session")
+}
+
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ",") }
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+func runLevelNew(_ context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("level new", stderr)
+	packArg := flags.String("pack", "", "pack ID or pack directory")
+	var packsDirs stringList
+	flags.Var(&packsDirs, "packs-dir", "pack root to look up --pack IDs in (repeatable; default: CLIDOJO_PACKS_PATH, then ./packs)")
+	id := flags.String("id", "", "level_id (default: next number in the pack plus a slug of the title)")
+	title := flags.String("title", "", "level title")
+	difficulty := flags.Int("difficulty", 0, "difficulty 1..5")
+	tools := flags.String("tools", "", "comma-separated tool_focus")
+	var objective stringList
+	flags.Var(&objective, "objective", "objective bullet (repeatable)")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 0 || strings.TrimSpace(*packArg) == "" {
+		fmt.Fprintln(stderr, "usage: clidojo level new --pack <id|dir> [--title T] [--difficulty N] [--tools a,b] [--objective O]...")
+		return exitUsage
+	}
+
+	packDir, err := findPackDir(*packArg, packsDirs)
+	if err != nil {
+		fmt.Fprintf(stderr, "level new: %v\n", err)
+		return exitFail
+	}
+	packFile := filepath.Join(packDir, "pack.yaml")
+	packSrc, err := os.ReadFile(packFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "level new: %v\n", err)
+		return exitFail
+	}
+	var pack levels.Pack
+	if err := yaml.Unmarshal(packSrc, &pack); err != nil {
+		fmt.Fprintf(stderr, "level new: parse %s: %v\n", packFile, err)
+		return exitFail
+	}
+
+	spec := levels.LevelScaffold{LevelID: *id, Title: strings.TrimSpace(*title), Difficulty: *difficulty, Objective: objective}
+	if err := promptScaffold(stdout, pack, &spec, *tools, true); err != nil {
+		fmt.Fprintf(stderr, "level new: %v\n", err)
+		return exitFail
+	}
+
+	if err := createLevel(packDir, packSrc, spec); err != nil {
+		fmt.Fprintf(stderr, "level new: %v\n", err)
+		return exitFail
+	}
+	levelDir := filepath.Join(packDir, "levels", spec.LevelID)
+	fmt.Fprintf(stdout, "created %s\n", filepath.Join(levelDir, "level.yaml"))
+	fmt.Fprintf(stdout, "registered %s in %s\n", spec.LevelID, packFile)
+	fmt.Fprintf(stdout, "next: add files to %s, replace the TODO check and solution, then run `clidojo pack lint %s`\n", filepath.Join(levelDir, "dataset"), packDir)
+	return exitOK
+}
+
+// promptScaffold asks on stdout for whatever the flags left out of spec and
+// reads the answers from stdin. Objective bullets are only asked for when
+// askObjective is set.
+func promptScaffold(stdout io.Writer, pack levels.Pack, spec *levels.LevelScaffold, tools string, askObjective bool) error {
+	var err error
+	in := bufio.NewScanner(stdin)
+	ask := func(label, def string) (string, error) {
+		if def != "" {
+			fmt.Fprintf(stdout, "%s [%s]: ", label, def)
+		} else {
+			fmt.Fprintf(stdout, "%s: ", label)
+		}
+		if !in.Scan() {
+			if err := in.Err(); err != nil {
+				return "", err
+			}
+			return "", errors.New("input ended before the level was complete")
+		}
+		if answer := strings.TrimSpace(in.Text()); answer != "" {
+			return answer, nil
+		}
+		return def, nil
+	}
+	for spec.Title == "" {
+		if spec.Title, err = ask("Title", ""); err != nil {
+			return err
+		}
+	}
+	if spec.LevelID == "" {
+		if spec.LevelID, err = ask("Level ID", levels.SuggestLevelID(pack.Levels, spec.Title)); err != nil {
+			return err
+		}
+	}
+	for spec.Difficulty < 1 || spec.Difficulty > 5 {
+		answer, err := ask("Difficulty (1-5)", "1")
+		if err != nil {
+			return err
+		}
+		if spec.Difficulty, _ = strconv.Atoi(answer); spec.Difficulty < 1 || spec.Difficulty > 5 {
+			fmt.Fprintln(stdout, "difficulty must be a number from 1 to 5")
+		}
+	}
+	toolList := tools
+	if toolList == "" {
+		label := "Tool focus (comma-separated)"
+		if len(pack.Tools) > 0 {
+			ids := make([]string, 0, len(pack.Tools))
+			for _, tool := range pack.Tools {
+				ids = append(ids, tool.ToolID)
+			}
+			label += "; pack tools: " + strings.Join(ids, ", ")
+		}
+		if toolList, err = ask(label, ""); err != nil {
+			return err
+		}
+	}
+	for _, tool := range strings.Split(toolList, ",") {
+		if tool = strings.TrimSpace(tool); tool != "" {
+			spec.ToolFocus = append(spec.ToolFocus, tool)
+		}
+	}
+	if !askObjective || len(spec.Objective) > 0 {
+		return nil
+	}
+	fmt.Fprintln(stdout, "Objective bullets, one per line; an empty line finishes:")
+	for {
+		bullet, err := ask("-", "")
+		switch {
+		case len(spec.Objective) > 0 && (err != nil || bullet == ""):
+			return nil
+		case err != nil:
+			return err
+		case bullet == "":
+			fmt.Fprintln(stdout, "the objective needs at least one bullet")
+		default:
+			spec.Objective = append(spec.Objective, bullet)
+		}
+	}
+}
+
+// createLevel writes the scaffold for spec into packDir and registers it in
+// pack.yaml. Nothing is written when the level would be invalid or exists.
+func createLevel(packDir string, packSrc []byte, spec levels.LevelScaffold) error {
+	levelYAML, err := levels.RenderLevelScaffold(spec)
+	if err != nil {
+		return err
+	}
+	rel := filepath.ToSlash(filepath.Join("levels", spec.LevelID))
+	updated, err := levels.AddPackLevel(packSrc, levels.PackLevelRef{LevelID: spec.LevelID, Path: rel})
+	if err != nil {
+		return err
+	}
+	levelDir := filepath.Join(packDir, rel)
+	if _, err := os.Stat(levelDir); err == nil {
+		return fmt.Errorf("%s already exists", levelDir)
+	}
+	// .gitkeep keeps the empty dataset dir in version control.
+	if err := os.MkdirAll(filepath.Join(levelDir, "dataset"), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(levelDir, "dataset", ".gitkeep"), nil, 0o644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(levelDir, "level.yaml"), levelYAML, 0o644); err != nil {
+		return err
+	}
+	_, err = writeFileAtomic(filepath.Join(packDir, "pack.yaml"), func(w io.Writer) (int, error) { return w.Write(updated) })
+	return err
+}
+
+// findPackDir resolves --pack: a directory with a pack.yaml, or a pack_id
+// looked up in roots, then CLIDOJO_PACKS_PATH, then ./packs. Installed
+// .dojopack archives cannot be edited, so only directories are searched.
+func findPackDir(arg string, roots []string) (string, error) {
+	if _, err := os.Stat(filepath.Join(arg, "pack.yaml")); err == nil {
+		return arg, nil
+	}
+	if len(roots) == 0 {
+		roots = append(filepath.SplitList(os.Getenv("CLIDOJO_PACKS_PATH")), "packs")
+	}
+	for _, root := range roots {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			dir := filepath.Join(root, entry.Name())
+			b, err := os.ReadFile(filepath.Join(dir, "pack.yaml"))
+			if err != nil {
+				continue
+			}
+			var head struct {
+				PackID string `yaml:"pack_id"`
+			}
+			if yaml.Unmarshal(b, &head) == nil && head.PackID == arg {
+				return dir, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("pack %q not found in %s", arg, strings.Join(roots, ", "))
+}
@@ -0,0 +1,252 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+
+	"clidojo/internal/levels"
+	"clidojo/internal/sandbox"
+	"clidojo/internal/selftest"
+)
+
+func runLevelRecord(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("level record", stderr)
+	packArg := flags.String("pack", "", "pack ID or pack directory")
+	var packsDirs stringList
+	flags.Var(&packsDirs, "packs-dir", "pack root to look up --pack IDs in (repeatable; default: CLIDOJO_PACKS_PATH, then ./packs)")
+	datasetArg := flags.String("dataset", "", "directory mounted read-only at /levels/current and copied into the level")
+	id := flags.String("id", "", "level_id (default: next number in the pack plus a slug of the title)")
+	title := flags.String("title", "", "level title")
+	difficulty := flags.Int("difficulty", 0, "difficulty 1..5")
+	tools := flags.String("tools", "", "comma-separated tool_focus")
+	var objective stringList
+	flags.Var(&objective, "objective", "objective bullet (repeatable; default: one per recorded file)")
+	sandboxMode := flags.String("sandbox", "auto", "sandbox mode: auto, docker, podman")
+	engine := flags.String("engine", "", "force container engine: docker or podman")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 0 || strings.TrimSpace(*packArg) == "" {
+		fmt.Fprintln(stderr, "usage: clidojo level record --pack <id|dir> [--dataset DIR] [--title T] [--difficulty N] [--tools a,b] [--objective O]... [--sandbox auto|docker|podman]")
+		return exitUsage
+	}
+	if *sandboxMode == "mock" {
+		fmt.Fprintln(stderr, "level record: recording needs a container engine; use --sandbox auto, docker or podman")
+		return exitUsage
+	}
+
+	packDir, err := findPackDir(*packArg, packsDirs)
+	if err != nil {
+		fmt.Fprintf(stderr, "level record: %v\n", err)
+		return exitFail
+	}
+	packFile := filepath.Join(packDir, "pack.yaml")
+	packSrc, err := os.ReadFile(packFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "level record: %v\n", err)
+		return exitFail
+	}
+	var pack levels.Pack
+	if err := yaml.Unmarshal(packSrc, &pack); err != nil {
+		fmt.Fprintf(stderr, "level record: parse %s: %v\n", packFile, err)
+		return exitFail
+	}
+	pack.Path = packDir
+
+	spec := levels.LevelScaffold{LevelID: *id, Title: strings.TrimSpace(*title), Difficulty: *difficulty, Objective: objective}
+	if err := promptScaffold(stdout, pack, &spec, *tools, false); err != nil {
+		fmt.Fprintf(stderr, "level record: %v\n", err)
+		return exitFail
+	}
+	// Catch a taken level_id before the author spends a session on it.
+	if _, err := levels.AddPackLevel(packSrc, levels.PackLevelRef{LevelID: spec.LevelID}); err != nil {
+		fmt.Fprintf(stderr, "level record: %v\n", err)
+		return exitFail
+	}
+	levelDir := filepath.Join(packDir, "levels", spec.LevelID)
+	if _, err := os.Stat(levelDir); err == nil {
+		fmt.Fprintf(stderr, "level record: %s already exists\n", levelDir)
+		return exitFail
+	}
+
+	workDir, err := os.MkdirTemp("", "clidojo-record-")
+	if err != nil {
+		fmt.Fprintf(stderr, "level record: %v\n", err)
+		return exitFail
+	}
+	defer levels.RemoveWorkdir(workDir)
+	datasetDir := ""
+	if *datasetArg != "" {
+		if datasetDir, err = filepath.Abs(*datasetArg); err != nil {
+			fmt.Fprintf(stderr, "level record: %v\n", err)
+			return exitFail
+		}
+		if _, err := os.Stat(filepath.Join(datasetDir, levels.RecordedExpectedDir)); err == nil {
+			fmt.Fprintf(stderr, "level record: %s must not contain %s; it holds the recorded output\n", datasetDir, levels.RecordedExpectedDir)
+			return exitFail
+		}
+	}
+
+	if err := recordSession(ctx, pack, spec.LevelID, datasetDir, workDir, *sandboxMode, *engine, stdout, stderr); err != nil {
+		fmt.Fprintf(stderr, "level record: %v\n", err)
+		return exitFail
+	}
+	rec, err := readRecording(workDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "level record: %v\n", err)
+		return exitFail
+	}
+	rec.LevelScaffold = spec
+	if err := saveRecordedLevel(packDir, packSrc, datasetDir, rec); err != nil {
+		fmt.Fprintf(stderr, "level record: %v\n", err)
+		return exitFail
+	}
+	fmt.Fprintf(stdout, "recorded %d command(s) and %d file(s)\n", len(rec.Commands), len(rec.Files))
+	fmt.Fprintf(stdout, "created %s\n", filepath.Join(levelDir, "level.yaml"))
+	fmt.Fprintf(stdout, "registered %s in %s\n", spec.LevelID, packFile)
+	fmt.Fprintf(stdout, "next: reword the TODOs and prune the derived checks, then run `clidojo pack test --level %s %s`\n", spec.LevelID, packDir)
+	return exitOK
+}
+
+// recordSession starts a sandbox with datasetDir at /levels/current and
+// workDir at /work and attaches the author's terminal until the shell exits.
+// The image's bashrc writes the .dojo_cmdlog as in a level.
+func recordSession(ctx context.Context, pack levels.Pack, levelID, datasetDir, workDir, mode, engine string, stdout, stderr io.Writer) error {
+	if datasetDir == "" {
+		empty, err := os.MkdirTemp("", "clidojo-record-dataset-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(empty)
+		datasetDir = empty
+	}
+	mgr := sandbox.NewManager(mode)
+	if _, err := mgr.Detect(ctx, engine); err != nil {
+		return err
+	}
+	image := strings.TrimSpace(pack.Image.Ref)
+	if err := selftest.EnsureImage(ctx, mgr.CurrentEngine(), pack, image); err != nil {
+		return err
+	}
+	sessionID := uuid.NewString()
+	handle, err := mgr.StartLevel(ctx, sandbox.StartSpec{
+		SessionID:     sessionID,
+		PackID:        pack.PackID,
+		LevelID:       levelID,
+		ContainerName: "clidojo_record_" + sessionID[:8],
+		Image:         image,
+		DatasetDir:    datasetDir,
+		DatasetMount:  "/levels/current",
+		WorkDir:       workDir,
+		WorkMount:     "/work",
+		ReadOnlyRoot:  true,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_ = handle.Stop(stopCtx)
+	}()
+
+	fmt.Fprintln(stdout, "Recording: solve the level in this shell, then type `exit` to write the draft.")
+	argv := handle.ShellCommand()
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, stdout, stderr
+	// The shell's status is that of the author's last command.
+	var exitErr *exec.ExitError
+	if err := cmd.Run(); err != nil && !errors.As(err, &exitErr) {
+		return fmt.Errorf("shell: %w", err)
+	}
+	return nil
+}
+
+// readRecording collects the commands and files a session left in workDir.
+func readRecording(workDir string) (levels.LevelRecording, error) {
+	rec := levels.LevelRecording{}
+	body, err := os.ReadFile(filepath.Join(workDir, ".dojo_cmdlog"))
+	if err != nil && !os.IsNotExist(err) {
+		return rec, err
+	}
+	rec.Commands = levels.ParseCmdlog(body)
+	err = filepath.WalkDir(workDir, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		rel, err := filepath.Rel(workDir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !levels.RecordedWorkFile(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rec.Files = append(rec.Files, levels.RecordedFile{Path: rel, Content: content})
+		return nil
+	})
+	sort.Slice(rec.Files, func(i, j int) bool { return rec.Files[i].Path < rec.Files[j].Path })
+	return rec, err
+}
+
+// saveRecordedLevel writes the draft level for rec into packDir: a copy of
+// datasetDir plus the recorded output under dataset/RecordedExpectedDir, the
+// level.yaml, and the pack.yaml entry.
+func saveRecordedLevel(packDir string, packSrc []byte, datasetDir string, rec levels.LevelRecording) error {
+	levelYAML, err := levels.RenderRecordedLevel(rec)
+	if err != nil {
+		return err
+	}
+	rel := filepath.ToSlash(filepath.Join("levels", rec.LevelID))
+	updated, err := levels.AddPackLevel(packSrc, levels.PackLevelRef{LevelID: rec.LevelID, Path: rel})
+	if err != nil {
+		return err
+	}
+	levelDir := filepath.Join(packDir, rel)
+	if _, err := os.Stat(levelDir); err == nil {
+		return fmt.Errorf("%s already exists", levelDir)
+	}
+	dataset := filepath.Join(levelDir, "dataset")
+	if datasetDir != "" {
+		if err := os.CopyFS(dataset, os.DirFS(datasetDir)); err != nil {
+			return fmt.Errorf("copy dataset: %w", err)
+		}
+	}
+	for _, f := range rec.Files {
+		dst := filepath.Join(dataset, levels.RecordedExpectedDir, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dst, f.Content, 0o644); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(levelDir, "level.yaml"), levelYAML, 0o644); err != nil {
+		return err
+	}
+	_, err = writeFileAtomic(filepath.Join(packDir, "pack.yaml"), func(w io.Writer) (int, error) { return w.Write(updated) })
+	return err
+}
@@ -0,0 +1,249 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"clidojo/internal/levels"
+)
+
+func runPackBuild(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("pack build", stderr)
+	out := fs.String("o", "", "output file (default <pack_id>-<version>.dojopack)")
+	compression := fs.String("compression", levels.CompressionZstd, "compression: zstd or gzip")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: clidojo pack build [-o file] [--compression zstd|gzip] <pack-dir>")
+		return exitUsage
+	}
+	if *compression != levels.CompressionZstd && *compression != levels.CompressionGzip {
+		fmt.Fprintf(stderr, "invalid --compression %q (want zstd or gzip)\n", *compression)
+		return exitUsage
+	}
+	pack, err := levels.NewLoader().LoadPack(ctx, positional[0])
+	if err != nil {
+		fmt.Fprintf(stderr, "pack build: %v\n", err)
+		return exitFail
+	}
+	dest := *out
+	if dest == "" {
+		dest = pack.PackID + "-" + pack.Version + levels.ArchiveExt
+	}
+	manifest, err := writeFileAtomic(dest, func(w io.Writer) (levels.ArchiveManifest, error) {
+		return levels.BuildArchive(positional[0], w, *compression)
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "pack build: %v\n", err)
+		return exitFail
+	}
+	fmt.Fprintf(stdout, "built %s (%s %s, %d files)\n", dest, manifest.PackID, manifest.Version, len(manifest.Files))
+	return exitOK
+}
+
+func runPackInstall(_ context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("pack install", stderr)
+	dir := fs.String("dir", levels.UserPacksDir(), "install directory")
+	force := fs.Bool("force", false, "replace an installed pack with the same pack_id")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: clidojo pack install [--dir D] [--force] <file.dojopack>")
+		return exitUsage
+	}
+	if *dir == "" {
+		fmt.Fprintln(stderr, "pack install: cannot determine the user pack directory; pass --dir")
+		return exitUsage
+	}
+	manifest, err := levels.VerifyArchive(positional[0])
+	if err != nil {
+		fmt.Fprintf(stderr, "pack install: %v\n", err)
+		return exitFail
+	}
+	dest := filepath.Join(*dir, manifest.PackID+levels.ArchiveExt)
+	if !*force {
+		if _, err := os.Stat(dest); err == nil {
+			fmt.Fprintf(stderr, "pack install: %s is already installed at %s (use --force to replace)\n", manifest.PackID, dest)
+			return exitFail
+		}
+		if _, err := os.Stat(filepath.Join(*dir, manifest.PackID, "pack.yaml")); err == nil {
+			fmt.Fprintf(stderr, "pack install: %s is already installed as a directory in %s\n", manifest.PackID, *dir)
+			return exitFail
+		}
+	}
+	src, err := os.Open(positional[0])
+	if err != nil {
+		fmt.Fprintf(stderr, "pack install: %v\n", err)
+		return exitFail
+	}
+	defer src.Close()
+	if _, err := writeFileAtomic(dest, func(w io.Writer) (struct{}, error) {
+		_, err := io.Copy(w, src)
+		return struct{}{}, err
+	}); err != nil {
+		fmt.Fprintf(stderr, "pack install: %v\n", err)
+		return exitFail
+	}
+	fmt.Fprintf(stdout, "installed %s %s to %s\n", manifest.PackID, manifest.Version, dest)
+	return exitOK
+}
+
+type installedPack struct {
+	PackID  string `json:"pack_id"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+	Path    string `json:"path"`
+	Error   string `json:"error,omitempty"`
+}
+
+func runPackList(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("pack list", stderr)
+	dir := fs.String("dir", levels.UserPacksDir(), "install directory")
+	format := fs.String("format", "human", "output format: human or json")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 0 {
+		fmt.Fprintln(stderr, "usage: clidojo pack list [--dir D] [--format human|json]")
+		return exitUsage
+	}
+	if !validFormat(*format) {
+		fmt.Fprintf(stderr, "invalid --format %q (want human or json)\n", *format)
+		return exitUsage
+	}
+	installed, err := listInstalledPacks(ctx, *dir)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack list: %v\n", err)
+		return exitFail
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(installed); err != nil {
+			fmt.Fprintf(stderr, "pack list: %v\n", err)
+			return exitFail
+		}
+		return exitOK
+	}
+	if len(installed) == 0 {
+		fmt.Fprintf(stdout, "no packs installed in %s\n", *dir)
+		return exitOK
+	}
+	for _, p := range installed {
+		if p.Error != "" {
+			fmt.Fprintf(stdout, "%-24s %-10s %-8s %s (error: %s)\n", p.PackID, "?", p.Kind, p.Path, p.Error)
+			continue
+		}
+		fmt.Fprintf(stdout, "%-24s %-10s %-8s %s\n", p.PackID, p.Version, p.Kind, p.Path)
+	}
+	return exitOK
+}
+
+func runPackRemove(_ context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("pack remove", stderr)
+	dir := fs.String("dir", levels.UserPacksDir(), "install directory")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: clidojo pack remove [--dir D] <pack_id>")
+		return exitUsage
+	}
+	packID := positional[0]
+	if packID == "" || strings.ContainsAny(packID, `/\`) || packID == "." || packID == ".." {
+		fmt.Fprintf(stderr, "pack remove: invalid pack_id %q\n", packID)
+		return exitUsage
+	}
+	dest := filepath.Join(*dir, packID+levels.ArchiveExt)
+	if err := os.Remove(dest); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(stderr, "pack remove: %s is not installed in %s\n", packID, *dir)
+		} else {
+			fmt.Fprintf(stderr, "pack remove: %v\n", err)
+		}
+		return exitFail
+	}
+	fmt.Fprintf(stdout, "removed %s\n", dest)
+	return exitOK
+}
+
+// listInstalledPacks reports the archives and pack directories directly under
+// dir. A missing dir is treated as empty.
+func listInstalledPacks(ctx context.Context, dir string) ([]installedPack, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []installedPack{}, nil
+		}
+		return nil, err
+	}
+	loader := levels.NewLoader()
+	out := []installedPack{}
+	for _, entry := range entries {
+		p := filepath.Join(dir, entry.Name())
+		switch {
+		case entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), levels.ArchiveExt):
+			item := installedPack{PackID: strings.TrimSuffix(entry.Name(), levels.ArchiveExt), Kind: "archive", Path: p}
+			if manifest, err := levels.ReadArchiveManifest(p); err != nil {
+				item.Error = err.Error()
+			} else {
+				item.PackID, item.Version = manifest.PackID, manifest.Version
+			}
+			out = append(out, item)
+		case entry.IsDir():
+			if _, err := os.Stat(filepath.Join(p, "pack.yaml")); err != nil {
+				continue
+			}
+			item := installedPack{PackID: entry.Name(), Kind: "dir", Path: p}
+			if pack, err := loader.LoadPack(ctx, p); err != nil {
+				item.Error = err.Error()
+			} else {
+				item.PackID, item.Version = pack.PackID, pack.Version
+			}
+			out = append(out, item)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].PackID < out[j].PackID })
+	return out, nil
+}
+
+// writeFileAtomic streams into a temp file next to dest and renames it into
+// place once fill succeeds.
+func writeFileAtomic[T any](dest string, fill func(io.Writer) (T, error)) (T, error) {
+	var zero T
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return zero, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*")
+	if err != nil {
+		return zero, err
+	}
+	defer os.Remove(tmp.Name())
+	result, err := fill(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return zero, err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return zero, err
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return zero, err
+	}
+	return result, nil
+}
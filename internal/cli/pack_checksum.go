@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"clidojo/internal/levels"
+)
+
+func runPackChecksum(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("pack checksum", stderr)
+	levelID := fs.String("level", "", "only write the manifest of this level")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: clidojo pack checksum [--level ID] <pack-dir>")
+		return exitUsage
+	}
+	loader := levels.NewLoader()
+	loader.SkipDatasetSums = true
+	pack, err := loader.LoadPack(ctx, positional[0])
+	if err != nil {
+		fmt.Fprintf(stderr, "pack checksum: %v\n", err)
+		return exitFail
+	}
+	written := 0
+	for _, level := range pack.LoadedLevels {
+		if *levelID != "" && level.LevelID != *levelID {
+			continue
+		}
+		dirs := level.DatasetDirs()
+		if len(dirs) == 0 {
+			fmt.Fprintf(stdout, "skipped %s: generated dataset\n", level.LevelID)
+			continue
+		}
+		n, err := levels.WriteDatasetSums(level.Path, dirs)
+		if err != nil {
+			fmt.Fprintf(stderr, "pack checksum: level %s: %v\n", level.LevelID, err)
+			return exitFail
+		}
+		rel, err := filepath.Rel(pack.Path, filepath.Join(level.Path, levels.DatasetSumsName))
+		if err != nil {
+			rel = filepath.Join(level.Path, levels.DatasetSumsName)
+		}
+		fmt.Fprintf(stdout, "wrote %s (%d files)\n", rel, n)
+		written++
+	}
+	if *levelID != "" && written == 0 {
+		fmt.Fprintf(stderr, "pack checksum: level %s not found in %s\n", *levelID, pack.PackID)
+		return exitFail
+	}
+	return exitOK
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"clidojo/internal/grading"
+	"clidojo/internal/levels"
+)
+
+type lintReport struct {
+	Diagnostics []levels.Diagnostic `json:"diagnostics"`
+	Errors      int                 `json:"errors"`
+	Warnings    int                 `json:"warnings"`
+}
+
+func runPackLint(_ context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("pack lint", stderr)
+	format := fs.String("format", "human", "output format: human or json")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: clidojo pack lint [--format human|json] <dir>")
+		return exitUsage
+	}
+	if !validFormat(*format) {
+		fmt.Fprintf(stderr, "invalid --format %q (want human or json)\n", *format)
+		return exitUsage
+	}
+
+	diags, err := levels.Lint(positional[0], levels.LintOptions{
+		CheckTypes: grading.NewGrader().CheckTypes(),
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "pack lint: %v\n", err)
+		return exitFail
+	}
+	report := lintReport{
+		Diagnostics: diags,
+		Errors:      levels.CountSeverity(diags, levels.SeverityError),
+		Warnings:    levels.CountSeverity(diags, levels.SeverityWarning),
+	}
+	if report.Diagnostics == nil {
+		report.Diagnostics = []levels.Diagnostic{}
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(stderr, "pack lint: %v\n", err)
+			return exitFail
+		}
+	} else {
+		for _, d := range report.Diagnostics {
+			fmt.Fprintln(stdout, d.String())
+		}
+		fmt.Fprintf(stdout, "%d error(s), %d warning(s)\n", report.Errors, report.Warnings)
+	}
+	if report.Errors > 0 {
+		return exitFail
+	}
+	return exitOK
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"clidojo/internal/levels"
+)
+
+func runPackMigrate(_ context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("pack migrate", stderr)
+	write := flags.Bool("write", false, "rewrite outdated files in place (default: report only)")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: clidojo pack migrate [--write] <dir>")
+		return exitUsage
+	}
+
+	files := []string{}
+	err = filepath.WalkDir(positional[0], func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path != positional[0] && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && (d.Name() == "pack.yaml" || d.Name() == "level.yaml") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "pack migrate: %v\n", err)
+		return exitFail
+	}
+
+	outdated, failed := 0, 0
+	for _, file := range files {
+		out, res, err := levels.MigrateFile(file)
+		if err != nil {
+			fmt.Fprintf(stderr, "pack migrate: %v\n", err)
+			failed++
+			continue
+		}
+		if !res.Changed() {
+			continue
+		}
+		outdated++
+		verb := "would migrate"
+		if *write {
+			verb = "migrated"
+			if _, err := writeFileAtomic(file, func(w io.Writer) (int, error) { return w.Write(out) }); err != nil {
+				fmt.Fprintf(stderr, "pack migrate: %v\n", err)
+				failed++
+				continue
+			}
+		}
+		fmt.Fprintf(stdout, "%s %s (%s v%d -> v%d)\n", verb, file, res.Kind, res.From, res.To)
+		for _, step := range res.Applied {
+			fmt.Fprintf(stdout, "  %s\n", step)
+		}
+	}
+	fmt.Fprintf(stdout, "%d of %d file(s) outdated\n", outdated, len(files))
+	switch {
+	case failed > 0:
+		return exitFail
+	case outdated > 0 && !*write:
+		return exitFail
+	}
+	return exitOK
+}
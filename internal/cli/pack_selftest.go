@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"clidojo/internal/levels"
+	"clidojo/internal/selftest"
+)
+
+func runPackTest(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("pack test", stderr)
+	format := fs.String("format", "human", "output format: human or json")
+	sandboxMode := fs.String("sandbox", "auto", "sandbox mode: auto, docker, podman")
+	engine := fs.String("engine", "", "force container engine: docker or podman")
+	levelFilter := fs.String("level", "", "comma-separated level IDs to test (default all)")
+	timeout := fs.Duration("timeout", 30*time.Second, "per-script timeout")
+	keep := fs.Bool("keep-workdirs", false, "keep staged workdirs for inspection")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: clidojo pack test [--format human|json] [--level id,...] [--sandbox auto|docker|podman] <dir>")
+		return exitUsage
+	}
+	if !validFormat(*format) {
+		fmt.Fprintf(stderr, "invalid --format %q (want human or json)\n", *format)
+		return exitUsage
+	}
+
+	packs, err := loadPacksAt(ctx, positional[0])
+	if err != nil {
+		fmt.Fprintf(stderr, "pack test: %v\n", err)
+		return exitFail
+	}
+
+	opts := selftest.Options{
+		SandboxMode:    *sandboxMode,
+		EngineOverride: *engine,
+		KeepWorkdirs:   *keep,
+		LevelIDs:       splitList(*levelFilter),
+		ScriptTimeout:  *timeout,
+	}
+	if *format == "human" {
+		opts.OnCase = func(c selftest.CaseResult) {
+			status := "PASS"
+			if !c.Passed {
+				status = "FAIL"
+			}
+			level := c.PackID + "/" + c.LevelID
+			if c.Variant != "" {
+				level += "@" + c.Variant
+			}
+			fmt.Fprintf(stdout, "%s %s %s:%s (%dms)\n", status, level, c.Kind, c.CaseID, c.DurationMS)
+			if !c.Passed {
+				fmt.Fprintf(stdout, "    %s\n", c.Message)
+				if c.Output != "" {
+					fmt.Fprintf(stdout, "    output: %s\n", strings.ReplaceAll(c.Output, "\n", "\n            "))
+				}
+			}
+		}
+	}
+	report, err := selftest.NewRunner(opts).Run(ctx, packs)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack test: %v\n", err)
+		return exitFail
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(stderr, "pack test: %v\n", err)
+			return exitFail
+		}
+	} else {
+		fmt.Fprintf(stdout, "%d passed, %d failed\n", report.Passed, report.Failed)
+	}
+	if report.Failed > 0 {
+		return exitFail
+	}
+	return exitOK
+}
+
+// loadPacksAt loads dir as a single pack when it holds pack.yaml, otherwise
+// as a directory of packs.
+func loadPacksAt(ctx context.Context, dir string) ([]levels.Pack, error) {
+	loader := levels.NewLoader()
+	if _, err := os.Stat(filepath.Join(dir, "pack.yaml")); err == nil {
+		pack, err := loader.LoadPack(ctx, dir)
+		if err != nil {
+			return nil, err
+		}
+		return []levels.Pack{pack}, nil
+	}
+	packs, err := loader.LoadPacks(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(packs) == 0 {
+		return nil, fmt.Errorf("no pack.yaml found in %s or its subdirectories", dir)
+	}
+	return packs, nil
+}
+
+func splitList(s string) []string {
+	out := []string{}
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
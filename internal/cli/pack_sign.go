@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"clidojo/internal/levels"
+)
+
+func runPackKeygen(_ context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("pack keygen", stderr)
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: clidojo pack keygen <name>   (writes <name>.key and <name>.pub)")
+		return exitUsage
+	}
+	id, err := levels.GenerateKey(positional[0])
+	if err != nil {
+		fmt.Fprintf(stderr, "pack keygen: %v\n", err)
+		return exitFail
+	}
+	fmt.Fprintf(stdout, "wrote %s.key and %s.pub (key %s)\n", positional[0], positional[0], id)
+	return exitOK
+}
+
+func runPackSign(_ context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("pack sign", stderr)
+	keyPath := fs.String("key", "", "ed25519 private key (PEM) from `pack keygen`")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 || *keyPath == "" {
+		fmt.Fprintln(stderr, "usage: clidojo pack sign --key <file.key> <pack-dir>")
+		return exitUsage
+	}
+	priv, err := levels.ReadPrivateKey(*keyPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack sign: %v\n", err)
+		return exitFail
+	}
+	sig, err := levels.SignPack(positional[0], priv)
+	if err != nil {
+		fmt.Fprintf(stderr, "pack sign: %v\n", err)
+		return exitFail
+	}
+	fmt.Fprintf(stdout, "signed %s %s with key %s (%d files)\n", sig.Signed.PackID, sig.Signed.Version, sig.KeyID, len(sig.Signed.Files))
+	return exitOK
+}
+
+func runPackVerify(_ context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("pack verify", stderr)
+	dataDir := fs.String("data-dir", defaultDataDir(), "data dir holding the trust store")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: clidojo pack verify [--data-dir D] <pack-dir|file.dojopack>")
+		return exitUsage
+	}
+	store, err := levels.LoadTrustStore(levels.TrustStoreDir(*dataDir))
+	if err != nil {
+		fmt.Fprintf(stderr, "pack verify: %v\n", err)
+	}
+	dir := positional[0]
+	if strings.HasSuffix(dir, levels.ArchiveExt) {
+		tmp, err := os.MkdirTemp("", "clidojo-verify-")
+		if err != nil {
+			fmt.Fprintf(stderr, "pack verify: %v\n", err)
+			return exitFail
+		}
+		defer os.RemoveAll(tmp)
+		dir = filepath.Join(tmp, "pack")
+		if _, err := levels.ExtractArchive(positional[0], dir); err != nil {
+			fmt.Fprintf(stderr, "pack verify: %v\n", err)
+			return exitFail
+		}
+	}
+	trust := levels.VerifyPack(dir, store)
+	line := trust.Status
+	if trust.KeyID != "" {
+		line += " (key " + trust.KeyID + ")"
+	}
+	if trust.Detail != "" {
+		line += ": " + trust.Detail
+	}
+	fmt.Fprintln(stdout, line)
+	if !trust.Trusted() {
+		return exitFail
+	}
+	return exitOK
+}
+
+func runPackTrust(_ context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("pack trust", stderr)
+	dataDir := fs.String("data-dir", defaultDataDir(), "data dir holding the trust store")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return exitUsage
+	}
+	usage := "usage: clidojo pack trust [--data-dir D] add <file.pub> | list | remove <key_id>"
+	if len(positional) == 0 {
+		fmt.Fprintln(stderr, usage)
+		return exitUsage
+	}
+	store, err := levels.LoadTrustStore(levels.TrustStoreDir(*dataDir))
+	if err != nil {
+		fmt.Fprintf(stderr, "pack trust: %v\n", err)
+	}
+	switch {
+	case positional[0] == "add" && len(positional) == 2:
+		pub, err := levels.ReadPublicKey(positional[1])
+		if err != nil {
+			fmt.Fprintf(stderr, "pack trust: %v\n", err)
+			return exitFail
+		}
+		id, err := store.Add(pub)
+		if err != nil {
+			fmt.Fprintf(stderr, "pack trust: %v\n", err)
+			return exitFail
+		}
+		fmt.Fprintf(stdout, "trusted key %s\n", id)
+	case positional[0] == "list" && len(positional) == 1:
+		ids := make([]string, 0, len(store.Keys))
+		for id := range store.Keys {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		if len(ids) == 0 {
+			fmt.Fprintf(stdout, "no trusted keys in %s\n", store.Dir)
+		}
+		for _, id := range ids {
+			fmt.Fprintln(stdout, id)
+		}
+	case positional[0] == "remove" && len(positional) == 2:
+		if err := store.Remove(positional[1]); err != nil {
+			fmt.Fprintf(stderr, "pack trust: %v\n", err)
+			return exitFail
+		}
+		fmt.Fprintf(stdout, "removed key %s\n", positional[1])
+	default:
+		fmt.Fprintln(stderr, usage)
+		return exitUsage
+	}
+	return exitOK
+}
+
+// defaultDataDir mirrors the TUI's --data-dir default.
+func defaultDataDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".local", "share", "clidojo")
+}
@@ -0,0 +1,213 @@
+package grading
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// csvTable is a parsed CSV file. Without a header, columns are named by
+// position ("column 1").
+type csvTable struct {
+	columns []string
+	rows    [][]string
+}
+
+func (g *DefaultGrader) evalFileCSVEquals(_ context.Context, req Request, check CheckSpec) (evaluation, error) {
+	b, err := os.ReadFile(resolveWorkPath(req.WorkDir, check.Path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return evaluation{Passed: false, Summary: "file missing", Message: "file not found"}, nil
+		}
+		return evaluation{}, err
+	}
+	actual, err := parseCSV(b, check.CSV)
+	if err != nil {
+		return evaluation{Passed: false, Summary: "invalid csv", Message: err.Error()}, nil
+	}
+	want := []byte(expectedText(check))
+	if check.CompareToPath != "" {
+		want, err = os.ReadFile(resolveWorkPath(req.WorkDir, check.CompareToPath))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return evaluation{Passed: false, Summary: "file missing", Message: "compare file not found"}, nil
+			}
+			return evaluation{}, err
+		}
+	}
+	expected, err := parseCSV(want, check.CSV)
+	if err != nil {
+		return evaluation{}, fmt.Errorf("check %s expected: %w", check.ID, err)
+	}
+
+	lines := diffCSV(expected, actual, check.CSV.IgnoreRowOrder)
+	if len(lines) == 0 {
+		return evaluation{Passed: true, Summary: "table matches", Message: "ok"}, nil
+	}
+	artifact := structuralDiff(check, lines)
+	return evaluation{Passed: false, Summary: "table mismatch", Message: fmt.Sprintf("%d differences", len(lines)), Artifact: &artifact}, nil
+}
+
+func parseCSV(b []byte, spec CSVSpec) (csvTable, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(b, []byte("\ufeff"))))
+	r.FieldsPerRecord = -1
+	if spec.Delimiter != "" {
+		r.Comma = []rune(spec.Delimiter)[0]
+	}
+	records, err := r.ReadAll()
+	if err != nil {
+		return csvTable{}, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if spec.TrimSpace {
+		for _, rec := range records {
+			for i := range rec {
+				rec[i] = strings.TrimSpace(rec[i])
+			}
+		}
+	}
+	t := csvTable{rows: records}
+	if (spec.Header == nil || *spec.Header) && len(records) > 0 {
+		// Columns are matched by name, so a repeated name would hide one.
+		seen := map[string]bool{}
+		for _, c := range records[0] {
+			if seen[c] {
+				return csvTable{}, fmt.Errorf("duplicate column %q in header", c)
+			}
+			seen[c] = true
+		}
+		t.columns, t.rows = records[0], records[1:]
+		return t, nil
+	}
+	width := 0
+	for _, rec := range records {
+		width = max(width, len(rec))
+	}
+	for i := 1; i <= width; i++ {
+		t.columns = append(t.columns, "column "+strconv.Itoa(i))
+	}
+	return t, nil
+}
+
+// diffCSV compares two tables. Columns are matched by name, so their order
+// does not matter; when the column sets differ only that is reported, as
+// every row would differ too.
+func diffCSV(expected, actual csvTable, ignoreRowOrder bool) []string {
+	lines := []string{}
+	index := map[string]int{}
+	for i, c := range actual.columns {
+		index[c] = i
+	}
+	proj := make([]int, len(expected.columns))
+	for i, c := range expected.columns {
+		j, ok := index[c]
+		if !ok {
+			lines = append(lines, "- "+columnLabel(c))
+		}
+		proj[i] = j
+		delete(index, c)
+	}
+	extra := make([]string, 0, len(index))
+	for c := range index {
+		extra = append(extra, c)
+	}
+	sort.Slice(extra, func(a, b int) bool { return index[extra[a]] < index[extra[b]] })
+	for _, c := range extra {
+		lines = append(lines, "+ "+columnLabel(c))
+	}
+	if len(lines) > 0 {
+		if len(expected.rows) != len(actual.rows) {
+			lines = append(lines, fmt.Sprintf("~ rows: %d → %d", len(expected.rows), len(actual.rows)))
+		}
+		return lines
+	}
+
+	// Actual rows in expected column order.
+	rows := make([][]string, len(actual.rows))
+	for r, rec := range actual.rows {
+		rows[r] = make([]string, len(proj))
+		for i, j := range proj {
+			if j < len(rec) {
+				rows[r][i] = rec[j]
+			}
+		}
+	}
+	if ignoreRowOrder {
+		return diffRowsUnordered(expected.rows, rows, len(expected.columns))
+	}
+	for r := 0; r < len(expected.rows) || r < len(rows); r++ {
+		switch {
+		case r >= len(rows):
+			lines = append(lines, fmt.Sprintf("- row %d: %s", r+1, joinRow(expected.rows[r])))
+		case r >= len(expected.rows):
+			lines = append(lines, fmt.Sprintf("+ row %d: %s", r+1, joinRow(rows[r])))
+		default:
+			for i, c := range expected.columns {
+				want := cell(expected.rows[r], i)
+				if got := rows[r][i]; got != want {
+					lines = append(lines, fmt.Sprintf("~ row %d, %s: %q → %q", r+1, columnLabel(c), want, got))
+				}
+			}
+		}
+	}
+	return lines
+}
+
+func diffRowsUnordered(expected, actual [][]string, width int) []string {
+	lines := []string{}
+	unmatched := map[string][]int{}
+	for r, rec := range actual {
+		key := strings.Join(rec, "\x00")
+		unmatched[key] = append(unmatched[key], r)
+	}
+	for r, rec := range expected {
+		key := strings.Join(padRow(rec, width), "\x00")
+		if idx := unmatched[key]; len(idx) > 0 {
+			unmatched[key] = idx[1:]
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("- row %d: %s", r+1, joinRow(rec)))
+	}
+	extra := []int{}
+	for _, idx := range unmatched {
+		extra = append(extra, idx...)
+	}
+	sort.Ints(extra)
+	for _, r := range extra {
+		lines = append(lines, fmt.Sprintf("+ row %d: %s", r+1, joinRow(actual[r])))
+	}
+	return lines
+}
+
+func cell(rec []string, i int) string {
+	if i < len(rec) {
+		return rec[i]
+	}
+	return ""
+}
+
+// padRow cuts or pads rec to n cells.
+func padRow(rec []string, n int) []string {
+	out := make([]string, n)
+	copy(out, rec)
+	return out
+}
+
+func columnLabel(c string) string {
+	if strings.HasPrefix(c, "column ") {
+		return c
+	}
+	return "column " + strconv.Quote(c)
+}
+
+func joinRow(rec []string) string {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	_ = w.Write(rec)
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
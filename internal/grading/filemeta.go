@@ -0,0 +1,312 @@
+package grading
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"clidojo/internal/levels"
+)
+
+// fileStat is the part of stat(2) the file metadata checks look at.
+type fileStat struct {
+	// mode holds the type and permission bits as in st_mode.
+	mode  uint32
+	uid   int
+	gid   int
+	user  string
+	group string
+	links int
+	mtime time.Time
+}
+
+// fileMeta describes a check path. lstat is the path itself; stat follows
+// symlinks and is nil for a dangling one.
+type fileMeta struct {
+	lstat  fileStat
+	stat   *fileStat
+	target string
+}
+
+const (
+	modeTypeMask = 0o170000
+	modePermMask = 0o7777
+)
+
+var fileTypeBits = map[uint32]string{
+	0o100000: "regular",
+	0o040000: "dir",
+	0o120000: "symlink",
+	0o010000: "fifo",
+	0o140000: "socket",
+	0o020000: "char",
+	0o060000: "block",
+}
+
+func (s fileStat) fileType() string {
+	if t, ok := fileTypeBits[s.mode&modeTypeMask]; ok {
+		return t
+	}
+	return "unknown"
+}
+
+// statFormat is the stat -c format parseStatOutput reads.
+const statFormat = "%f %u %g %h %Y %U %G"
+
+// statCheckPath stats a check path where the player sees it: inside the
+// level container when there is one, since host UIDs differ from container
+// UIDs, and under WorkDir otherwise. A missing path returns nil.
+func statCheckPath(ctx context.Context, req Request, path string) (*fileMeta, error) {
+	if req.Engine != "docker" && req.Engine != "podman" {
+		return statHostPath(resolveWorkPath(req.WorkDir, path))
+	}
+	script := strings.Join([]string{
+		"p=" + shellQuote(path),
+		`if [ ! -e "$p" ] && [ ! -L "$p" ]; then echo missing; exit 0; fi`,
+		`stat -c '` + statFormat + `' -- "$p"`,
+		`stat -L -c '` + statFormat + `' -- "$p" 2>/dev/null || echo dangling`,
+		`readlink -- "$p" || true`,
+	}, "\n")
+	out, err := runCommand(ctx, req, script, 0)
+	if err != nil {
+		return nil, err
+	}
+	return parseStatOutput(string(out))
+}
+
+// parseStatOutput reads the output of statCheckPath's script: "missing", or
+// the lstat line, the stat line (or "dangling") and the symlink target.
+func parseStatOutput(out string) (*fileMeta, error) {
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	if lines[0] == "missing" {
+		return nil, nil
+	}
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("unexpected stat output: %q", out)
+	}
+	lstat, err := parseStatLine(lines[0])
+	if err != nil {
+		return nil, err
+	}
+	meta := &fileMeta{lstat: lstat}
+	if lines[1] != "dangling" {
+		stat, err := parseStatLine(lines[1])
+		if err != nil {
+			return nil, err
+		}
+		meta.stat = &stat
+	}
+	if len(lines) > 2 {
+		meta.target = strings.Join(lines[2:], "\n")
+	}
+	return meta, nil
+}
+
+func parseStatLine(line string) (fileStat, error) {
+	f := strings.Fields(line)
+	if len(f) != 7 {
+		return fileStat{}, fmt.Errorf("unexpected stat output: %q", line)
+	}
+	mode, err1 := strconv.ParseUint(f[0], 16, 32)
+	uid, err2 := strconv.Atoi(f[1])
+	gid, err3 := strconv.Atoi(f[2])
+	links, err4 := strconv.Atoi(f[3])
+	mtime, err5 := strconv.ParseInt(f[4], 10, 64)
+	for _, err := range []error{err1, err2, err3, err4, err5} {
+		if err != nil {
+			return fileStat{}, fmt.Errorf("unexpected stat output: %q", line)
+		}
+	}
+	return fileStat{mode: uint32(mode), uid: uid, gid: gid, links: links, mtime: time.Unix(mtime, 0), user: f[5], group: f[6]}, nil
+}
+
+func statHostPath(path string) (*fileMeta, error) {
+	lstat, err := hostStat(path, false)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	meta := &fileMeta{lstat: lstat}
+	if stat, err := hostStat(path, true); err == nil {
+		meta.stat = &stat
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if lstat.fileType() == "symlink" {
+		if meta.target, err = os.Readlink(path); err != nil {
+			return nil, err
+		}
+	}
+	return meta, nil
+}
+
+// followedStat stats a check path through symlinks, as chmod, chown and touch
+// do. A failed evaluation is returned for a missing path or dangling link.
+func followedStat(ctx context.Context, req Request, path string) (*fileStat, *evaluation, error) {
+	meta, err := statCheckPath(ctx, req, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if meta == nil {
+		return nil, &evaluation{Passed: false, Summary: "file missing", Message: "file not found"}, nil
+	}
+	if meta.stat == nil {
+		return nil, &evaluation{Passed: false, Summary: "dangling symlink", Message: "symlink target " + meta.target + " not found"}, nil
+	}
+	return meta.stat, nil, nil
+}
+
+func (g *DefaultGrader) evalFileMode(ctx context.Context, req Request, check CheckSpec) (evaluation, error) {
+	st, eval, err := followedStat(ctx, req, check.Path)
+	if err != nil || eval != nil {
+		return derefEval(eval), err
+	}
+	perm := st.mode & modePermMask
+	if check.Perm != "" {
+		want, err := levels.ParsePerm(check.Perm)
+		if err != nil {
+			return evaluation{}, err
+		}
+		if perm != want {
+			return evaluation{Passed: false, Summary: "mode mismatch", Message: fmt.Sprintf("expected %04o got %04o", want, perm)}, nil
+		}
+	}
+	if check.PermSet != "" {
+		want, err := levels.ParsePerm(check.PermSet)
+		if err != nil {
+			return evaluation{}, err
+		}
+		if missing := want &^ perm; missing != 0 {
+			return evaluation{Passed: false, Summary: "mode bits not set", Message: fmt.Sprintf("bits %04o not set in %04o", missing, perm)}, nil
+		}
+	}
+	if check.PermUnset != "" {
+		want, err := levels.ParsePerm(check.PermUnset)
+		if err != nil {
+			return evaluation{}, err
+		}
+		if extra := want & perm; extra != 0 {
+			return evaluation{Passed: false, Summary: "mode bits set", Message: fmt.Sprintf("bits %04o set in %04o", extra, perm)}, nil
+		}
+	}
+	return evaluation{Passed: true, Summary: "mode matches", Message: "ok"}, nil
+}
+
+func (g *DefaultGrader) evalFileOwner(ctx context.Context, req Request, check CheckSpec) (evaluation, error) {
+	st, eval, err := followedStat(ctx, req, check.Path)
+	if err != nil || eval != nil {
+		return derefEval(eval), err
+	}
+	if check.Owner != "" && !matchesOwner(check.Owner, st.uid, st.user) {
+		return evaluation{Passed: false, Summary: "owner mismatch", Message: fmt.Sprintf("expected owner %s got %s (%d)", check.Owner, st.user, st.uid)}, nil
+	}
+	if check.Group != "" && !matchesOwner(check.Group, st.gid, st.group) {
+		return evaluation{Passed: false, Summary: "group mismatch", Message: fmt.Sprintf("expected group %s got %s (%d)", check.Group, st.group, st.gid)}, nil
+	}
+	return evaluation{Passed: true, Summary: "owner matches", Message: "ok"}, nil
+}
+
+// matchesOwner compares a user or group given by name or numeric ID.
+func matchesOwner(want string, id int, name string) bool {
+	if n, err := strconv.Atoi(want); err == nil {
+		return n == id
+	}
+	return want == name
+}
+
+func (g *DefaultGrader) evalFileType(ctx context.Context, req Request, check CheckSpec) (evaluation, error) {
+	meta, err := statCheckPath(ctx, req, check.Path)
+	if err != nil {
+		return evaluation{}, err
+	}
+	if meta == nil {
+		return evaluation{Passed: false, Summary: "file missing", Message: "file not found"}, nil
+	}
+	if got := meta.lstat.fileType(); got != check.FileType {
+		return evaluation{Passed: false, Summary: "type mismatch", Message: fmt.Sprintf("expected %s got %s", check.FileType, got)}, nil
+	}
+	return evaluation{Passed: true, Summary: "type matches", Message: "ok"}, nil
+}
+
+func (g *DefaultGrader) evalSymlinkTarget(ctx context.Context, req Request, check CheckSpec) (evaluation, error) {
+	meta, err := statCheckPath(ctx, req, check.Path)
+	if err != nil {
+		return evaluation{}, err
+	}
+	if meta == nil {
+		return evaluation{Passed: false, Summary: "file missing", Message: "file not found"}, nil
+	}
+	if t := meta.lstat.fileType(); t != "symlink" {
+		return evaluation{Passed: false, Summary: "not a symlink", Message: "expected symlink got " + t}, nil
+	}
+	if check.Pattern != "" {
+		r, err := regexp.Compile(check.Pattern)
+		if err != nil {
+			return evaluation{}, err
+		}
+		if !r.MatchString(meta.target) {
+			return evaluation{Passed: false, Summary: "target mismatch", Message: fmt.Sprintf("target %s does not match %s", meta.target, check.Pattern)}, nil
+		}
+	} else if want := expectedText(check); meta.target != want {
+		return evaluation{Passed: false, Summary: "target mismatch", Message: fmt.Sprintf("expected -> %s got -> %s", want, meta.target)}, nil
+	}
+	return evaluation{Passed: true, Summary: "target matches", Message: "ok"}, nil
+}
+
+func (g *DefaultGrader) evalFileMtime(ctx context.Context, req Request, check CheckSpec) (evaluation, error) {
+	st, eval, err := followedStat(ctx, req, check.Path)
+	if err != nil || eval != nil {
+		return derefEval(eval), err
+	}
+	// The container reports whole seconds.
+	mtime := st.mtime.Truncate(time.Second).UTC()
+	if check.MtimeAfter != "" {
+		after, err := levels.ParseCheckTime(check.MtimeAfter)
+		if err != nil {
+			return evaluation{}, err
+		}
+		if mtime.Before(after) {
+			return evaluation{Passed: false, Summary: "modified too early", Message: fmt.Sprintf("mtime %s is before %s", mtime.Format(time.RFC3339), after.Format(time.RFC3339))}, nil
+		}
+	}
+	if check.MtimeBefore != "" {
+		before, err := levels.ParseCheckTime(check.MtimeBefore)
+		if err != nil {
+			return evaluation{}, err
+		}
+		if mtime.After(before) {
+			return evaluation{Passed: false, Summary: "modified too late", Message: fmt.Sprintf("mtime %s is after %s", mtime.Format(time.RFC3339), before.Format(time.RFC3339))}, nil
+		}
+	}
+	return evaluation{Passed: true, Summary: "mtime within range", Message: "ok"}, nil
+}
+
+func (g *DefaultGrader) evalHardlinkCount(ctx context.Context, req Request, check CheckSpec) (evaluation, error) {
+	st, eval, err := followedStat(ctx, req, check.Path)
+	if err != nil || eval != nil {
+		return derefEval(eval), err
+	}
+	if check.Equals > 0 {
+		if st.links == check.Equals {
+			return evaluation{Passed: true, Summary: "link count matches", Message: "ok"}, nil
+		}
+		return evaluation{Passed: false, Summary: "link count mismatch", Message: fmt.Sprintf("expected %d links got %d", check.Equals, st.links)}, nil
+	}
+	if check.Min != nil && st.links < *check.Min {
+		return evaluation{Passed: false, Summary: "link count below minimum", Message: fmt.Sprintf("min %d got %d", *check.Min, st.links)}, nil
+	}
+	if check.Max != nil && st.links > *check.Max {
+		return evaluation{Passed: false, Summary: "link count above maximum", Message: fmt.Sprintf("max %d got %d", *check.Max, st.links)}, nil
+	}
+	return evaluation{Passed: true, Summary: "link count within range", Message: "ok"}, nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package grading
+
+import "fmt"
+
+// hostStat needs unix stat fields; elsewhere the checks need a container.
+func hostStat(path string, _ bool) (fileStat, error) {
+	return fileStat{}, fmt.Errorf("stat %s: file metadata checks need docker or podman on this platform", path)
+}
@@ -0,0 +1,100 @@
+//go:build unix
+
+package grading
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFileMetadataChecksOnHost(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out.txt")
+	if err := os.WriteFile(out, []byte("x\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, err := range []error{
+		os.Chmod(out, 0o640),
+		os.Chtimes(out, mtime, mtime),
+		os.Link(out, filepath.Join(dir, "copy.txt")),
+		os.Symlink("out.txt", filepath.Join(dir, "link")),
+		os.Symlink("gone.txt", filepath.Join(dir, "dangling")),
+		os.Mkdir(filepath.Join(dir, "sub"), 0o755),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	uid := strconv.Itoa(os.Getuid())
+	pass := []CheckSpec{
+		{Type: "file_mode", Path: "/work/out.txt", Perm: "640"},
+		{Type: "file_mode", Path: "/work/link", PermSet: "0600", PermUnset: "0007"},
+		{Type: "file_owner", Path: "/work/out.txt", Owner: uid},
+		{Type: "file_type", Path: "/work/link", FileType: "symlink"},
+		{Type: "file_type", Path: "/work/sub", FileType: "dir"},
+		{Type: "symlink_target", Path: "/work/link", Expected: strPtr("out.txt")},
+		{Type: "symlink_target", Path: "/work/dangling", Pattern: `^gone`},
+		{Type: "file_mtime", Path: "/work/out.txt", MtimeAfter: "2024-01-02", MtimeBefore: "2024-01-02 03:04:05"},
+		{Type: "hardlink_count", Path: "/work/copy.txt", Equals: 2},
+	}
+	fail := map[string]CheckSpec{
+		"mode mismatch":      {Type: "file_mode", Path: "/work/out.txt", Perm: "0644"},
+		"mode bits set":      {Type: "file_mode", Path: "/work/out.txt", PermUnset: "0040"},
+		"dangling symlink":   {Type: "file_mode", Path: "/work/dangling", Perm: "0644"},
+		"owner mismatch":     {Type: "file_owner", Path: "/work/out.txt", Owner: "no-such-user"},
+		"type mismatch":      {Type: "file_type", Path: "/work/link", FileType: "regular"},
+		"not a symlink":      {Type: "symlink_target", Path: "/work/out.txt", Expected: strPtr("x")},
+		"modified too early": {Type: "file_mtime", Path: "/work/out.txt", MtimeAfter: "2024-01-02T03:04:06Z"},
+		"file missing":       {Type: "hardlink_count", Path: "/work/none.txt", Equals: 1},
+	}
+	checks := []CheckSpec{}
+	for i, c := range pass {
+		c.ID, c.Required = "pass"+strconv.Itoa(i), true
+		checks = append(checks, c)
+	}
+	for summary, c := range fail {
+		c.ID, c.Required = summary, true
+		checks = append(checks, c)
+	}
+	res, err := NewGrader().Grade(context.Background(), Request{LevelID: "l", WorkDir: dir, Checks: checks})
+	if err != nil {
+		t.Fatalf("grade: %v", err)
+	}
+	for i, r := range res.Checks {
+		if i < len(pass) && !r.Passed {
+			t.Errorf("%s %s: %s", checks[i].Type, checks[i].Path, r.Message)
+		}
+		if i >= len(pass) && (r.Passed || r.Summary != r.ID) {
+			t.Errorf("%s %s: got %q (%s), want %q", checks[i].Type, checks[i].Path, r.Summary, r.Message, r.ID)
+		}
+	}
+}
+
+func TestParseStatOutputReadsContainerStat(t *testing.T) {
+	meta, err := parseStatOutput("a1ff 0 0 1 1704164645 root root\n81a4 1000 1000 2 1704164645 dojo dojo\nout.txt\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.lstat.fileType() != "symlink" || meta.stat == nil || meta.stat.fileType() != "regular" || meta.target != "out.txt" {
+		t.Fatalf("unexpected meta: %+v", meta)
+	}
+	if st := meta.stat; st.mode&modePermMask != 0o644 || st.user != "dojo" || st.gid != 1000 || st.links != 2 || !st.mtime.Equal(time.Unix(1704164645, 0)) {
+		t.Fatalf("unexpected stat: %+v", st)
+	}
+
+	if meta, err := parseStatOutput("missing\n"); err != nil || meta != nil {
+		t.Fatalf("missing: %+v %v", meta, err)
+	}
+	if meta, err := parseStatOutput("a1ff 0 0 1 0 root root\ndangling\n../gone\n"); err != nil || meta.stat != nil || meta.target != "../gone" {
+		t.Fatalf("dangling: %+v %v", meta, err)
+	}
+	if _, err := parseStatOutput("stat: cannot stat\n"); err == nil {
+		t.Fatalf("expected an error for unexpected output")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -26,12 +27,31 @@ func NewGrader() *DefaultGrader {
 	g.registry["file_lines_count"] = g.evalFileLinesCount
 	g.registry["file_lines_match_regex"] = g.evalFileLinesMatchRegex
 	g.registry["file_sorted"] = g.evalFileSorted
+	g.registry["file_json_path"] = g.evalFileJSONPath
+	g.registry["file_json_equals"] = g.evalFileJSONEquals
+	g.registry["file_csv_equals"] = g.evalFileCSVEquals
+	g.registry["file_mode"] = g.evalFileMode
+	g.registry["file_owner"] = g.evalFileOwner
+	g.registry["file_type"] = g.evalFileType
+	g.registry["symlink_target"] = g.evalSymlinkTarget
+	g.registry["file_mtime"] = g.evalFileMtime
+	g.registry["hardlink_count"] = g.evalHardlinkCount
 	g.registry["command_output_equals_file"] = g.evalCommandOutputEqualsFile
 	g.registry["cmdlog_contains_regex"] = g.evalCmdlogContainsRegex
 	g.registry["cmdlog_forbids_regex"] = g.evalCmdlogForbidsRegex
 	return g
 }
 
+// CheckTypes returns the registered check types in sorted order.
+func (g *DefaultGrader) CheckTypes() []string {
+	out := make([]string, 0, len(g.registry))
+	for t := range g.registry {
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
+
 func (g *DefaultGrader) Grade(ctx context.Context, req Request) (Result, error) {
 	if req.FinishedAt.IsZero() {
 		req.FinishedAt = time.Now()
@@ -171,7 +191,7 @@ func (g *DefaultGrader) evalFileTextExact(_ context.Context, req Request, check
 		}
 		return evaluation{}, err
 	}
-	expected := normalizeText(check.Expected, check.Normalize)
+	expected := normalizeText(expectedText(check), check.Normalize)
 	actual := normalizeText(string(content), check.Normalize)
 	if actual == expected {
 		return evaluation{Passed: true, Summary: "content matches", Message: "ok"}, nil
@@ -290,7 +310,13 @@ func (g *DefaultGrader) evalFileSorted(_ context.Context, req Request, check Che
 }
 
 func (g *DefaultGrader) evalCommandOutputEqualsFile(ctx context.Context, req Request, check CheckSpec) (evaluation, error) {
-	out, err := runCommand(ctx, req, check.Command, check.TimeoutSeconds)
+	var out []byte
+	var err error
+	if check.Service != "" {
+		out, err = runServiceCommand(ctx, req, check.Service, check.Command, check.TimeoutSeconds)
+	} else {
+		out, err = runCommand(ctx, req, check.Command, check.TimeoutSeconds)
+	}
 	if err != nil {
 		return evaluation{}, err
 	}
@@ -388,6 +414,9 @@ func runCommand(ctx context.Context, req Request, command string, timeoutSeconds
 		}
 		return out, nil
 	}
+	if req.Restricted {
+		return nil, fmt.Errorf("command checks of sandbox-only packs need docker or podman")
+	}
 	cmd := exec.CommandContext(cctx, "bash", "-lc", command)
 	cmd.Dir = req.WorkDir
 	out, err := cmd.CombinedOutput()
@@ -397,6 +426,25 @@ func runCommand(ctx context.Context, req Request, command string, timeoutSeconds
 	return out, nil
 }
 
+// runServiceCommand runs a command in one of the level's service containers,
+// which may not have bash or /work.
+func runServiceCommand(ctx context.Context, req Request, service, command string, timeoutSeconds int) ([]byte, error) {
+	container, ok := req.Services[service]
+	if !ok || (req.Engine != "docker" && req.Engine != "podman") {
+		return nil, fmt.Errorf("service %q is not running", service)
+	}
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = 3
+	}
+	cctx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(cctx, req.Engine, "exec", "-i", container, "sh", "-c", command).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%s exec in service %s failed: %s", req.Engine, service, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
+
 func readLines(path string) ([]string, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -459,6 +507,14 @@ func compareSort(a, b, key string, ignoreCase bool) int {
 	}
 }
 
+// expectedText is the check's expected value, "" when unset.
+func expectedText(check CheckSpec) string {
+	if check.Expected == nil {
+		return ""
+	}
+	return *check.Expected
+}
+
 func normalizeText(s string, n NormalizeSpec) string {
 	if n.Newlines == "" {
 		n.Newlines = "any"
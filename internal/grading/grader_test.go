@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -70,7 +71,7 @@ func TestGradeGeneratesDiffArtifact(t *testing.T) {
 		Engine:      "mock",
 		WorkDir:     dir,
 		Checks: []CheckSpec{
-			{ID: "exact", Type: "file_text_exact", Required: true, Path: "/work/out.txt", Expected: "expected\n"},
+			{ID: "exact", Type: "file_text_exact", Required: true, Path: "/work/out.txt", Expected: strPtr("expected\n")},
 		},
 		BasePoints: 1000,
 	})
@@ -84,3 +85,23 @@ func TestGradeGeneratesDiffArtifact(t *testing.T) {
 		t.Fatalf("expected diff artifact")
 	}
 }
+
+func TestRestrictedCommandChecksDoNotRunOnTheHost(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "ran")
+	req := Request{
+		LevelID: "l",
+		Engine:  "mock",
+		WorkDir: dir,
+		Checks: []CheckSpec{
+			{ID: "cmd", Type: "command_output_equals_file", Required: true, Command: "touch " + marker, CompareToPath: "/work/ran"},
+		},
+		Restricted: true,
+	}
+	if _, err := NewGrader().Grade(context.Background(), req); err == nil || !strings.Contains(err.Error(), "sandbox-only") {
+		t.Fatalf("expected a sandbox-only error, got %v", err)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Fatalf("command ran on the host")
+	}
+}
@@ -0,0 +1,69 @@
+package grading
+
+import "clidojo/internal/levels"
+
+// ChecksForLevel maps a level's checks, those of all its stages, and its
+// cmdlog bonuses to grader specs. Use Level.AtStage to grade a single stage.
+func ChecksForLevel(level levels.Level) []CheckSpec {
+	checks := level.AllChecks()
+	out := make([]CheckSpec, 0, len(checks)+len(level.Scoring.CmdlogBonuses))
+	for _, c := range checks {
+		required := c.Required == nil || *c.Required
+		out = append(out, CheckSpec{
+			ID:             c.ID,
+			Type:           c.Type,
+			Description:    c.Description,
+			Required:       required,
+			Points:         c.Points,
+			OnFailMessage:  c.OnFailMessage,
+			OnPassMessage:  c.OnPassMessage,
+			Path:           c.Path,
+			Expected:       c.Expected,
+			Normalize:      NormalizeSpec(c.Normalize),
+			Equals:         c.Equals,
+			Min:            c.Min,
+			Max:            c.Max,
+			Pattern:        c.Pattern,
+			Mode:           c.Mode,
+			MinMatches:     c.MinMatches,
+			Order:          c.Order,
+			Key:            c.Key,
+			Unique:         c.Unique,
+			IgnoreCase:     c.IgnoreCase,
+			Split:          FileSplitSpec(c.Split),
+			Column:         c.Column,
+			Command:        c.Command,
+			CompareToPath:  c.CompareToPath,
+			TimeoutSeconds: c.TimeoutSeconds,
+			MinCount:       c.MinCount,
+			Service:        c.Service,
+
+			Query:            c.Query,
+			ValueType:        c.ValueType,
+			Count:            c.Count,
+			Format:           c.Format,
+			IgnoreArrayOrder: c.IgnoreArrayOrder,
+			CSV:              CSVSpec(c.CSV),
+
+			Perm:        c.Perm,
+			PermSet:     c.PermSet,
+			PermUnset:   c.PermUnset,
+			Owner:       c.Owner,
+			Group:       c.Group,
+			FileType:    c.FileType,
+			MtimeAfter:  c.MtimeAfter,
+			MtimeBefore: c.MtimeBefore,
+		})
+	}
+	for _, bonus := range level.Scoring.CmdlogBonuses {
+		out = append(out, CheckSpec{
+			ID:       bonus.ID,
+			Type:     "cmdlog_contains_regex",
+			Required: false,
+			Points:   bonus.Points,
+			Pattern:  bonus.Pattern,
+			MinCount: 1,
+		})
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package grading
+
+// MergeStages combines the results of a multi-stage level's stages, in order,
+// into the result of the whole level: checks, artifacts and stages are
+// concatenated, scores are summed and the run spans the stages' durations.
+// The run and engine details otherwise come from the final stage.
+func MergeStages(results []Result) Result {
+	if len(results) == 0 {
+		return Result{}
+	}
+	out := results[len(results)-1]
+	out.Passed = true
+	out.Checks, out.Artifacts, out.Stages = nil, nil, nil
+	out.Score = Score{}
+	breakdown := map[string]int{}
+	for _, r := range results {
+		out.Passed = out.Passed && r.Passed
+		out.Checks = append(out.Checks, r.Checks...)
+		out.Artifacts = append(out.Artifacts, r.Artifacts...)
+		out.Stages = append(out.Stages, r.Stages...)
+
+		s := r.Score
+		out.Score.BasePoints += s.BasePoints
+		out.Score.TimeGraceSeconds += s.TimeGraceSeconds
+		out.Score.TimePenaltyPoints += s.TimePenaltyPoints
+		out.Score.HintPenaltyPoints += s.HintPenaltyPoints
+		out.Score.ResetPenaltyPoints += s.ResetPenaltyPoints
+		out.Score.OptionalBonusPoints += s.OptionalBonusPoints
+		out.Score.TotalPoints += s.TotalPoints
+		for _, d := range s.Breakdown {
+			if i, ok := breakdown[d.Kind]; ok {
+				out.Score.Breakdown[i].Points += d.Points
+				continue
+			}
+			breakdown[d.Kind] = len(out.Score.Breakdown)
+			out.Score.Breakdown = append(out.Score.Breakdown, d)
+		}
+	}
+	var duration int64
+	for _, s := range out.Stages {
+		duration += s.DurationMS
+	}
+	out.Run.DurationMS = duration
+	out.Run.StartedAtUnixMS = out.Run.FinishedAtUnixMS - duration
+	return out
+}
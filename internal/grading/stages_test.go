@@ -0,0 +1,48 @@
+package grading
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeStagesSumsStageScores(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.log"), []byte("ERROR 10.0.0.1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	g := NewGrader()
+	grade := func(id string, hints int, checks ...CheckSpec) Result {
+		res, err := g.Grade(context.Background(), Request{LevelID: "level-incident", WorkDir: dir, BasePoints: 500, HintPenaltyPoints: 80, HintsUsed: hints, Checks: checks})
+		if err != nil {
+			t.Fatalf("grade %s: %v", id, err)
+		}
+		res.Stages = []StageResult{{StageID: id, DurationMS: 30_000, Points: res.Score.TotalPoints}}
+		return res
+	}
+	find := grade("find", 1, CheckSpec{ID: "bad_log", Type: "file_exists", Required: true, Path: "/work/bad.log"})
+	extract := grade("extract", 0,
+		CheckSpec{ID: "ip", Type: "file_lines_match_regex", Required: true, Path: "/work/bad.log", Pattern: `10\.0\.0\.1`, Mode: "any_line"},
+		CheckSpec{ID: "bonus", Type: "file_exists", Points: 25, Path: "/work/bad.log"},
+	)
+
+	merged := MergeStages([]Result{find, extract})
+	if !merged.Passed || len(merged.Checks) != 3 || len(merged.Stages) != 2 {
+		t.Fatalf("merged result: passed=%v checks=%d stages=%d", merged.Passed, len(merged.Checks), len(merged.Stages))
+	}
+	if merged.Score.TotalPoints != 420+525 || merged.Score.BasePoints != 1000 || merged.Score.HintPenaltyPoints != 80 {
+		t.Fatalf("merged score: %+v", merged.Score)
+	}
+	for _, d := range merged.Score.Breakdown {
+		if d.Kind == "bonus" && d.Points != 25 {
+			t.Fatalf("bonus breakdown = %d", d.Points)
+		}
+	}
+	if merged.Run.DurationMS != 60_000 || merged.Run.FinishedAtUnixMS-merged.Run.StartedAtUnixMS != 60_000 {
+		t.Fatalf("run should span both stages: %+v", merged.Run)
+	}
+	if merged.Stages[1].StageID != "extract" || merged.Stages[0].Points != 420 {
+		t.Fatalf("stages: %+v", merged.Stages)
+	}
+}
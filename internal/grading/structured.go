@@ -0,0 +1,445 @@
+package grading
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxDiffLines caps structural diffs so one broken file cannot flood the
+// result.
+const maxDiffLines = 40
+
+// querySeg is one step of a file_json_path query: an object key, an array
+// index (negative counts from the end) or every element.
+type querySeg struct {
+	key     string
+	index   int
+	isIndex bool
+	all     bool
+}
+
+var (
+	queryName  = regexp.MustCompile(`^\.([A-Za-z_][A-Za-z0-9_-]*)`)
+	queryIndex = regexp.MustCompile(`^\[(-?[0-9]+)\]`)
+	queryAll   = regexp.MustCompile(`^(\[\*?\]|\.\*)`)
+	queryKey   = regexp.MustCompile(`^\["((?:[^"\\]|\\.)*)"\]`)
+	plainKey   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+)
+
+// parseQuery parses the jq-like path subset file_json_path accepts:
+// .name, ["any key"], [2], [-1] and [] (or [*], .*) for every element. A
+// leading $ is allowed and "." alone is the whole document.
+func parseQuery(q string) ([]querySeg, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(q), "$")
+	if rest == "." || rest == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(rest, ".") && !strings.HasPrefix(rest, "[") {
+		rest = "." + rest
+	}
+	segs := []querySeg{}
+	for rest != "" {
+		// ".[0]" is jq for "[0]".
+		if strings.HasPrefix(rest, ".[") {
+			rest = rest[1:]
+		}
+		if m := queryAll.FindString(rest); m != "" {
+			segs = append(segs, querySeg{all: true})
+			rest = rest[len(m):]
+			continue
+		}
+		if m := queryName.FindStringSubmatch(rest); m != nil {
+			segs = append(segs, querySeg{key: m[1]})
+			rest = rest[len(m[0]):]
+			continue
+		}
+		if m := queryIndex.FindStringSubmatch(rest); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			segs = append(segs, querySeg{index: n, isIndex: true})
+			rest = rest[len(m[0]):]
+			continue
+		}
+		if m := queryKey.FindStringSubmatch(rest); m != nil {
+			key, err := strconv.Unquote(`"` + m[1] + `"`)
+			if err != nil {
+				return nil, fmt.Errorf("invalid query %q: %w", q, err)
+			}
+			segs = append(segs, querySeg{key: key})
+			rest = rest[len(m[0]):]
+			continue
+		}
+		return nil, fmt.Errorf("invalid query %q at %q", q, rest)
+	}
+	return segs, nil
+}
+
+// runQuery returns the value at segs. With a wildcard the value is the list
+// of all matches and elements lacking the rest of the path are skipped.
+func runQuery(doc any, segs []querySeg) (any, bool) {
+	values := []any{doc}
+	wildcard := false
+	for _, seg := range segs {
+		next := []any{}
+		for _, v := range values {
+			switch {
+			case seg.all:
+				switch t := v.(type) {
+				case []any:
+					next = append(next, t...)
+				case map[string]any:
+					keys := sortedKeys(t)
+					for _, k := range keys {
+						next = append(next, t[k])
+					}
+				}
+			case seg.isIndex:
+				arr, ok := v.([]any)
+				i := seg.index
+				if i < 0 {
+					i += len(arr)
+				}
+				if ok && i >= 0 && i < len(arr) {
+					next = append(next, arr[i])
+				}
+			default:
+				if obj, ok := v.(map[string]any); ok {
+					if child, ok := obj[seg.key]; ok {
+						next = append(next, child)
+					}
+				}
+			}
+		}
+		wildcard = wildcard || seg.all
+		values = next
+	}
+	if wildcard {
+		return values, true
+	}
+	if len(values) == 0 {
+		return nil, false
+	}
+	return values[0], true
+}
+
+func (g *DefaultGrader) evalFileJSONPath(_ context.Context, req Request, check CheckSpec) (evaluation, error) {
+	doc, eval, err := readStructured(req, check.Path, check.Format)
+	if err != nil || eval != nil {
+		return derefEval(eval), err
+	}
+	segs, err := parseQuery(check.Query)
+	if err != nil {
+		return evaluation{}, err
+	}
+	diffEval := func(summary, message, line string) (evaluation, error) {
+		artifact := structuralDiff(check, []string{line})
+		return evaluation{Passed: false, Summary: summary, Message: message, Artifact: &artifact}, nil
+	}
+	value, ok := runQuery(doc, segs)
+	if !ok {
+		return diffEval("path not found", check.Query+" not found", "- "+check.Query+": (missing)")
+	}
+	if check.ValueType != "" {
+		if got := jsonType(value); got != check.ValueType {
+			return diffEval("type mismatch", fmt.Sprintf("expected %s at %s, got %s", check.ValueType, check.Query, got),
+				fmt.Sprintf("~ %s: %s → %s", check.Query, check.ValueType, got))
+		}
+	}
+	if check.Count != nil {
+		var n int
+		switch t := value.(type) {
+		case []any:
+			n = len(t)
+		case map[string]any:
+			n = len(t)
+		default:
+			return diffEval("count mismatch", fmt.Sprintf("%s is a %s, not an array or object", check.Query, jsonType(value)),
+				fmt.Sprintf("~ %s: array → %s", check.Query, jsonType(value)))
+		}
+		if n != *check.Count {
+			return diffEval("count mismatch", fmt.Sprintf("expected %d items at %s, got %d", *check.Count, check.Query, n),
+				fmt.Sprintf("~ %s: %d items → %d items", check.Query, *check.Count, n))
+		}
+	}
+	if check.Expected != nil {
+		var want any
+		if err := json.Unmarshal([]byte(*check.Expected), &want); err != nil {
+			// Bare words are strings, so `expected: alice` works, and
+			// `expected: ""` is the empty string.
+			want = *check.Expected
+		}
+		if canonical(want, check.IgnoreArrayOrder) != canonical(value, check.IgnoreArrayOrder) {
+			lines := []string{}
+			diffValues(check.Query, want, value, check.IgnoreArrayOrder, &lines)
+			artifact := structuralDiff(check, lines)
+			return evaluation{Passed: false, Summary: "value mismatch", Message: fmt.Sprintf("%s is %s", check.Query, preview(value)), Artifact: &artifact}, nil
+		}
+	}
+	return evaluation{Passed: true, Summary: "value matches", Message: "ok"}, nil
+}
+
+func (g *DefaultGrader) evalFileJSONEquals(_ context.Context, req Request, check CheckSpec) (evaluation, error) {
+	actual, eval, err := readStructured(req, check.Path, check.Format)
+	if err != nil || eval != nil {
+		return derefEval(eval), err
+	}
+	var expected any
+	if check.CompareToPath != "" {
+		var compareEval *evaluation
+		expected, compareEval, err = readStructured(req, check.CompareToPath, check.Format)
+		if err != nil {
+			return evaluation{}, err
+		}
+		if compareEval != nil {
+			return evaluation{Passed: false, Summary: "compare file unreadable", Message: "compare file: " + compareEval.Message}, nil
+		}
+	} else {
+		format := structuredFormat(check.Format, check.Path)
+		if expected, err = decodeStructured([]byte(expectedText(check)), format); err != nil {
+			return evaluation{}, fmt.Errorf("check %s expected: %w", check.ID, err)
+		}
+	}
+	if canonical(expected, check.IgnoreArrayOrder) == canonical(actual, check.IgnoreArrayOrder) {
+		return evaluation{Passed: true, Summary: "document matches", Message: "ok"}, nil
+	}
+	lines := []string{}
+	diffValues("", expected, actual, check.IgnoreArrayOrder, &lines)
+	artifact := structuralDiff(check, lines)
+	return evaluation{Passed: false, Summary: "document mismatch", Message: fmt.Sprintf("%d differences", len(lines)), Artifact: &artifact}, nil
+}
+
+// readStructured reads and decodes a JSON or YAML file. A missing or invalid
+// file is a failed evaluation rather than an error.
+func readStructured(req Request, path, format string) (any, *evaluation, error) {
+	b, err := os.ReadFile(resolveWorkPath(req.WorkDir, path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, &evaluation{Passed: false, Summary: "file missing", Message: "file not found"}, nil
+		}
+		return nil, nil, err
+	}
+	format = structuredFormat(format, path)
+	doc, err := decodeStructured(b, format)
+	if err != nil {
+		return nil, &evaluation{Passed: false, Summary: "invalid " + format, Message: err.Error()}, nil
+	}
+	return doc, nil, nil
+}
+
+func derefEval(e *evaluation) evaluation {
+	if e == nil {
+		return evaluation{}
+	}
+	return *e
+}
+
+func structuredFormat(format, path string) string {
+	if format != "" {
+		return format
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	}
+	return "json"
+}
+
+// decodeStructured decodes JSON or YAML into the values encoding/json
+// produces, so both formats compare alike.
+func decodeStructured(b []byte, format string) (any, error) {
+	var doc any
+	if format == "yaml" {
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+		j, err := json.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("YAML is not JSON-compatible: %w", err)
+		}
+		b = j
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return doc, nil
+}
+
+func jsonType(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return "null"
+}
+
+// canonical encodes v with sorted keys and, if ignoreOrder, sorted arrays, so
+// equal documents encode alike.
+func canonical(v any, ignoreOrder bool) string {
+	switch t := v.(type) {
+	case map[string]any:
+		parts := make([]string, 0, len(t))
+		for _, k := range sortedKeys(t) {
+			parts = append(parts, strconv.Quote(k)+":"+canonical(t[k], ignoreOrder))
+		}
+		return "{" + strings.Join(parts, ",") + "}"
+	case []any:
+		parts := make([]string, 0, len(t))
+		for _, e := range t {
+			parts = append(parts, canonical(e, ignoreOrder))
+		}
+		if ignoreOrder {
+			sort.Strings(parts)
+		}
+		return "[" + strings.Join(parts, ",") + "]"
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// diffValues appends one line per difference between expected and actual:
+// "- path: value" for missing, "+ path: value" for unexpected and
+// "~ path: old → new" for changed values.
+func diffValues(path string, expected, actual any, ignoreOrder bool, out *[]string) {
+	if canonical(expected, ignoreOrder) == canonical(actual, ignoreOrder) {
+		return
+	}
+	switch exp := expected.(type) {
+	case map[string]any:
+		act, ok := actual.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := sortedKeys(exp)
+		for _, k := range sortedKeys(act) {
+			if _, ok := exp[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := joinPath(path, k)
+			e, inExp := exp[k]
+			a, inAct := act[k]
+			switch {
+			case !inAct:
+				*out = append(*out, "- "+child+": "+preview(e))
+			case !inExp:
+				*out = append(*out, "+ "+child+": "+preview(a))
+			default:
+				diffValues(child, e, a, ignoreOrder, out)
+			}
+		}
+		return
+	case []any:
+		act, ok := actual.([]any)
+		if !ok {
+			break
+		}
+		if ignoreOrder {
+			diffUnordered(path, exp, act, out)
+			return
+		}
+		for i := 0; i < len(exp) || i < len(act); i++ {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(act):
+				*out = append(*out, "- "+child+": "+preview(exp[i]))
+			case i >= len(exp):
+				*out = append(*out, "+ "+child+": "+preview(act[i]))
+			default:
+				diffValues(child, exp[i], act[i], ignoreOrder, out)
+			}
+		}
+		return
+	}
+	*out = append(*out, fmt.Sprintf("~ %s: %s → %s", displayPath(path), preview(expected), preview(actual)))
+}
+
+// diffUnordered pairs up equal elements and reports the rest as missing or
+// unexpected.
+func diffUnordered(path string, exp, act []any, out *[]string) {
+	unmatched := map[string][]int{}
+	for j, a := range act {
+		key := canonical(a, true)
+		unmatched[key] = append(unmatched[key], j)
+	}
+	for i, e := range exp {
+		key := canonical(e, true)
+		if idx := unmatched[key]; len(idx) > 0 {
+			unmatched[key] = idx[1:]
+			continue
+		}
+		*out = append(*out, fmt.Sprintf("- %s[%d]: %s", path, i, preview(e)))
+	}
+	extra := []int{}
+	for _, idx := range unmatched {
+		extra = append(extra, idx...)
+	}
+	sort.Ints(extra)
+	for _, j := range extra {
+		*out = append(*out, fmt.Sprintf("+ %s[%d]: %s", path, j, preview(act[j])))
+	}
+}
+
+func joinPath(path, key string) string {
+	if plainKey.MatchString(key) {
+		return path + "." + key
+	}
+	return path + "[" + strconv.Quote(key) + "]"
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "."
+	}
+	return path
+}
+
+// preview is v as compact JSON, shortened for diff lines.
+func preview(v any) string {
+	b, _ := json.Marshal(v)
+	s := string(b)
+	if r := []rune(s); len(r) > 60 {
+		s = string(r[:57]) + "..."
+	}
+	return s
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// structuralDiff is the artifact of a failed structured-data check.
+func structuralDiff(check CheckSpec, lines []string) Artifact {
+	if len(lines) > maxDiffLines {
+		more := len(lines) - maxDiffLines
+		lines = append(lines[:maxDiffLines:maxDiffLines], fmt.Sprintf("... %d more differences", more))
+	}
+	return Artifact{
+		Ref:         "diff_" + safeID(check.ID),
+		Kind:        "structural_diff",
+		Title:       fmt.Sprintf("%s vs expected", check.Path),
+		TextPreview: "--- expected\n+++ actual\n" + strings.Join(lines, "\n") + "\n",
+	}
+}
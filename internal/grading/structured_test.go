@@ -0,0 +1,156 @@
+package grading
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gradeOne grades a single required check against a workdir holding files.
+func gradeOne(t *testing.T, files map[string]string, check CheckSpec) (CheckResult, []Artifact) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check.ID, check.Required = "c", true
+	res, err := NewGrader().Grade(context.Background(), Request{LevelID: "l", WorkDir: dir, Checks: []CheckSpec{check}})
+	if err != nil {
+		t.Fatalf("grade: %v", err)
+	}
+	return res.Checks[0], res.Artifacts
+}
+
+// gradeDiff grades a check that must fail and returns its diff lines.
+func gradeDiff(t *testing.T, files map[string]string, check CheckSpec) string {
+	t.Helper()
+	r, artifacts := gradeOne(t, files, check)
+	if r.Passed || len(artifacts) != 1 || artifacts[0].Kind != "structural_diff" {
+		t.Fatalf("expected a failure with a structural diff, got %+v %+v", r, artifacts)
+	}
+	return strings.TrimSpace(strings.TrimPrefix(artifacts[0].TextPreview, "--- expected\n+++ actual\n"))
+}
+
+func TestFileJSONPathAssertions(t *testing.T) {
+	files := map[string]string{
+		"users.json": `{"users": [{"name": "ada", "roles": ["admin"]}, {"name": "bob", "roles": []}], "meta key": {"total": 2}}`,
+		"conf.yaml":  "server:\n  port: 8080\n  tls: true\n",
+	}
+	pass := []CheckSpec{
+		{Path: "/work/users.json", Query: ".users[0].name", Expected: strPtr("ada")},
+		{Path: "/work/users.json", Query: `$.users[-1].roles`, ValueType: "array", Count: intPtr(0)},
+		{Path: "/work/users.json", Query: ".users[].name", Expected: strPtr(`["ada", "bob"]`)},
+		{Path: "/work/users.json", Query: `.["meta key"].total`, Expected: strPtr("2")},
+		{Path: "/work/conf.yaml", Query: ".server", Expected: strPtr(`{"tls": true, "port": 8080}`)},
+	}
+	for _, c := range pass {
+		c.Type = "file_json_path"
+		if r, _ := gradeOne(t, files, c); !r.Passed {
+			t.Errorf("%s %s: %s", c.Path, c.Query, r.Message)
+		}
+	}
+
+	fail := map[string]CheckSpec{
+		"- .users[2].name: (missing)":     {Query: ".users[2].name", Expected: strPtr("eve")},
+		"~ .users: object → array":        {Query: ".users", ValueType: "object"},
+		"~ .users: 3 items → 2 items":     {Query: ".users", Count: intPtr(3)},
+		`~ .users[1].name: "Bob" → "bob"`: {Query: ".users[1]", Expected: strPtr(`{"name": "Bob", "roles": []}`)},
+	}
+	for want, c := range fail {
+		c.Type, c.Path = "file_json_path", "/work/users.json"
+		if diff := gradeDiff(t, files, c); diff != want {
+			t.Errorf("%s: diff %q, want %q", c.Query, diff, want)
+		}
+	}
+	if r, _ := gradeOne(t, map[string]string{"bad.json": "{"}, CheckSpec{Type: "file_json_path", Path: "/work/bad.json", Query: ".", ValueType: "object"}); r.Passed || r.Summary != "invalid json" {
+		t.Fatalf("invalid JSON should fail: %+v", r)
+	}
+}
+
+func TestFileJSONEqualsIsSemantic(t *testing.T) {
+	files := map[string]string{"out.json": `{"b": [3, 1, 2], "a": {"x": 1.0}}`}
+	check := CheckSpec{Type: "file_json_equals", Path: "/work/out.json", Expected: strPtr(`{"a": {"x": 1}, "b": [1, 2, 3]}`)}
+	if r, _ := gradeOne(t, files, check); r.Passed {
+		t.Fatalf("array order should matter by default")
+	}
+	check.IgnoreArrayOrder = true
+	if r, _ := gradeOne(t, files, check); !r.Passed {
+		t.Fatalf("key order and array order should not matter: %s", r.Message)
+	}
+
+	check = CheckSpec{Type: "file_json_equals", Path: "/work/out.yaml", CompareToPath: "/work/want.yaml", IgnoreArrayOrder: true}
+	files = map[string]string{"out.yaml": "a: {x: 1}\nb: [3, 1, 2]\n", "want.yaml": "a: {x: 2, y: null}\nb: [3, 1]\n"}
+	want := "~ .a.x: 2 → 1\n- .a.y: null\n+ .b[2]: 2"
+	if diff := gradeDiff(t, files, check); diff != want {
+		t.Fatalf("diff:\n%s\nwant:\n%s", diff, want)
+	}
+}
+
+func TestFileCSVEqualsMatchesColumnsByHeader(t *testing.T) {
+	files := map[string]string{"top.csv": "count,ip\n5,10.0.0.1\n3,10.0.0.2\n"}
+	check := CheckSpec{Type: "file_csv_equals", Path: "/work/top.csv", Expected: strPtr("ip,count\n10.0.0.1,5\n10.0.0.2,3\n")}
+	if r, _ := gradeOne(t, files, check); !r.Passed {
+		t.Fatalf("column order should not matter: %s", r.Message)
+	}
+
+	check.Expected = strPtr("ip,count\n10.0.0.1,5\n10.0.0.2,4\n10.0.0.3,1\n")
+	want := "~ row 2, column \"count\": \"4\" → \"3\"\n- row 3: 10.0.0.3,1"
+	if diff := gradeDiff(t, files, check); diff != want {
+		t.Fatalf("diff:\n%s\nwant:\n%s", diff, want)
+	}
+
+	check.Expected = strPtr("ip,hits\n10.0.0.1,5\n")
+	want = "- column \"hits\"\n+ column \"count\"\n~ rows: 1 → 2"
+	if diff := gradeDiff(t, files, check); diff != want {
+		t.Fatalf("diff:\n%s\nwant:\n%s", diff, want)
+	}
+
+	files = map[string]string{"rows.tsv": "b\t2\n a \t1\n"}
+	noHeader := false
+	check = CheckSpec{Type: "file_csv_equals", Path: "/work/rows.tsv", Expected: strPtr("a\t1\nb\t2\n"),
+		CSV: CSVSpec{Delimiter: "\t", Header: &noHeader, IgnoreRowOrder: true, TrimSpace: true}}
+	if r, _ := gradeOne(t, files, check); !r.Passed {
+		t.Fatalf("unordered headerless rows should match: %s", r.Message)
+	}
+}
+
+func TestFileCSVEqualsRejectsDuplicateColumns(t *testing.T) {
+	files := map[string]string{"dup.csv": "ip,ip\n10.0.0.1,10.0.0.2\n", "top.csv": "ip\n10.0.0.1\n"}
+	check := CheckSpec{Type: "file_csv_equals", Path: "/work/dup.csv", Expected: strPtr("ip\n10.0.0.1\n")}
+	if r, _ := gradeOne(t, files, check); r.Passed || r.Summary != "invalid csv" || !strings.Contains(r.Message, `duplicate column "ip"`) {
+		t.Fatalf("a duplicate column in the output should fail: %+v", r)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "top.csv"), []byte(files["top.csv"]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	check = CheckSpec{ID: "c", Type: "file_csv_equals", Required: true, Path: "/work/top.csv", Expected: strPtr("ip,ip\n1,2\n")}
+	if _, err := NewGrader().Grade(context.Background(), Request{LevelID: "l", WorkDir: dir, Checks: []CheckSpec{check}}); err == nil || !strings.Contains(err.Error(), `duplicate column "ip"`) {
+		t.Fatalf("a duplicate column in expected should be a grading error, got %v", err)
+	}
+}
+
+func TestEmptyExpectedValuesAreAsserted(t *testing.T) {
+	files := map[string]string{"out.json": `{"name": "", "tags": ["x"]}`, "empty.csv": "", "empty.yaml": ""}
+	if r, _ := gradeOne(t, files, CheckSpec{Type: "file_json_path", Path: "/work/out.json", Query: ".name", Expected: strPtr("")}); !r.Passed {
+		t.Fatalf("an empty string should match expected \"\": %s", r.Message)
+	}
+	if r, _ := gradeOne(t, files, CheckSpec{Type: "file_json_path", Path: "/work/out.json", Query: ".tags[0]", Expected: strPtr("")}); r.Passed {
+		t.Fatalf("a non-empty value should not match expected \"\"")
+	}
+	if r, _ := gradeOne(t, files, CheckSpec{Type: "file_csv_equals", Path: "/work/empty.csv", Expected: strPtr("")}); !r.Passed {
+		t.Fatalf("an empty table should match expected \"\": %s", r.Message)
+	}
+	if r, _ := gradeOne(t, files, CheckSpec{Type: "file_json_equals", Path: "/work/empty.yaml", Expected: strPtr("")}); !r.Passed {
+		t.Fatalf("an empty document should match expected \"\": %s", r.Message)
+	}
+}
+
+func intPtr(n int) *int { return &n }
+
+func strPtr(s string) *string { return &s }
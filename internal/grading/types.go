@@ -22,7 +22,11 @@ type Request struct {
 	Container string
 	ImageRef  string
 	WorkDir   string
-	Checks    []CheckSpec
+	// Services maps the level's service names to their container names.
+	Services map[string]string
+	// Restricted refuses host fallbacks, for levels of sandbox-only packs.
+	Restricted bool
+	Checks     []CheckSpec
 
 	BasePoints           int
 	TimeGraceSeconds     int
@@ -42,8 +46,9 @@ type CheckSpec struct {
 	OnFailMessage string
 	OnPassMessage string
 
-	Path      string
-	Expected  string
+	Path string
+	// Expected is nil when the check sets none.
+	Expected  *string
 	Normalize NormalizeSpec
 
 	Equals int
@@ -65,6 +70,30 @@ type CheckSpec struct {
 	CompareToPath  string
 	TimeoutSeconds int
 	MinCount       int
+	Service        string
+
+	Query            string
+	ValueType        string
+	Count            *int
+	Format           string
+	IgnoreArrayOrder bool
+	CSV              CSVSpec
+
+	Perm        string
+	PermSet     string
+	PermUnset   string
+	Owner       string
+	Group       string
+	FileType    string
+	MtimeAfter  string
+	MtimeBefore string
+}
+
+type CSVSpec struct {
+	Delimiter      string
+	Header         *bool
+	IgnoreRowOrder bool
+	TrimSpace      bool
 }
 
 type NormalizeSpec struct {
@@ -93,7 +122,9 @@ type Result struct {
 	Checks         []CheckResult   `json:"checks"`
 	Artifacts      []Artifact      `json:"artifacts,omitempty"`
 	CmdlogAnalysis *CmdlogAnalysis `json:"cmdlog_analysis,omitempty"`
-	EngineDebug    EngineDebug     `json:"engine_debug,omitempty"`
+	// Stages lists the passed stages of a multi-stage level; see MergeStages.
+	Stages      []StageResult `json:"stages,omitempty"`
+	EngineDebug EngineDebug   `json:"engine_debug,omitempty"`
 }
 
 type RunInfo struct {
@@ -121,6 +152,14 @@ type ScoreDelta struct {
 	Description string `json:"description"`
 }
 
+// StageResult is the timing and score of one passed stage.
+type StageResult struct {
+	StageID    string `json:"stage_id"`
+	Title      string `json:"title,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+	Points     int    `json:"points"`
+}
+
 type CheckResult struct {
 	ID            string        `json:"id"`
 	Type          string        `json:"type"`
@@ -0,0 +1,545 @@
+package levels
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	ArchiveExt            = ".dojopack"
+	ArchiveManifestName   = "manifest.json"
+	ArchiveManifestKind   = "dojopack_manifest"
+	ArchiveFormatVersion  = 1
+	CompressionGzip       = "gzip"
+	CompressionZstd       = "zstd"
+	maxArchiveFileBytes   = 1 << 30
+	maxArchiveManifestLen = 16 << 20
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ArchiveManifest is the first entry of a .dojopack archive and lists a
+// SHA-256 digest for every file that follows it.
+type ArchiveManifest struct {
+	Kind          string        `json:"kind"`
+	FormatVersion int           `json:"format_version"`
+	PackID        string        `json:"pack_id"`
+	Version       string        `json:"version"`
+	Files         []ArchiveFile `json:"files"`
+	// Dirs lists the pack's directories, so empty ones survive extraction.
+	// Parents of Files are implied.
+	Dirs []string `json:"dirs,omitempty"`
+}
+
+type ArchiveFile struct {
+	Path   string `json:"path"`
+	Mode   int64  `json:"mode"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256,omitempty"`
+	Link   string `json:"link,omitempty"`
+}
+
+// BuildArchive writes packDir as a .dojopack archive to w.
+func BuildArchive(packDir string, w io.Writer, compression string) (ArchiveManifest, error) {
+	pack, err := readPack(filepath.Join(packDir, "pack.yaml"))
+	if err != nil {
+		return ArchiveManifest{}, fmt.Errorf("load pack %s: %w", packDir, err)
+	}
+	manifest := ArchiveManifest{
+		Kind:          ArchiveManifestKind,
+		FormatVersion: ArchiveFormatVersion,
+		PackID:        pack.PackID,
+		Version:       pack.Version,
+	}
+	files, dirs, err := scanPackFiles(packDir, func(rel string) bool { return rel == ArchiveManifestName })
+	if err != nil {
+		return ArchiveManifest{}, err
+	}
+	manifest.Files = files
+	manifest.Dirs = dirs
+
+	cw, err := newArchiveWriter(w, compression)
+	if err != nil {
+		return ArchiveManifest{}, err
+	}
+	tw := tar.NewWriter(cw)
+	body, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return ArchiveManifest{}, err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: ArchiveManifestName, Mode: 0o644, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+		return ArchiveManifest{}, err
+	}
+	if _, err := tw.Write(body); err != nil {
+		return ArchiveManifest{}, err
+	}
+	for _, dir := range dirs {
+		if err := tw.WriteHeader(&tar.Header{Name: dir + "/", Mode: 0o755, Typeflag: tar.TypeDir}); err != nil {
+			return ArchiveManifest{}, err
+		}
+	}
+	for _, f := range manifest.Files {
+		if f.Link != "" {
+			if err := tw.WriteHeader(&tar.Header{Name: f.Path, Mode: f.Mode, Linkname: f.Link, Typeflag: tar.TypeSymlink}); err != nil {
+				return ArchiveManifest{}, err
+			}
+			continue
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: f.Path, Mode: f.Mode, Size: f.Size, Typeflag: tar.TypeReg}); err != nil {
+			return ArchiveManifest{}, err
+		}
+		in, err := os.Open(filepath.Join(packDir, filepath.FromSlash(f.Path)))
+		if err != nil {
+			return ArchiveManifest{}, err
+		}
+		_, err = io.Copy(tw, in)
+		in.Close()
+		if err != nil {
+			return ArchiveManifest{}, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return ArchiveManifest{}, err
+	}
+	if err := cw.Close(); err != nil {
+		return ArchiveManifest{}, err
+	}
+	return manifest, nil
+}
+
+// ReadArchiveManifest returns the manifest of a .dojopack without verifying
+// the file digests.
+func ReadArchiveManifest(archivePath string) (ArchiveManifest, error) {
+	var manifest ArchiveManifest
+	err := walkArchive(archivePath, func(m ArchiveManifest, _ *tar.Header, _ io.Reader) error {
+		manifest = m
+		return errStopArchive
+	})
+	if errors.Is(err, errStopArchive) {
+		err = nil
+	}
+	return manifest, err
+}
+
+// VerifyArchive checks every archive entry against the manifest digests.
+func VerifyArchive(archivePath string) (ArchiveManifest, error) {
+	return extractArchive(archivePath, "")
+}
+
+// ExtractArchive verifies the archive and unpacks it into dest, which must
+// not exist yet. Nothing is left at dest when verification fails.
+func ExtractArchive(archivePath, dest string) (ArchiveManifest, error) {
+	if _, err := os.Stat(dest); err == nil {
+		return ArchiveManifest{}, fmt.Errorf("%s already exists", dest)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return ArchiveManifest{}, err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dest), ".extract-")
+	if err != nil {
+		return ArchiveManifest{}, err
+	}
+	manifest, err := extractArchive(archivePath, tmp)
+	if err != nil {
+		_ = os.RemoveAll(tmp)
+		return ArchiveManifest{}, err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		_ = os.RemoveAll(tmp)
+		return ArchiveManifest{}, err
+	}
+	return manifest, nil
+}
+
+// LoadArchive loads a pack straight from a .dojopack file. The archive is
+// verified and unpacked once into the loader's cache, keyed by its digest.
+func (l *FSLoader) LoadArchive(ctx context.Context, archivePath string) (Pack, error) {
+	return l.loadArchive(ctx, archivePath, PackRoot{})
+}
+
+func (l *FSLoader) loadArchive(ctx context.Context, archivePath string, root PackRoot) (Pack, error) {
+	digest, err := fileSHA256(archivePath)
+	if err != nil {
+		return Pack{}, err
+	}
+	cacheRoot := l.ArchiveCacheDir
+	if cacheRoot == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return Pack{}, fmt.Errorf("resolve archive cache dir: %w", err)
+		}
+		cacheRoot = filepath.Join(base, "clidojo", "packs")
+	}
+	dir := filepath.Join(cacheRoot, digest[:32])
+	if _, err := os.Stat(filepath.Join(dir, "pack.yaml")); err != nil {
+		_ = os.RemoveAll(dir)
+		if _, err := ExtractArchive(archivePath, dir); err != nil {
+			return Pack{}, fmt.Errorf("load pack archive %s: %w", archivePath, err)
+		}
+	}
+	pack, err := l.loadPack(ctx, dir, root)
+	if err != nil {
+		return Pack{}, err
+	}
+	pack.Archive, _ = filepath.Abs(archivePath)
+	return pack, nil
+}
+
+var errStopArchive = errors.New("stop")
+
+func extractArchive(archivePath, dest string) (ArchiveManifest, error) {
+	var (
+		manifest ArchiveManifest
+		expected map[string]ArchiveFile
+		// dirs are the manifest's dirs and the parents of its files; links
+		// maps every symlink in the manifest to its target.
+		dirs  = map[string]bool{}
+		links = map[string]string{}
+		seen  = map[string]bool{}
+	)
+	err := walkArchive(archivePath, func(m ArchiveManifest, hdr *tar.Header, r io.Reader) error {
+		if expected == nil {
+			manifest = m
+			expected = map[string]ArchiveFile{}
+			for _, f := range m.Files {
+				expected[f.Path] = f
+				if f.Link != "" {
+					links[f.Path] = f.Link
+				}
+				for dir := path.Dir(f.Path); dir != "."; dir = path.Dir(dir) {
+					dirs[dir] = true
+				}
+			}
+			for _, dir := range m.Dirs {
+				dirs[dir] = true
+			}
+		}
+		if hdr == nil {
+			return nil
+		}
+		name, err := cleanArchivePath(hdr.Name)
+		if err != nil {
+			return err
+		}
+		target := ""
+		if dest != "" {
+			target = filepath.Join(dest, filepath.FromSlash(name))
+		}
+		want, ok := expected[name]
+		if hdr.Typeflag == tar.TypeDir {
+			ok = dirs[name] && want.Link == ""
+		}
+		if !ok {
+			return fmt.Errorf("%s is not listed in the manifest", name)
+		}
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if expected[dir].Link != "" {
+				return fmt.Errorf("%s: parent %s is a symlink", name, dir)
+			}
+		}
+		if hdr.Typeflag == tar.TypeDir {
+			if target != "" {
+				return os.MkdirAll(target, 0o755)
+			}
+			return nil
+		}
+		if seen[name] {
+			return fmt.Errorf("%s appears twice in the archive", name)
+		}
+		seen[name] = true
+		switch hdr.Typeflag {
+		case tar.TypeSymlink:
+			if want.Link == "" || hdr.Linkname != want.Link {
+				return fmt.Errorf("%s: symlink target does not match the manifest", name)
+			}
+			if !archiveLinkInside(links, name) {
+				return fmt.Errorf("%s: symlink target %q escapes the pack", name, hdr.Linkname)
+			}
+			if target == "" {
+				return nil
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			return os.Symlink(hdr.Linkname, target)
+		case tar.TypeReg:
+			if want.Link != "" {
+				return fmt.Errorf("%s: expected a symlink", name)
+			}
+			return extractArchiveFile(r, target, want)
+		default:
+			return fmt.Errorf("%s: unsupported entry type %q", name, string(hdr.Typeflag))
+		}
+	})
+	if err != nil {
+		return ArchiveManifest{}, err
+	}
+	for _, f := range manifest.Files {
+		if !seen[f.Path] {
+			return ArchiveManifest{}, fmt.Errorf("%s is listed in the manifest but missing from the archive", f.Path)
+		}
+	}
+	if !seen["pack.yaml"] {
+		return ArchiveManifest{}, fmt.Errorf("archive has no pack.yaml")
+	}
+	return manifest, nil
+}
+
+func extractArchiveFile(r io.Reader, target string, want ArchiveFile) error {
+	h := sha256.New()
+	var w io.Writer = h
+	var out *os.File
+	if target != "" {
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, fs.FileMode(want.Mode).Perm()|0o600)
+		if err != nil {
+			return err
+		}
+		out = f
+		defer out.Close()
+		w = io.MultiWriter(h, out)
+	}
+	n, err := io.Copy(w, io.LimitReader(r, maxArchiveFileBytes+1))
+	if err != nil {
+		return err
+	}
+	if n > maxArchiveFileBytes {
+		return fmt.Errorf("%s exceeds the maximum file size", want.Path)
+	}
+	if n != want.Size || hex.EncodeToString(h.Sum(nil)) != want.SHA256 {
+		return fmt.Errorf("%s: sha256 mismatch", want.Path)
+	}
+	if out != nil {
+		return out.Chmod(fs.FileMode(want.Mode).Perm())
+	}
+	return nil
+}
+
+// walkArchive decodes the manifest (always the first entry) and calls fn once
+// with a nil header for it, then once per remaining entry.
+func walkArchive(archivePath string, fn func(ArchiveManifest, *tar.Header, io.Reader) error) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r, closeReader, err := newArchiveReader(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", archivePath, err)
+	}
+	defer closeReader()
+	tr := tar.NewReader(r)
+
+	hdr, err := tr.Next()
+	if err != nil {
+		return fmt.Errorf("%s: read archive: %w", archivePath, err)
+	}
+	if hdr.Name != ArchiveManifestName {
+		return fmt.Errorf("%s: first entry must be %s, got %s", archivePath, ArchiveManifestName, hdr.Name)
+	}
+	body, err := io.ReadAll(io.LimitReader(tr, maxArchiveManifestLen))
+	if err != nil {
+		return err
+	}
+	var manifest ArchiveManifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return fmt.Errorf("%s: parse manifest: %w", archivePath, err)
+	}
+	if manifest.Kind != ArchiveManifestKind {
+		return fmt.Errorf("%s: manifest kind must be %q", archivePath, ArchiveManifestKind)
+	}
+	if manifest.FormatVersion > ArchiveFormatVersion {
+		return fmt.Errorf("%s: unsupported archive format_version %d (max supported %d)", archivePath, manifest.FormatVersion, ArchiveFormatVersion)
+	}
+	if err := fn(manifest, nil, nil); err != nil {
+		return err
+	}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: read archive: %w", archivePath, err)
+		}
+		if err := fn(manifest, hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func newArchiveReader(f io.Reader) (io.Reader, func(), error) {
+	br := bufio.NewReader(f)
+	magic, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zr, zr.Close, nil
+	case bytes.HasPrefix(magic, gzipMagic):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, err
+		}
+		return gr, func() { _ = gr.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("not a .dojopack archive (expected zstd or gzip compressed tar)")
+	}
+}
+
+func newArchiveWriter(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case "", CompressionZstd:
+		return zstd.NewWriter(w)
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression %q (want zstd or gzip)", compression)
+	}
+}
+
+// scanPackFiles lists the regular files and symlinks under packDir with their
+// digests, plus every directory, both sorted. .git and paths for which skip
+// returns true are left out.
+func scanPackFiles(packDir string, skip func(rel string) bool) ([]ArchiveFile, []string, error) {
+	files := []ArchiveFile{}
+	dirs := []string{}
+	err := filepath.WalkDir(packDir, func(p string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		rel, err := filepath.Rel(packDir, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if skip != nil && skip(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			dirs = append(dirs, rel)
+		case info.Mode()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			files = append(files, ArchiveFile{Path: rel, Mode: int64(info.Mode().Perm()), Link: target})
+		case info.Mode().IsRegular():
+			sum, err := fileSHA256(p)
+			if err != nil {
+				return err
+			}
+			files = append(files, ArchiveFile{Path: rel, Mode: int64(info.Mode().Perm()), Size: info.Size(), SHA256: sum})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	sort.Strings(dirs)
+	return files, dirs, nil
+}
+
+// maxLinkHops bounds how many symlinks archiveLinkInside follows, like the
+// kernel's ELOOP limit.
+const maxLinkHops = 40
+
+// archiveLinkInside reports whether the symlink at name resolves inside the
+// archive root once every symlink in links is followed, as it will on disk.
+// A lexical check alone misses links such as "d/x -> y/../../d" when d/y
+// points back at d.
+func archiveLinkInside(links map[string]string, name string) bool {
+	cur := []string{}
+	if dir := path.Dir(name); dir != "." {
+		cur = strings.Split(dir, "/")
+	}
+	rest := strings.Split(links[name], "/")
+	if path.IsAbs(links[name]) {
+		return false
+	}
+	for hops := 0; len(rest) > 0; {
+		comp := rest[0]
+		rest = rest[1:]
+		switch comp {
+		case "", ".":
+			continue
+		case "..":
+			if len(cur) == 0 {
+				return false
+			}
+			cur = cur[:len(cur)-1]
+			continue
+		}
+		cur = append(cur, comp)
+		target, ok := links[strings.Join(cur, "/")]
+		if !ok {
+			continue
+		}
+		if hops++; hops > maxLinkHops || path.IsAbs(target) {
+			return false
+		}
+		cur = cur[:len(cur)-1]
+		rest = append(strings.Split(target, "/"), rest...)
+	}
+	return true
+}
+
+func cleanArchivePath(name string) (string, error) {
+	clean := path.Clean(strings.TrimSuffix(name, "/"))
+	if clean == "." || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("unsafe archive path %q", name)
+	}
+	return clean, nil
+}
+
+func fileSHA256(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
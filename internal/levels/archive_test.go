@@ -0,0 +1,200 @@
+package levels
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func buildTestArchive(t *testing.T, packDir, compression string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := BuildArchive(packDir, &buf, compression); err != nil {
+		t.Fatalf("build archive: %v", err)
+	}
+	out := filepath.Join(t.TempDir(), "pack"+ArchiveExt)
+	if err := os.WriteFile(out, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestArchiveRoundTripBothCompressions(t *testing.T) {
+	src := filepath.Join("..", "..", "packs", "builtin-core")
+	for _, compression := range []string{CompressionZstd, CompressionGzip} {
+		archive := buildTestArchive(t, src, compression)
+		manifest, err := VerifyArchive(archive)
+		if err != nil {
+			t.Fatalf("%s: verify: %v", compression, err)
+		}
+		if manifest.PackID != "builtin-core" || len(manifest.Files) == 0 {
+			t.Fatalf("%s: unexpected manifest %+v", compression, manifest)
+		}
+		dest := filepath.Join(t.TempDir(), "out")
+		if _, err := ExtractArchive(archive, dest); err != nil {
+			t.Fatalf("%s: extract: %v", compression, err)
+		}
+		want, _ := os.ReadFile(filepath.Join(src, "levels", "level-001-pipes-101", "level.yaml"))
+		got, err := os.ReadFile(filepath.Join(dest, "levels", "level-001-pipes-101", "level.yaml"))
+		if err != nil || !bytes.Equal(got, want) {
+			t.Fatalf("%s: extracted level.yaml differs (err=%v)", compression, err)
+		}
+	}
+}
+
+func TestLoadPacksReadsArchivesFromRoot(t *testing.T) {
+	archive := buildTestArchive(t, filepath.Join("..", "..", "packs", "builtin-core"), CompressionZstd)
+	root := t.TempDir()
+	if err := os.Rename(archive, filepath.Join(root, "builtin-core"+ArchiveExt)); err != nil {
+		t.Fatal(err)
+	}
+	loader := &FSLoader{ArchiveCacheDir: t.TempDir()}
+	packs, err := loader.LoadPacks(context.Background(), root)
+	if err != nil {
+		t.Fatalf("load packs: %v", err)
+	}
+	if len(packs) != 1 || packs[0].PackID != "builtin-core" || len(packs[0].LoadedLevels) != 3 {
+		t.Fatalf("unexpected packs %+v", packs)
+	}
+	if !strings.HasSuffix(packs[0].Archive, ArchiveExt) || !strings.HasPrefix(packs[0].Path, loader.ArchiveCacheDir) {
+		t.Fatalf("expected archive-backed pack, got path=%s archive=%s", packs[0].Path, packs[0].Archive)
+	}
+}
+
+func TestVerifyArchiveRejectsTamperedFile(t *testing.T) {
+	manifest := ArchiveManifest{
+		Kind:          ArchiveManifestKind,
+		FormatVersion: ArchiveFormatVersion,
+		PackID:        "tampered",
+		Version:       "1.0.0",
+		Files:         []ArchiveFile{{Path: "pack.yaml", Mode: 0o644, Size: 5, SHA256: strings.Repeat("0", 64)}},
+	}
+	body, _ := json.Marshal(manifest)
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	_ = tw.WriteHeader(&tar.Header{Name: ArchiveManifestName, Mode: 0o644, Size: int64(len(body)), Typeflag: tar.TypeReg})
+	_, _ = tw.Write(body)
+	_ = tw.WriteHeader(&tar.Header{Name: "pack.yaml", Mode: 0o644, Size: 5, Typeflag: tar.TypeReg})
+	_, _ = tw.Write([]byte("hello"))
+	_ = tw.Close()
+	_ = gz.Close()
+	path := filepath.Join(t.TempDir(), "bad"+ArchiveExt)
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyArchive(path); err == nil || !strings.Contains(err.Error(), "sha256 mismatch") {
+		t.Fatalf("expected sha256 mismatch, got %v", err)
+	}
+	dest := filepath.Join(t.TempDir(), "out")
+	if _, err := ExtractArchive(path, dest); err == nil {
+		t.Fatalf("expected extract to fail")
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("expected no partial extraction at %s", dest)
+	}
+}
+
+func TestCleanArchivePathRejectsEscapes(t *testing.T) {
+	for _, name := range []string{"../evil", "/etc/passwd", "a/../../b", "."} {
+		if _, err := cleanArchivePath(name); err == nil {
+			t.Fatalf("expected %q to be rejected", name)
+		}
+	}
+	if got, err := cleanArchivePath("levels/x/"); err != nil || got != "levels/x" {
+		t.Fatalf("unexpected clean result %q %v", got, err)
+	}
+}
+
+// writeRawArchive writes a gzip .dojopack whose manifest lists files and
+// dirs, followed by entries; regular files get the content "hello".
+func writeRawArchive(t *testing.T, files []ArchiveFile, dirs []string, entries []tar.Header) string {
+	t.Helper()
+	manifest := ArchiveManifest{Kind: ArchiveManifestKind, FormatVersion: ArchiveFormatVersion, PackID: "raw", Version: "1.0.0", Files: files, Dirs: dirs}
+	body, _ := json.Marshal(manifest)
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	_ = tw.WriteHeader(&tar.Header{Name: ArchiveManifestName, Mode: 0o644, Size: int64(len(body)), Typeflag: tar.TypeReg})
+	_, _ = tw.Write(body)
+	for _, hdr := range entries {
+		if hdr.Typeflag == tar.TypeReg {
+			hdr.Size = 5
+		}
+		_ = tw.WriteHeader(&hdr)
+		if hdr.Typeflag == tar.TypeReg {
+			_, _ = tw.Write([]byte("hello"))
+		}
+	}
+	_ = tw.Close()
+	_ = gz.Close()
+	out := filepath.Join(t.TempDir(), "raw"+ArchiveExt)
+	if err := os.WriteFile(out, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestExtractArchiveRejectsChainedSymlinkEscape(t *testing.T) {
+	hello := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	files := []ArchiveFile{
+		{Path: "pack.yaml", Mode: 0o644, Size: 5, SHA256: hello},
+		{Path: "d/y", Mode: 0o777, Link: "."},
+		{Path: "d/x", Mode: 0o777, Link: "y/../../d"},
+	}
+	archive := writeRawArchive(t, files, nil, []tar.Header{
+		{Name: "pack.yaml", Mode: 0o644, Typeflag: tar.TypeReg},
+		{Name: "d/y", Linkname: ".", Typeflag: tar.TypeSymlink},
+		{Name: "d/x", Linkname: "y/../../d", Typeflag: tar.TypeSymlink},
+	})
+	if _, err := ExtractArchive(archive, filepath.Join(t.TempDir(), "out")); err == nil || !strings.Contains(err.Error(), "escapes the pack") {
+		t.Fatalf("expected the chained link to be rejected, got %v", err)
+	}
+
+	links := map[string]string{"levels/a/data": "../../shared/data", "shared/data": "raw", "loop": "loop"}
+	if !archiveLinkInside(links, "levels/a/data") {
+		t.Fatalf("a link climbing inside the pack should be allowed")
+	}
+	if archiveLinkInside(links, "loop") {
+		t.Fatalf("a symlink loop should be rejected")
+	}
+}
+
+func TestExtractArchiveValidatesDirEntries(t *testing.T) {
+	hello := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	files := []ArchiveFile{
+		{Path: "pack.yaml", Mode: 0o644, Size: 5, SHA256: hello},
+		{Path: "levels/one", Mode: 0o644, Size: 5, SHA256: hello},
+		{Path: "l", Mode: 0o777, Link: "levels"},
+	}
+	entries := func(dir string) []tar.Header {
+		return []tar.Header{
+			{Name: dir, Mode: 0o755, Typeflag: tar.TypeDir},
+			{Name: "pack.yaml", Mode: 0o644, Typeflag: tar.TypeReg},
+			{Name: "levels/one", Mode: 0o644, Typeflag: tar.TypeReg},
+			{Name: "l", Linkname: "levels", Typeflag: tar.TypeSymlink},
+		}
+	}
+	if _, err := ExtractArchive(writeRawArchive(t, files, nil, entries("levels/")), filepath.Join(t.TempDir(), "out")); err != nil {
+		t.Fatalf("a declared dir should extract: %v", err)
+	}
+	for _, tc := range []struct{ dir, wantErr string }{
+		{"extra/", "not listed in the manifest"},
+		{"l/sub/", "parent l is a symlink"},
+		{"l/", "not listed in the manifest"},
+	} {
+		dest := filepath.Join(t.TempDir(), "out")
+		if _, err := ExtractArchive(writeRawArchive(t, files, []string{"l/sub"}, entries(tc.dir)), dest); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Fatalf("expected dir entry %s to be rejected with %q, got %v", tc.dir, tc.wantErr, err)
+		}
+		if _, err := os.Stat(dest); !os.IsNotExist(err) {
+			t.Fatalf("%s: expected no partial extraction", tc.dir)
+		}
+	}
+}
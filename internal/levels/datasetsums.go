@@ -0,0 +1,276 @@
+package levels
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DatasetSumsName is the optional per-level manifest of dataset checksums. It
+// uses the sha256sum format with paths relative to the level dir, so
+// `sha256sum -c dataset.sha256sums` works from there too.
+const DatasetSumsName = "dataset.sha256sums"
+
+// DatasetChange is one dataset file that differs from dataset.sha256sums.
+type DatasetChange struct {
+	// Path is relative to the level dir, with forward slashes.
+	Path string
+	// Change is "modified", "missing" or "added".
+	Change string
+	// Detail explains a modification when it can be told apart, such as
+	// converted line endings.
+	Detail string
+}
+
+func (c DatasetChange) String() string {
+	if c.Detail != "" {
+		return fmt.Sprintf("%s %s (%s)", c.Change, c.Path, c.Detail)
+	}
+	return c.Change + " " + c.Path
+}
+
+// DatasetMismatchError lists the dataset files that differ from a level's
+// dataset.sha256sums.
+type DatasetMismatchError struct {
+	LevelID string
+	Changes []DatasetChange
+}
+
+func (e *DatasetMismatchError) Error() string {
+	parts := make([]string, 0, len(e.Changes))
+	for _, c := range e.Changes {
+		parts = append(parts, c.String())
+	}
+	return fmt.Sprintf("level %s dataset does not match %s: %s", e.LevelID, DatasetSumsName, strings.Join(parts, "; "))
+}
+
+// cachedSum is a file's checksum and the size and mtime it was hashed at.
+type cachedSum struct {
+	size  int64
+	mtime time.Time
+	sum   string
+}
+
+var sumLine = regexp.MustCompile(`^([0-9a-f]{64}) [ *](.+)$`)
+
+// WriteDatasetSums writes dataset.sha256sums into levelDir for every regular
+// file under the given level-relative dataset dirs and returns the number of
+// files listed.
+func WriteDatasetSums(levelDir string, datasetDirs []string) (int, error) {
+	sums := map[string]string{}
+	for _, dir := range datasetDirs {
+		err := walkDatasetFiles(levelDir, filepath.Join(levelDir, dir), func(rel, path string, _ fs.FileInfo) error {
+			sum, err := hashFile(path)
+			sums[rel] = sum
+			return err
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	paths := make([]string, 0, len(sums))
+	for p := range sums {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	var b strings.Builder
+	for _, p := range paths {
+		fmt.Fprintf(&b, "%s  %s\n", sums[p], p)
+	}
+	if err := os.WriteFile(filepath.Join(levelDir, DatasetSumsName), []byte(b.String()), 0o644); err != nil {
+		return 0, err
+	}
+	return len(paths), nil
+}
+
+// DatasetDirs lists the level-relative dataset dirs of the level and all its
+// variants. Generated datasets have none.
+func (l Level) DatasetDirs() []string {
+	variants := []Level{l}
+	for _, v := range l.Variants {
+		if lv, err := l.WithVariant(v.VariantID); err == nil {
+			variants = append(variants, lv)
+		}
+	}
+	seen := map[string]struct{}{}
+	out := []string{}
+	for _, lv := range variants {
+		ds := lv.Filesystem.Dataset
+		if ds.Source == "generator" {
+			continue
+		}
+		dir := filepath.Clean(ds.Path)
+		if _, ok := seen[dir]; !ok {
+			seen[dir] = struct{}{}
+			out = append(out, dir)
+		}
+	}
+	return out
+}
+
+// verifyDataset compares the level's dataset with its dataset.sha256sums, if
+// it has one, and returns a *DatasetMismatchError listing every difference.
+// Only files changed since the loader last hashed them are read again.
+// Entries outside the level's current dataset dir belong to other variants
+// and are ignored.
+func (l *FSLoader) verifyDataset(level Level) error {
+	manifest := filepath.Join(level.Path, DatasetSumsName)
+	if _, err := os.Stat(manifest); l.SkipDatasetSums || errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if level.Filesystem.Dataset.Source == "generator" {
+		return fmt.Errorf("level %s: %s does not apply to generated datasets", level.LevelID, DatasetSumsName)
+	}
+	if !DatasetReady(level) {
+		// PrepareDataset reports the missing dir.
+		return nil
+	}
+	want, err := readDatasetSums(manifest)
+	if err != nil {
+		return fmt.Errorf("level %s: %w", level.LevelID, err)
+	}
+	prefix, err := filepath.Rel(level.Path, level.DatasetHostPath)
+	if err != nil {
+		return err
+	}
+	prefix = filepath.ToSlash(prefix) + "/"
+
+	changes := []DatasetChange{}
+	seen := map[string]struct{}{}
+	err = walkDatasetFiles(level.Path, level.DatasetHostPath, func(rel, path string, info fs.FileInfo) error {
+		seen[rel] = struct{}{}
+		expected, ok := want[rel]
+		if !ok {
+			changes = append(changes, DatasetChange{Path: rel, Change: "added"})
+			return nil
+		}
+		sum, err := l.cachedFileSum(path, info)
+		if err != nil {
+			return err
+		}
+		if sum != expected {
+			changes = append(changes, DatasetChange{Path: rel, Change: "modified", Detail: lineEndingChange(path, expected)})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("level %s: verify dataset: %w", level.LevelID, err)
+	}
+	for rel := range want {
+		if _, ok := seen[rel]; !ok && strings.HasPrefix(rel, prefix) {
+			changes = append(changes, DatasetChange{Path: rel, Change: "missing"})
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return &DatasetMismatchError{LevelID: level.LevelID, Changes: changes}
+}
+
+// cachedFileSum is hashFile memoized by size and mtime.
+func (l *FSLoader) cachedFileSum(path string, info fs.FileInfo) (string, error) {
+	l.keyMu.Lock()
+	cached, ok := l.sums[path]
+	l.keyMu.Unlock()
+	if ok && cached.size == info.Size() && cached.mtime.Equal(info.ModTime()) {
+		return cached.sum, nil
+	}
+	sum, err := hashFile(path)
+	if err != nil {
+		return "", err
+	}
+	l.keyMu.Lock()
+	if l.sums == nil {
+		l.sums = map[string]cachedSum{}
+	}
+	l.sums[path] = cachedSum{size: info.Size(), mtime: info.ModTime(), sum: sum}
+	l.keyMu.Unlock()
+	return sum, nil
+}
+
+// walkDatasetFiles calls fn for every regular file under dir with its path
+// relative to levelDir. Symlinks and other special files are not covered.
+func walkDatasetFiles(levelDir, dir string, fn func(rel, path string, info fs.FileInfo) error) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(levelDir, path)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(rel), path, info)
+	})
+}
+
+func readDatasetSums(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	out := map[string]string{}
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSuffix(s.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		m := sumLine.FindStringSubmatch(text)
+		if m == nil {
+			return nil, fmt.Errorf("%s:%d: want \"<sha256>  <path>\"", DatasetSumsName, line)
+		}
+		out[filepath.ToSlash(filepath.Clean(m[2]))] = m[1]
+	}
+	return out, s.Err()
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// lineEndingChange tells whether a modified file only had its line endings
+// converted, which is what git's autocrlf does to shared text datasets.
+func lineEndingChange(path, expected string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	sumOf := func(b []byte) string {
+		sum := sha256.Sum256(b)
+		return hex.EncodeToString(sum[:])
+	}
+	lf := bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+	switch {
+	case !bytes.Equal(lf, b) && sumOf(lf) == expected:
+		return "line endings converted to CRLF"
+	case sumOf(bytes.ReplaceAll(lf, []byte("\n"), []byte("\r\n"))) == expected:
+		return "line endings converted to LF"
+	}
+	return ""
+}
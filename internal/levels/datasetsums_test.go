@@ -0,0 +1,98 @@
+package levels
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDatasetSumsReportPreciseChanges(t *testing.T) {
+	root := t.TempDir()
+	writeTestPack(t, root, "sums-pack", "Sums")
+	levelDir := filepath.Join(root, "sums-pack", "levels", "level-one")
+	dataset := filepath.Join(levelDir, "dataset")
+	write := func(rel, body string) {
+		t.Helper()
+		path := filepath.Join(dataset, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("app.log", "GET /\nGET /health\n")
+	write("logs/old.log", "ERROR x\n")
+	write("notes.txt", "keep\n")
+	level := Level{LevelID: "level-one", Path: levelDir, DatasetHostPath: dataset}
+	level.Filesystem.Dataset = DatasetSpec{Source: "dir", Path: "dataset"}
+	if n, err := WriteDatasetSums(levelDir, level.DatasetDirs()); err != nil || n != 3 {
+		t.Fatalf("write sums: n=%d err=%v", n, err)
+	}
+
+	loader := &FSLoader{IsolateErrors: true}
+	if err := loader.verifyDataset(level); err != nil {
+		t.Fatalf("fresh manifest should verify: %v", err)
+	}
+
+	write("app.log", "GET /\r\nGET /health\r\n")
+	write("notes.txt", "changed\n")
+	write("extra.bin", "x")
+	if err := os.Remove(filepath.Join(dataset, "logs", "old.log")); err != nil {
+		t.Fatal(err)
+	}
+
+	err := loader.verifyDataset(level)
+	var mismatch *DatasetMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected a mismatch, got %v", err)
+	}
+	want := "modified dataset/app.log (line endings converted to CRLF); added dataset/extra.bin; missing dataset/logs/old.log; modified dataset/notes.txt"
+	got := []string{}
+	for _, c := range mismatch.Changes {
+		got = append(got, c.String())
+	}
+	if strings.Join(got, "; ") != want {
+		t.Fatalf("changes:\n got %s\nwant %s", strings.Join(got, "; "), want)
+	}
+
+	packs, err := loader.LoadPacks(context.Background(), root)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(packs[0].LoadedLevels) != 0 || len(packs[0].BrokenLevels) != 1 || !strings.Contains(packs[0].BrokenLevels[0].Error, "dataset/app.log") {
+		t.Fatalf("level should load as broken: %+v", packs[0].BrokenLevels)
+	}
+	loader.SkipDatasetSums = true
+	if packs, err := loader.LoadPacks(context.Background(), root); err != nil || len(packs[0].LoadedLevels) != 1 {
+		t.Fatalf("SkipDatasetSums should load the level: %v", err)
+	}
+}
+
+func TestDatasetSumsCatchEditsBeforeStaging(t *testing.T) {
+	root := t.TempDir()
+	writeTestPack(t, root, "sums-pack", "Sums")
+	levelDir := filepath.Join(root, "sums-pack", "levels", "level-one")
+	log := filepath.Join(levelDir, "dataset", "app.log")
+	if err := os.WriteFile(log, []byte("ok\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WriteDatasetSums(levelDir, []string{"dataset"}); err != nil {
+		t.Fatal(err)
+	}
+	loader := NewLoader()
+	packs, err := loader.LoadPacks(context.Background(), root)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if err := os.WriteFile(log, []byte("truncated"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = loader.PrepareDataset(context.Background(), packs[0], packs[0].LoadedLevels[0], nil)
+	if err == nil || !strings.Contains(err.Error(), "modified dataset/app.log") {
+		t.Fatalf("expected PrepareDataset to catch the edit, got %v", err)
+	}
+}
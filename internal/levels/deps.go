@@ -0,0 +1,120 @@
+package levels
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LevelRef names a level in a specific pack. Prerequisites are written as
+// "pack_id/level_id", or as a bare level_id for a level in the same pack.
+type LevelRef struct {
+	PackID  string
+	LevelID string
+}
+
+func (r LevelRef) String() string { return r.PackID + "/" + r.LevelID }
+
+// ParseLevelRef parses a prerequisite; bare level IDs resolve to ownerPack.
+func ParseLevelRef(s, ownerPack string) (LevelRef, error) {
+	packID, levelID, qualified := strings.Cut(strings.TrimSpace(s), "/")
+	if !qualified {
+		packID, levelID = ownerPack, packID
+	} else if !idPattern.MatchString(packID) {
+		return LevelRef{}, fmt.Errorf("invalid pack_id in prerequisite %q", s)
+	}
+	if !idPattern.MatchString(levelID) {
+		return LevelRef{}, fmt.Errorf("invalid level_id in prerequisite %q", s)
+	}
+	return LevelRef{PackID: packID, LevelID: levelID}, nil
+}
+
+// PrerequisiteRefs returns the level's prerequisites resolved against pack.
+// Malformed entries are rejected by Level.Validate and skipped here.
+func (l Level) PrerequisiteRefs(pack Pack) []LevelRef {
+	out := make([]LevelRef, 0, len(l.Progression.Prerequisites))
+	for _, p := range l.Progression.Prerequisites {
+		if ref, err := ParseLevelRef(p, pack.PackID); err == nil {
+			out = append(out, ref)
+		}
+	}
+	return out
+}
+
+// CheckDependencies records, on each pack, the requires entries that no
+// loaded pack satisfies and, on each level, the prerequisites that point at
+// missing levels or at packs the pack does not require.
+func CheckDependencies(packs []Pack) {
+	byID := make(map[string]*Pack, len(packs))
+	for i := range packs {
+		if packs[i].LoadError != "" {
+			// A pack that failed to load satisfies nothing.
+			continue
+		}
+		byID[packs[i].PackID] = &packs[i]
+	}
+	for i := range packs {
+		p := &packs[i]
+		p.DependencyErrors = nil
+		required := map[string]struct{}{}
+		for _, req := range p.Requires {
+			required[req.PackID] = struct{}{}
+			if msg := unsatisfiedRequire(req, byID[req.PackID]); msg != "" {
+				p.DependencyErrors = append(p.DependencyErrors, msg)
+			}
+		}
+		for j := range p.LoadedLevels {
+			lv := &p.LoadedLevels[j]
+			lv.DependencyErrors = nil
+			for _, ref := range lv.PrerequisiteRefs(*p) {
+				if ref.PackID != p.PackID {
+					if _, ok := required[ref.PackID]; !ok {
+						lv.DependencyErrors = append(lv.DependencyErrors, fmt.Sprintf("prerequisite %s: pack %s is not listed in requires", ref, ref.PackID))
+						continue
+					}
+				}
+				target := byID[ref.PackID]
+				if target == nil {
+					// Already reported as an unsatisfied require.
+					continue
+				}
+				if !target.hasLevel(ref.LevelID) {
+					lv.DependencyErrors = append(lv.DependencyErrors, fmt.Sprintf("prerequisite %s: no such level in pack %s", ref, ref.PackID))
+				}
+			}
+		}
+	}
+}
+
+func unsatisfiedRequire(req PackRequire, found *Pack) string {
+	want := "any version"
+	if req.VersionConstraint != "" {
+		want = req.VersionConstraint
+	}
+	if found == nil {
+		return fmt.Sprintf("requires pack %s (%s), which is not installed", req.PackID, want)
+	}
+	if req.VersionConstraint == "" {
+		return ""
+	}
+	c, err := ParseConstraint(req.VersionConstraint)
+	if err != nil {
+		return fmt.Sprintf("requires pack %s: %v", req.PackID, err)
+	}
+	v, err := ParseVersion(found.Version)
+	if err != nil {
+		return fmt.Sprintf("requires pack %s %s, but installed version %q is not a semantic version", req.PackID, want, found.Version)
+	}
+	if !c.Check(v) {
+		return fmt.Sprintf("requires pack %s %s, found %s (%s)", req.PackID, want, found.Version, found.Path)
+	}
+	return ""
+}
+
+func (p *Pack) hasLevel(levelID string) bool {
+	for _, lv := range p.LoadedLevels {
+		if lv.LevelID == levelID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package levels
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeIncidentPack(t *testing.T, root, requires, prereq string) {
+	t.Helper()
+	writeTestPack(t, root, "incident-response", "Incident Response")
+	packYAML := filepath.Join(root, "incident-response", "pack.yaml")
+	b, err := os.ReadFile(packYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(packYAML, append(b, []byte(requires)...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	levelYAML := filepath.Join(root, "incident-response", "levels", "level-one", "level.yaml")
+	b, err = os.ReadFile(levelYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(levelYAML, append(b, []byte("x-progression:\n  prerequisites: [\""+prereq+"\"]\n")...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckDependenciesAcrossRoots(t *testing.T) {
+	builtin := filepath.Join("..", "..", "packs")
+	cases := []struct {
+		name     string
+		requires string
+		prereq   string
+		wantErr  string
+		// levelErr means the error belongs to the level, not the pack.
+		levelErr bool
+	}{
+		{"satisfied", "requires:\n  - { pack_id: builtin-core, version_constraint: \"^0.1\" }\n", "builtin-core/level-003-top-ips", "", false},
+		{"version", "requires:\n  - { pack_id: builtin-core, version_constraint: \">=1.0.0\" }\n", "builtin-core/level-003-top-ips", "requires pack builtin-core >=1.0.0, found 0.1.0", false},
+		{"missing pack", "requires:\n  - { pack_id: forensics-pack }\n", "level-one", "requires pack forensics-pack (any version), which is not installed", false},
+		{"not required", "", "builtin-core/level-003-top-ips", "pack builtin-core is not listed in requires", true},
+		{"missing level", "requires:\n  - { pack_id: builtin-core }\n", "builtin-core/level-999-nope", "no such level in pack builtin-core", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			user := t.TempDir()
+			writeIncidentPack(t, user, tc.requires, tc.prereq)
+			packs, err := NewLoader().LoadPackRoots(context.Background(), []PackRoot{
+				{Path: user, Source: RootSourceUser},
+				{Path: builtin, Source: RootSourceBuiltin},
+			})
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			var ir Pack
+			for _, p := range packs {
+				if p.PackID == "incident-response" {
+					ir = p
+				}
+				if p.PackID == "builtin-core" && len(p.DependencyErrors) != 0 {
+					t.Fatalf("builtin-core should have no dependency errors: %v", p.DependencyErrors)
+				}
+			}
+			got, other := strings.Join(ir.DependencyErrors, "; "), ""
+			for _, lv := range ir.LoadedLevels {
+				if lv.LevelID == "level-one" {
+					other = strings.Join(lv.DependencyErrors, "; ")
+				}
+			}
+			if tc.levelErr {
+				got, other = other, got
+			}
+			if (tc.wantErr == "" && got != "") || other != "" {
+				t.Fatalf("unexpected dependency errors: %q and %q", got, other)
+			}
+			if !strings.Contains(got, tc.wantErr) {
+				t.Fatalf("expected %q in %q", tc.wantErr, got)
+			}
+		})
+	}
+}
+
+func TestParseLevelRef(t *testing.T) {
+	ref, err := ParseLevelRef("level-001-pipes-101", "builtin-core")
+	if err != nil || ref.String() != "builtin-core/level-001-pipes-101" {
+		t.Fatalf("bare ref: %v %v", ref, err)
+	}
+	ref, err = ParseLevelRef("builtin-core/level-002-find-safe", "incident-response")
+	if err != nil || ref.PackID != "builtin-core" || ref.LevelID != "level-002-find-safe" {
+		t.Fatalf("qualified ref: %v %v", ref, err)
+	}
+	for _, bad := range []string{"a/b/c", "/level-001", "builtin-core/", "Bad/level-001"} {
+		if _, err := ParseLevelRef(bad, "x-pack"); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package levels
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileTypes are the values file_type checks accept.
+var FileTypes = []string{"regular", "dir", "symlink", "fifo", "socket", "char", "block"}
+
+// checkTimeLayouts are the file_mtime bound formats, tried in order.
+var checkTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseCheckTime parses a file_mtime bound: RFC 3339, or a date with an
+// optional time of day. Times without a zone are UTC, the container default.
+func ParseCheckTime(s string) (time.Time, error) {
+	for _, layout := range checkTimeLayouts {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(s), time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: want RFC 3339 or \"2006-01-02 15:04:05\"", s)
+}
+
+// ParsePerm parses octal permission bits such as "640", "0640" or "2755".
+func ParsePerm(s string) (uint32, error) {
+	n, err := strconv.ParseUint(strings.TrimPrefix(s, "0o"), 8, 32)
+	if err != nil || n > 0o7777 {
+		return 0, fmt.Errorf("invalid permission %q: want octal such as 0640", s)
+	}
+	return uint32(n), nil
+}
+
+// validateFileMeta checks the fields of the file metadata check types.
+func (c CheckSpec) validateFileMeta() error {
+	switch c.Type {
+	case "file_mode":
+		if c.Perm == "" && c.PermSet == "" && c.PermUnset == "" {
+			return fmt.Errorf("file_mode needs perm, perm_set or perm_unset")
+		}
+		if c.Perm != "" && (c.PermSet != "" || c.PermUnset != "") {
+			return fmt.Errorf("perm cannot be combined with perm_set or perm_unset")
+		}
+		bits := map[string]uint32{}
+		for name, v := range map[string]string{"perm": c.Perm, "perm_set": c.PermSet, "perm_unset": c.PermUnset} {
+			if v == "" {
+				continue
+			}
+			n, err := ParsePerm(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			bits[name] = n
+		}
+		if both := bits["perm_set"] & bits["perm_unset"]; both != 0 {
+			return fmt.Errorf("perm_set and perm_unset both name bits %04o", both)
+		}
+	case "file_owner":
+		if c.Owner == "" && c.Group == "" {
+			return fmt.Errorf("file_owner needs owner or group")
+		}
+	case "file_type":
+		if !slices.Contains(FileTypes, c.FileType) {
+			return fmt.Errorf("file_type must be one of %s", strings.Join(FileTypes, ", "))
+		}
+	case "symlink_target":
+		if (c.Expected == nil) == (c.Pattern == "") {
+			return fmt.Errorf("symlink_target needs exactly one of expected and pattern")
+		}
+	case "file_mtime":
+		if c.MtimeAfter == "" && c.MtimeBefore == "" {
+			return fmt.Errorf("file_mtime needs mtime_after or mtime_before")
+		}
+		var after, before time.Time
+		var err error
+		if c.MtimeAfter != "" {
+			if after, err = ParseCheckTime(c.MtimeAfter); err != nil {
+				return fmt.Errorf("mtime_after: %w", err)
+			}
+		}
+		if c.MtimeBefore != "" {
+			if before, err = ParseCheckTime(c.MtimeBefore); err != nil {
+				return fmt.Errorf("mtime_before: %w", err)
+			}
+			if before.Before(after) {
+				return fmt.Errorf("mtime_before is earlier than mtime_after")
+			}
+		}
+	case "hardlink_count":
+		if c.Equals <= 0 && c.Min == nil && c.Max == nil {
+			return fmt.Errorf("hardlink_count needs equals, min or max")
+		}
+	}
+	return nil
+}
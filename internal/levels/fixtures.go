@@ -0,0 +1,734 @@
+package levels
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
+	"net/mail"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// WriteFileSpec writes one file with inline content. With Template set the
+// content is a text/template over FixtureData.
+type WriteFileSpec struct {
+	Path     string `yaml:"path"`
+	Content  string `yaml:"content"`
+	Template bool   `yaml:"template"`
+	Mode     string `yaml:"mode"`
+}
+
+// GenerateFileSpec writes seeded random text: Lines lines of words, or Size
+// bytes of them.
+type GenerateFileSpec struct {
+	Path  string `yaml:"path"`
+	Size  string `yaml:"size"`
+	Lines int    `yaml:"lines"`
+	Seed  int64  `yaml:"seed"`
+}
+
+// SparseFileSpec creates a file of Size bytes that is one hole.
+type SparseFileSpec struct {
+	Path string `yaml:"path"`
+	Size string `yaml:"size"`
+}
+
+// GitRepoSpec initializes a repository and replays Commits in order. The
+// first commit's branch (default main) is the initial branch; a commit on a
+// new branch forks it from the current HEAD. Checkout defaults to the
+// initial branch.
+type GitRepoSpec struct {
+	Path     string      `yaml:"path"`
+	Checkout string      `yaml:"checkout"`
+	Commits  []GitCommit `yaml:"commits"`
+}
+
+type GitCommit struct {
+	Message string            `yaml:"message"`
+	Branch  string            `yaml:"branch"`
+	Files   map[string]string `yaml:"files"`
+	Delete  []string          `yaml:"delete"`
+	Author  string            `yaml:"author"`
+	Date    string            `yaml:"date"`
+	Tags    []string          `yaml:"tags"`
+}
+
+// ArchiveSpec packs dataset paths into a tar, tar.gz or zip in the workdir.
+// Entries keep their dataset-relative names.
+type ArchiveSpec struct {
+	Path   string   `yaml:"path"`
+	Format string   `yaml:"format"`
+	From   []string `yaml:"from"`
+}
+
+// FixtureData is what write_files templates see.
+type FixtureData struct {
+	LevelID string
+	Variant string
+	Seed    int64
+	Params  map[string]string
+}
+
+const (
+	defaultGitAuthor = "Dojo Author <author@dojo.invalid>"
+	defaultGitBranch = "main"
+)
+
+// gitEpoch dates commits without a date: one hour apart from here.
+var gitEpoch = time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+// Templates are rendered in memory on the host, so their output and the
+// helpers that grow it are bounded.
+const (
+	maxTemplateBytes = 16 << 20
+	maxTemplateSeq   = 1 << 20
+)
+
+var fixtureFuncs = template.FuncMap{
+	"seq": func(n int) ([]int, error) {
+		if n > maxTemplateSeq {
+			return nil, fmt.Errorf("seq %d exceeds the limit of %d", n, maxTemplateSeq)
+		}
+		out := make([]int, max(0, n))
+		for i := range out {
+			out[i] = i + 1
+		}
+		return out, nil
+	},
+	"repeat": func(n int, s string) (string, error) {
+		if n > 0 && len(s) > 0 && n > maxTemplateBytes/len(s) {
+			return "", fmt.Errorf("repeat %d exceeds the template limit of %d bytes", n, maxTemplateBytes)
+		}
+		return strings.Repeat(s, max(0, n)), nil
+	},
+	"add": func(a, b int) int { return a + b },
+}
+
+// cappedBuffer fails writes past maxTemplateBytes.
+type cappedBuffer struct{ bytes.Buffer }
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > maxTemplateBytes {
+		return 0, fmt.Errorf("template output exceeds %d bytes", maxTemplateBytes)
+	}
+	return b.Buffer.Write(p)
+}
+
+var fixtureWords = strings.Fields(`alpha bravo charlie delta echo foxtrot golf hotel india juliet kilo lima
+mike november oscar papa quebec romeo sierra tango uniform victor whiskey xray yankee zulu
+error warning info debug request response timeout retry cache index queue worker
+disk memory network socket thread process signal kernel user group file path`)
+
+func (l InitialLayout) validateFixtures() error {
+	for _, w := range l.WriteFiles {
+		if err := fixturePath(w.Path); err != nil {
+			return fmt.Errorf("write_files %q: %w", w.Path, err)
+		}
+		if w.Template {
+			if _, err := template.New(w.Path).Funcs(fixtureFuncs).Parse(w.Content); err != nil {
+				return fmt.Errorf("write_files %q: %w", w.Path, err)
+			}
+		}
+		if w.Mode != "" {
+			if _, err := ParseFileMode(w.Mode); err != nil {
+				return fmt.Errorf("write_files %q: %w", w.Path, err)
+			}
+		}
+	}
+	for _, g := range l.GenerateFile {
+		if err := fixturePath(g.Path); err != nil {
+			return fmt.Errorf("generate_file %q: %w", g.Path, err)
+		}
+		if (g.Size == "") == (g.Lines <= 0) {
+			return fmt.Errorf("generate_file %q: set exactly one of size and lines", g.Path)
+		}
+		if g.Size != "" {
+			if _, err := ParseSize(g.Size); err != nil {
+				return fmt.Errorf("generate_file %q: %w", g.Path, err)
+			}
+		}
+	}
+	for _, s := range l.SparseFile {
+		if err := fixturePath(s.Path); err != nil {
+			return fmt.Errorf("sparse_file %q: %w", s.Path, err)
+		}
+		if _, err := ParseSize(s.Size); err != nil {
+			return fmt.Errorf("sparse_file %q: %w", s.Path, err)
+		}
+	}
+	for _, g := range l.GitRepo {
+		if err := g.validate(); err != nil {
+			return fmt.Errorf("git_repo %q: %w", g.Path, err)
+		}
+	}
+	for _, a := range l.Archive {
+		if err := fixturePath(a.Path); err != nil {
+			return fmt.Errorf("archive %q: %w", a.Path, err)
+		}
+		if _, err := archiveFormat(a); err != nil {
+			return fmt.Errorf("archive %q: %w", a.Path, err)
+		}
+		if len(a.From) == 0 {
+			return fmt.Errorf("archive %q: from must list at least one dataset path", a.Path)
+		}
+		for _, from := range a.From {
+			if !workRelative(from) {
+				return fmt.Errorf("archive %q: from %q must stay inside the dataset", a.Path, from)
+			}
+		}
+	}
+	return nil
+}
+
+func fixturePath(p string) error {
+	if p == "" {
+		return fmt.Errorf("path is required")
+	}
+	if !workRelative(p) {
+		return fmt.Errorf("path must stay inside the workdir")
+	}
+	return nil
+}
+
+func (g GitRepoSpec) validate() error {
+	if err := fixturePath(g.Path); err != nil {
+		return err
+	}
+	if len(g.Commits) == 0 {
+		return fmt.Errorf("commits must list at least one commit")
+	}
+	if g.Checkout != "" {
+		if err := checkRefName(g.Checkout); err != nil {
+			return fmt.Errorf("checkout %w", err)
+		}
+	}
+	for i, c := range g.Commits {
+		if strings.TrimSpace(c.Message) == "" {
+			return fmt.Errorf("commits[%d].message is required", i)
+		}
+		if c.Author != "" {
+			if _, err := mail.ParseAddress(c.Author); err != nil {
+				return fmt.Errorf("commits[%d].author %q is not \"Name <email>\"", i, c.Author)
+			}
+		}
+		if c.Date != "" {
+			if _, err := ParseMtime(c.Date); err != nil {
+				return fmt.Errorf("commits[%d]: %w", i, err)
+			}
+		}
+		if c.Branch != "" {
+			if err := checkRefName(c.Branch); err != nil {
+				return fmt.Errorf("commits[%d].branch %w", i, err)
+			}
+		}
+		for _, tag := range c.Tags {
+			if err := checkRefName(tag); err != nil {
+				return fmt.Errorf("commits[%d].tags %w", i, err)
+			}
+		}
+		for name := range c.Files {
+			if err := repoPath(name); err != nil {
+				return fmt.Errorf("commits[%d].files %q %w", i, name, err)
+			}
+		}
+		for _, name := range c.Delete {
+			if err := repoPath(name); err != nil {
+				return fmt.Errorf("commits[%d].delete %q %w", i, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkRefName applies the rules of `git check-ref-format --branch` to a
+// branch or tag name. A leading "-" is refused too, as git would take the
+// name for an option.
+func checkRefName(name string) error {
+	bad := name == "" || name == "@" || strings.HasPrefix(name, "-") ||
+		strings.HasSuffix(name, "/") || strings.HasSuffix(name, ".") ||
+		strings.Contains(name, "..") || strings.Contains(name, "//") || strings.Contains(name, "@{") ||
+		strings.ContainsAny(name, " ~^:?*[\\\x7f")
+	for _, r := range name {
+		bad = bad || r < 0x20
+	}
+	for _, part := range strings.Split(name, "/") {
+		bad = bad || strings.HasPrefix(part, ".") || strings.HasSuffix(part, ".lock")
+	}
+	if bad {
+		return fmt.Errorf("%q is not a valid git ref name", name)
+	}
+	return nil
+}
+
+// repoPath checks a commit path. Paths into .git are refused: the repository
+// is built with the host's git, and its config can name commands to run.
+func repoPath(name string) error {
+	if name == "" || !workRelative(name) {
+		return fmt.Errorf("must stay inside the repository")
+	}
+	for _, part := range strings.Split(filepath.ToSlash(filepath.Clean(name)), "/") {
+		if strings.EqualFold(part, ".git") {
+			return fmt.Errorf("must not be inside .git")
+		}
+	}
+	return nil
+}
+
+// buildFixtures runs the fixture builders of level's initial_layout in
+// workdir, in the order they are declared in InitialLayout.
+func buildFixtures(level Level, workdir string) error {
+	layout := level.Filesystem.Work.InitialLayout
+	data := FixtureData{LevelID: level.LevelID, Variant: level.Variant, Params: map[string]string{}}
+	if level.RunSeed != nil {
+		data.Seed = *level.RunSeed
+	}
+	if v, ok := level.variant(level.Variant); ok {
+		data.Params = v.Params
+	}
+	for _, w := range layout.WriteFiles {
+		if err := writeFixtureFile(workdir, w, data); err != nil {
+			return fmt.Errorf("write_files %q: %w", w.Path, err)
+		}
+	}
+	for _, g := range layout.GenerateFile {
+		if err := generateFixtureFile(workdir, g); err != nil {
+			return fmt.Errorf("generate_file %q: %w", g.Path, err)
+		}
+	}
+	for _, s := range layout.SparseFile {
+		if err := sparseFixtureFile(workdir, s); err != nil {
+			return fmt.Errorf("sparse_file %q: %w", s.Path, err)
+		}
+	}
+	for _, g := range layout.GitRepo {
+		if level.Restricted {
+			return fmt.Errorf("git_repo %q is built with the host's git: %w", g.Path, ErrSandboxOnly)
+		}
+		if err := buildGitRepo(workdir, g); err != nil {
+			return fmt.Errorf("git_repo %q: %w", g.Path, err)
+		}
+	}
+	for _, a := range layout.Archive {
+		if err := buildArchiveFixture(workdir, level.DatasetHostPath, a); err != nil {
+			return fmt.Errorf("archive %q: %w", a.Path, err)
+		}
+	}
+	return nil
+}
+
+// createFixture opens a fresh file at rel inside workdir.
+func createFixture(workdir, rel string, perm fs.FileMode) (*os.File, error) {
+	target, err := stagePath(workdir, rel)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+}
+
+func writeFixtureFile(workdir string, w WriteFileSpec, data FixtureData) error {
+	content := []byte(w.Content)
+	if w.Template {
+		tmpl, err := template.New(w.Path).Funcs(fixtureFuncs).Option("missingkey=error").Parse(w.Content)
+		if err != nil {
+			return err
+		}
+		var b cappedBuffer
+		if err := tmpl.Execute(&b, data); err != nil {
+			return err
+		}
+		content = b.Bytes()
+	}
+	mode := fs.FileMode(0o644)
+	if w.Mode != "" {
+		m, err := ParseFileMode(w.Mode)
+		if err != nil {
+			return err
+		}
+		mode = m
+	}
+	f, err := createFixture(workdir, w.Path, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func generateFixtureFile(workdir string, g GenerateFileSpec) error {
+	seed := g.Seed
+	if seed == 0 {
+		seed = 1
+	}
+	rng := rand.New(rand.NewSource(seed))
+	line := func() string {
+		words := make([]string, 4+rng.Intn(9))
+		for i := range words {
+			words[i] = fixtureWords[rng.Intn(len(fixtureWords))]
+		}
+		return strings.Join(words, " ") + "\n"
+	}
+	size := int64(-1)
+	if g.Size != "" {
+		n, err := ParseSize(g.Size)
+		if err != nil {
+			return err
+		}
+		size = n
+	}
+	f, err := createFixture(workdir, g.Path, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	for i, written := 0, int64(0); (size < 0 && i < g.Lines) || written < size; i++ {
+		l := line()
+		if size >= 0 && int64(len(l)) > size-written {
+			l = l[:size-written]
+		}
+		n, err := w.WriteString(l)
+		if err != nil {
+			return err
+		}
+		written += int64(n)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func sparseFixtureFile(workdir string, s SparseFileSpec) error {
+	size, err := ParseSize(s.Size)
+	if err != nil {
+		return err
+	}
+	f, err := createFixture(workdir, s.Path, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// buildGitRepo replays g with the host's git. Authors, dates and config are
+// pinned so the same spec always yields the same commit IDs.
+func buildGitRepo(workdir string, g GitRepoSpec) error {
+	if _, err := exec.LookPath("git"); err != nil {
+		return fmt.Errorf("git_repo needs git on the host: %w", err)
+	}
+	dir, err := stagePath(workdir, g.Path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	// A symlink staged from the dataset would run git outside the workdir.
+	if info, err := os.Lstat(dir); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("path %q is not a directory", g.Path)
+	}
+	branch := cmp.Or(g.Commits[0].Branch, defaultGitBranch)
+	git := func(env []string, args ...string) error {
+		cmd := exec.Command("git", append([]string{"-c", "commit.gpgsign=false", "-c", "tag.gpgsign=false", "-c", "core.hooksPath=/dev/null", "-c", "core.fsmonitor=false"}, args...)...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "GIT_CONFIG_GLOBAL="+os.DevNull, "HOME="+dir, "LC_ALL=C")
+		cmd.Env = append(cmd.Env, env...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+	if err := git(nil, "init", "-q"); err != nil {
+		return err
+	}
+	if err := git(nil, "symbolic-ref", "HEAD", "refs/heads/"+branch); err != nil {
+		return err
+	}
+	branches := map[string]bool{branch: true}
+	current := branch
+	for i, c := range g.Commits {
+		if c.Branch != "" && c.Branch != current {
+			args := []string{"checkout", "-q", c.Branch, "--"}
+			if !branches[c.Branch] {
+				args = []string{"checkout", "-q", "-b", c.Branch, "--"}
+			}
+			if err := git(nil, args...); err != nil {
+				return err
+			}
+			branches[c.Branch] = true
+			current = c.Branch
+		}
+		names := make([]string, 0, len(c.Files))
+		for name := range c.Files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			f, err := createFixture(workdir, filepath.Join(g.Path, filepath.FromSlash(name)), 0o644)
+			if err != nil {
+				return err
+			}
+			if _, err := f.WriteString(c.Files[name]); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		}
+		for _, name := range c.Delete {
+			target, err := stagePath(workdir, filepath.Join(g.Path, filepath.FromSlash(name)))
+			if err != nil {
+				return err
+			}
+			if err := os.RemoveAll(target); err != nil {
+				return err
+			}
+		}
+		author, _ := mail.ParseAddress(cmp.Or(c.Author, defaultGitAuthor))
+		date := gitEpoch.Add(time.Duration(i) * time.Hour)
+		if c.Date != "" {
+			date, _ = ParseMtime(c.Date)
+		}
+		stamp := date.Format(time.RFC3339)
+		env := []string{
+			"GIT_AUTHOR_NAME=" + author.Name, "GIT_AUTHOR_EMAIL=" + author.Address, "GIT_AUTHOR_DATE=" + stamp,
+			"GIT_COMMITTER_NAME=" + author.Name, "GIT_COMMITTER_EMAIL=" + author.Address, "GIT_COMMITTER_DATE=" + stamp,
+		}
+		if err := git(nil, "add", "-A"); err != nil {
+			return err
+		}
+		if err := git(env, "commit", "-q", "--allow-empty", "-m", c.Message); err != nil {
+			return err
+		}
+		for _, tag := range c.Tags {
+			if err := git(env, "tag", "--", tag); err != nil {
+				return err
+			}
+		}
+	}
+	if checkout := cmp.Or(g.Checkout, branch); checkout != current {
+		return git(nil, "checkout", "-q", checkout, "--")
+	}
+	return nil
+}
+
+func archiveFormat(a ArchiveSpec) (string, error) {
+	format := a.Format
+	if format == "" {
+		switch name := strings.ToLower(a.Path); {
+		case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+			format = "tar.gz"
+		case strings.HasSuffix(name, ".tar"):
+			format = "tar"
+		case strings.HasSuffix(name, ".zip"):
+			format = "zip"
+		}
+	}
+	switch format {
+	case "tar", "tar.gz", "zip":
+		return format, nil
+	case "":
+		return "", fmt.Errorf("cannot tell the format from the name; set format: tar, tar.gz or zip")
+	default:
+		return "", fmt.Errorf("unsupported format %q (want tar, tar.gz or zip)", format)
+	}
+}
+
+// buildArchiveFixture packs dataset paths with their modes, mtimes and
+// symlinks into an archive in workdir.
+func buildArchiveFixture(workdir, dataset string, a ArchiveSpec) error {
+	format, err := archiveFormat(a)
+	if err != nil {
+		return err
+	}
+	f, err := createFixture(workdir, a.Path, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	base, err := filepath.EvalSymlinks(dataset)
+	if err != nil {
+		return err
+	}
+	add, finish := archiveWriter(f, format)
+	for _, from := range a.From {
+		root := filepath.Join(dataset, filepath.FromSlash(from))
+		// WalkDir does not follow links below root, but it does follow
+		// them in root's parents, so those must stay inside the dataset.
+		parent, err := filepath.EvalSymlinks(filepath.Dir(root))
+		if err != nil {
+			return fmt.Errorf("archive %q: from %q: %w", a.Path, from, err)
+		}
+		if rel, err := filepath.Rel(base, parent); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("archive %q: from %q resolves outside the dataset", a.Path, from)
+		}
+		err = filepath.WalkDir(root, func(p string, d fs.DirEntry, walkErr error) error {
+			if walkErr != nil {
+				return walkErr
+			}
+			rel, err := filepath.Rel(dataset, p)
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			return add(filepath.ToSlash(rel), p, info)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := finish(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func archiveWriter(w io.Writer, format string) (add func(name, src string, info fs.FileInfo) error, finish func() error) {
+	if format == "zip" {
+		zw := zip.NewWriter(w)
+		add = func(name, src string, info fs.FileInfo) error {
+			hdr, err := zip.FileInfoHeader(info)
+			if err != nil {
+				return err
+			}
+			hdr.Name = name
+			hdr.Method = zip.Deflate
+			if info.IsDir() {
+				hdr.Name += "/"
+				hdr.Method = zip.Store
+			}
+			out, err := zw.CreateHeader(hdr)
+			if err != nil || info.IsDir() {
+				return err
+			}
+			if info.Mode()&fs.ModeSymlink != 0 {
+				target, err := os.Readlink(src)
+				if err != nil {
+					return err
+				}
+				_, err = io.WriteString(out, target)
+				return err
+			}
+			return copyInto(out, src, info)
+		}
+		return add, zw.Close
+	}
+	var gz *gzip.Writer
+	if format == "tar.gz" {
+		gz = gzip.NewWriter(w)
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	add = func(name, src string, info fs.FileInfo) error {
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			target, err := os.Readlink(src)
+			if err != nil {
+				return err
+			}
+			link = target
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = name
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		// Host owners mean nothing inside the sandbox.
+		hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil
+		}
+		return copyInto(tw, src, info)
+	}
+	finish = func() error {
+		if err := tw.Close(); err != nil {
+			return err
+		}
+		if gz != nil {
+			return gz.Close()
+		}
+		return nil
+	}
+	return add, finish
+}
+
+func copyInto(w io.Writer, src string, info fs.FileInfo) error {
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	_, err = io.Copy(w, in)
+	return err
+}
+
+// ParseSize parses a fixture size: bytes, or a number with a binary unit
+// suffix (K, M, G, optionally followed by "iB" or "B").
+func ParseSize(s string) (int64, error) {
+	trimmed := strings.ToUpper(strings.TrimSpace(s))
+	trimmed = strings.TrimSuffix(strings.TrimSuffix(trimmed, "B"), "I")
+	mult := int64(1)
+	if n := len(trimmed); n > 0 {
+		switch trimmed[n-1] {
+		case 'K':
+			mult = 1 << 10
+		case 'M':
+			mult = 1 << 20
+		case 'G':
+			mult = 1 << 30
+		}
+		if mult > 1 {
+			trimmed = trimmed[:n-1]
+		}
+	}
+	n, err := strconv.ParseInt(trimmed, 10, 64)
+	if err != nil || n < 0 || n > (1<<40)/mult {
+		return 0, fmt.Errorf("size %q is not a byte count like 4096, 64K or 10M", s)
+	}
+	return n * mult, nil
+}
@@ -0,0 +1,286 @@
+package levels
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const fixtureLayoutYAML = `
+level_id: fixtures
+variants:
+  - variant_id: small
+    params: { n: "3" }
+filesystem:
+  work:
+    initial_layout:
+      write_files:
+        - path: "notes/plain.txt"
+          content: "hello\n"
+        - path: "bin/report.sh"
+          content: "#!/bin/sh\necho {{.LevelID}}\n"
+          template: true
+          mode: "0755"
+        - path: "rows.csv"
+          template: true
+          content: "{{range seq 3}}row{{.}}-{{$.Params.n}}\n{{end}}"
+      generate_file:
+        - { path: "big.log", size: "10K", seed: 7 }
+        - { path: "lines.txt", lines: 25 }
+      sparse_file:
+        - { path: "disk.img", size: "64M" }
+      archive:
+        - { path: "backup.tar.gz", from: ["logs"] }
+        - { path: "backup.zip", from: ["logs/app.log"] }
+      git_repo:
+        - path: "repo"
+          checkout: main
+          commits:
+            - message: "Initial commit"
+              files: { "README.md": "hi\n" }
+              tags: [v1.0]
+            - message: "Add feature"
+              branch: feature
+              files: { "feature.txt": "wip\n" }
+            - message: "Fix typo"
+              branch: main
+              files: { "README.md": "hello\n" }
+              author: "Ada Lovelace <ada@example.com>"
+`
+
+func TestStageWorkdirBuildsFixtures(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	var level Level
+	if err := yaml.Unmarshal([]byte(fixtureLayoutYAML), &level); err != nil {
+		t.Fatal(err)
+	}
+	if err := level.Filesystem.Work.InitialLayout.validateFixtures(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	dataset := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dataset, "logs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dataset, "logs", "app.log"), []byte("boot\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	level.DatasetHostPath = dataset
+	work := filepath.Join(t.TempDir(), "work")
+	if err := NewLoader().StageWorkdir(level, work); err != nil {
+		t.Fatalf("stage: %v", err)
+	}
+	read := func(rel string) string {
+		b, err := os.ReadFile(filepath.Join(work, rel))
+		if err != nil {
+			t.Fatalf("%s: %v", rel, err)
+		}
+		return string(b)
+	}
+
+	if read("notes/plain.txt") != "hello\n" || read("bin/report.sh") != "#!/bin/sh\necho fixtures\n" || read("rows.csv") != "row1-3\nrow2-3\nrow3-3\n" {
+		t.Fatalf("write_files content wrong: %q %q", read("bin/report.sh"), read("rows.csv"))
+	}
+	if info, _ := os.Stat(filepath.Join(work, "bin/report.sh")); info.Mode().Perm() != 0o755 {
+		t.Fatalf("report.sh mode = %v", info.Mode())
+	}
+	if big := read("big.log"); len(big) != 10<<10 {
+		t.Fatalf("big.log is %d bytes", len(big))
+	}
+	if n := strings.Count(read("lines.txt"), "\n"); n != 25 {
+		t.Fatalf("lines.txt has %d lines", n)
+	}
+	if info, _ := os.Stat(filepath.Join(work, "disk.img")); info.Size() != 64<<20 {
+		t.Fatalf("disk.img is %d bytes", info.Size())
+	}
+
+	f, err := os.Open(filepath.Join(work, "backup.tar.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+	if strings.Join(names, ",") != "logs/,logs/app.log" {
+		t.Fatalf("tar entries = %v", names)
+	}
+	zr, err := zip.OpenReader(filepath.Join(work, "backup.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	if len(zr.File) != 1 || zr.File[0].Name != "logs/app.log" {
+		t.Fatalf("zip entries = %v", zr.File)
+	}
+
+	git := func(args ...string) string {
+		out, err := exec.Command("git", append([]string{"-C", filepath.Join(work, "repo")}, args...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	if got := git("log", "--format=%s|%an", "main"); got != "Fix typo|Ada Lovelace\nInitial commit|Dojo Author" {
+		t.Fatalf("main log = %q", got)
+	}
+	if got := git("branch", "--show-current"); got != "main" {
+		t.Fatalf("checked out %q", got)
+	}
+	if got := git("log", "--format=%s", "-1", "feature"); got != "Add feature" {
+		t.Fatalf("feature log = %q", got)
+	}
+	if got := git("tag"); got != "v1.0" {
+		t.Fatalf("tags = %q", got)
+	}
+	// Pinned authors and dates make the history reproducible.
+	head := git("rev-parse", "HEAD")
+	if err := NewLoader().StageWorkdir(level, work); err != nil {
+		t.Fatalf("restage: %v", err)
+	}
+	if git("rev-parse", "HEAD") != head {
+		t.Fatal("restaging changed the commit IDs")
+	}
+}
+
+func TestGitRepoFixtureStaysBelowStagedSymlinks(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "keep.txt"), []byte("host\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dataset := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dataset, "repo"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dataset, "repo", "out")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dataset, "linked")); err != nil {
+		t.Fatal(err)
+	}
+	for name, g := range map[string]GitRepoSpec{
+		"write":   {Path: "repo", Commits: []GitCommit{{Message: "m", Files: map[string]string{"out/x.txt": "x"}}}},
+		"delete":  {Path: "repo", Commits: []GitCommit{{Message: "m", Delete: []string{"out/keep.txt"}}}},
+		"symlink": {Path: "linked", Commits: []GitCommit{{Message: "m", Files: map[string]string{"x.txt": "x"}}}},
+	} {
+		level := Level{LevelID: "fixtures", DatasetHostPath: dataset}
+		level.Filesystem.Work.InitialLayout = InitialLayout{
+			CopyFromDataset: []CopyMapping{{From: ".", To: "."}},
+			GitRepo:         []GitRepoSpec{g},
+		}
+		err := NewLoader().StageWorkdir(level, filepath.Join(t.TempDir(), "work"))
+		if err == nil || !strings.Contains(err.Error(), "symlink") && !strings.Contains(err.Error(), "not a directory") {
+			t.Errorf("%s: err = %v, want a symlink error", name, err)
+		}
+	}
+	entries, err := os.ReadDir(outside)
+	if err != nil || len(entries) != 1 || entries[0].Name() != "keep.txt" {
+		t.Fatalf("git_repo touched files outside the workdir: %v %v", entries, err)
+	}
+}
+
+func TestArchiveFixtureStaysInsideDataset(t *testing.T) {
+	outside := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(outside, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "sub", "secret.txt"), []byte("host\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dataset := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(dataset, "link")); err != nil {
+		t.Fatal(err)
+	}
+	level := Level{LevelID: "fixtures", DatasetHostPath: dataset}
+	level.Filesystem.Work.InitialLayout.Archive = []ArchiveSpec{{Path: "out.tar", From: []string{"link/sub"}}}
+	err := NewLoader().StageWorkdir(level, filepath.Join(t.TempDir(), "work"))
+	if err == nil || !strings.Contains(err.Error(), "outside the dataset") {
+		t.Fatalf("err = %v, want an outside-the-dataset error", err)
+	}
+}
+
+func TestTemplateHelpersAreBounded(t *testing.T) {
+	for _, content := range []string{
+		`{{ repeat 1000000000 "x" }}`,
+		`{{ range seq 1000000000 }}{{ end }}`,
+		`{{ range seq 1000000 }}{{ repeat 100 "x" }}{{ end }}`,
+	} {
+		level := Level{LevelID: "fixtures", DatasetHostPath: t.TempDir()}
+		level.Filesystem.Work.InitialLayout.WriteFiles = []WriteFileSpec{{Path: "x", Content: content, Template: true}}
+		err := NewLoader().StageWorkdir(level, filepath.Join(t.TempDir(), "work"))
+		if err == nil || !strings.Contains(err.Error(), "limit") && !strings.Contains(err.Error(), "exceeds") {
+			t.Errorf("%s: err = %v, want a size limit error", content, err)
+		}
+	}
+}
+
+func TestRestrictedLevelsRefuseGitRepo(t *testing.T) {
+	level := Level{LevelID: "fixtures", DatasetHostPath: t.TempDir(), Restricted: true}
+	level.Filesystem.Work.InitialLayout.GitRepo = []GitRepoSpec{{Path: "repo", Commits: []GitCommit{{Message: "m"}}}}
+	work := filepath.Join(t.TempDir(), "work")
+	err := NewLoader().StageWorkdir(level, work)
+	if !errors.Is(err, ErrSandboxOnly) {
+		t.Fatalf("expected ErrSandboxOnly, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(work, "repo")); !os.IsNotExist(err) {
+		t.Fatalf("git_repo of a restricted level should not be built")
+	}
+}
+
+func TestFixtureValidation(t *testing.T) {
+	for _, tc := range []struct {
+		layout InitialLayout
+		want   string
+	}{
+		{InitialLayout{WriteFiles: []WriteFileSpec{{Path: "../x"}}}, "inside the workdir"},
+		{InitialLayout{WriteFiles: []WriteFileSpec{{Path: "x", Content: "{{", Template: true}}}, "unclosed action"},
+		{InitialLayout{GenerateFile: []GenerateFileSpec{{Path: "x"}}}, "exactly one of size and lines"},
+		{InitialLayout{GenerateFile: []GenerateFileSpec{{Path: "x", Size: "lots"}}}, "byte count"},
+		{InitialLayout{SparseFile: []SparseFileSpec{{Path: "x"}}}, "byte count"},
+		{InitialLayout{Archive: []ArchiveSpec{{Path: "x.rar", From: []string{"a"}}}}, "format"},
+		{InitialLayout{Archive: []ArchiveSpec{{Path: "x.tar", From: []string{"../a"}}}}, "inside the dataset"},
+		{InitialLayout{GitRepo: []GitRepoSpec{{Path: "r"}}}, "at least one commit"},
+		{InitialLayout{GitRepo: []GitRepoSpec{{Path: "r", Commits: []GitCommit{{Message: "m", Author: "nobody"}}}}}, "Name <email>"},
+		{InitialLayout{GitRepo: []GitRepoSpec{{Path: "r", Commits: []GitCommit{{Message: "m", Files: map[string]string{".git/config": "x"}}}}}}, "inside .git"},
+		{InitialLayout{GitRepo: []GitRepoSpec{{Path: "r", Commits: []GitCommit{{Message: "m", Delete: []string{"sub/.GIT"}}}}}}, "inside .git"},
+		{InitialLayout{GitRepo: []GitRepoSpec{{Path: "r", Commits: []GitCommit{{Message: "m", Branch: "--orphan"}}}}}, "not a valid git ref name"},
+		{InitialLayout{GitRepo: []GitRepoSpec{{Path: "r", Commits: []GitCommit{{Message: "m", Tags: []string{"v1..2"}}}}}}, "not a valid git ref name"},
+		{InitialLayout{GitRepo: []GitRepoSpec{{Path: "r", Checkout: "-f", Commits: []GitCommit{{Message: "m"}}}}}, "not a valid git ref name"},
+	} {
+		err := tc.layout.validateFixtures()
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%+v: err = %v, want %q", tc.layout, err, tc.want)
+		}
+	}
+	for s, want := range map[string]int64{"4096": 4096, "64K": 64 << 10, "10MiB": 10 << 20, "1gb": 1 << 30} {
+		if got, err := ParseSize(s); err != nil || got != want {
+			t.Fatalf("ParseSize(%q) = %d, %v", s, got, err)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package levels
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Generators run inside the level's image with no network. The level dir is
+// mounted read-only at GeneratorLevelMount (also the working directory) and
+// the output dir read-write at GeneratorOutputMount, exported to the
+// generator as DOJO_DATASET_DIR.
+const (
+	GeneratorLevelMount  = "/level"
+	GeneratorOutputMount = "/dataset"
+)
+
+// GeneratorJob is one run of a level's dataset generator. Its fields mirror
+// sandbox.GeneratorSpec so callers can convert between the two.
+type GeneratorJob struct {
+	SessionID string
+	PackID    string
+	LevelID   string
+	Image     string
+	LevelDir  string
+	OutputDir string
+	Command   string
+	Args      []string
+	Env       map[string]string
+}
+
+// GeneratorFunc runs a GeneratorJob in the sandbox.
+type GeneratorFunc func(ctx context.Context, job GeneratorJob) error
+
+// ImageIDFunc resolves an image ref to the ID of the local image.
+type ImageIDFunc func(ctx context.Context, image string) (string, error)
+
+// ErrNoGenerator is returned when a generated dataset is not cached and the
+// loader has no sandbox to run the generator in.
+var ErrNoGenerator = errors.New("dataset generators need a container engine")
+
+// ImageRef is the image a level runs in: its own override or the pack's.
+func (l Level) ImageRef(pack Pack) string {
+	if ref := strings.TrimSpace(l.Image.Ref); ref != "" {
+		return ref
+	}
+	return strings.TrimSpace(pack.Image.Ref)
+}
+
+// generatorKey hashes everything a generated dataset depends on: the image
+// (its ID when resolved, since tags move), command, args and seed, and the
+// content of the level dir.
+func generatorKey(level Level, image string) (string, error) {
+	gen := level.Filesystem.Dataset.Generator
+	outDir := filepath.ToSlash(filepath.Clean(level.Filesystem.Dataset.Path))
+	files, _, err := scanPackFiles(level.Path, func(rel string) bool {
+		// Output of host generators from older versions is not an input.
+		return rel == outDir || strings.HasPrefix(rel, outDir+"/")
+	})
+	if err != nil {
+		return "", fmt.Errorf("hash level %s: %w", level.LevelID, err)
+	}
+	for i := range files {
+		files[i] = normalizeSignedFile(files[i])
+	}
+	b, err := json.Marshal(struct {
+		Image   string        `json:"image"`
+		Command string        `json:"command"`
+		Args    []string      `json:"args"`
+		Seed    *DatasetSeed  `json:"seed"`
+		Files   []ArchiveFile `json:"files"`
+	}{image, gen.Command, gen.Args, gen.Seed, files})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (l *FSLoader) datasetCacheRoot() (string, error) {
+	if l.DatasetCacheDir != "" {
+		return l.DatasetCacheDir, nil
+	}
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve dataset cache dir: %w", err)
+	}
+	return filepath.Join(base, "clidojo", "datasets"), nil
+}
+
+// PerRunSeed reports whether the level generates a fresh dataset per run.
+func (l Level) PerRunSeed() bool {
+	gen := l.Filesystem.Dataset.Generator
+	return l.Filesystem.Dataset.Source == "generator" && gen != nil && gen.Seed != nil && gen.Seed.PerRun
+}
+
+// WithRunSeed returns the level with its per-run dataset generated from seed
+// into dir, which must be private to the run.
+func (l Level) WithRunSeed(seed int64, dir string) Level {
+	l.RunSeed = &seed
+	l.DatasetHostPath = dir
+	return l
+}
+
+// DatasetReady reports whether the level's dataset exists on the host. It is
+// false until PrepareDataset has resolved the dataset.
+func DatasetReady(level Level) bool {
+	if level.DatasetHostPath == "" {
+		return false
+	}
+	_, err := os.Stat(level.DatasetHostPath)
+	return err == nil
+}
+
+// DatasetProgress reports PrepareDataset's progress as Done of Total steps.
+type DatasetProgress struct {
+	Step  string
+	Done  int
+	Total int
+}
+
+// DatasetProgressFunc receives PrepareDataset progress; it may be nil.
+type DatasetProgressFunc func(DatasetProgress)
+
+// cachedKey is a generator key and the level dir signature it was hashed at.
+type cachedKey struct {
+	sig string
+	key string
+}
+
+// PrepareDataset returns the level with DatasetHostPath pointing at its
+// dataset on the host. Dataset dirs are checked for existence and against
+// their dataset.sha256sums; generated datasets are resolved to their cache
+// entry, or the run's dir for per-run seeds, and generated unless already
+// there. Loading packs leaves generation to the first start so startup does
+// not hash generator inputs or generate anything. The level's image must be
+// available.
+func (l *FSLoader) PrepareDataset(ctx context.Context, pack Pack, level Level, progress DatasetProgressFunc) (Level, error) {
+	report := func(step string, done, total int) {
+		if progress != nil {
+			progress(DatasetProgress{Step: step, Done: done, Total: total})
+		}
+	}
+	gen := level.Filesystem.Dataset.Generator
+	if level.Filesystem.Dataset.Source != "generator" || gen == nil {
+		report("Checking dataset", 0, 2)
+		if !DatasetReady(level) {
+			return level, fmt.Errorf("dataset path not found for level %s: %s", level.LevelID, level.DatasetHostPath)
+		}
+		// Catch edits made since the pack was loaded.
+		report("Verifying dataset", 1, 2)
+		if err := l.verifyDataset(level); err != nil {
+			return level, err
+		}
+		report("Dataset ready", 2, 2)
+		return level, nil
+	}
+
+	var image string
+	if level.PerRunSeed() {
+		if level.RunSeed == nil || level.DatasetHostPath == "" {
+			return level, fmt.Errorf("level %s uses seed %s but the run has no seed", level.LevelID, SeedPerRun)
+		}
+	} else {
+		report("Hashing generator inputs", 0, 3)
+		var err error
+		image, err = l.generatorImage(ctx, pack, level)
+		if err != nil {
+			return level, err
+		}
+		key, err := l.cachedGeneratorKey(level, image)
+		if err != nil {
+			return level, err
+		}
+		cacheRoot, err := l.datasetCacheRoot()
+		if err != nil {
+			return level, err
+		}
+		level.DatasetHostPath = filepath.Join(cacheRoot, key)
+	}
+	if DatasetReady(level) {
+		report("Dataset ready (cached)", 3, 3)
+		return level, nil
+	}
+	report("Generating dataset", 1, 3)
+	if image == "" {
+		var err error
+		if image, err = l.generatorImage(ctx, pack, level); err != nil {
+			return level, err
+		}
+	}
+	if err := l.generate(ctx, pack, level, image); err != nil {
+		return level, err
+	}
+	report("Dataset ready", 3, 3)
+	return level, nil
+}
+
+// generatorImage is the image a generator runs in and its key is hashed with:
+// the ID of the level's image when the loader can resolve it, otherwise its
+// ref. Running by ID keeps a retagged image from filling a cached key.
+func (l *FSLoader) generatorImage(ctx context.Context, pack Pack, level Level) (string, error) {
+	ref := level.ImageRef(pack)
+	if l.ImageID == nil {
+		return ref, nil
+	}
+	id, err := l.ImageID(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("resolve image for level %s: %w", level.LevelID, err)
+	}
+	return id, nil
+}
+
+// cachedGeneratorKey is generatorKey memoized until the level dir changes, so
+// restarting a level does not rehash its files.
+func (l *FSLoader) cachedGeneratorKey(level Level, image string) (string, error) {
+	id := level.Path + "\x00" + level.Variant + "\x00" + image
+	sig := DirSignature(level.Path)
+	l.keyMu.Lock()
+	cached, ok := l.keys[id]
+	l.keyMu.Unlock()
+	if ok && cached.sig == sig {
+		return cached.key, nil
+	}
+	key, err := generatorKey(level, image)
+	if err != nil {
+		return "", err
+	}
+	l.keyMu.Lock()
+	if l.keys == nil {
+		l.keys = map[string]cachedKey{}
+	}
+	l.keys[id] = cachedKey{sig: sig, key: key}
+	l.keyMu.Unlock()
+	return key, nil
+}
+
+// generate runs the level's generator in image into a temp dir next to
+// DatasetHostPath and renames it into place.
+func (l *FSLoader) generate(ctx context.Context, pack Pack, level Level, image string) error {
+	gen := level.Filesystem.Dataset.Generator
+	if l.RunGenerator == nil {
+		return fmt.Errorf("level %s: %w", level.LevelID, ErrNoGenerator)
+	}
+	cacheRoot := filepath.Dir(level.DatasetHostPath)
+	if err := os.MkdirAll(cacheRoot, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(cacheRoot, ".tmp-"+filepath.Base(level.DatasetHostPath)+"-")
+	if err != nil {
+		return err
+	}
+	// MkdirTemp creates 0700. The generator runs as our UID (or as root
+	// mapped to it under rootless podman), so the dir needs no wider mode.
+	defer os.RemoveAll(tmp)
+
+	env := map[string]string{"DOJO_DATASET_DIR": GeneratorOutputMount}
+	switch {
+	case level.RunSeed != nil:
+		env["DOJO_DATASET_SEED"] = fmt.Sprintf("%d", *level.RunSeed)
+	case gen.Seed != nil:
+		env["DOJO_DATASET_SEED"] = fmt.Sprintf("%d", gen.Seed.Value)
+	}
+	err = l.RunGenerator(ctx, GeneratorJob{
+		PackID:    pack.PackID,
+		LevelID:   level.LevelID,
+		Image:     image,
+		LevelDir:  level.Path,
+		OutputDir: tmp,
+		Command:   gen.Command,
+		Args:      append([]string(nil), gen.Args...),
+		Env:       env,
+	})
+	if err != nil {
+		return fmt.Errorf("generator failed for level %s: %w", level.LevelID, err)
+	}
+	if err := os.Chmod(tmp, 0o755); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, level.DatasetHostPath); err != nil && !DatasetReady(level) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package levels
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loadGeneratorPack loads the generator test pack from root, writing it first
+// when root is empty.
+func loadGeneratorPack(t *testing.T, loader *FSLoader, root string) (string, Pack, Level) {
+	t.Helper()
+	if root == "" {
+		root = t.TempDir()
+		writeTestPack(t, root, "gen", "Gen")
+		writeGeneratorLevel(t, filepath.Join(root, "gen"))
+	}
+	packs, err := loader.LoadPacks(context.Background(), root)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	for _, level := range packs[0].LoadedLevels {
+		if level.LevelID == "level-gen" {
+			return root, packs[0], level
+		}
+	}
+	t.Fatalf("level-gen not loaded")
+	return "", Pack{}, Level{}
+}
+
+func TestGeneratorKeyTracksInputs(t *testing.T) {
+	_, pack, level := loadGeneratorPack(t, &FSLoader{DatasetCacheDir: t.TempDir()}, "")
+	base, err := generatorKey(level, level.ImageRef(pack))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := generatorKey(level, level.ImageRef(pack)); again != base {
+		t.Fatalf("key is not stable: %s vs %s", base, again)
+	}
+
+	seeded := level
+	gen := *level.Filesystem.Dataset.Generator
+	gen.Seed = &DatasetSeed{Value: 7}
+	seeded.Filesystem.Dataset.Generator = &gen
+	withArgs := level
+	argGen := *level.Filesystem.Dataset.Generator
+	argGen.Args = []string{"--rows", "10"}
+	withArgs.Filesystem.Dataset.Generator = &argGen
+	otherImage := level
+	otherImage.Image.Ref = "other:latest"
+	for name, lv := range map[string]Level{"seed": seeded, "args": withArgs, "image": otherImage} {
+		if key, _ := generatorKey(lv, lv.ImageRef(pack)); key == base {
+			t.Fatalf("changing %s did not change the key", name)
+		}
+	}
+
+	// Old host-generated output in the level dir is not an input.
+	if err := os.MkdirAll(filepath.Join(level.Path, "dataset"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(level.Path, "dataset", "data.txt"), []byte("stale"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if key, _ := generatorKey(level, level.ImageRef(pack)); key != base {
+		t.Fatalf("dataset output changed the key")
+	}
+	if err := os.WriteFile(filepath.Join(level.Path, "gen.sh"), []byte("#!/bin/sh\necho v2\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if key, _ := generatorKey(level, level.ImageRef(pack)); key == base {
+		t.Fatalf("editing gen.sh did not change the key")
+	}
+}
+
+func TestPrepareDatasetRunsGeneratorOnce(t *testing.T) {
+	cache := t.TempDir()
+	jobs := []GeneratorJob{}
+	loader := &FSLoader{DatasetCacheDir: cache, RunGenerator: func(_ context.Context, job GeneratorJob) error {
+		jobs = append(jobs, job)
+		return os.WriteFile(filepath.Join(job.OutputDir, "data.txt"), []byte("generated\n"), 0o644)
+	}}
+	root, pack, loaded := loadGeneratorPack(t, loader, "")
+	if loaded.DatasetHostPath != "" || DatasetReady(loaded) {
+		t.Fatalf("loading should not resolve the dataset, got %s", loaded.DatasetHostPath)
+	}
+
+	var level Level
+	steps := []DatasetProgress{}
+	for i := 0; i < 2; i++ {
+		var err error
+		level, err = loader.PrepareDataset(context.Background(), pack, loaded, func(p DatasetProgress) { steps = append(steps, p) })
+		if err != nil {
+			t.Fatalf("prepare dataset: %v", err)
+		}
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("expected one generator run, got %d", len(jobs))
+	}
+	if last := steps[len(steps)-1]; last.Done != last.Total || filepath.Dir(level.DatasetHostPath) != cache {
+		t.Fatalf("expected a finished cache entry, got %+v %s", last, level.DatasetHostPath)
+	}
+	if jobs[0].Image != "img" || jobs[0].LevelDir != level.Path || jobs[0].Env["DOJO_DATASET_DIR"] != GeneratorOutputMount {
+		t.Fatalf("unexpected job %+v", jobs[0])
+	}
+	if b, err := os.ReadFile(filepath.Join(level.DatasetHostPath, "data.txt")); err != nil || string(b) != "generated\n" {
+		t.Fatalf("dataset not cached: %q %v", b, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "gen", "levels", "level-gen", "dataset")); !os.IsNotExist(err) {
+		t.Fatalf("generator wrote into the pack")
+	}
+
+	// A loader without a sandbox still serves cached datasets.
+	if _, err := (&FSLoader{DatasetCacheDir: cache}).PrepareDataset(context.Background(), pack, loaded, nil); err != nil {
+		t.Fatalf("cached dataset should not need a generator: %v", err)
+	}
+
+	// Editing the level invalidates the memoized key.
+	time.Sleep(10 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(loaded.Path, "gen.sh"), []byte("#!/bin/sh\necho v2\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	edited, err := loader.PrepareDataset(context.Background(), pack, loaded, nil)
+	if err != nil || len(jobs) != 2 || edited.DatasetHostPath == level.DatasetHostPath {
+		t.Fatalf("expected a new cache entry after editing gen.sh, got %s runs=%d err=%v", edited.DatasetHostPath, len(jobs), err)
+	}
+}
+
+func TestPrepareDatasetKeysByImageID(t *testing.T) {
+	imageID := "sha256:aaa"
+	refs := []string{}
+	runs := 0
+	ran := []string{}
+	loader := &FSLoader{
+		DatasetCacheDir: t.TempDir(),
+		RunGenerator: func(_ context.Context, job GeneratorJob) error {
+			runs++
+			ran = append(ran, job.Image)
+			return os.WriteFile(filepath.Join(job.OutputDir, "data.txt"), []byte(imageID), 0o644)
+		},
+		ImageID: func(_ context.Context, image string) (string, error) {
+			refs = append(refs, image)
+			return imageID, nil
+		},
+	}
+	_, pack, level := loadGeneratorPack(t, loader, "")
+	first, err := loader.PrepareDataset(context.Background(), pack, level, nil)
+	if err != nil {
+		t.Fatalf("prepare dataset: %v", err)
+	}
+	if len(refs) != 1 || refs[0] != "img" {
+		t.Fatalf("expected the level's image to be resolved, got %v", refs)
+	}
+	if again, err := loader.PrepareDataset(context.Background(), pack, level, nil); err != nil || again.DatasetHostPath != first.DatasetHostPath || runs != 1 {
+		t.Fatalf("expected the cached dataset for the same image ID, got %s runs=%d err=%v", again.DatasetHostPath, runs, err)
+	}
+
+	// A rebuilt image under the same tag gets a new dataset.
+	imageID = "sha256:bbb"
+	rebuilt, err := loader.PrepareDataset(context.Background(), pack, level, nil)
+	if err != nil || rebuilt.DatasetHostPath == first.DatasetHostPath || runs != 2 {
+		t.Fatalf("expected a new cache entry for a new image ID, got %s runs=%d err=%v", rebuilt.DatasetHostPath, runs, err)
+	}
+	if len(ran) != 2 || ran[0] != "sha256:aaa" || ran[1] != "sha256:bbb" {
+		t.Fatalf("expected the generator to run by the resolved image IDs, got %v", ran)
+	}
+
+	failing := &FSLoader{DatasetCacheDir: t.TempDir(), RunGenerator: loader.RunGenerator, ImageID: func(context.Context, string) (string, error) {
+		return "", errors.New("no such image")
+	}}
+	if _, err := failing.PrepareDataset(context.Background(), pack, level, nil); err == nil || !strings.Contains(err.Error(), "no such image") {
+		t.Fatalf("expected the image lookup error, got %v", err)
+	}
+}
+
+func TestPrepareDatasetFailuresLeaveNoCacheEntry(t *testing.T) {
+	cache := t.TempDir()
+	_, pack, level := loadGeneratorPack(t, &FSLoader{DatasetCacheDir: cache}, "")
+	if _, err := (&FSLoader{DatasetCacheDir: cache}).PrepareDataset(context.Background(), pack, level, nil); !errors.Is(err, ErrNoGenerator) {
+		t.Fatalf("expected ErrNoGenerator, got %v", err)
+	}
+	failing := &FSLoader{DatasetCacheDir: cache, RunGenerator: func(context.Context, GeneratorJob) error {
+		return errors.New("exit status 1")
+	}}
+	if _, err := failing.PrepareDataset(context.Background(), pack, level, nil); err == nil {
+		t.Fatalf("expected generator failure")
+	}
+	entries, _ := os.ReadDir(cache)
+	if len(entries) != 0 {
+		t.Fatalf("failed run left cache entries: %v", entries)
+	}
+}
+
+func TestPrepareDatasetChecksDatasetDirOnStart(t *testing.T) {
+	root := t.TempDir()
+	writeTestPack(t, root, "pack", "Pack")
+	if err := os.RemoveAll(filepath.Join(root, "pack", "levels", "level-one", "dataset")); err != nil {
+		t.Fatal(err)
+	}
+	loader := NewLoader()
+	packs, err := loader.LoadPacks(context.Background(), root)
+	if err != nil {
+		t.Fatalf("a missing dataset should not fail loading: %v", err)
+	}
+	if _, err := loader.PrepareDataset(context.Background(), packs[0], packs[0].LoadedLevels[0], nil); err == nil || !strings.Contains(err.Error(), "dataset path not found") {
+		t.Fatalf("expected a missing dataset error, got %v", err)
+	}
+}
+
+func TestPerRunSeedGeneratesIntoRunDir(t *testing.T) {
+	root := t.TempDir()
+	writeTestPack(t, root, "gen", "Gen")
+	writeGeneratorLevel(t, filepath.Join(root, "gen"))
+	levelYAML := filepath.Join(root, "gen", "levels", "level-gen", "level.yaml")
+	src := strings.Replace(generatorLevelYAML, "{ command: ./gen.sh }", "{ command: ./gen.sh, seed: per_run }", 1)
+	if err := os.WriteFile(levelYAML, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	jobs := []GeneratorJob{}
+	loader := &FSLoader{DatasetCacheDir: t.TempDir(), RunGenerator: func(_ context.Context, job GeneratorJob) error {
+		jobs = append(jobs, job)
+		return os.WriteFile(filepath.Join(job.OutputDir, "seed.txt"), []byte(job.Env["DOJO_DATASET_SEED"]), 0o644)
+	}}
+	_, pack, level := loadGeneratorPack(t, loader, root)
+	if !level.PerRunSeed() || level.DatasetHostPath != "" {
+		t.Fatalf("expected a per-run level without a dataset dir, got %q", level.DatasetHostPath)
+	}
+	if _, err := loader.PrepareDataset(context.Background(), pack, level, nil); err == nil {
+		t.Fatalf("expected an error without a run seed")
+	}
+
+	run := level.WithRunSeed(-12, filepath.Join(t.TempDir(), "runs", "level-gen-12"))
+	for i := 0; i < 2; i++ {
+		if _, err := loader.PrepareDataset(context.Background(), pack, run, nil); err != nil {
+			t.Fatalf("prepare dataset: %v", err)
+		}
+	}
+	if b, err := os.ReadFile(filepath.Join(run.DatasetHostPath, "seed.txt")); err != nil || string(b) != "-12" || len(jobs) != 1 {
+		t.Fatalf("expected one run seeded with -12, got %q runs=%d err=%v", b, len(jobs), err)
+	}
+}
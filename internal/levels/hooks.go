@@ -0,0 +1,72 @@
+package levels
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultHookTimeout bounds hooks that set no timeout_seconds.
+const DefaultHookTimeout = 30 * time.Second
+
+// HookExecFunc runs a script in the level container and returns its combined
+// output.
+type HookExecFunc func(ctx context.Context, script string) ([]byte, error)
+
+// HookLogFunc receives the output of every hook that ran.
+type HookLogFunc func(hook HookSpec, output []byte, err error)
+
+// Timeout is the hook's timeout_seconds, or DefaultHookTimeout.
+func (h HookSpec) Timeout() time.Duration {
+	if h.TimeoutSeconds > 0 {
+		return time.Duration(h.TimeoutSeconds) * time.Second
+	}
+	return DefaultHookTimeout
+}
+
+// RunHooks runs the in-container hooks in order, each under its own timeout,
+// and stops at the first failure. The error names the phase ("setup" or
+// "teardown") and hook and quotes the last line of its output.
+func (s HooksSpec) RunHooks(ctx context.Context, phase string, exec HookExecFunc, logf HookLogFunc) error {
+	for _, h := range s.InContainer {
+		hookCtx, cancel := context.WithTimeout(ctx, h.Timeout())
+		out, err := exec(hookCtx, h.ScriptSH)
+		cancel()
+		if logf != nil {
+			logf(h, out, err)
+		}
+		if err != nil {
+			if last := lastOutputLine(out); last != "" {
+				return fmt.Errorf("%s hook %s: %w: %s", phase, h.HookID, err, last)
+			}
+			return fmt.Errorf("%s hook %s: %w", phase, h.HookID, err)
+		}
+	}
+	return nil
+}
+
+func lastOutputLine(out []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+func (s HooksSpec) validate() error {
+	seen := map[string]struct{}{}
+	for _, h := range s.InContainer {
+		if h.HookID == "" {
+			return fmt.Errorf("in_container[].hook_id is required")
+		}
+		if _, ok := seen[h.HookID]; ok {
+			return fmt.Errorf("in_container: duplicate hook_id %q", h.HookID)
+		}
+		seen[h.HookID] = struct{}{}
+		if strings.TrimSpace(h.ScriptSH) == "" {
+			return fmt.Errorf("in_container hook %q script_sh is required", h.HookID)
+		}
+		if h.TimeoutSeconds < 0 {
+			return fmt.Errorf("in_container hook %q timeout_seconds must be >= 0", h.HookID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package levels
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunHooksStopsAtFirstFailure(t *testing.T) {
+	hooks := HooksSpec{InContainer: []HookSpec{
+		{HookID: "workers", ScriptSH: "nohup sleep 600 >/dev/null 2>&1 &", TimeoutSeconds: 5},
+		{HookID: "fill_tmp", ScriptSH: "fallocate -l 10M /tmp/big"},
+		{HookID: "never", ScriptSH: "true"},
+	}}
+	ran := []string{}
+	logged := []string{}
+	exec := func(ctx context.Context, script string) ([]byte, error) {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			t.Fatalf("hook %q ran without a deadline", script)
+		}
+		ran = append(ran, script)
+		if strings.HasPrefix(script, "fallocate") {
+			if time.Until(deadline) > DefaultHookTimeout {
+				t.Fatalf("default timeout not applied")
+			}
+			return []byte("starting\nfallocate: /tmp/big: No space left on device\n"), errors.New("script failed: exit status 1")
+		}
+		if time.Until(deadline) > 5*time.Second {
+			t.Fatalf("timeout_seconds not applied")
+		}
+		return nil, nil
+	}
+	err := hooks.RunHooks(context.Background(), "setup", exec, func(h HookSpec, _ []byte, _ error) { logged = append(logged, h.HookID) })
+	if err == nil {
+		t.Fatalf("expected the failing hook to abort the run")
+	}
+	for _, want := range []string{"setup hook fill_tmp", "exit status 1", "No space left on device"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error %q does not mention %q", err, want)
+		}
+	}
+	if len(ran) != 2 || strings.Join(logged, ",") != "workers,fill_tmp" {
+		t.Fatalf("hooks after a failure must not run: ran %v, logged %v", ran, logged)
+	}
+}
+
+func TestHooksValidation(t *testing.T) {
+	cases := map[string]HooksSpec{
+		"hook_id is required": {InContainer: []HookSpec{{ScriptSH: "true"}}},
+		"duplicate hook_id":   {InContainer: []HookSpec{{HookID: "a", ScriptSH: "true"}, {HookID: "a", ScriptSH: "true"}}},
+		"script_sh":           {InContainer: []HookSpec{{HookID: "a", ScriptSH: " "}}},
+		"timeout_seconds":     {InContainer: []HookSpec{{HookID: "a", ScriptSH: "true", TimeoutSeconds: -1}}},
+	}
+	for want, hooks := range cases {
+		if err := hooks.validate(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q, got %v", want, err)
+		}
+	}
+	if err := (HooksSpec{InContainer: []HookSpec{{HookID: "a", ScriptSH: "true"}}}).validate(); err != nil {
+		t.Fatalf("valid hooks rejected: %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+package levels
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LevelLocale overrides a level's player-facing text for one locale. Hints
+// are keyed by hint_id, on_fail_messages by check id and stages by stage_id;
+// anything left out falls back to the level's default language.
+type LevelLocale struct {
+	Title          string                 `yaml:"title"`
+	SummaryMD      string                 `yaml:"summary_md"`
+	Objective      LocaleObjective        `yaml:"objective"`
+	Hints          map[string]string      `yaml:"hints"`
+	OnFailMessages map[string]string      `yaml:"on_fail_messages"`
+	Stages         map[string]LocaleStage `yaml:"stages"`
+}
+
+type LocaleObjective struct {
+	Bullets []string `yaml:"bullets"`
+}
+
+type LocaleStage struct {
+	Title     string          `yaml:"title"`
+	Objective LocaleObjective `yaml:"objective"`
+}
+
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}([_-][A-Za-z0-9]{2,8})?$`)
+
+// Locales lists the locales the level has i18n entries for, sorted.
+func (l Level) Locales() []string {
+	out := make([]string, 0, len(l.I18n))
+	for locale := range l.I18n {
+		out = append(out, locale)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// localeFor returns the i18n entry for locale ("de_DE"): an exact match, else
+// the entry for its language ("de").
+func (l Level) localeFor(locale string) (LevelLocale, bool) {
+	want := canonicalLocale(locale)
+	if want == "" {
+		return LevelLocale{}, false
+	}
+	lang, _, _ := strings.Cut(want, "_")
+	var fallback *LevelLocale
+	for key, entry := range l.I18n {
+		switch canonicalLocale(key) {
+		case want:
+			return entry, true
+		case lang:
+			e := entry
+			fallback = &e
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	return LevelLocale{}, false
+}
+
+func canonicalLocale(s string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), "-", "_"))
+}
+
+// Localized returns the level with the text of its i18n entry for locale
+// applied. Levels without a matching entry are returned unchanged.
+func (l Level) Localized(locale string) Level {
+	loc, ok := l.localeFor(locale)
+	if !ok {
+		return l
+	}
+	if loc.Title != "" {
+		l.Title = loc.Title
+	}
+	if loc.SummaryMD != "" {
+		l.SummaryMD = loc.SummaryMD
+	}
+	if len(loc.Objective.Bullets) > 0 {
+		l.Objective.Bullets = append([]string(nil), loc.Objective.Bullets...)
+	}
+	l.Hints = loc.localizeHints(l.Hints)
+	l.Checks = loc.localizeChecks(l.Checks)
+	if len(l.Stages) > 0 {
+		l.Stages = append([]StageSpec(nil), l.Stages...)
+		for i := range l.Stages {
+			s := &l.Stages[i]
+			if st, ok := loc.Stages[s.StageID]; ok {
+				if st.Title != "" {
+					s.Title = st.Title
+				}
+				if len(st.Objective.Bullets) > 0 {
+					s.Objective.Bullets = append([]string(nil), st.Objective.Bullets...)
+				}
+			}
+			s.Hints = loc.localizeHints(s.Hints)
+			s.Checks = loc.localizeChecks(s.Checks)
+		}
+	}
+	return l
+}
+
+func (loc LevelLocale) localizeHints(hints []HintSpec) []HintSpec {
+	if len(loc.Hints) == 0 {
+		return hints
+	}
+	hints = append([]HintSpec(nil), hints...)
+	for i := range hints {
+		if text, ok := loc.Hints[hints[i].HintID]; ok && text != "" {
+			hints[i].TextMD = text
+		}
+	}
+	return hints
+}
+
+func (loc LevelLocale) localizeChecks(checks []CheckSpec) []CheckSpec {
+	if len(loc.OnFailMessages) == 0 {
+		return checks
+	}
+	checks = append([]CheckSpec(nil), checks...)
+	for i := range checks {
+		if msg, ok := loc.OnFailMessages[checks[i].ID]; ok && msg != "" {
+			checks[i].OnFailMessage = msg
+		}
+	}
+	return checks
+}
+
+// validateI18n checks locale keys and that translated hints and checks exist.
+func (l Level) validateI18n() error {
+	for _, locale := range l.Locales() {
+		if !localePattern.MatchString(locale) {
+			return fmt.Errorf("i18n: invalid locale %q (want e.g. de or pt_BR)", locale)
+		}
+		entry := l.I18n[locale]
+		for id := range entry.Hints {
+			if !l.hasHint(id) {
+				return fmt.Errorf("i18n.%s.hints: unknown hint_id %q", locale, id)
+			}
+		}
+		for id := range entry.OnFailMessages {
+			if !l.hasCheck(id) {
+				return fmt.Errorf("i18n.%s.on_fail_messages: unknown check id %q", locale, id)
+			}
+		}
+		for id := range entry.Stages {
+			if !l.hasStage(id) {
+				return fmt.Errorf("i18n.%s.stages: unknown stage_id %q", locale, id)
+			}
+		}
+	}
+	return nil
+}
+
+func (l Level) hasHint(id string) bool {
+	for _, h := range l.AllHints() {
+		if h.HintID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (l Level) hasCheck(id string) bool {
+	for _, c := range l.AllChecks() {
+		if c.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (l Level) hasStage(id string) bool {
+	for _, s := range l.Stages {
+		if s.StageID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// untranslated lists the translatable keys of the level that the i18n entry
+// for locale does not cover, as i18n paths.
+func (l Level) untranslated(locale string) []string {
+	entry := l.I18n[locale]
+	out := []string{}
+	if entry.Title == "" {
+		out = append(out, "title")
+	}
+	if l.SummaryMD != "" && entry.SummaryMD == "" {
+		out = append(out, "summary_md")
+	}
+	if len(l.Objective.Bullets) > 0 && len(entry.Objective.Bullets) == 0 {
+		out = append(out, "objective.bullets")
+	}
+	for _, s := range l.Stages {
+		st := entry.Stages[s.StageID]
+		if s.Title != "" && st.Title == "" {
+			out = append(out, "stages."+s.StageID+".title")
+		}
+		if len(st.Objective.Bullets) == 0 {
+			out = append(out, "stages."+s.StageID+".objective.bullets")
+		}
+	}
+	for _, h := range l.AllHints() {
+		if entry.Hints[h.HintID] == "" {
+			out = append(out, "hints."+h.HintID)
+		}
+	}
+	for _, c := range l.AllChecks() {
+		if c.OnFailMessage != "" && entry.OnFailMessages[c.ID] == "" {
+			out = append(out, "on_fail_messages."+c.ID)
+		}
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+package levels
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const localizedLevelYAML = `kind: level
+schema_version: 2
+level_id: level-one
+title: "One"
+summary_md: "Count lines."
+difficulty: 1
+estimated_minutes: 1
+filesystem:
+  dataset: { source: dir, path: dataset, mount_point: /levels/current }
+  work: { mount_point: /work }
+objective:
+  bullets: ["do it"]
+hints:
+  - { hint_id: h1, text_md: "Use wc." }
+  - { hint_id: h2, text_md: "Use -l." }
+checks:
+  - { id: c1, type: file_exists, description: "exists", path: /work/out.txt, on_fail_message: "Write out.txt." }
+i18n:
+  de:
+    title: "Eins"
+    objective: { bullets: ["mach es"] }
+    hints: { h1: "Nutze wc." }
+    on_fail_messages: { c1: "Schreibe out.txt." }
+`
+
+func writeLocalizedPack(t *testing.T, packYAMLExtra string) string {
+	t.Helper()
+	root := t.TempDir()
+	writeTestPack(t, root, "loc-pack", "Loc")
+	packDir := filepath.Join(root, "loc-pack")
+	if packYAMLExtra != "" {
+		f, err := os.OpenFile(filepath.Join(packDir, "pack.yaml"), os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = f.WriteString(packYAMLExtra)
+		_ = f.Close()
+	}
+	if err := os.WriteFile(filepath.Join(packDir, "levels", "level-one", "level.yaml"), []byte(localizedLevelYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestLocalizedAppliesBestMatchingLocale(t *testing.T) {
+	root := writeLocalizedPack(t, "")
+	packs, err := NewLoader().LoadPacks(context.Background(), root)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	level := packs[0].LoadedLevels[0]
+
+	de := level.Localized("de_AT")
+	if de.Title != "Eins" || de.Objective.Bullets[0] != "mach es" || de.Checks[0].OnFailMessage != "Schreibe out.txt." {
+		t.Fatalf("expected the de translation, got %q %v %q", de.Title, de.Objective.Bullets, de.Checks[0].OnFailMessage)
+	}
+	if de.Hints[0].TextMD != "Nutze wc." || de.Hints[1].TextMD != "Use -l." || de.SummaryMD != "Count lines." {
+		t.Fatalf("untranslated keys should fall back to the default: %+v %q", de.Hints, de.SummaryMD)
+	}
+	if level.Title != "One" || level.Hints[0].TextMD != "Use wc." || level.Checks[0].OnFailMessage != "Write out.txt." {
+		t.Fatalf("Localized modified the original level")
+	}
+	if fr := level.Localized("fr_FR"); fr.Title != "One" {
+		t.Fatalf("expected the default language without a fr entry, got %q", fr.Title)
+	}
+
+	bad := level
+	bad.I18n = map[string]LevelLocale{"de": {Hints: map[string]string{"h9": "?"}}}
+	if err := bad.Validate(); err == nil || !strings.Contains(err.Error(), "h9") {
+		t.Fatalf("expected an unknown hint error, got %v", err)
+	}
+}
+
+func TestLintWarnsAboutUntranslatedKeys(t *testing.T) {
+	root := writeLocalizedPack(t, "locales: [de, fr]\n")
+	diags, err := Lint(root, LintOptions{})
+	if err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	msgs := []string{}
+	for _, d := range diags {
+		if d.Rule == "untranslated" {
+			if d.Severity != SeverityWarning {
+				t.Fatalf("untranslated keys should be warnings: %s", d)
+			}
+			msgs = append(msgs, d.Message)
+		}
+	}
+	got := strings.Join(msgs, "\n")
+	if len(msgs) != 2 || !strings.Contains(got, "i18n.de is missing summary_md, hints.h2") || !strings.Contains(got, "no fr translation") {
+		t.Fatalf("unexpected untranslated diagnostics:\n%s", got)
+	}
+}
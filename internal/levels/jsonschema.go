@@ -0,0 +1,536 @@
+package levels
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed level.schema.json
+var levelSchemaJSON []byte
+
+//go:embed pack.schema.json
+var packSchemaJSON []byte
+
+// schemaNode is the subset of JSON Schema (draft 2020-12) used by the bundled
+// pack/level schemas. Validation runs directly against yaml.Node trees so every
+// violation can be reported with the source line and column.
+type schemaNode struct {
+	Type                 any                    `json:"type"`
+	Const                any                    `json:"const"`
+	Enum                 []any                  `json:"enum"`
+	Required             []string               `json:"required"`
+	Properties           map[string]*schemaNode `json:"properties"`
+	AdditionalProperties json.RawMessage        `json:"additionalProperties"`
+	Items                *schemaNode            `json:"items"`
+	MinItems             *int                   `json:"minItems"`
+	MinLength            *int                   `json:"minLength"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	ExclusiveMinimum     *float64               `json:"exclusiveMinimum"`
+	Pattern              string                 `json:"pattern"`
+	AllOf                []*schemaNode          `json:"allOf"`
+	OneOf                []*schemaNode          `json:"oneOf"`
+	Ref                  string                 `json:"$ref"`
+
+	hasConst   bool
+	additional *schemaNode
+	closed     bool
+	pattern    *regexp.Regexp
+	ref        *schemaNode
+}
+
+type schemaViolation struct {
+	Path    string
+	Line    int
+	Column  int
+	Message string
+
+	constMismatch bool
+}
+
+var (
+	levelSchema = mustCompileSchema("level.schema.json", levelSchemaJSON)
+	packSchema  = mustCompileSchema("pack.schema.json", packSchemaJSON)
+)
+
+func mustCompileSchema(name string, raw []byte) *schemaNode {
+	s, err := compileSchema(raw)
+	if err == nil {
+		err = resolveRefs(s, s)
+	}
+	if err != nil {
+		panic(fmt.Sprintf("compile %s: %v", name, err))
+	}
+	return s
+}
+
+func compileSchema(raw []byte) (*schemaNode, error) {
+	var s schemaNode
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, err
+	}
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return nil, err
+	}
+	_, s.hasConst = probe["const"]
+	if len(s.AdditionalProperties) > 0 {
+		switch strings.TrimSpace(string(s.AdditionalProperties)) {
+		case "true":
+		case "false":
+			s.closed = true
+		default:
+			sub, err := compileSchema(s.AdditionalProperties)
+			if err != nil {
+				return nil, err
+			}
+			s.additional = sub
+		}
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+	for key, rawProp := range propertiesRaw(probe["properties"]) {
+		sub, err := compileSchema(rawProp)
+		if err != nil {
+			return nil, fmt.Errorf("properties.%s: %w", key, err)
+		}
+		s.Properties[key] = sub
+	}
+	if raw, ok := probe["items"]; ok {
+		sub, err := compileSchema(raw)
+		if err != nil {
+			return nil, fmt.Errorf("items: %w", err)
+		}
+		s.Items = sub
+	}
+	for _, group := range []struct {
+		key  string
+		into []*schemaNode
+	}{{"allOf", s.AllOf}, {"oneOf", s.OneOf}} {
+		var parts []json.RawMessage
+		if raw, ok := probe[group.key]; ok {
+			if err := json.Unmarshal(raw, &parts); err != nil {
+				return nil, err
+			}
+		}
+		for i, part := range parts {
+			sub, err := compileSchema(part)
+			if err != nil {
+				return nil, fmt.Errorf("%s[%d]: %w", group.key, i, err)
+			}
+			group.into[i] = sub
+		}
+	}
+	return &s, nil
+}
+
+// resolveRefs links every $ref below s to its target in root. Only local
+// pointers through properties and items are supported, such as
+// "#/properties/checks".
+func resolveRefs(root, s *schemaNode) error {
+	if s == nil {
+		return nil
+	}
+	if s.Ref != "" {
+		target := root
+		segs := strings.Split(strings.TrimPrefix(s.Ref, "#/"), "/")
+		for i := 0; i < len(segs) && target != nil; i++ {
+			switch {
+			case segs[i] == "items":
+				target = target.Items
+			case segs[i] == "properties" && i+1 < len(segs):
+				i++
+				target = target.Properties[segs[i]]
+			default:
+				target = nil
+			}
+		}
+		if !strings.HasPrefix(s.Ref, "#/") || target == nil {
+			return fmt.Errorf("unresolvable $ref %q", s.Ref)
+		}
+		s.ref = target
+		return nil
+	}
+	subs := append([]*schemaNode{s.Items, s.additional}, s.AllOf...)
+	subs = append(subs, s.OneOf...)
+	for _, sub := range s.Properties {
+		subs = append(subs, sub)
+	}
+	for _, sub := range subs {
+		if err := resolveRefs(root, sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func propertiesRaw(raw json.RawMessage) map[string]json.RawMessage {
+	if len(raw) == 0 {
+		return nil
+	}
+	out := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil
+	}
+	return out
+}
+
+// validateNode checks a YAML document node against a compiled schema and
+// returns every violation found (not just the first).
+func validateNode(s *schemaNode, node *yaml.Node, path string) []schemaViolation {
+	node = resolveAlias(node)
+	if node == nil {
+		return nil
+	}
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return []schemaViolation{{Path: path, Line: 1, Column: 1, Message: "document is empty"}}
+		}
+		return validateNode(s, node.Content[0], path)
+	}
+	if s.ref != nil {
+		return validateNode(s.ref, node, path)
+	}
+
+	var out []schemaViolation
+	fail := func(msg string, args ...any) {
+		out = append(out, schemaViolation{Path: path, Line: node.Line, Column: node.Column, Message: fmt.Sprintf(msg, args...)})
+	}
+
+	if s.Type != nil && !matchesSchemaType(s.Type, node) {
+		fail("expected %s, got %s", describeSchemaType(s.Type), describeNodeType(node))
+		return out
+	}
+	if s.hasConst && !scalarEquals(node, s.Const) {
+		out = append(out, schemaViolation{
+			Path:          path,
+			Line:          node.Line,
+			Column:        node.Column,
+			Message:       fmt.Sprintf("must be %s", formatSchemaValue(s.Const)),
+			constMismatch: true,
+		})
+	}
+	if len(s.Enum) > 0 {
+		ok := false
+		for _, v := range s.Enum {
+			if scalarEquals(node, v) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			allowed := make([]string, 0, len(s.Enum))
+			for _, v := range s.Enum {
+				allowed = append(allowed, formatSchemaValue(v))
+			}
+			fail("must be one of %s, got %q", strings.Join(allowed, ", "), node.Value)
+		}
+	}
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if s.MinLength != nil && node.Tag == "!!str" && len([]rune(node.Value)) < *s.MinLength {
+			if *s.MinLength == 1 {
+				fail("must not be empty")
+			} else {
+				fail("must be at least %d characters", *s.MinLength)
+			}
+		}
+		if s.pattern != nil && node.Tag == "!!str" && !s.pattern.MatchString(node.Value) {
+			fail("%q does not match pattern %s", node.Value, s.Pattern)
+		}
+		if n, ok := scalarNumber(node); ok {
+			if s.Minimum != nil && n < *s.Minimum {
+				fail("must be >= %s", formatNumber(*s.Minimum))
+			}
+			if s.Maximum != nil && n > *s.Maximum {
+				fail("must be <= %s", formatNumber(*s.Maximum))
+			}
+			if s.ExclusiveMinimum != nil && n <= *s.ExclusiveMinimum {
+				fail("must be > %s", formatNumber(*s.ExclusiveMinimum))
+			}
+		}
+	case yaml.MappingNode:
+		present := map[string]struct{}{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			value := node.Content[i+1]
+			present[key.Value] = struct{}{}
+			childPath := joinSchemaPath(path, key.Value)
+			if prop, ok := s.Properties[key.Value]; ok {
+				out = append(out, validateNode(prop, value, childPath)...)
+				continue
+			}
+			if s.closed {
+				out = append(out, schemaViolation{Path: childPath, Line: key.Line, Column: key.Column, Message: fmt.Sprintf("unknown field %q", key.Value)})
+				continue
+			}
+			if s.additional != nil {
+				out = append(out, validateNode(s.additional, value, childPath)...)
+			}
+		}
+		for _, req := range s.Required {
+			if _, ok := present[req]; !ok {
+				fail("missing required field %q", req)
+			}
+		}
+	case yaml.SequenceNode:
+		if s.MinItems != nil && len(node.Content) < *s.MinItems {
+			fail("must contain at least %d item(s)", *s.MinItems)
+		}
+		if s.Items != nil {
+			for i, item := range node.Content {
+				out = append(out, validateNode(s.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+
+	for _, sub := range s.AllOf {
+		out = append(out, validateNode(sub, node, path)...)
+	}
+	if len(s.OneOf) > 0 {
+		out = append(out, validateOneOf(s.OneOf, node, path)...)
+	}
+	return out
+}
+
+func validateOneOf(branches []*schemaNode, node *yaml.Node, path string) []schemaViolation {
+	results := make([][]schemaViolation, len(branches))
+	passed := 0
+	for i, b := range branches {
+		results[i] = validateNode(b, node, path)
+		if len(results[i]) == 0 {
+			passed++
+		}
+	}
+	switch {
+	case passed == 1:
+		return nil
+	case passed > 1:
+		return []schemaViolation{{Path: path, Line: node.Line, Column: node.Column, Message: "matches more than one allowed shape"}}
+	}
+
+	// When every branch is rejected by a const discriminator on the same field
+	// (e.g. checks[].type), report the allowed values once instead of N times.
+	discPath := ""
+	allowed := []string{}
+	discriminated := true
+	for i, res := range results {
+		found := false
+		for _, v := range res {
+			if v.constMismatch {
+				if discPath == "" {
+					discPath = v.Path
+				}
+				if v.Path == discPath {
+					found = true
+					if c := branchConst(branches[i], strings.TrimPrefix(strings.TrimPrefix(v.Path, path), ".")); c != "" {
+						allowed = append(allowed, c)
+					}
+				}
+			}
+		}
+		if !found {
+			discriminated = false
+			break
+		}
+	}
+	if discriminated && discPath != "" {
+		child := lookupSchemaPath(node, strings.TrimPrefix(strings.TrimPrefix(discPath, path), "."))
+		line, col, value := node.Line, node.Column, ""
+		if child != nil {
+			line, col, value = child.Line, child.Column, child.Value
+		}
+		sort.Strings(allowed)
+		return []schemaViolation{{
+			Path:    discPath,
+			Line:    line,
+			Column:  col,
+			Message: fmt.Sprintf("unsupported value %q (allowed: %s)", value, strings.Join(allowed, ", ")),
+		}}
+	}
+
+	best := results[0]
+	for _, res := range results[1:] {
+		if len(res) < len(best) {
+			best = res
+		}
+	}
+	return best
+}
+
+func branchConst(s *schemaNode, field string) string {
+	if s == nil || field == "" {
+		return ""
+	}
+	prop, ok := s.Properties[field]
+	if !ok || !prop.hasConst {
+		return ""
+	}
+	return fmt.Sprint(prop.Const)
+}
+
+func lookupSchemaPath(node *yaml.Node, field string) *yaml.Node {
+	node = resolveAlias(node)
+	if node == nil || node.Kind != yaml.MappingNode || field == "" {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == field {
+			return resolveAlias(node.Content[i+1])
+		}
+	}
+	return nil
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+func joinSchemaPath(base, key string) string {
+	if base == "" {
+		return key
+	}
+	return base + "." + key
+}
+
+func matchesSchemaType(t any, node *yaml.Node) bool {
+	switch v := t.(type) {
+	case string:
+		return matchesSingleType(v, node)
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok && matchesSingleType(s, node) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesSingleType(t string, node *yaml.Node) bool {
+	switch t {
+	case "object":
+		return node.Kind == yaml.MappingNode
+	case "array":
+		return node.Kind == yaml.SequenceNode
+	case "string":
+		return node.Kind == yaml.ScalarNode && node.Tag == "!!str"
+	case "integer":
+		if node.Kind != yaml.ScalarNode {
+			return false
+		}
+		if node.Tag == "!!int" {
+			return true
+		}
+		if node.Tag == "!!float" {
+			f, err := strconv.ParseFloat(node.Value, 64)
+			return err == nil && f == math.Trunc(f)
+		}
+		return false
+	case "number":
+		return node.Kind == yaml.ScalarNode && (node.Tag == "!!int" || node.Tag == "!!float")
+	case "boolean":
+		return node.Kind == yaml.ScalarNode && node.Tag == "!!bool"
+	case "null":
+		return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+	}
+	return true
+}
+
+func describeSchemaType(t any) string {
+	switch v := t.(type) {
+	case string:
+		return v
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, fmt.Sprint(item))
+		}
+		return strings.Join(parts, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func describeNodeType(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	case yaml.ScalarNode:
+		switch node.Tag {
+		case "!!int":
+			return "integer"
+		case "!!float":
+			return "number"
+		case "!!bool":
+			return "boolean"
+		case "!!null":
+			return "null"
+		}
+		return "string"
+	}
+	return "unknown"
+}
+
+func scalarNumber(node *yaml.Node) (float64, bool) {
+	if node.Kind != yaml.ScalarNode || (node.Tag != "!!int" && node.Tag != "!!float") {
+		return 0, false
+	}
+	var f float64
+	if err := node.Decode(&f); err != nil {
+		return 0, false
+	}
+	return f, true
+}
+
+func scalarEquals(node *yaml.Node, want any) bool {
+	if node.Kind != yaml.ScalarNode {
+		return false
+	}
+	switch w := want.(type) {
+	case string:
+		return node.Tag == "!!str" && node.Value == w
+	case float64:
+		n, ok := scalarNumber(node)
+		return ok && n == w
+	case bool:
+		var b bool
+		return node.Tag == "!!bool" && node.Decode(&b) == nil && b == w
+	case nil:
+		return node.Tag == "!!null"
+	}
+	return false
+}
+
+func formatSchemaValue(v any) string {
+	switch x := v.(type) {
+	case string:
+		return strconv.Quote(x)
+	case float64:
+		return formatNumber(x)
+	}
+	return fmt.Sprint(v)
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
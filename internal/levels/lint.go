@@ -0,0 +1,567 @@
+package levels
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is a single lint finding anchored to a file position.
+type Diagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s [%s]", d.File, max(1, d.Line), max(1, d.Column), d.Severity, d.Message, d.Rule)
+}
+
+type LintOptions struct {
+	// CheckTypes lists check types the grader can evaluate. When empty the
+	// unknown-check-type lint is skipped.
+	CheckTypes []string
+}
+
+// Lint validates every pack found under root against the bundled JSON schemas
+// and runs semantic lints on their levels. root may be a single pack directory
+// (containing pack.yaml) or a directory of packs. The returned error is only
+// set when root itself cannot be read; content problems are diagnostics.
+func Lint(root string, opts LintOptions) ([]Diagnostic, error) {
+	packDirs, err := lintPackDirs(root)
+	if err != nil {
+		return nil, err
+	}
+	l := &linter{opts: opts, levelIDs: map[string]struct{}{}, packs: map[string]Pack{}, packLevels: map[string]map[string]struct{}{}}
+	for _, dir := range packDirs {
+		l.lintPack(dir)
+	}
+	l.lintRequires()
+	l.lintPrerequisites()
+	sortDiagnostics(l.diags)
+	return l.diags, nil
+}
+
+// CountSeverity returns the number of diagnostics with the given severity.
+func CountSeverity(diags []Diagnostic, severity string) int {
+	n := 0
+	for _, d := range diags {
+		if d.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+func lintPackDirs(root string) ([]string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+	if _, err := os.Stat(filepath.Join(root, "pack.yaml")); err == nil {
+		return []string{root}, nil
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, e.Name())
+		if _, err := os.Stat(filepath.Join(dir, "pack.yaml")); err == nil {
+			out = append(out, dir)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no pack.yaml found in %s or its subdirectories", root)
+	}
+	return out, nil
+}
+
+type lintPrereq struct {
+	file  string
+	node  *yaml.Node
+	level string
+	pack  string
+}
+
+type lintRequire struct {
+	file string
+	node *yaml.Node
+	req  PackRequire
+}
+
+type linter struct {
+	opts     LintOptions
+	diags    []Diagnostic
+	levelIDs map[string]struct{}
+	prereqs  []lintPrereq
+	requires []lintRequire
+	// packs and packLevels index what has been linted so far, for the
+	// cross-pack checks that run after every pack is read.
+	packs      map[string]Pack
+	packLevels map[string]map[string]struct{}
+	curPack    string
+}
+
+func (l *linter) add(file string, node *yaml.Node, severity, rule, msg string, args ...any) {
+	d := Diagnostic{File: file, Severity: severity, Rule: rule, Message: fmt.Sprintf(msg, args...)}
+	if node != nil {
+		d.Line = node.Line
+		d.Column = node.Column
+	}
+	l.diags = append(l.diags, d)
+}
+
+func (l *linter) errorsIn(file string) bool {
+	for _, d := range l.diags {
+		if d.File == file && d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *linter) lintPack(dir string) {
+	file := filepath.Join(dir, "pack.yaml")
+	doc, ok := l.parseFile(file, packSchema)
+	if !ok {
+		return
+	}
+	var pack Pack
+	if err := doc.Decode(&pack); err != nil {
+		l.addDecodeError(file, err)
+		return
+	}
+	if !l.errorsIn(file) {
+		if err := pack.Validate(); err != nil {
+			l.add(file, docRoot(doc), SeverityError, "validate", "%s", err.Error())
+		}
+	}
+	pack.Path = dir
+	l.curPack = pack.PackID
+	if pack.PackID != "" {
+		l.packs[pack.PackID] = pack
+		l.packLevels[pack.PackID] = map[string]struct{}{}
+	}
+	for i, req := range pack.Requires {
+		l.requires = append(l.requires, lintRequire{file: file, node: nodeAt(doc, "requires", i), req: req})
+	}
+
+	if pack.Image.Build != nil && pack.Image.Build.ContextDir != "" {
+		ctxDir := filepath.Join(dir, pack.Image.Build.ContextDir)
+		if _, err := os.Stat(ctxDir); err != nil {
+			l.add(file, nodeAt(doc, "image", "build", "context_dir"), SeverityError, "missing-path", "image.build.context_dir does not exist: %s", ctxDir)
+		}
+	}
+
+	if len(pack.Levels) > 0 {
+		for i, ref := range pack.Levels {
+			if ref.Enabled != nil && !*ref.Enabled {
+				continue
+			}
+			levelYAML := filepath.Join(dir, ref.Path, "level.yaml")
+			if _, err := os.Stat(levelYAML); err != nil {
+				l.add(file, nodeAt(doc, "levels", i, "path"), SeverityError, "missing-path", "level %s: %s not found", ref.LevelID, levelYAML)
+				continue
+			}
+			id := l.lintLevel(levelYAML)
+			if id != "" && ref.LevelID != "" && id != ref.LevelID {
+				l.add(file, nodeAt(doc, "levels", i, "level_id"), SeverityError, "level-id-mismatch", "manifest level_id %q does not match %s (level_id %q)", ref.LevelID, levelYAML, id)
+			}
+		}
+		return
+	}
+
+	levelRoot := filepath.Join(dir, "levels")
+	entries, err := os.ReadDir(levelRoot)
+	if err != nil {
+		l.add(file, docRoot(doc), SeverityError, "missing-path", "pack has no levels: list or %s directory", levelRoot)
+		return
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		levelYAML := filepath.Join(levelRoot, e.Name(), "level.yaml")
+		if _, err := os.Stat(levelYAML); err != nil {
+			continue
+		}
+		l.lintLevel(levelYAML)
+	}
+}
+
+// lintLevel lints one level.yaml and returns its level_id (empty if unknown).
+func (l *linter) lintLevel(file string) string {
+	doc, ok := l.parseFile(file, levelSchema)
+	if !ok {
+		return ""
+	}
+	var level Level
+	if err := doc.Decode(&level); err != nil {
+		l.addDecodeError(file, err)
+		return ""
+	}
+	if level.LevelID != "" {
+		if _, dup := l.levelIDs[level.LevelID]; dup {
+			l.add(file, nodeAt(doc, "level_id"), SeverityWarning, "duplicate-level-id", "level_id %q is defined more than once", level.LevelID)
+		}
+		l.levelIDs[level.LevelID] = struct{}{}
+		if ids, ok := l.packLevels[l.curPack]; ok {
+			ids[level.LevelID] = struct{}{}
+		}
+	}
+
+	l.lintDuplicateIDs(file, doc, level)
+	l.lintChecks(file, doc, level)
+	l.lintHints(file, doc, level)
+	l.lintTranslations(file, doc, level)
+	for i, bonus := range level.Scoring.CmdlogBonuses {
+		if _, err := regexp.Compile(bonus.Pattern); err != nil {
+			l.add(file, nodeAt(doc, "scoring", "cmdlog_bonuses", i, "pattern"), SeverityError, "invalid-regex", "cmdlog bonus %q: %s", bonus.ID, regexErrorText(err))
+		}
+	}
+	for i, prereq := range level.Progression.Prerequisites {
+		l.prereqs = append(l.prereqs, lintPrereq{file: file, node: nodeAt(doc, "progression", "prerequisites", i), level: prereq, pack: l.curPack})
+	}
+	if level.Filesystem.Dataset.Source == "dir" && level.Filesystem.Dataset.Path != "" {
+		datasetDir := filepath.Join(filepath.Dir(file), level.Filesystem.Dataset.Path)
+		if _, err := os.Stat(datasetDir); err != nil {
+			l.add(file, nodeAt(doc, "filesystem", "dataset", "path"), SeverityError, "missing-path", "dataset path not found: %s", datasetDir)
+		}
+	}
+
+	if !l.errorsIn(file) {
+		if err := level.Validate(); err != nil {
+			l.add(file, docRoot(doc), SeverityError, "validate", "%s", err.Error())
+		}
+	}
+	return level.LevelID
+}
+
+// lintVariants checks variant params against the {{placeholders}} of a level
+// and then expands the first variant in place, so the remaining checks see
+// the level as it loads by default.
+func (l *linter) lintVariants(file string, doc *yaml.Node) {
+	used := placeholdersIn(doc)
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var variants []VariantSpec
+	if n := lookupSchemaPath(docRoot(doc), "variants"); n != nil {
+		if err := n.Decode(&variants); err != nil {
+			// Reported by the schema.
+			return
+		}
+	}
+	if len(variants) == 0 {
+		for _, name := range names {
+			l.add(file, used[name], SeverityError, "variant-params", "placeholder {{%s}} is used but the level declares no variants", name)
+		}
+		return
+	}
+	seen := map[string]struct{}{}
+	for i, v := range variants {
+		if _, dup := seen[v.VariantID]; dup {
+			l.add(file, nodeAt(doc, "variants", i, "variant_id"), SeverityError, "duplicate-id", "duplicate variant_id %q", v.VariantID)
+		}
+		seen[v.VariantID] = struct{}{}
+		for _, name := range names {
+			if _, ok := v.Params[name]; !ok {
+				l.add(file, nodeAt(doc, "variants", i), SeverityError, "variant-params", "variant %q does not set %q (used at line %d)", v.VariantID, name, used[name].Line)
+			}
+		}
+		for name := range v.Params {
+			if _, ok := used[name]; !ok {
+				l.add(file, nodeAt(doc, "variants", i, "params", name), SeverityWarning, "unused-variant-param", "variant %q: param %q is not used by any placeholder", v.VariantID, name)
+			}
+		}
+	}
+	expandVariant(doc, variants[0].Params)
+}
+
+func (l *linter) lintDuplicateIDs(file string, doc *yaml.Node, level Level) {
+	seenHints := map[string]struct{}{}
+	seenChecks := map[string]struct{}{}
+	for _, g := range level.specGroups() {
+		for i, h := range g.hints {
+			if h.HintID == "" {
+				continue
+			}
+			if _, ok := seenHints[h.HintID]; ok {
+				l.add(file, nodeAt(doc, g.at("hints", i, "hint_id")...), SeverityError, "duplicate-id", "duplicate hint_id %q", h.HintID)
+			}
+			seenHints[h.HintID] = struct{}{}
+		}
+		for i, c := range g.checks {
+			if c.ID == "" {
+				continue
+			}
+			if _, ok := seenChecks[c.ID]; ok {
+				l.add(file, nodeAt(doc, g.at("checks", i, "id")...), SeverityError, "duplicate-id", "duplicate checks id %q", c.ID)
+			}
+			seenChecks[c.ID] = struct{}{}
+		}
+	}
+}
+
+func (l *linter) lintChecks(file string, doc *yaml.Node, level Level) {
+	known := map[string]struct{}{}
+	for _, t := range l.opts.CheckTypes {
+		known[t] = struct{}{}
+	}
+	workMount := level.Filesystem.Work.MountPoint
+	if workMount == "" {
+		workMount = "/work"
+	}
+	for _, g := range level.specGroups() {
+		for i, c := range g.checks {
+			if len(known) > 0 && c.Type != "" {
+				if _, ok := known[c.Type]; !ok {
+					l.add(file, nodeAt(doc, g.at("checks", i, "type")...), SeverityError, "unknown-check-type", "check %q: type %q is not registered in the grader", c.ID, c.Type)
+				}
+			}
+			if c.Pattern != "" {
+				if _, err := regexp.Compile(c.Pattern); err != nil {
+					l.add(file, nodeAt(doc, g.at("checks", i, "pattern")...), SeverityError, "invalid-regex", "check %q: %s", c.ID, regexErrorText(err))
+				}
+			}
+			if c.CompareToPath != "" && !underMount(c.CompareToPath, workMount) {
+				l.add(file, nodeAt(doc, g.at("checks", i, "compare_to_path")...), SeverityError, "compare-outside-work", "check %q: compare_to_path %q must be inside %s", c.ID, c.CompareToPath, workMount)
+			}
+		}
+	}
+}
+
+func (l *linter) lintHints(file string, doc *yaml.Node, level Level) {
+	for _, g := range level.specGroups() {
+		l.lintHintUnlocks(file, doc, g)
+	}
+}
+
+// lintHintUnlocks checks one hint list; every stage reveals its own hints
+// from the first.
+func (l *linter) lintHintUnlocks(file string, doc *yaml.Node, g specGroup) {
+	for i, h := range g.hints {
+		u := h.Unlock
+		node := nodeAt(doc, g.at("hints", i, "unlock")...)
+		if i == 0 {
+			if u.AfterSeconds > 0 || u.AfterFailedChecks > 0 || u.AfterReveals > 0 {
+				l.add(file, node, SeverityWarning, "unreachable-unlock", "hint %q: the first hint is always unlocked; its unlock rule is ignored", h.HintID)
+			}
+			continue
+		}
+		// Hints are revealed in order, so when hint i is evaluated at most i
+		// hints can have been revealed.
+		if u.AfterReveals > i {
+			if u.AfterSeconds <= 0 && u.AfterFailedChecks <= 0 {
+				l.add(file, node, SeverityError, "unreachable-unlock", "hint %q can never unlock: after_reveals %d requires more reveals than the %d hint(s) before it", h.HintID, u.AfterReveals, i)
+			} else {
+				l.add(file, node, SeverityWarning, "unreachable-unlock", "hint %q: after_reveals %d can never be satisfied (only %d hint(s) before it)", h.HintID, u.AfterReveals, i)
+			}
+		}
+	}
+}
+
+// lintTranslations warns about keys left untranslated, for every locale the
+// pack declares and every locale the level has an i18n entry for.
+func (l *linter) lintTranslations(file string, doc *yaml.Node, level Level) {
+	locales := map[string]struct{}{}
+	for _, locale := range l.packs[l.curPack].Locales {
+		locales[locale] = struct{}{}
+	}
+	for locale := range level.I18n {
+		locales[locale] = struct{}{}
+	}
+	names := make([]string, 0, len(locales))
+	for locale := range locales {
+		names = append(names, locale)
+	}
+	sort.Strings(names)
+	for _, locale := range names {
+		if _, ok := level.I18n[locale]; !ok {
+			l.add(file, docRoot(doc), SeverityWarning, "untranslated", "level %s has no %s translation", level.LevelID, locale)
+			continue
+		}
+		if missing := level.untranslated(locale); len(missing) > 0 {
+			l.add(file, nodeAt(doc, "i18n", locale), SeverityWarning, "untranslated", "i18n.%s is missing %s", locale, strings.Join(missing, ", "))
+		}
+	}
+}
+
+func (l *linter) lintPrerequisites() {
+	for _, p := range l.prereqs {
+		ref, err := ParseLevelRef(p.level, p.pack)
+		if err != nil {
+			// Reported by the schema pattern.
+			continue
+		}
+		if ref.PackID != p.pack {
+			if !l.packRequires(p.pack, ref.PackID) {
+				l.add(p.file, p.node, SeverityError, "unrequired-pack", "prerequisite %q refers to pack %q, which pack %q does not list in requires", p.level, ref.PackID, p.pack)
+				continue
+			}
+			if _, linted := l.packLevels[ref.PackID]; !linted {
+				continue
+			}
+		}
+		ids, ok := l.packLevels[ref.PackID]
+		if !ok {
+			ids = l.levelIDs
+		}
+		if _, ok := ids[ref.LevelID]; !ok {
+			l.add(p.file, p.node, SeverityError, "unknown-prerequisite", "prerequisite %q does not match any level in pack %q", p.level, ref.PackID)
+		}
+	}
+}
+
+func (l *linter) packRequires(packID, required string) bool {
+	for _, req := range l.packs[packID].Requires {
+		if req.PackID == required {
+			return true
+		}
+	}
+	return false
+}
+
+// lintRequires checks requires entries against the other linted packs. A
+// required pack that was not linted is not an error.
+func (l *linter) lintRequires() {
+	for _, r := range l.requires {
+		found, ok := l.packs[r.req.PackID]
+		if !ok {
+			continue
+		}
+		if msg := unsatisfiedRequire(r.req, &found); msg != "" {
+			l.add(r.file, r.node, SeverityWarning, "unsatisfied-requires", "%s", msg)
+		}
+	}
+}
+
+// parseFile reads a YAML file, reports syntax and schema errors, and returns
+// the document node. ok is false when the file could not be parsed at all.
+func (l *linter) parseFile(file string, schema *schemaNode) (*yaml.Node, bool) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		l.add(file, nil, SeverityError, "io", "%s", err.Error())
+		return nil, false
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		l.addDecodeError(file, err)
+		return nil, false
+	}
+	res, err := Migrate(&doc)
+	if err != nil {
+		l.add(file, docRoot(&doc), SeverityError, "migration", "%s", err.Error())
+		return nil, false
+	}
+	if res.Changed() {
+		l.add(file, nodeAt(docRoot(&doc), "schema_version"), SeverityWarning, "outdated-schema",
+			"schema_version %d is outdated (latest %d); run `clidojo pack migrate --write`", res.From, res.To)
+	}
+	if schema == levelSchema {
+		l.lintVariants(file, &doc)
+	}
+	for _, v := range validateNode(schema, &doc, "") {
+		msg := v.Message
+		if v.Path != "" {
+			msg = v.Path + ": " + msg
+		}
+		l.diags = append(l.diags, Diagnostic{File: file, Line: v.Line, Column: v.Column, Severity: SeverityError, Rule: "schema", Message: msg})
+	}
+	return &doc, true
+}
+
+var yamlLinePattern = regexp.MustCompile(`line (\d+):\s*(.*)`)
+
+func (l *linter) addDecodeError(file string, err error) {
+	var typeErr *yaml.TypeError
+	msgs := []string{err.Error()}
+	if errors.As(err, &typeErr) {
+		msgs = typeErr.Errors
+	}
+	for _, msg := range msgs {
+		d := Diagnostic{File: file, Severity: SeverityError, Rule: "yaml", Message: strings.TrimPrefix(msg, "yaml: ")}
+		if m := yamlLinePattern.FindStringSubmatch(msg); m != nil {
+			d.Line, _ = strconv.Atoi(m[1])
+			d.Message = m[2]
+		}
+		l.diags = append(l.diags, d)
+	}
+}
+
+func docRoot(doc *yaml.Node) *yaml.Node {
+	if doc != nil && doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		return doc.Content[0]
+	}
+	return doc
+}
+
+// nodeAt walks mapping keys (string) and sequence indices (int) and returns
+// the deepest node that exists, so diagnostics always carry a position.
+func nodeAt(doc *yaml.Node, segs ...any) *yaml.Node {
+	cur := resolveAlias(docRoot(doc))
+	for _, seg := range segs {
+		var next *yaml.Node
+		switch s := seg.(type) {
+		case string:
+			next = lookupSchemaPath(cur, s)
+		case int:
+			if cur != nil && cur.Kind == yaml.SequenceNode && s >= 0 && s < len(cur.Content) {
+				next = resolveAlias(cur.Content[s])
+			}
+		}
+		if next == nil {
+			return cur
+		}
+		cur = next
+	}
+	return cur
+}
+
+func underMount(p, mount string) bool {
+	clean := path.Clean(p)
+	mount = path.Clean(mount)
+	return clean == mount || strings.HasPrefix(clean, mount+"/")
+}
+
+func regexErrorText(err error) string {
+	return strings.TrimPrefix(err.Error(), "error parsing regexp: ")
+}
+
+func sortDiagnostics(diags []Diagnostic) {
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].File != diags[j].File {
+			return diags[i].File < diags[j].File
+		}
+		if diags[i].Line != diags[j].Line {
+			return diags[i].Line < diags[j].Line
+		}
+		return diags[i].Column < diags[j].Column
+	})
+}
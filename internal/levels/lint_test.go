@@ -0,0 +1,127 @@
+package levels
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLintBuiltinPacksHaveNoErrors(t *testing.T) {
+	diags, err := Lint(filepath.Join("..", "..", "packs"), LintOptions{
+		CheckTypes: []string{
+			"file_exists", "file_text_exact", "file_lines_count", "file_lines_match_regex",
+			"file_sorted", "command_output_equals_file", "cmdlog_contains_regex", "cmdlog_forbids_regex",
+		},
+	})
+	if err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			t.Errorf("unexpected error: %s", d)
+		}
+	}
+}
+
+const brokenLevelYAML = `kind: level
+schema_version: 1
+level_id: level-broken
+title: "Broken"
+difficulty: 9
+estimated_minutes: 5
+filesystem:
+  dataset:
+    source: dir
+    path: "dataset"
+    mount_point: "/levels/current"
+  work:
+    mount_point: "/work"
+objective:
+  bullets: ["do it"]
+hints:
+  - hint_id: h1
+    text_md: "first"
+  - hint_id: h2
+    text_md: "second"
+    unlock: { after_reveals: 3 }
+checks:
+  - id: c1
+    type: file_lines_match_regex
+    description: "bad regex"
+    path: "/work/out.txt"
+    pattern: "([a-z"
+  - id: c2
+    type: file_magic
+    description: "unknown type"
+    path: "/work/out.txt"
+  - id: c3
+    type: command_output_equals_file
+    description: "escape"
+    command: "true"
+    compare_to_path: "/etc/passwd"
+x-progression:
+  prerequisites: ["level-missing"]
+`
+
+func TestLintReportsEveryProblemWithPositions(t *testing.T) {
+	root := t.TempDir()
+	packDir := filepath.Join(root, "broken")
+	levelDir := filepath.Join(packDir, "levels", "level-broken")
+	if err := os.MkdirAll(filepath.Join(levelDir, "dataset"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	pack := "kind: pack\nschema_version: 1\npack_id: broken\nname: Broken\nversion: \"0.1.0\"\nimage:\n  ref: img\n"
+	if err := os.WriteFile(filepath.Join(packDir, "pack.yaml"), []byte(pack), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(levelDir, "level.yaml"), []byte(brokenLevelYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	diags, err := Lint(root, LintOptions{CheckTypes: []string{"file_lines_match_regex", "command_output_equals_file"}})
+	if err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	want := map[string]int{
+		"schema":               5,  // difficulty
+		"invalid-regex":        27, // c1 pattern
+		"unknown-check-type":   29, // c2 type
+		"compare-outside-work": 36, // c3 compare_to_path
+		"unreachable-unlock":   21, // h2 unlock
+		"unknown-prerequisite": 38, // prerequisites[0]
+	}
+	for rule, line := range want {
+		found := false
+		for _, d := range diags {
+			if d.Rule == rule && d.Line == line {
+				found = true
+				if !strings.HasSuffix(d.File, "level.yaml") {
+					t.Errorf("rule %s reported on %s", rule, d.File)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("expected %s diagnostic at line %d, got %v", rule, line, diags)
+		}
+	}
+}
+
+func TestLintReportsUnsupportedCheckTypeOnce(t *testing.T) {
+	root := t.TempDir()
+	file := filepath.Join(root, "level.yaml")
+	if err := os.WriteFile(file, []byte(brokenLevelYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l := &linter{levelIDs: map[string]struct{}{}}
+	l.parseFile(file, levelSchema)
+	count := 0
+	for _, d := range l.diags {
+		if strings.HasPrefix(d.Message, "checks[1].type") {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Fatalf("expected one schema diagnostic for checks[1].type, got %d: %v", count, l.diags)
+	}
+}
@@ -2,57 +2,98 @@ package levels
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io"
-	"io/fs"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 
 	"gopkg.in/yaml.v3"
 )
 
-type FSLoader struct{}
+type FSLoader struct {
+	// ArchiveCacheDir is where .dojopack archives are unpacked. Defaults to
+	// the user cache dir.
+	ArchiveCacheDir string
+	// TrustStore and TrustPolicy decide how packs without a trusted
+	// signature are loaded. An empty policy behaves like TrustPolicyOff.
+	TrustStore  *TrustStore
+	TrustPolicy string
+	// OnRefused is called for every pack LoadPacks skips under
+	// TrustPolicyRefuse.
+	OnRefused func(path string, err error)
+	// DatasetCacheDir holds generated datasets, keyed by a hash of their
+	// inputs. Defaults to the user cache dir.
+	DatasetCacheDir string
+	// RunGenerator runs dataset generators in the sandbox. Without it only
+	// cached generated datasets are available.
+	RunGenerator GeneratorFunc
+	// ImageID resolves level images to their IDs so cached datasets follow
+	// rebuilt or re-pulled images. Without it the image ref is hashed.
+	ImageID ImageIDFunc
+	// IsolateErrors keeps loading when a pack or level is broken: broken
+	// packs become placeholders with LoadError set and broken levels are
+	// listed in Pack.BrokenLevels. Otherwise the first error is returned.
+	IsolateErrors bool
+	// SkipDatasetSums loads levels without checking their datasets against
+	// dataset.sha256sums, so the manifests can be rewritten.
+	SkipDatasetSums bool
+
+	// keyMu guards the memoized generator keys and dataset file sums.
+	keyMu sync.Mutex
+	keys  map[string]cachedKey
+	sums  map[string]cachedSum
+}
+
+// ErrPackUntrusted is returned for packs refused by TrustPolicyRefuse.
+var ErrPackUntrusted = errors.New("pack is not signed by a trusted key")
+
+// ErrSandboxOnly is returned for host-side steps of restricted packs.
+var ErrSandboxOnly = errors.New("pack is sandbox-only and may not run anything on the host")
 
 func NewLoader() *FSLoader { return &FSLoader{} }
 
 func (l *FSLoader) LoadPacks(ctx context.Context, root string) ([]Pack, error) {
-	entries, err := os.ReadDir(root)
+	return l.loadPacksFrom(ctx, PackRoot{Path: root})
+}
+
+func (l *FSLoader) loadPacksFrom(ctx context.Context, root PackRoot) ([]Pack, error) {
+	entries, err := os.ReadDir(root.Path)
 	if err != nil {
 		return nil, err
 	}
 
 	packs := make([]Pack, 0)
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		var (
+			pack Pack
+			path = filepath.Join(root.Path, entry.Name())
+		)
+		switch {
+		case entry.IsDir():
+			if _, err := os.Stat(filepath.Join(path, "pack.yaml")); err != nil {
+				continue
+			}
+			pack, err = l.loadPack(ctx, path, root)
+		case entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), ArchiveExt):
+			pack, err = l.loadArchive(ctx, path, root)
+		default:
 			continue
 		}
-		packPath := filepath.Join(root, entry.Name())
-		packYAML := filepath.Join(packPath, "pack.yaml")
-		if _, err := os.Stat(packYAML); err != nil {
+		if errors.Is(err, ErrPackUntrusted) {
+			if l.OnRefused != nil {
+				l.OnRefused(path, err)
+			}
 			continue
 		}
-		pack, err := readPack(packYAML)
-		if err != nil {
-			return nil, fmt.Errorf("load pack %s: %w", packPath, err)
-		}
-		absPackPath, err := filepath.Abs(packPath)
-		if err != nil {
-			return nil, fmt.Errorf("resolve pack path %s: %w", packPath, err)
-		}
-		pack.Path = absPackPath
-		applyPackDefaults(&pack)
-		if err := validatePackBuildPath(pack); err != nil {
-			return nil, fmt.Errorf("%s: %w", packPath, err)
-		}
-
-		levels, err := l.readLevels(ctx, pack)
 		if err != nil {
-			return nil, err
+			if !l.IsolateErrors {
+				return nil, err
+			}
+			pack = brokenPack(path, root, err)
 		}
-		pack.LoadedLevels = levels
 		packs = append(packs, pack)
 	}
 
@@ -60,13 +101,76 @@ func (l *FSLoader) LoadPacks(ctx context.Context, root string) ([]Pack, error) {
 	return packs, nil
 }
 
+// LoadPack loads a single pack directory containing pack.yaml.
+func (l *FSLoader) LoadPack(ctx context.Context, packPath string) (Pack, error) {
+	return l.loadPack(ctx, packPath, PackRoot{})
+}
+
+func (l *FSLoader) loadPack(ctx context.Context, packPath string, root PackRoot) (Pack, error) {
+	pack, err := readPack(filepath.Join(packPath, "pack.yaml"))
+	if err != nil {
+		return Pack{}, fmt.Errorf("load pack %s: %w", packPath, err)
+	}
+	absPackPath, err := filepath.Abs(packPath)
+	if err != nil {
+		return Pack{}, fmt.Errorf("resolve pack path %s: %w", packPath, err)
+	}
+	pack.Path = absPackPath
+	pack.Root = root
+	applyPackDefaults(&pack)
+	if err := validatePackBuildPath(pack); err != nil {
+		return Pack{}, fmt.Errorf("%s: %w", packPath, err)
+	}
+	pack.Trust = l.packTrust(absPackPath, root)
+	if !pack.Trust.Trusted() {
+		switch l.TrustPolicy {
+		case TrustPolicyRefuse:
+			return Pack{}, fmt.Errorf("%s: %w (%s: %s)", packPath, ErrPackUntrusted, pack.Trust.Status, pack.Trust.Detail)
+		case TrustPolicySandboxOnly:
+			pack.Trust.Restricted = true
+		}
+	}
+
+	levels, broken, err := l.readLevels(ctx, pack)
+	if err != nil {
+		return Pack{}, err
+	}
+	pack.LoadedLevels = levels
+	pack.BrokenLevels = broken
+	return pack, nil
+}
+
+// brokenPack is the placeholder for a pack dir or archive that failed to
+// load.
+func brokenPack(path string, root PackRoot, err error) Pack {
+	abs, absErr := filepath.Abs(path)
+	if absErr != nil {
+		abs = path
+	}
+	id := strings.TrimSuffix(filepath.Base(path), ArchiveExt)
+	pack := Pack{PackID: id, Name: id, Path: abs, Root: root, LoadError: err.Error()}
+	if strings.HasSuffix(path, ArchiveExt) {
+		pack.Archive = abs
+	}
+	return pack
+}
+
+// packTrust verifies the pack's signature. Packs from the built-in and system
+// roots are trusted as installed.
+func (l *FSLoader) packTrust(packPath string, root PackRoot) PackTrust {
+	if root.Source == RootSourceBuiltin || root.Source == RootSourceSystem {
+		return PackTrust{Status: TrustStatusBuiltin}
+	}
+	return VerifyPack(packPath, l.TrustStore)
+}
+
 func readPack(path string) (Pack, error) {
 	var pack Pack
 	b, err := os.ReadFile(path)
 	if err != nil {
 		return pack, err
 	}
-	if err := yaml.Unmarshal(b, &pack); err != nil {
+	if err := decodeMigrated(b, &pack); err != nil {
 		return pack, err
 	}
 	if err := pack.Validate(); err != nil {
@@ -126,62 +230,81 @@ func applyPackDefaults(pack *Pack) {
 	}
 }
 
-func (l *FSLoader) readLevels(ctx context.Context, pack Pack) ([]Level, error) {
+// readLevels returns the pack's levels and, when errors are isolated, the
+// levels that failed to load.
+func (l *FSLoader) readLevels(ctx context.Context, pack Pack) ([]Level, []BrokenLevel, error) {
 	if len(pack.Levels) > 0 {
 		return l.readLevelsFromManifest(ctx, pack)
 	}
 	return l.readLevelsFromScan(ctx, pack)
 }
 
-func (l *FSLoader) readLevelsFromManifest(ctx context.Context, pack Pack) ([]Level, error) {
+func (l *FSLoader) readLevelsFromManifest(ctx context.Context, pack Pack) ([]Level, []BrokenLevel, error) {
 	levels := make([]Level, 0, len(pack.Levels))
+	broken := []BrokenLevel{}
 	for _, ref := range pack.Levels {
 		if ref.Enabled != nil && !*ref.Enabled {
 			continue
 		}
 		levelDir := filepath.Join(pack.Path, ref.Path)
-		levelYAML := filepath.Join(levelDir, "level.yaml")
-		level, err := loadLevelFile(levelYAML)
+		level, err := l.readLevel(ctx, pack, levelDir, ref.LevelID)
 		if err != nil {
-			return nil, err
-		}
-		if level.LevelID != ref.LevelID {
-			return nil, fmt.Errorf("level id mismatch for %s: manifest=%s file=%s", levelYAML, ref.LevelID, level.LevelID)
-		}
-		if err := hydrateLevel(ctx, &level, pack, levelDir); err != nil {
-			return nil, err
+			if !l.IsolateErrors {
+				return nil, nil, err
+			}
+			broken = append(broken, BrokenLevel{LevelID: ref.LevelID, Path: levelDir, Error: err.Error()})
+			continue
 		}
 		levels = append(levels, level)
 	}
-	return levels, nil
+	return levels, broken, nil
 }
 
-func (l *FSLoader) readLevelsFromScan(ctx context.Context, pack Pack) ([]Level, error) {
+func (l *FSLoader) readLevelsFromScan(ctx context.Context, pack Pack) ([]Level, []BrokenLevel, error) {
 	levelRoot := filepath.Join(pack.Path, "levels")
 	entries, err := os.ReadDir(levelRoot)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	levels := make([]Level, 0)
+	broken := []BrokenLevel{}
 	for _, e := range entries {
 		if !e.IsDir() {
 			continue
 		}
-		ly := filepath.Join(levelRoot, e.Name(), "level.yaml")
-		if _, err := os.Stat(ly); err != nil {
+		levelDir := filepath.Join(levelRoot, e.Name())
+		if _, err := os.Stat(filepath.Join(levelDir, "level.yaml")); err != nil {
 			continue
 		}
-		level, err := loadLevelFile(ly)
+		level, err := l.readLevel(ctx, pack, levelDir, "")
 		if err != nil {
-			return nil, err
-		}
-		if err := hydrateLevel(ctx, &level, pack, filepath.Dir(ly)); err != nil {
-			return nil, err
+			if !l.IsolateErrors {
+				return nil, nil, err
+			}
+			broken = append(broken, BrokenLevel{LevelID: e.Name(), Path: levelDir, Error: err.Error()})
+			continue
 		}
 		levels = append(levels, level)
 	}
 	sort.Slice(levels, func(i, j int) bool { return levels[i].LevelID < levels[j].LevelID })
-	return levels, nil
+	return levels, broken, nil
+}
+
+// readLevel loads and hydrates one level dir. A non-empty wantID must match
+// the file's level_id.
+func (l *FSLoader) readLevel(ctx context.Context, pack Pack, levelDir, wantID string) (Level, error) {
+	levelYAML := filepath.Join(levelDir, "level.yaml")
+	level, err := loadLevelFile(levelYAML)
+	if err != nil {
+		return Level{}, err
+	}
+	if wantID != "" && level.LevelID != wantID {
+		return Level{}, fmt.Errorf("level id mismatch for %s: manifest=%s file=%s", levelYAML, wantID, level.LevelID)
+	}
+	if err := l.hydrateLevel(ctx, &level, pack, levelDir); err != nil {
+		return Level{}, err
+	}
+	return level, nil
 }
 
 func loadLevelFile(path string) (Level, error) {
@@ -190,30 +313,91 @@ func loadLevelFile(path string) (Level, error) {
 	if err != nil {
 		return level, err
 	}
-	if err := yaml.Unmarshal(b, &level); err != nil {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
 		return level, fmt.Errorf("parse %s: %w", path, err)
 	}
+	if doc.Kind != 0 {
+		if _, err := Migrate(&doc); err != nil {
+			return level, fmt.Errorf("parse %s: %w", path, err)
+		}
+	}
+	// Placeholders only decode once a variant is expanded, so read the
+	// variants first. Every variant must expand and validate; the first one
+	// is the level's default.
+	if n := lookupSchemaPath(docRoot(&doc), "variants"); n != nil {
+		if err := n.Decode(&level.Variants); err != nil {
+			return level, fmt.Errorf("parse %s: %w", path, err)
+		}
+	}
+	if len(level.Variants) == 0 {
+		if doc.Kind != 0 {
+			if err := doc.Decode(&level); err != nil {
+				return level, fmt.Errorf("parse %s: %w", path, err)
+			}
+		}
+	} else {
+		if n := lookupSchemaPath(docRoot(&doc), "level_id"); n != nil {
+			level.LevelID = n.Value
+		}
+		level.source = &doc
+		var first Level
+		for i := len(level.Variants) - 1; i >= 0; i-- {
+			lv, err := level.WithVariant(level.Variants[i].VariantID)
+			if err != nil {
+				return level, fmt.Errorf("parse %s: %w", path, err)
+			}
+			if err := lv.Validate(); err != nil {
+				return level, fmt.Errorf("validate %s: variant %q: %w", path, lv.Variant, err)
+			}
+			first = lv
+		}
+		level = first
+	}
 	if err := level.Validate(); err != nil {
 		return level, fmt.Errorf("validate %s: %w", path, err)
 	}
 	return level, nil
 }
 
-func hydrateLevel(ctx context.Context, level *Level, pack Pack, levelDir string) error {
+// PrepareVariant returns level with variant id applied (the first variant
+// when id is empty) and hydrated for pack, so a generated dataset points at
+// the variant's cache entry. Levels without variants are returned as is.
+func (l *FSLoader) PrepareVariant(ctx context.Context, pack Pack, level Level, id string) (Level, error) {
+	if len(level.Variants) == 0 {
+		return level, nil
+	}
+	lv, err := level.WithVariant(id)
+	if err != nil {
+		return level, err
+	}
+	if err := l.hydrateLevel(ctx, &lv, pack, level.Path); err != nil {
+		return level, err
+	}
+	return lv, nil
+}
+
+// hydrateLevel resolves paths and pack defaults and checks the dataset
+// against its dataset.sha256sums, if any. Other dataset checks and generation
+// are left to PrepareDataset when the level starts.
+func (l *FSLoader) hydrateLevel(ctx context.Context, level *Level, pack Pack, levelDir string) error {
 	level.Path = levelDir
 	level.DatasetHostPath = filepath.Join(levelDir, level.Filesystem.Dataset.Path)
+	level.Restricted = pack.Trust.Restricted
 
 	if level.Filesystem.Dataset.Source == "generator" {
-		if level.Filesystem.Dataset.Generator == nil {
+		gen := level.Filesystem.Dataset.Generator
+		if gen == nil {
 			return fmt.Errorf("level %s dataset source=generator requires generator section", level.LevelID)
 		}
-		if err := runGenerator(ctx, *level); err != nil {
-			return err
+		if gen.Command == "" {
+			return fmt.Errorf("level %s generator.command is required", level.LevelID)
 		}
+		// Resolved by PrepareDataset, or WithRunSeed for per-run seeds.
+		level.DatasetHostPath = ""
 	}
-
-	if _, err := os.Stat(level.DatasetHostPath); err != nil {
-		return fmt.Errorf("dataset path not found for level %s: %s", level.LevelID, level.DatasetHostPath)
+	if err := l.verifyDataset(*level); err != nil {
+		return err
 	}
 
 	applyLevelDefaults(level, pack)
@@ -272,33 +456,18 @@ func applyLevelDefaults(level *Level, pack Pack) {
 	if level.Filesystem.Work.MountPoint == "" {
 		level.Filesystem.Work.MountPoint = "/work"
 	}
-	for i := range level.Checks {
-		if level.Checks[i].Required == nil {
-			v := true
-			level.Checks[i].Required = &v
+	defaultRequired := func(checks []CheckSpec) {
+		for i := range checks {
+			if checks[i].Required == nil {
+				v := true
+				checks[i].Required = &v
+			}
 		}
 	}
-}
-
-func runGenerator(ctx context.Context, level Level) error {
-	gen := level.Filesystem.Dataset.Generator
-	if gen == nil {
-		return nil
-	}
-	if gen.Command == "" {
-		return fmt.Errorf("level %s generator.command is required", level.LevelID)
+	defaultRequired(level.Checks)
+	for _, stage := range level.Stages {
+		defaultRequired(stage.Checks)
 	}
-	cmd := exec.CommandContext(ctx, gen.Command, gen.Args...)
-	cmd.Dir = level.Path
-	cmd.Env = os.Environ()
-	if gen.Seed != nil {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("DOJO_DATASET_SEED=%d", *gen.Seed))
-	}
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("generator failed for level %s: %s", level.LevelID, strings.TrimSpace(string(out)))
-	}
-	return nil
 }
 
 func (l *FSLoader) FindLevel(packs []Pack, packID string, levelID string) (Pack, Level, error) {
@@ -316,75 +485,41 @@ func (l *FSLoader) FindLevel(packs []Pack, packID string, levelID string) (Pack,
 }
 
 func (l *FSLoader) StageWorkdir(level Level, workdir string) error {
-	if err := os.RemoveAll(workdir); err != nil {
+	if err := RemoveWorkdir(workdir); err != nil {
 		return err
 	}
 	if err := os.MkdirAll(workdir, 0o755); err != nil {
 		return err
 	}
 
-	for _, dir := range level.Filesystem.Work.InitialLayout.Mkdirs {
-		target := filepath.Join(workdir, dir)
+	layout := level.Filesystem.Work.InitialLayout
+	for _, dir := range layout.Mkdirs {
+		target, err := stagePath(workdir, dir)
+		if err != nil {
+			return fmt.Errorf("mkdirs: %w", err)
+		}
 		if err := os.MkdirAll(target, 0o755); err != nil {
 			return err
 		}
 	}
-	for _, cp := range level.Filesystem.Work.InitialLayout.CopyFromDataset {
+	s := newStager()
+	for _, cp := range layout.CopyFromDataset {
 		src := filepath.Join(level.DatasetHostPath, cp.From)
-		dst := filepath.Join(workdir, cp.To)
-		if err := copyPath(src, dst); err != nil {
+		dst, err := stagePath(workdir, cp.To)
+		if err == nil {
+			err = s.copyPath(src, dst)
+		}
+		if err != nil {
 			return fmt.Errorf("copy_from_dataset from=%s to=%s: %w", cp.From, cp.To, err)
 		}
 	}
-	return nil
-}
-
-func copyPath(src, dst string) error {
-	info, err := os.Stat(src)
-	if err != nil {
-		return err
+	if err := buildFixtures(level, workdir); err != nil {
+		return fmt.Errorf("initial_layout.%w", err)
 	}
-	if info.IsDir() {
-		if err := os.MkdirAll(dst, info.Mode()); err != nil {
-			return err
+	for _, o := range layout.Overrides {
+		if err := applyOverride(workdir, o); err != nil {
+			return fmt.Errorf("initial_layout override %q: %w", o.Path, err)
 		}
-		return filepath.WalkDir(src, func(path string, d fs.DirEntry, walkErr error) error {
-			if walkErr != nil {
-				return walkErr
-			}
-			rel, err := filepath.Rel(src, path)
-			if err != nil {
-				return err
-			}
-			if rel == "." {
-				return nil
-			}
-			target := filepath.Join(dst, rel)
-			if d.IsDir() {
-				return os.MkdirAll(target, 0o755)
-			}
-			return copyFile(path, target)
-		})
 	}
-	return copyFile(src, dst)
-}
-
-func copyFile(src, dst string) error {
-	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
-		return err
-	}
-	in, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer in.Close()
-	out, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-	if _, err := io.Copy(out, in); err != nil {
-		return err
-	}
-	return out.Chmod(0o644)
+	return nil
 }
@@ -2,7 +2,9 @@ package levels
 
 import (
 	"context"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -36,3 +38,146 @@ func TestBuiltinCorePackLoadsExpectedLevels(t *testing.T) {
 		}
 	}
 }
+
+func writeTestPack(t *testing.T, root, packID, name string) {
+	t.Helper()
+	levelDir := filepath.Join(root, packID, "levels", "level-one")
+	if err := os.MkdirAll(filepath.Join(levelDir, "dataset"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	pack := "kind: pack\nschema_version: 1\npack_id: " + packID + "\nname: " + name + "\nversion: \"0.1.0\"\nimage:\n  ref: img\n"
+	level := `kind: level
+schema_version: 1
+level_id: level-one
+title: "One"
+difficulty: 1
+estimated_minutes: 1
+filesystem:
+  dataset: { source: dir, path: dataset, mount_point: /levels/current }
+  work: { mount_point: /work }
+objective:
+  bullets: ["do it"]
+checks:
+  - { id: c1, type: file_exists, description: "exists", path: /work/out.txt }
+`
+	if err := os.WriteFile(filepath.Join(root, packID, "pack.yaml"), []byte(pack), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(levelDir, "level.yaml"), []byte(level), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadPackRootsFirstRootWinsOnDuplicatePackID(t *testing.T) {
+	userRoot := t.TempDir()
+	systemRoot := t.TempDir()
+	writeTestPack(t, userRoot, "shared-pack", "User Copy")
+	writeTestPack(t, systemRoot, "shared-pack", "System Copy")
+	writeTestPack(t, systemRoot, "system-only", "System Only")
+
+	packs, err := NewLoader().LoadPackRoots(context.Background(), []PackRoot{
+		{Path: userRoot, Source: RootSourceUser},
+		{Path: filepath.Join(userRoot, "missing"), Source: RootSourceEnv},
+		{Path: systemRoot, Source: RootSourceSystem},
+	})
+	if err != nil {
+		t.Fatalf("load roots: %v", err)
+	}
+	if len(packs) != 2 {
+		t.Fatalf("expected 2 packs, got %d", len(packs))
+	}
+	if packs[0].PackID != "shared-pack" || packs[0].Name != "User Copy" || packs[0].Root.Source != RootSourceUser {
+		t.Fatalf("expected user copy to win, got %+v", packs[0].Root)
+	}
+	if len(packs[0].Shadows) != 1 || filepath.Dir(packs[0].Shadows[0]) != systemRoot {
+		t.Fatalf("expected system copy to be recorded as shadowed, got %v", packs[0].Shadows)
+	}
+	if packs[1].PackID != "system-only" || packs[1].Root.Source != RootSourceSystem {
+		t.Fatalf("unexpected second pack %s from %s", packs[1].PackID, packs[1].Root.Source)
+	}
+}
+
+func TestReloadPackKeepsRootAndReportsErrors(t *testing.T) {
+	userRoot := t.TempDir()
+	systemRoot := t.TempDir()
+	writeTestPack(t, userRoot, "shared-pack", "User Copy")
+	writeTestPack(t, systemRoot, "shared-pack", "System Copy")
+	loader := NewLoader()
+	packs, err := loader.LoadPackRoots(context.Background(), []PackRoot{
+		{Path: userRoot, Source: RootSourceUser},
+		{Path: systemRoot, Source: RootSourceSystem},
+	})
+	if err != nil {
+		t.Fatalf("load roots: %v", err)
+	}
+
+	levelYAML := filepath.Join(userRoot, "shared-pack", "levels", "level-one", "level.yaml")
+	src := string(mustRead(t, levelYAML))
+	if err := os.WriteFile(levelYAML, []byte(strings.Replace(src, `title: "One"`, `title: "One, edited"`, 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := loader.ReloadPack(context.Background(), packs, packs[0].Path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got := reloaded[0].LoadedLevels[0].Title; got != "One, edited" {
+		t.Fatalf("expected the edited title, got %q", got)
+	}
+	if reloaded[0].Root.Source != RootSourceUser || len(reloaded[0].Shadows) != 1 {
+		t.Fatalf("reload lost root or shadows: %+v %v", reloaded[0].Root, reloaded[0].Shadows)
+	}
+	if packs[0].LoadedLevels[0].Title != "One" {
+		t.Fatalf("reload modified the input packs")
+	}
+
+	if err := os.WriteFile(levelYAML, []byte("kind: level\nlevel_id: [broken\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loader.ReloadPack(context.Background(), packs, packs[0].Path); err == nil {
+		t.Fatalf("expected a reload error for broken YAML")
+	}
+	if _, err := loader.ReloadPack(context.Background(), packs, filepath.Join(userRoot, "missing")); err == nil {
+		t.Fatalf("expected an error for a pack that is not loaded")
+	}
+}
+
+func TestLoadPackRootsIsolatesBrokenPacksAndLevels(t *testing.T) {
+	root := t.TempDir()
+	writeTestPack(t, root, "good-pack", "Good")
+	writeTestPack(t, root, "broken-pack", "Broken")
+	writeTestPack(t, root, "mixed-pack", "Mixed")
+	if err := os.WriteFile(filepath.Join(root, "broken-pack", "pack.yaml"), []byte("pack_id: [broken\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	badDir := filepath.Join(root, "mixed-pack", "levels", "level-two")
+	if err := os.MkdirAll(badDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(badDir, "level.yaml"), []byte("kind: level\nlevel_id: [broken\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	roots := []PackRoot{{Path: root, Source: RootSourceUser}}
+
+	if _, err := NewLoader().LoadPackRoots(context.Background(), roots); err == nil {
+		t.Fatalf("expected a strict loader to fail")
+	}
+	loader := NewLoader()
+	loader.IsolateErrors = true
+	packs, err := loader.LoadPackRoots(context.Background(), roots)
+	if err != nil {
+		t.Fatalf("load roots: %v", err)
+	}
+	if len(packs) != 3 {
+		t.Fatalf("expected 3 packs, got %d", len(packs))
+	}
+	broken, good, mixed := packs[0], packs[1], packs[2]
+	if broken.PackID != "broken-pack" || broken.LoadError == "" || len(broken.LoadedLevels) != 0 {
+		t.Fatalf("expected a broken placeholder, got %+v", broken)
+	}
+	if len(good.LoadErrors()) != 0 || len(good.LoadedLevels) != 1 {
+		t.Fatalf("good pack affected: %v", good.LoadErrors())
+	}
+	if len(mixed.LoadedLevels) != 1 || len(mixed.BrokenLevels) != 1 || mixed.BrokenLevels[0].LevelID != "level-two" {
+		t.Fatalf("expected level-one loaded and level-two broken, got %d loaded %+v", len(mixed.LoadedLevels), mixed.BrokenLevels)
+	}
+}
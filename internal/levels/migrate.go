@@ -0,0 +1,283 @@
+package levels
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Migration upgrades a document of Kind from schema_version From to From+1.
+// Migrations edit the parsed YAML tree through MigrationDoc so that files can
+// be rewritten with comments, blank lines and key order intact.
+type Migration struct {
+	Kind        string
+	From        int
+	Description string
+	Apply       func(d *MigrationDoc) error
+}
+
+var migrations = map[string]map[int]Migration{}
+
+// RegisterMigration adds m to the registry. Registering two migrations for the
+// same kind and version panics.
+func RegisterMigration(m Migration) {
+	if migrations[m.Kind] == nil {
+		migrations[m.Kind] = map[int]Migration{}
+	}
+	if _, dup := migrations[m.Kind][m.From]; dup {
+		panic(fmt.Sprintf("levels: duplicate %s migration from v%d", m.Kind, m.From))
+	}
+	migrations[m.Kind][m.From] = m
+}
+
+func init() {
+	RegisterMigration(Migration{
+		Kind:        LevelKind,
+		From:        1,
+		Description: "promote x-autocheck, x-progression and x-teaching to autocheck, progression and teaching",
+		Apply: func(d *MigrationDoc) error {
+			for _, key := range []string{"autocheck", "progression", "teaching"} {
+				if err := d.RenameKey("x-"+key, key); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
+
+// LatestSchemaVersion returns the newest schema_version for a document kind.
+func LatestSchemaVersion(kind string) int {
+	switch kind {
+	case PackKind:
+		return PackSchemaVersion
+	case LevelKind:
+		return LevelSchemaVersion
+	}
+	return 0
+}
+
+// MigrationDoc is a top-level YAML mapping being migrated. It records the
+// textual edits that reproduce each change on the original bytes.
+type MigrationDoc struct {
+	root       *yaml.Node
+	edits      []textEdit
+	structural bool
+}
+
+type textEdit struct {
+	line, column int
+	old, new     string
+}
+
+// Root gives direct access to the mapping node. Files migrated through Root
+// are re-encoded on write, which keeps comments but not blank lines.
+func (d *MigrationDoc) Root() *yaml.Node {
+	d.structural = true
+	return d.root
+}
+
+// Lookup returns the value node for a top-level key.
+func (d *MigrationDoc) Lookup(key string) *yaml.Node {
+	if _, v := d.pair(key); v != nil {
+		return v
+	}
+	return nil
+}
+
+// RenameKey renames a top-level key, keeping its value, position and
+// comments. It is a no-op when old is absent and an error when both exist.
+func (d *MigrationDoc) RenameKey(old, new string) error {
+	k, _ := d.pair(old)
+	if k == nil {
+		return nil
+	}
+	if other, _ := d.pair(new); other != nil {
+		return fmt.Errorf("line %d: both %q and %q are set; remove %q", k.Line, old, new, old)
+	}
+	d.edits = append(d.edits, textEdit{line: k.Line, column: k.Column, old: scalarSource(k), new: new})
+	k.Value = new
+	k.Style = 0
+	return nil
+}
+
+// SetScalar replaces the value of an existing top-level scalar key.
+func (d *MigrationDoc) SetScalar(key, value string) error {
+	_, v := d.pair(key)
+	if v == nil || v.Kind != yaml.ScalarNode {
+		return fmt.Errorf("%s is not a scalar", key)
+	}
+	d.edits = append(d.edits, textEdit{line: v.Line, column: v.Column, old: scalarSource(v), new: value})
+	v.Value = value
+	v.Style = 0
+	return nil
+}
+
+func (d *MigrationDoc) pair(key string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(d.root.Content); i += 2 {
+		if d.root.Content[i].Value == key {
+			return d.root.Content[i], d.root.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// scalarSource is how a single-line scalar appears in the file. Styles that
+// cannot be reproduced yield "" and force a re-encode.
+func scalarSource(n *yaml.Node) string {
+	switch n.Style {
+	case 0:
+		return n.Value
+	case yaml.DoubleQuotedStyle:
+		if !strings.ContainsAny(n.Value, "\"\\\n") {
+			return `"` + n.Value + `"`
+		}
+	case yaml.SingleQuotedStyle:
+		if !strings.ContainsAny(n.Value, "'\n") {
+			return "'" + n.Value + "'"
+		}
+	}
+	return ""
+}
+
+// MigrationResult describes what Migrate did to a document.
+type MigrationResult struct {
+	Kind    string
+	From    int
+	To      int
+	Applied []string
+
+	doc *MigrationDoc
+}
+
+// Changed reports whether any migration ran.
+func (r MigrationResult) Changed() bool { return r.From != r.To }
+
+// Migrate upgrades a parsed pack.yaml or level.yaml document to the latest
+// schema_version in place. Documents without a kind or schema_version, or
+// already at (or past) the latest version, are left alone for Validate to
+// judge.
+func Migrate(doc *yaml.Node) (MigrationResult, error) {
+	root := doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
+		return MigrationResult{}, nil
+	}
+	d := &MigrationDoc{root: root}
+	kindNode, versionNode := d.Lookup("kind"), d.Lookup("schema_version")
+	if kindNode == nil || versionNode == nil {
+		return MigrationResult{}, nil
+	}
+	version, err := strconv.Atoi(versionNode.Value)
+	if err != nil {
+		return MigrationResult{}, nil
+	}
+	res := MigrationResult{Kind: kindNode.Value, From: version, To: version, doc: d}
+	latest := LatestSchemaVersion(res.Kind)
+	for res.To < latest {
+		m, ok := migrations[res.Kind][res.To]
+		if !ok {
+			return res, fmt.Errorf("no migration for %s schema_version %d", res.Kind, res.To)
+		}
+		if err := m.Apply(d); err != nil {
+			return res, fmt.Errorf("migrate %s v%d to v%d: %w", res.Kind, res.To, res.To+1, err)
+		}
+		res.To++
+		res.Applied = append(res.Applied, fmt.Sprintf("v%d -> v%d: %s", m.From, res.To, m.Description))
+	}
+	if res.Changed() {
+		if err := d.SetScalar("schema_version", strconv.Itoa(res.To)); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// MigrateFile returns the migrated content of a YAML file. Unchanged files
+// come back byte for byte.
+func MigrateFile(path string) ([]byte, MigrationResult, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, MigrationResult{}, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return nil, MigrationResult{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	res, err := Migrate(&doc)
+	if err != nil {
+		return nil, res, fmt.Errorf("%s: %w", path, err)
+	}
+	if !res.Changed() {
+		return src, res, nil
+	}
+	if !res.doc.structural {
+		if out, ok := applyTextEdits(src, res.doc.edits); ok {
+			return out, res, nil
+		}
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, res, fmt.Errorf("encode %s: %w", path, err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, res, err
+	}
+	return buf.Bytes(), res, nil
+}
+
+// applyTextEdits splices each edit into src at its line and column (both
+// 1-based, columns in characters). ok is false if any edit does not find the
+// text it expects, in which case the caller falls back to re-encoding.
+func applyTextEdits(src []byte, edits []textEdit) ([]byte, bool) {
+	lines := strings.SplitAfter(string(src), "\n")
+	sorted := append([]textEdit(nil), edits...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].line != sorted[j].line {
+			return sorted[i].line > sorted[j].line
+		}
+		return sorted[i].column > sorted[j].column
+	})
+	for _, e := range sorted {
+		if e.old == "" || e.line < 1 || e.line > len(lines) {
+			return nil, false
+		}
+		line := lines[e.line-1]
+		offset := 0
+		for col := 1; col < e.column && offset < len(line); col++ {
+			_, size := utf8.DecodeRuneInString(line[offset:])
+			offset += size
+		}
+		if !strings.HasPrefix(line[offset:], e.old) {
+			return nil, false
+		}
+		lines[e.line-1] = line[:offset] + e.new + line[offset+len(e.old):]
+	}
+	return []byte(strings.Join(lines, "")), true
+}
+
+// decodeMigrated parses YAML, migrates it to the latest schema and decodes it
+// into out.
+func decodeMigrated(b []byte, out any) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	if doc.Kind == 0 {
+		return nil
+	}
+	if _, err := Migrate(&doc); err != nil {
+		return err
+	}
+	return doc.Decode(out)
+}
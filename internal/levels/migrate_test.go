@@ -0,0 +1,100 @@
+package levels
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const levelV1 = `# Level header comment.
+kind: level
+schema_version: 1   # bumped by migrations
+
+level_id: level-migrate
+title: "Migrate me"
+
+# Autocheck keeps its comment.
+"x-autocheck":
+  mode: off
+
+x-progression:
+  tier: 2
+  prerequisites: []   # none yet
+
+x-teaching:
+  concepts: ["yaml"]
+`
+
+func TestMigrateFilePreservesCommentsAndOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "level.yaml")
+	if err := os.WriteFile(path, []byte(levelV1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, res, err := MigrateFile(path)
+	if err != nil {
+		t.Fatalf("MigrateFile: %v", err)
+	}
+	if res.From != 1 || res.To != LevelSchemaVersion || len(res.Applied) != 1 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	want := strings.NewReplacer(
+		"schema_version: 1 ", "schema_version: 2 ",
+		`"x-autocheck":`, "autocheck:",
+		"x-progression:", "progression:",
+		"x-teaching:", "teaching:",
+	).Replace(levelV1)
+	if string(out) != want {
+		t.Fatalf("migrated file differs\n--- got\n%s\n--- want\n%s", out, want)
+	}
+
+	again := filepath.Join(t.TempDir(), "level.yaml")
+	if err := os.WriteFile(again, out, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out2, res2, err := MigrateFile(again)
+	if err != nil || res2.Changed() || string(out2) != string(out) {
+		t.Fatalf("second migration should be a no-op: changed=%v err=%v", res2.Changed(), err)
+	}
+}
+
+func TestMigrateRejectsConflictingKeys(t *testing.T) {
+	var doc yaml.Node
+	src := "kind: level\nschema_version: 1\nx-teaching: {}\nteaching: {}\n"
+	if err := yaml.Unmarshal([]byte(src), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Migrate(&doc); err == nil || !strings.Contains(err.Error(), `"x-teaching" and "teaching"`) {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+}
+
+func TestLoadLevelFileMigratesV1(t *testing.T) {
+	src := strings.Replace(string(mustRead(t, filepath.Join("..", "..", "packs", "builtin-core", "levels", "level-001-pipes-101", "level.yaml"))),
+		"schema_version: 2", "schema_version: 1", 1)
+	for _, key := range []string{"autocheck", "progression", "teaching"} {
+		src = strings.Replace(src, "\n"+key+":", "\nx-"+key+":", 1)
+	}
+	path := filepath.Join(t.TempDir(), "level.yaml")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	level, err := loadLevelFile(path)
+	if err != nil {
+		t.Fatalf("loadLevelFile: %v", err)
+	}
+	if level.SchemaVersion != LevelSchemaVersion || level.Progression.Tier != 1 || len(level.Teaching.Concepts) == 0 {
+		t.Fatalf("v1 fields not migrated: version=%d progression=%+v teaching=%+v", level.SchemaVersion, level.Progression, level.Teaching)
+	}
+}
+
+func mustRead(t *testing.T, path string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
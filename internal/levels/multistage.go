@@ -0,0 +1,103 @@
+package levels
+
+import "fmt"
+
+// AllChecks returns the level's checks followed by those of every stage.
+func (l Level) AllChecks() []CheckSpec {
+	out := append([]CheckSpec(nil), l.Checks...)
+	for _, s := range l.Stages {
+		out = append(out, s.Checks...)
+	}
+	return out
+}
+
+// AllHints returns the level's hints followed by those of every stage.
+func (l Level) AllHints() []HintSpec {
+	out := append([]HintSpec(nil), l.Hints...)
+	for _, s := range l.Stages {
+		out = append(out, s.Hints...)
+	}
+	return out
+}
+
+// AtStage returns the level as it is played during stage idx: the stage's
+// objective, hints and checks replace the level's, base points and time grace
+// are split evenly across stages (the remainder goes to the final one) and
+// cmdlog bonuses are only graded with the final stage. The result has no
+// stages. Levels without stages are returned unchanged.
+func (l Level) AtStage(idx int) Level {
+	n := len(l.Stages)
+	if n == 0 {
+		return l
+	}
+	idx = max(0, min(idx, n-1))
+	s := l.Stages[idx]
+	l.Objective = s.Objective
+	l.Hints = s.Hints
+	l.Checks = s.Checks
+	l.Stages = nil
+	l.Scoring.BasePoints = stageShare(l.Scoring.BasePoints, n, idx)
+	l.Scoring.TimeGraceSeconds = stageShare(l.Scoring.TimeGraceSeconds, n, idx)
+	if idx < n-1 {
+		l.Scoring.CmdlogBonuses = nil
+	}
+	return l
+}
+
+func stageShare(total, n, idx int) int {
+	share := total / n
+	if idx == n-1 {
+		share += total % n
+	}
+	return share
+}
+
+func (l Level) validateStages() error {
+	if len(l.Objective.Bullets) > 0 || len(l.Hints) > 0 || len(l.Checks) > 0 {
+		return fmt.Errorf("stages replace objective, hints and checks; move them into a stage")
+	}
+	seen := map[string]struct{}{}
+	for i, s := range l.Stages {
+		if s.StageID == "" {
+			return fmt.Errorf("stages[%d].stage_id is required", i)
+		}
+		if _, ok := seen[s.StageID]; ok {
+			return fmt.Errorf("duplicate stage_id %q", s.StageID)
+		}
+		seen[s.StageID] = struct{}{}
+		if len(s.Objective.Bullets) == 0 {
+			return fmt.Errorf("stage %q objective.bullets must contain at least one item", s.StageID)
+		}
+		required := 0
+		for _, c := range s.Checks {
+			if c.Required == nil || *c.Required {
+				required++
+			}
+		}
+		if required == 0 {
+			return fmt.Errorf("stage %q must have at least one required check", s.StageID)
+		}
+	}
+	return nil
+}
+
+// specGroup is a list of checks and hints with the document path it is
+// declared at: the level itself, or one of its stages.
+type specGroup struct {
+	path   []any
+	checks []CheckSpec
+	hints  []HintSpec
+}
+
+func (l Level) specGroups() []specGroup {
+	out := []specGroup{{checks: l.Checks, hints: l.Hints}}
+	for i, s := range l.Stages {
+		out = append(out, specGroup{path: []any{"stages", i}, checks: s.Checks, hints: s.Hints})
+	}
+	return out
+}
+
+// at returns the document path of segs inside the group.
+func (g specGroup) at(segs ...any) []any {
+	return append(append([]any(nil), g.path...), segs...)
+}
@@ -0,0 +1,168 @@
+package levels
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const stagedLevelYAML = `kind: level
+schema_version: 2
+level_id: level-one
+title: "Incident"
+difficulty: 2
+estimated_minutes: 5
+filesystem:
+  dataset: { source: dir, path: dataset, mount_point: /levels/current }
+  work: { mount_point: /work }
+scoring:
+  base_points: 1000
+  cmdlog_bonuses:
+    - { id: used_grep, description: "Used grep", pattern: "grep", points: 20 }
+stages:
+  - stage_id: find
+    title: "Find the bad log"
+    objective: { bullets: ["Copy the failing log to /work/bad.log"] }
+    hints:
+      - { hint_id: h1, text_md: "Look for ERROR." }
+    checks:
+      - { id: bad_log, type: file_exists, description: "bad.log exists", path: /work/bad.log }
+  - stage_id: extract
+    objective: { bullets: ["Write its IPs to /work/ips.txt"] }
+    hints:
+      - { hint_id: h2, text_md: "Use grep -o." }
+    checks:
+      - { id: ips, type: file_exists, description: "ips.txt exists", path: /work/ips.txt, on_fail_message: "Write ips.txt." }
+tests:
+  - test_id: nothing
+    script_sh: "true"
+    expect_failed_checks: [bad_log, ips]
+i18n:
+  de:
+    stages:
+      extract: { objective: { bullets: ["Schreibe die IPs nach /work/ips.txt"] } }
+    on_fail_messages: { ips: "Schreibe ips.txt." }
+`
+
+func writeStagedPack(t *testing.T, levelYAML string) string {
+	t.Helper()
+	root := t.TempDir()
+	writeTestPack(t, root, "stage-pack", "Stages")
+	if err := os.WriteFile(filepath.Join(root, "stage-pack", "levels", "level-one", "level.yaml"), []byte(levelYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestStagedLevelLoadsAndSplitsIntoStages(t *testing.T) {
+	root := writeStagedPack(t, stagedLevelYAML)
+	packs, err := NewLoader().LoadPacks(context.Background(), root)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	level := packs[0].LoadedLevels[0]
+	if ids := checkIDs(level.AllChecks()); ids != "bad_log,ips" {
+		t.Fatalf("AllChecks = %s", ids)
+	}
+	if level.Stages[1].Checks[0].Required == nil || !*level.Stages[1].Checks[0].Required {
+		t.Fatalf("stage checks should default to required")
+	}
+
+	first, last := level.AtStage(0), level.AtStage(1)
+	if checkIDs(first.Checks) != "bad_log" || first.Hints[0].HintID != "h1" || len(first.Stages) != 0 {
+		t.Fatalf("stage 0 view: %+v", first)
+	}
+	if checkIDs(last.Checks) != "ips" || last.Objective.Bullets[0] != "Write its IPs to /work/ips.txt" {
+		t.Fatalf("stage 1 view: %+v", last)
+	}
+	if first.Scoring.BasePoints+last.Scoring.BasePoints != 1000 || first.Scoring.BasePoints != 500 {
+		t.Fatalf("base points split %d + %d", first.Scoring.BasePoints, last.Scoring.BasePoints)
+	}
+	if len(first.Scoring.CmdlogBonuses) != 0 || len(last.Scoring.CmdlogBonuses) != 1 {
+		t.Fatalf("cmdlog bonuses belong to the final stage")
+	}
+
+	de := level.Localized("de")
+	if got := de.Stages[1].Objective.Bullets[0]; got != "Schreibe die IPs nach /work/ips.txt" {
+		t.Fatalf("stage objective not localized: %q", got)
+	}
+	if de.Stages[1].Checks[0].OnFailMessage != "Schreibe ips.txt." || level.Stages[1].Checks[0].OnFailMessage != "Write ips.txt." {
+		t.Fatalf("stage on_fail_message localization leaked or was skipped")
+	}
+	if missing := strings.Join(level.untranslated("de"), ","); !strings.Contains(missing, "stages.find.title") || strings.Contains(missing, "stages.extract.objective") {
+		t.Fatalf("untranslated = %s", missing)
+	}
+}
+
+func TestStagedLevelValidation(t *testing.T) {
+	root := writeStagedPack(t, stagedLevelYAML)
+	packs, err := NewLoader().LoadPacks(context.Background(), root)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	base := packs[0].LoadedLevels[0]
+
+	mixed := base
+	mixed.Checks = base.Stages[0].Checks
+	if err := mixed.Validate(); err == nil || !strings.Contains(err.Error(), "stages replace") {
+		t.Fatalf("expected an error for checks next to stages, got %v", err)
+	}
+	dup := base
+	dup.Stages = append([]StageSpec(nil), base.Stages...)
+	dup.Stages[1].Checks = base.Stages[0].Checks
+	if err := dup.Validate(); err == nil || !strings.Contains(err.Error(), "duplicate checks id") {
+		t.Fatalf("expected a duplicate check id across stages, got %v", err)
+	}
+	noRequired := base
+	optional := false
+	noRequired.Stages = append([]StageSpec(nil), base.Stages...)
+	noRequired.Stages[0].Checks = []CheckSpec{{ID: "x", Type: "file_exists", Description: "x", Path: "/work/x", Required: &optional}}
+	if err := noRequired.Validate(); err == nil || !strings.Contains(err.Error(), `stage "find"`) {
+		t.Fatalf("expected a stage without required checks to fail, got %v", err)
+	}
+}
+
+func TestLintReportsStageProblemsWithPositions(t *testing.T) {
+	broken := strings.Replace(stagedLevelYAML, "- { id: ips, type: file_exists", "- { id: bad_log, type: file_exists", 1)
+	broken = strings.Replace(broken, "objective: { bullets: [\"Write its IPs to /work/ips.txt\"] }", "objective: { bullets: [] }", 1)
+	root := writeStagedPack(t, broken)
+
+	diags, err := Lint(root, LintOptions{CheckTypes: []string{"file_exists"}})
+	if err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	want := map[string]int{
+		"duplicate-id": 27, // stages[1].checks[0].id
+		"schema":       23, // stages[1].objective.bullets
+	}
+	for rule, line := range want {
+		found := false
+		for _, d := range diags {
+			found = found || d.Rule == rule && d.Line == line
+		}
+		if !found {
+			t.Errorf("expected %s diagnostic at line %d, got %v", rule, line, diags)
+		}
+	}
+
+	clean := writeStagedPack(t, stagedLevelYAML)
+	diags, err = Lint(clean, LintOptions{CheckTypes: []string{"file_exists"}})
+	if err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			t.Fatalf("unexpected error on a valid staged level: %v", d)
+		}
+	}
+}
+
+func checkIDs(checks []CheckSpec) string {
+	ids := make([]string, 0, len(checks))
+	for _, c := range checks {
+		ids = append(ids, c.ID)
+	}
+	return strings.Join(ids, ",")
+}
@@ -0,0 +1,194 @@
+package levels
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// LevelRecording is a solved session captured by `clidojo level record`: the
+// commands the author ran and the files they left in /work.
+type LevelRecording struct {
+	LevelScaffold
+	Commands []string
+	Files    []RecordedFile
+}
+
+// RecordedFile is one file of a recorded /work tree. Path is slash-separated
+// and relative to the work mount.
+type RecordedFile struct {
+	Path    string
+	Content []byte
+}
+
+// RecordedExpectedDir holds copies of the author's output, under dataset/ and
+// in the staged workdir, for the command_output_equals_file checks.
+const RecordedExpectedDir = ".expected"
+
+// ParseCmdlog returns the commands of a .dojo_cmdlog. The bashrc logs the
+// last history entry at every prompt, so an empty Enter repeats the previous
+// command; repeats are collapsed and a final exit or logout is dropped.
+func ParseCmdlog(body []byte) []string {
+	out := []string{}
+	for _, line := range strings.Split(string(body), "\n") {
+		_, cmd, ok := strings.Cut(line, "\t")
+		if cmd = strings.TrimSpace(cmd); !ok || cmd == "" {
+			continue
+		}
+		if n := len(out); n > 0 && out[n-1] == cmd {
+			continue
+		}
+		out = append(out, cmd)
+	}
+	for n := len(out); n > 0 && (out[n-1] == "exit" || out[n-1] == "logout"); n-- {
+		out = out[:n-1]
+	}
+	return out
+}
+
+// RecordedWorkFile reports whether a work tree file belongs in a draft; the
+// shell's bookkeeping and the expected copies do not.
+func RecordedWorkFile(rel string) bool {
+	switch rel {
+	case ".dojo_cmdlog", ".dojo_bash_history":
+		return false
+	}
+	return rel != RecordedExpectedDir && !strings.HasPrefix(rel, RecordedExpectedDir+"/")
+}
+
+// RenderRecordedLevel renders a draft level.yaml for a recording. Every file
+// gets a file_exists check; text files also get file_lines_count and a
+// command_output_equals_file check against the copy the CLI writes to
+// dataset/RecordedExpectedDir. The commands become reference solution sol1.
+func RenderRecordedLevel(rec LevelRecording) ([]byte, error) {
+	if len(rec.Files) == 0 {
+		return nil, fmt.Errorf("the session left no files in /work")
+	}
+	spec := rec.LevelScaffold
+	if len(spec.Objective) == 0 {
+		for _, f := range rec.Files {
+			spec.Objective = append(spec.Objective, "Create /work/"+f.Path)
+		}
+	}
+	q := strconv.Quote
+	var b bytes.Buffer
+	writeScaffoldHead(&b, spec)
+	fmt.Fprintf(&b, `
+filesystem:
+  dataset:
+    # Files in dataset/ are mounted read-only at mount_point.
+    source: dir
+    path: "dataset"
+    mount_point: "/levels/current"
+    read_only: true
+  work:
+    mount_point: "/work"
+    initial_layout:
+      copy_from_dataset:
+        # The recorded output that content checks compare against.
+        - from: %[1]s
+          to: %[1]s
+`, q(RecordedExpectedDir))
+	writeScaffoldObjective(&b, spec)
+
+	b.WriteString("\nchecks:\n  # Derived from the recorded /work tree; reword and prune as needed.\n")
+	ids := []string{}
+	used := map[string]bool{}
+	for _, f := range rec.Files {
+		slug := strings.Trim(slugUnsafe.ReplaceAllString(strings.ToLower(f.Path), "_"), "_")
+		if slug == "" {
+			slug = "file"
+		}
+		base := slug
+		for i := 2; used[base]; i++ {
+			base = fmt.Sprintf("%s_%d", slug, i)
+		}
+		used[base] = true
+		work := "/work/" + f.Path
+		fmt.Fprintf(&b, `  - id: %s_exists
+    type: file_exists
+    description: %s
+    required: true
+    path: %s
+    on_fail_message: %s
+`, base, q("Create "+work), q(work), q("No "+work+" found."))
+		ids = append(ids, base+"_exists")
+		if !recordedText(f.Content) {
+			continue
+		}
+		if n := recordedLines(f.Content); n > 0 {
+			fmt.Fprintf(&b, `  - id: %s_lines
+    type: file_lines_count
+    description: %s
+    required: true
+    path: %s
+    equals: %d
+    on_fail_message: %s
+`, base, q(fmt.Sprintf("%s has %d lines", work, n)), q(work), n, q(fmt.Sprintf("Expected %d lines in %s.", n, work)))
+			ids = append(ids, base+"_lines")
+		}
+		fmt.Fprintf(&b, `  - id: %s_content
+    type: command_output_equals_file
+    description: %s
+    required: true
+    command: %s
+    compare_to_path: %s
+    timeout_seconds: 3
+    normalize:
+      newlines: any
+      trim_trailing_whitespace: true
+      trim_final_newline: true
+    on_fail_message: %s
+`, base, q(work+" matches the expected output"), q("cat "+shellQuote(work)), q(path.Join("/work", RecordedExpectedDir, f.Path)), q(work+" does not match the expected output."))
+		ids = append(ids, base+"_content")
+	}
+	writeScaffoldScoring(&b)
+
+	b.WriteString(`
+reference_solutions:
+  - solution_id: sol1
+    title: "Recorded solution"
+    script_sh: |
+`)
+	if len(rec.Commands) == 0 {
+		b.WriteString("      # TODO: no commands were recorded.\n      true\n")
+	}
+	for _, cmd := range rec.Commands {
+		fmt.Fprintf(&b, "      %s\n", strings.ReplaceAll(cmd, "\n", "\n      "))
+	}
+	fmt.Fprintf(&b, `    explanation_md: "TODO: explain the solution."
+
+tests:
+  - test_id: no_output
+    description: "Doing nothing fails every required check."
+    script_sh: |
+      true
+    expect_failed_checks: [%s]
+`, strings.Join(ids, ", "))
+	return validScaffold(b.Bytes())
+}
+
+// recordedText reports whether content can be compared as text.
+func recordedText(content []byte) bool {
+	return utf8.Valid(content) && bytes.IndexByte(content, 0) < 0
+}
+
+// recordedLines counts lines the way file_lines_count does: a final line
+// without a newline still counts.
+func recordedLines(content []byte) int {
+	n := bytes.Count(content, []byte("\n"))
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		n++
+	}
+	return n
+}
+
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_./-") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
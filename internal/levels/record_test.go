@@ -0,0 +1,66 @@
+package levels
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseCmdlogCollapsesPromptRepeats(t *testing.T) {
+	body := "100\tsort a.txt > out.txt\n101\tsort a.txt > out.txt\n\ngarbage\n102\twc -l out.txt\n103\texit\n"
+	got := ParseCmdlog([]byte(body))
+	if strings.Join(got, "|") != "sort a.txt > out.txt|wc -l out.txt" {
+		t.Fatalf("unexpected commands %q", got)
+	}
+}
+
+func TestRenderRecordedLevelDerivesChecks(t *testing.T) {
+	src, err := RenderRecordedLevel(LevelRecording{
+		LevelScaffold: LevelScaffold{LevelID: "level-004-rec", Title: "Recorded", Difficulty: 1, ToolFocus: []string{"sort"}},
+		Commands:      []string{"mkdir -p out", "sort /levels/current/a.txt > 'out/my file.txt'"},
+		Files: []RecordedFile{
+			{Path: "out/my file.txt", Content: []byte("a\nb\nc")},
+			{Path: "empty.txt"},
+			{Path: "blob.bin", Content: []byte{0, 1, 2}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	var level Level
+	if err := yaml.Unmarshal(src, &level); err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	for _, c := range level.Checks {
+		ids = append(ids, c.ID)
+		switch c.ID {
+		case "out_my_file_txt_lines":
+			if c.Equals != 3 || c.Path != "/work/out/my file.txt" {
+				t.Fatalf("bad lines check %+v", c)
+			}
+		case "out_my_file_txt_content":
+			if c.Command != "cat '/work/out/my file.txt'" || c.CompareToPath != "/work/.expected/out/my file.txt" {
+				t.Fatalf("bad content check %+v", c)
+			}
+		}
+	}
+	want := "out_my_file_txt_exists,out_my_file_txt_lines,out_my_file_txt_content,empty_txt_exists,empty_txt_content,blob_bin_exists"
+	if strings.Join(ids, ",") != want {
+		t.Fatalf("checks = %s", strings.Join(ids, ","))
+	}
+	if got := level.ReferenceSolutions[0].ScriptSH; got != "mkdir -p out\nsort /levels/current/a.txt > 'out/my file.txt'\n" {
+		t.Fatalf("solution = %q", got)
+	}
+	if cp := level.Filesystem.Work.InitialLayout.CopyFromDataset; len(cp) != 1 || cp[0].From != RecordedExpectedDir {
+		t.Fatalf("expected dir not staged: %+v", cp)
+	}
+	if len(level.Objective.Bullets) != 3 || len(level.Tests[0].ExpectFailedChecks) != len(ids) {
+		t.Fatalf("objective or negative test not derived: %+v %+v", level.Objective, level.Tests)
+	}
+
+	if _, err := RenderRecordedLevel(LevelRecording{LevelScaffold: LevelScaffold{LevelID: "x", Title: "x", Difficulty: 1}}); err == nil {
+		t.Fatal("expected an error for a session without files")
+	}
+}
@@ -0,0 +1,158 @@
+package levels
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	RootSourceFlag    = "flag"
+	RootSourceEnv     = "env"
+	RootSourceUser    = "user"
+	RootSourceSystem  = "system"
+	RootSourceBuiltin = "builtin"
+)
+
+// PackRoot is a directory searched for pack directories.
+type PackRoot struct {
+	Path   string
+	Source string
+}
+
+// LoadPackRoots loads packs from every existing root. Roots earlier in the
+// list take precedence: when two roots provide the same pack_id the first one
+// wins and the later copy is recorded in the winner's Shadows. Cross-pack
+// dependencies are checked once every root is loaded.
+func (l *FSLoader) LoadPackRoots(ctx context.Context, roots []PackRoot) ([]Pack, error) {
+	byID := map[string]int{}
+	seenRoots := map[string]struct{}{}
+	packs := make([]Pack, 0)
+	for _, root := range roots {
+		abs, err := filepath.Abs(root.Path)
+		if err != nil {
+			return nil, fmt.Errorf("resolve pack root %s: %w", root.Path, err)
+		}
+		if _, ok := seenRoots[abs]; ok {
+			continue
+		}
+		seenRoots[abs] = struct{}{}
+		info, err := os.Stat(abs)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		found, err := l.loadPacksFrom(ctx, PackRoot{Path: abs, Source: root.Source})
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range found {
+			if p.LoadError != "" {
+				// A broken copy does not shadow a working one.
+				packs = append(packs, p)
+				continue
+			}
+			if idx, ok := byID[p.PackID]; ok {
+				packs[idx].Shadows = append(packs[idx].Shadows, p.Path)
+				continue
+			}
+			byID[p.PackID] = len(packs)
+			packs = append(packs, p)
+		}
+	}
+	sort.Slice(packs, func(i, j int) bool { return packs[i].PackID < packs[j].PackID })
+	CheckDependencies(packs)
+	return packs, nil
+}
+
+// ReloadPack reloads one pack of a LoadPackRoots result from its dir or
+// archive, keeping its root and shadows, and rechecks dependencies. packs is
+// not modified.
+func (l *FSLoader) ReloadPack(ctx context.Context, packs []Pack, path string) ([]Pack, error) {
+	idx := -1
+	for i := range packs {
+		if packs[i].Path == path || (packs[i].Archive != "" && packs[i].Archive == path) {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return nil, fmt.Errorf("pack %s is not loaded", path)
+	}
+	old := packs[idx]
+	var (
+		fresh Pack
+		err   error
+	)
+	if old.Archive != "" {
+		fresh, err = l.loadArchive(ctx, old.Archive, old.Root)
+	} else {
+		fresh, err = l.loadPack(ctx, old.Path, old.Root)
+	}
+	if err != nil {
+		if !l.IsolateErrors {
+			return nil, err
+		}
+		src := old.Path
+		if old.Archive != "" {
+			src = old.Archive
+		}
+		fresh = brokenPack(src, old.Root, err)
+	}
+	fresh.Shadows = old.Shadows
+	out := append([]Pack(nil), packs...)
+	out[idx] = fresh
+	sort.Slice(out, func(i, j int) bool { return out[i].PackID < out[j].PackID })
+	CheckDependencies(out)
+	return out, nil
+}
+
+// DirSignature summarizes the size and mtime of every file below dir,
+// skipping dot dirs.
+func DirSignature(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	var b strings.Builder
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		b.WriteString(path)
+		b.WriteString(":")
+		b.WriteString(strconv.FormatInt(info.Size(), 10))
+		b.WriteString(":")
+		b.WriteString(strconv.FormatInt(info.ModTime().UnixNano(), 10))
+		b.WriteString(";")
+		return nil
+	})
+	return b.String()
+}
+
+// UserPacksDir is the per-user pack root, $XDG_DATA_HOME/clidojo/packs
+// (~/.local/share/clidojo/packs by default). Installed archives live here.
+func UserPacksDir() string {
+	dataHome := strings.TrimSpace(os.Getenv("XDG_DATA_HOME"))
+	if dataHome == "" || !filepath.IsAbs(dataHome) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "clidojo", "packs")
+}
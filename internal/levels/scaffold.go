@@ -0,0 +1,235 @@
+package levels
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LevelScaffold is what `clidojo level new` asks for; everything else in the
+// generated level.yaml is a placeholder for the author to replace.
+type LevelScaffold struct {
+	LevelID    string
+	Title      string
+	Difficulty int
+	ToolFocus  []string
+	Objective  []string
+}
+
+// ScaffoldOutputPath is the file the stub check and reference solution use.
+const ScaffoldOutputPath = "/work/output.txt"
+
+var (
+	slugUnsafe    = regexp.MustCompile(`[^a-z0-9]+`)
+	numberedLevel = regexp.MustCompile(`^(.*?)(\d+)-`)
+)
+
+// SuggestLevelID proposes a level_id for title that continues the pack's
+// numbering: after level-003-top-ips it suggests level-004-<slug>.
+func SuggestLevelID(refs []PackLevelRef, title string) string {
+	slug := strings.Trim(slugUnsafe.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(slug) > 40 {
+		slug = strings.Trim(slug[:40], "-")
+	}
+	prefix, width, next := "level-", 3, 1
+	for _, ref := range refs {
+		m := numberedLevel.FindStringSubmatch(ref.LevelID)
+		if m == nil {
+			continue
+		}
+		n, err := strconv.Atoi(m[2])
+		if err != nil {
+			continue
+		}
+		if n >= next {
+			prefix, width, next = m[1], len(m[2]), n+1
+		}
+	}
+	id := fmt.Sprintf("%s%0*d", prefix, width, next)
+	if slug != "" {
+		id += "-" + slug
+	}
+	return id
+}
+
+// RenderLevelScaffold renders a level.yaml for spec that passes
+// Level.Validate: a dir dataset, one stub hint, one required file_exists
+// check on ScaffoldOutputPath and a reference solution that satisfies it.
+func RenderLevelScaffold(spec LevelScaffold) ([]byte, error) {
+	if len(spec.Objective) == 0 {
+		return nil, fmt.Errorf("objective needs at least one bullet")
+	}
+	var b bytes.Buffer
+	writeScaffoldHead(&b, spec)
+	b.WriteString(`
+filesystem:
+  dataset:
+    # Files in dataset/ are mounted read-only at mount_point.
+    source: dir
+    path: "dataset"
+    mount_point: "/levels/current"
+    read_only: true
+  work:
+    mount_point: "/work"
+`)
+	writeScaffoldObjective(&b, spec)
+	fmt.Fprintf(&b, `
+checks:
+  # TODO: replace with checks for the objective.
+  - id: out_exists
+    type: file_exists
+    description: "Create %[1]s"
+    required: true
+    path: "%[1]s"
+    on_fail_message: "No %[1]s found."
+`, ScaffoldOutputPath)
+	writeScaffoldScoring(&b)
+	fmt.Fprintf(&b, `
+reference_solutions:
+  - solution_id: sol1
+    title: "Reference solution"
+    script_sh: |
+      # TODO: replace with the canonical solution.
+      touch %s
+    explanation_md: "TODO: explain the solution."
+`, ScaffoldOutputPath)
+	return validScaffold(b.Bytes())
+}
+
+// writeScaffoldHead writes the level metadata of a scaffold: everything
+// above filesystem.
+func writeScaffoldHead(b *bytes.Buffer, spec LevelScaffold) {
+	q := strconv.Quote
+	fmt.Fprintf(b, "kind: level\nschema_version: %d\n\n", LevelSchemaVersion)
+	fmt.Fprintf(b, "level_id: %s\n", spec.LevelID)
+	fmt.Fprintf(b, "title: %s\n", q(spec.Title))
+	b.WriteString("summary_md: \"TODO: one-line summary shown in level select.\"\n")
+	fmt.Fprintf(b, "difficulty: %d\n", spec.Difficulty)
+	fmt.Fprintf(b, "estimated_minutes: %d\n", 5*max(1, spec.Difficulty))
+	tools := make([]string, 0, len(spec.ToolFocus))
+	for _, tool := range spec.ToolFocus {
+		tools = append(tools, q(tool))
+	}
+	fmt.Fprintf(b, "tags: [%s]\n", strings.Join(tools, ", "))
+	fmt.Fprintf(b, "tool_focus: [%s]\n", strings.Join(tools, ", "))
+}
+
+// writeScaffoldObjective writes the objective bullets and a stub hint.
+func writeScaffoldObjective(b *bytes.Buffer, spec LevelScaffold) {
+	b.WriteString(`
+objective:
+  bullets:
+`)
+	for _, bullet := range spec.Objective {
+		fmt.Fprintf(b, "    - %s\n", strconv.Quote(bullet))
+	}
+	b.WriteString(`
+hints:
+  - hint_id: h1
+    text_md: "TODO: a first nudge."
+    unlock: { after_seconds: 0 }
+`)
+}
+
+// writeScaffoldScoring writes the default scoring and progression blocks.
+func writeScaffoldScoring(b *bytes.Buffer) {
+	b.WriteString(`
+scoring:
+  base_points: 1000
+  time_grace_seconds: 60
+  time_penalty_per_second: 1
+  hint_penalty_points: 80
+  reset_penalty_points: 120
+
+progression:
+  tier: 1
+  prerequisites: []
+  mastery:
+    min_score: 700
+`)
+}
+
+// validScaffold checks that a rendered level.yaml decodes and validates.
+func validScaffold(src []byte) ([]byte, error) {
+	var level Level
+	if err := yaml.Unmarshal(src, &level); err != nil {
+		return nil, fmt.Errorf("render level.yaml: %w", err)
+	}
+	if err := level.Validate(); err != nil {
+		return nil, err
+	}
+	return src, nil
+}
+
+// AddPackLevel appends ref to the levels list of a pack.yaml and returns the
+// new file. Like migrations it edits the text, so comments and layout stay.
+func AddPackLevel(src []byte, ref PackLevelRef) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return nil, fmt.Errorf("parse pack.yaml: %w", err)
+	}
+	root := docRoot(&doc)
+	if root == nil || root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("pack.yaml is not a mapping")
+	}
+	lines := strings.SplitAfter(string(src), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if n := len(lines); n > 0 && !strings.HasSuffix(lines[n-1], "\n") {
+		lines[n-1] += "\n"
+	}
+	item := func(indent int) string {
+		pad := strings.Repeat(" ", indent)
+		return fmt.Sprintf("%s- level_id: %s\n%s  path: %s\n%s  enabled: true\n", pad, ref.LevelID, pad, strconv.Quote(ref.Path), pad)
+	}
+	insert := func(after int, text string) []byte {
+		out := append([]string{}, lines[:after]...)
+		out = append(out, text)
+		out = append(out, lines[after:]...)
+		return []byte(strings.Join(out, ""))
+	}
+
+	var key, seq *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "levels" {
+			key, seq = root.Content[i], root.Content[i+1]
+		}
+	}
+	switch {
+	case key == nil:
+		return insert(len(lines), "\nlevels:\n"+item(2)), nil
+	case seq.Kind == yaml.SequenceNode && len(seq.Content) > 0 && seq.Style&yaml.FlowStyle == 0:
+		for _, existing := range seq.Content {
+			var r PackLevelRef
+			if err := existing.Decode(&r); err == nil && r.LevelID == ref.LevelID {
+				return nil, fmt.Errorf("pack.yaml already lists level %s", ref.LevelID)
+			}
+		}
+		last := seq.Content[len(seq.Content)-1]
+		return insert(lastLine(last), item(last.Column-3)), nil
+	case (seq.Kind == yaml.SequenceNode && len(seq.Content) == 0) || seq.Tag == "!!null":
+		// `levels: []` or `levels:` with nothing under it.
+		line := key.Line - 1
+		lines[line] = lines[line][:key.Column-1] + "levels:\n"
+		return insert(key.Line, item(key.Column+1)), nil
+	default:
+		return nil, fmt.Errorf("pack.yaml levels is not a block list; add %s by hand", ref.LevelID)
+	}
+}
+
+// lastLine returns the last source line of n and its children.
+func lastLine(n *yaml.Node) int {
+	line := n.Line
+	if n.Kind == yaml.ScalarNode && (n.Style&(yaml.LiteralStyle|yaml.FoldedStyle)) != 0 {
+		line += strings.Count(strings.TrimSuffix(n.Value, "\n"), "\n") + 1
+	}
+	for _, c := range n.Content {
+		line = max(line, lastLine(c))
+	}
+	return line
+}
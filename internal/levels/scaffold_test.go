@@ -0,0 +1,76 @@
+package levels
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSuggestLevelIDContinuesNumbering(t *testing.T) {
+	refs := []PackLevelRef{{LevelID: "level-001-pipes-101"}, {LevelID: "level-003-top-ips"}, {LevelID: "bonus"}}
+	if got := SuggestLevelID(refs, "Find the Big Files!"); got != "level-004-find-the-big-files" {
+		t.Fatalf("unexpected id %q", got)
+	}
+	if got := SuggestLevelID([]PackLevelRef{{LevelID: "ir-09-triage"}}, "Ünïcode"); got != "ir-10-n-code" {
+		t.Fatalf("unexpected id %q", got)
+	}
+	if got := SuggestLevelID(nil, "???"); got != "level-001" {
+		t.Fatalf("unexpected id %q", got)
+	}
+}
+
+func TestRenderLevelScaffoldValidates(t *testing.T) {
+	src, err := RenderLevelScaffold(LevelScaffold{
+		LevelID:    "level-004-quotes",
+		Title:      `Say "hi": a #1 level`,
+		Difficulty: 2,
+		ToolFocus:  []string{"awk", "sort"},
+		Objective:  []string{"Print: the \"top\" line", "Write it to /work/output.txt"},
+	})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	var level Level
+	if err := yaml.Unmarshal(src, &level); err != nil {
+		t.Fatal(err)
+	}
+	if level.Title != `Say "hi": a #1 level` || level.Objective.Bullets[0] != `Print: the "top" line` || strings.Join(level.ToolFocus, ",") != "awk,sort" {
+		t.Fatalf("text did not round-trip: %+v", level)
+	}
+	if len(level.ReferenceSolutions) != 1 || !strings.Contains(level.ReferenceSolutions[0].ScriptSH, ScaffoldOutputPath) {
+		t.Fatalf("expected a stub reference solution")
+	}
+	if _, err := RenderLevelScaffold(LevelScaffold{LevelID: "Bad ID", Title: "x", Difficulty: 1, Objective: []string{"o"}}); err == nil {
+		t.Fatalf("expected an invalid level_id to be rejected")
+	}
+}
+
+func TestAddPackLevelKeepsLayout(t *testing.T) {
+	ref := PackLevelRef{LevelID: "level-002-new", Path: "levels/level-002-new"}
+	block := "pack_id: p1\n# the levels\nlevels:\n    - level_id: level-001-old\n      path: \"levels/level-001-old\"\n      enabled: true\n\n# trailing\nextensions: {}\n"
+	cases := map[string]struct{ src, want string }{
+		"block": {block, "      enabled: true\n    - level_id: level-002-new\n      path: \"levels/level-002-new\"\n      enabled: true\n\n# trailing\n"},
+		"empty": {"pack_id: p1\nlevels: []\nextensions: {}", "levels:\n  - level_id: level-002-new\n    path: \"levels/level-002-new\"\n    enabled: true\nextensions: {}\n"},
+		"none":  {"pack_id: p1\n", "pack_id: p1\n\nlevels:\n  - level_id: level-002-new\n"},
+	}
+	for name, tc := range cases {
+		out, err := AddPackLevel([]byte(tc.src), ref)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !strings.Contains(string(out), tc.want) {
+			t.Fatalf("%s: expected %q in\n%s", name, tc.want, out)
+		}
+		var pack Pack
+		if err := yaml.Unmarshal(out, &pack); err != nil || pack.Levels[len(pack.Levels)-1].Path != ref.Path {
+			t.Fatalf("%s: levels did not parse: %+v %v", name, pack.Levels, err)
+		}
+	}
+	if _, err := AddPackLevel([]byte(block), PackLevelRef{LevelID: "level-001-old"}); err == nil {
+		t.Fatalf("expected a duplicate level to be rejected")
+	}
+	if _, err := AddPackLevel([]byte("levels: [{level_id: a}]\n"), ref); err == nil {
+		t.Fatalf("expected flow lists to be rejected")
+	}
+}
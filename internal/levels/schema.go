@@ -3,12 +3,17 @@ package levels
 import (
 	"fmt"
 	"regexp"
+
+	"gopkg.in/yaml.v3"
 )
 
 const (
-	PackKind               = "pack"
-	LevelKind              = "level"
-	SupportedSchemaVersion = 1
+	PackKind  = "pack"
+	LevelKind = "level"
+	// PackSchemaVersion and LevelSchemaVersion are the latest document
+	// versions. Older documents are upgraded in memory by Migrate.
+	PackSchemaVersion  = 1
+	LevelSchemaVersion = 2
 )
 
 var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{2,63}$`)
@@ -24,10 +29,45 @@ type Pack struct {
 	Defaults      PackDefaults   `yaml:"defaults"`
 	Tools         []PackTool     `yaml:"tools"`
 	Levels        []PackLevelRef `yaml:"levels"`
-	Extensions    map[string]any `yaml:"extensions"`
-
-	Path         string  `yaml:"-"`
-	LoadedLevels []Level `yaml:"-"`
+	Requires      []PackRequire  `yaml:"requires"`
+	// Locales lists the translations every level should provide; pack lint
+	// warns about untranslated keys.
+	Locales    []string       `yaml:"locales"`
+	Extensions map[string]any `yaml:"extensions"`
+
+	Path         string    `yaml:"-"`
+	Archive      string    `yaml:"-"`
+	Root         PackRoot  `yaml:"-"`
+	Shadows      []string  `yaml:"-"`
+	Trust        PackTrust `yaml:"-"`
+	LoadedLevels []Level   `yaml:"-"`
+	// DependencyErrors lists requires entries that no loaded pack satisfies,
+	// found by CheckDependencies. Campaign locks every level of such a pack.
+	DependencyErrors []string `yaml:"-"`
+	// LoadError is set on placeholder packs that failed to load, and
+	// BrokenLevels lists levels skipped for errors. Both are only filled when
+	// the loader isolates errors.
+	LoadError    string        `yaml:"-"`
+	BrokenLevels []BrokenLevel `yaml:"-"`
+}
+
+// BrokenLevel is a level that failed to load or validate.
+type BrokenLevel struct {
+	LevelID string
+	Path    string
+	Error   string
+}
+
+// LoadErrors describes why the pack or any of its levels failed to load.
+func (p Pack) LoadErrors() []string {
+	out := []string{}
+	if p.LoadError != "" {
+		out = append(out, p.LoadError)
+	}
+	for _, b := range p.BrokenLevels {
+		out = append(out, fmt.Sprintf("level %s: %s", b.LevelID, b.Error))
+	}
+	return out
 }
 
 type PackImage struct {
@@ -83,6 +123,12 @@ type PackTool struct {
 	DifficultyBias int    `yaml:"difficulty_bias"`
 }
 
+// PackRequire declares that a pack builds on another pack.
+type PackRequire struct {
+	PackID            string `yaml:"pack_id"`
+	VersionConstraint string `yaml:"version_constraint"`
+}
+
 type PackLevelRef struct {
 	LevelID string `yaml:"level_id"`
 	Path    string `yaml:"path"`
@@ -90,33 +136,56 @@ type PackLevelRef struct {
 }
 
 type Level struct {
-	Kind               string               `yaml:"kind"`
-	SchemaVersion      int                  `yaml:"schema_version"`
-	LevelID            string               `yaml:"level_id"`
-	Title              string               `yaml:"title"`
-	SummaryMD          string               `yaml:"summary_md"`
-	DescriptionMD      string               `yaml:"description_md"`
-	Difficulty         int                  `yaml:"difficulty"`
-	EstimatedMinutes   int                  `yaml:"estimated_minutes"`
-	Tags               []string             `yaml:"tags"`
-	ToolFocus          []string             `yaml:"tool_focus"`
-	Image              ImageOverride        `yaml:"image"`
-	Shell              ShellSpec            `yaml:"shell"`
-	Sandbox            SandboxSpec          `yaml:"sandbox"`
-	Filesystem         FilesystemSpec       `yaml:"filesystem"`
-	Objective          ObjectiveSpec        `yaml:"objective"`
-	Hints              []HintSpec           `yaml:"hints"`
-	Checks             []CheckSpec          `yaml:"checks"`
-	Scoring            ScoringSpec          `yaml:"scoring"`
-	ReferenceSolutions []ReferenceSolution  `yaml:"reference_solutions"`
-	UI                 UISpec               `yaml:"ui"`
-	XAutoCheck         AutoCheckExtension   `yaml:"x-autocheck"`
-	XProgression       ProgressionExtension `yaml:"x-progression"`
-	XTeaching          TeachingExtension    `yaml:"x-teaching"`
-	Extensions         map[string]any       `yaml:"extensions"`
+	Kind             string         `yaml:"kind"`
+	SchemaVersion    int            `yaml:"schema_version"`
+	LevelID          string         `yaml:"level_id"`
+	Title            string         `yaml:"title"`
+	SummaryMD        string         `yaml:"summary_md"`
+	DescriptionMD    string         `yaml:"description_md"`
+	Difficulty       int            `yaml:"difficulty"`
+	EstimatedMinutes int            `yaml:"estimated_minutes"`
+	Tags             []string       `yaml:"tags"`
+	ToolFocus        []string       `yaml:"tool_focus"`
+	Image            ImageOverride  `yaml:"image"`
+	Shell            ShellSpec      `yaml:"shell"`
+	Sandbox          SandboxSpec    `yaml:"sandbox"`
+	Filesystem       FilesystemSpec `yaml:"filesystem"`
+	Services         []ServiceSpec  `yaml:"services"`
+	Setup            HooksSpec      `yaml:"setup"`
+	Teardown         HooksSpec      `yaml:"teardown"`
+	Objective        ObjectiveSpec  `yaml:"objective"`
+	Hints            []HintSpec     `yaml:"hints"`
+	Checks           []CheckSpec    `yaml:"checks"`
+	// Stages, when set, replace Objective, Hints and Checks with an ordered
+	// list of steps played in one sandbox.
+	Stages             []StageSpec         `yaml:"stages"`
+	Scoring            ScoringSpec         `yaml:"scoring"`
+	ReferenceSolutions []ReferenceSolution `yaml:"reference_solutions"`
+	Tests              []LevelTest         `yaml:"tests"`
+	UI                 UISpec              `yaml:"ui"`
+	AutoCheck          AutoCheckSpec       `yaml:"autocheck"`
+	Progression        ProgressionSpec     `yaml:"progression"`
+	Teaching           TeachingSpec        `yaml:"teaching"`
+	Variants           []VariantSpec       `yaml:"variants"`
+	// I18n holds per-locale text overrides, keyed by locale (de, pt_BR).
+	I18n       map[string]LevelLocale `yaml:"i18n"`
+	Extensions map[string]any         `yaml:"extensions"`
 
 	Path            string `yaml:"-"`
 	DatasetHostPath string `yaml:"-"`
+	// Variant is the applied variant_id, empty for levels without variants.
+	Variant string `yaml:"-"`
+	// RunSeed is the seed of the current run for seed: per_run levels.
+	RunSeed *int64 `yaml:"-"`
+	// Restricted is copied from the pack's trust: nothing of the level may
+	// run on the host.
+	Restricted bool `yaml:"-"`
+	// DependencyErrors lists prerequisites of this level that point at
+	// missing levels or at packs the pack does not require. Only the level
+	// is affected; see Pack.DependencyErrors for the pack's requires.
+	DependencyErrors []string `yaml:"-"`
+
+	source *yaml.Node
 }
 
 type ImageOverride struct {
@@ -137,9 +206,32 @@ type DatasetSpec struct {
 }
 
 type GeneratorSpec struct {
-	Command string   `yaml:"command"`
-	Args    []string `yaml:"args"`
-	Seed    *int64   `yaml:"seed"`
+	Command string       `yaml:"command"`
+	Args    []string     `yaml:"args"`
+	Seed    *DatasetSeed `yaml:"seed"`
+}
+
+// SeedPerRun is the generator seed that gives every level run its own
+// dataset.
+const SeedPerRun = "per_run"
+
+// DatasetSeed is a fixed generator seed, or a fresh one per run when PerRun.
+type DatasetSeed struct {
+	Value  int64
+	PerRun bool
+}
+
+func (s *DatasetSeed) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode && n.Value == SeedPerRun {
+		*s = DatasetSeed{PerRun: true}
+		return nil
+	}
+	var v int64
+	if err := n.Decode(&v); err != nil {
+		return fmt.Errorf("line %d: seed must be an integer or %q", n.Line, SeedPerRun)
+	}
+	*s = DatasetSeed{Value: v}
+	return nil
 }
 
 type WorkSpec struct {
@@ -148,8 +240,14 @@ type WorkSpec struct {
 }
 
 type InitialLayout struct {
-	Mkdirs          []string      `yaml:"mkdirs"`
-	CopyFromDataset []CopyMapping `yaml:"copy_from_dataset"`
+	Mkdirs          []string           `yaml:"mkdirs"`
+	CopyFromDataset []CopyMapping      `yaml:"copy_from_dataset"`
+	WriteFiles      []WriteFileSpec    `yaml:"write_files"`
+	GenerateFile    []GenerateFileSpec `yaml:"generate_file"`
+	SparseFile      []SparseFileSpec   `yaml:"sparse_file"`
+	GitRepo         []GitRepoSpec      `yaml:"git_repo"`
+	Archive         []ArchiveSpec      `yaml:"archive"`
+	Overrides       []PathOverride     `yaml:"overrides"`
 }
 
 type CopyMapping struct {
@@ -157,11 +255,93 @@ type CopyMapping struct {
 	To   string `yaml:"to"`
 }
 
+// PathOverride adjusts one workdir path after the copies, in order. Content
+// writes a regular file and SymlinkTo a symlink, which also stages names a
+// pack cannot hold, such as ones with newlines.
+type PathOverride struct {
+	Path      string  `yaml:"path"`
+	Content   *string `yaml:"content"`
+	SymlinkTo string  `yaml:"symlink_to"`
+	Mode      string  `yaml:"mode"`
+	Mtime     string  `yaml:"mtime"`
+}
+
+func (o PathOverride) validate() error {
+	if o.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if !workRelative(o.Path) {
+		return fmt.Errorf("path must stay inside the workdir")
+	}
+	if o.SymlinkTo != "" && o.Content != nil {
+		return fmt.Errorf("content and symlink_to are exclusive")
+	}
+	if o.SymlinkTo != "" && (o.Mode != "" || o.Mtime != "") {
+		return fmt.Errorf("mode and mtime cannot be set on a symlink")
+	}
+	if o.Mode != "" {
+		if _, err := ParseFileMode(o.Mode); err != nil {
+			return err
+		}
+	}
+	if o.Mtime != "" {
+		if _, err := ParseMtime(o.Mtime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServiceSpec is a sidecar container started next to the level container on
+// a private network without internet access. The level reaches it by
+// Hostname, which defaults to Name. Services start without capabilities;
+// CapAdd grants back the ones the image needs.
+type ServiceSpec struct {
+	Name        string            `yaml:"name"`
+	Image       string            `yaml:"image"`
+	Command     []string          `yaml:"command"`
+	Hostname    string            `yaml:"hostname"`
+	Env         map[string]string `yaml:"env"`
+	CapAdd      []string          `yaml:"cap_add"`
+	Healthcheck *HealthcheckSpec  `yaml:"healthcheck"`
+}
+
+// HealthcheckSpec is a shell command run in a service container until it
+// succeeds; the level starts only once every service is healthy.
+type HealthcheckSpec struct {
+	Command         string `yaml:"command"`
+	IntervalSeconds int    `yaml:"interval_seconds"`
+	Retries         int    `yaml:"retries"`
+}
+
+// HooksSpec lists a level's setup or teardown scripts.
+type HooksSpec struct {
+	// InContainer scripts run in the level container, in order: setup ones
+	// before the shell opens, teardown ones before the container is removed.
+	InContainer []HookSpec `yaml:"in_container"`
+}
+
+type HookSpec struct {
+	HookID         string `yaml:"hook_id"`
+	ScriptSH       string `yaml:"script_sh"`
+	TimeoutSeconds int    `yaml:"timeout_seconds"`
+}
+
 type ObjectiveSpec struct {
 	Bullets       []string `yaml:"bullets"`
 	SuccessHintMD string   `yaml:"success_hint_md"`
 }
 
+// StageSpec is one step of a multi-stage level. Passing its required checks
+// reveals the next stage.
+type StageSpec struct {
+	StageID   string        `yaml:"stage_id"`
+	Title     string        `yaml:"title"`
+	Objective ObjectiveSpec `yaml:"objective"`
+	Hints     []HintSpec    `yaml:"hints"`
+	Checks    []CheckSpec   `yaml:"checks"`
+}
+
 type HintSpec struct {
 	HintID     string     `yaml:"hint_id"`
 	TextMD     string     `yaml:"text_md"`
@@ -184,8 +364,9 @@ type CheckSpec struct {
 	OnFailMessage string `yaml:"on_fail_message"`
 	OnPassMessage string `yaml:"on_pass_message"`
 
-	Path      string        `yaml:"path"`
-	Expected  string        `yaml:"expected"`
+	Path string `yaml:"path"`
+	// Expected is nil when unset, so checks can expect an empty value.
+	Expected  *string       `yaml:"expected"`
 	Normalize NormalizeSpec `yaml:"normalize"`
 
 	Equals int  `yaml:"equals"`
@@ -208,6 +389,73 @@ type CheckSpec struct {
 	TimeoutSeconds int    `yaml:"timeout_seconds"`
 
 	MinCount int `yaml:"min_count"`
+
+	// Query, ValueType and Count are file_json_path assertions; Format
+	// picks json or yaml for the structured-data checks (default: by file
+	// extension).
+	Query            string  `yaml:"query"`
+	ValueType        string  `yaml:"value_type"`
+	Count            *int    `yaml:"count"`
+	Format           string  `yaml:"format"`
+	IgnoreArrayOrder bool    `yaml:"ignore_array_order"`
+	CSV              CSVSpec `yaml:"csv"`
+
+	// Perm, PermSet and PermUnset are octal file_mode assertions: the exact
+	// permission bits, bits that must be set and bits that must be clear.
+	Perm      string `yaml:"perm"`
+	PermSet   string `yaml:"perm_set"`
+	PermUnset string `yaml:"perm_unset"`
+	Owner     string `yaml:"owner"`
+	Group     string `yaml:"group"`
+	FileType  string `yaml:"file_type"`
+	// MtimeAfter and MtimeBefore bound file_mtime, inclusive; see
+	// ParseCheckTime.
+	MtimeAfter  string `yaml:"mtime_after"`
+	MtimeBefore string `yaml:"mtime_before"`
+
+	// Service runs the command of a command_output_equals_file check in the
+	// named service container instead of the level container.
+	Service string `yaml:"service"`
+}
+
+// CSVSpec configures file_csv_equals. Header defaults to true: the first row
+// names the columns, which are then matched by name rather than position.
+type CSVSpec struct {
+	Delimiter      string `yaml:"delimiter"`
+	Header         *bool  `yaml:"header"`
+	IgnoreRowOrder bool   `yaml:"ignore_row_order"`
+	TrimSpace      bool   `yaml:"trim_space"`
+}
+
+// validateStructured checks the fields of the structured-data check types.
+func (c CheckSpec) validateStructured() error {
+	switch c.Format {
+	case "", "json", "yaml":
+	default:
+		return fmt.Errorf("format must be json or yaml")
+	}
+	switch c.Type {
+	case "file_json_path":
+		if c.Query == "" {
+			return fmt.Errorf("file_json_path needs a query")
+		}
+		if c.Expected == nil && c.ValueType == "" && c.Count == nil {
+			return fmt.Errorf("file_json_path needs expected, value_type or count")
+		}
+		switch c.ValueType {
+		case "", "object", "array", "string", "number", "boolean", "null":
+		default:
+			return fmt.Errorf("invalid value_type %q", c.ValueType)
+		}
+	case "file_json_equals", "file_csv_equals":
+		if (c.Expected == nil) == (c.CompareToPath == "") {
+			return fmt.Errorf("%s needs exactly one of expected and compare_to_path", c.Type)
+		}
+		if len([]rune(c.CSV.Delimiter)) > 1 {
+			return fmt.Errorf("csv.delimiter must be a single character")
+		}
+	}
+	return nil
 }
 
 type NormalizeSpec struct {
@@ -245,14 +493,25 @@ type ReferenceSolution struct {
 	Tags          []string `yaml:"tags"`
 }
 
-type AutoCheckExtension struct {
+// LevelTest is a negative fixture for `pack test`: a script that must leave
+// the level failing with exactly the listed checks.
+type LevelTest struct {
+	TestID             string   `yaml:"test_id"`
+	Description        string   `yaml:"description"`
+	ScriptSH           string   `yaml:"script_sh"`
+	ExpectFailedChecks []string `yaml:"expect_failed_checks"`
+}
+
+type AutoCheckSpec struct {
 	Mode       string `yaml:"mode"`
 	DebounceMS int    `yaml:"debounce_ms"`
 	QuietFail  *bool  `yaml:"quiet_fail"`
 }
 
-type ProgressionExtension struct {
-	Tier          int                `yaml:"tier"`
+type ProgressionSpec struct {
+	Tier int `yaml:"tier"`
+	// Prerequisites are level IDs that must be passed first. A qualified
+	// pack_id/level_id in another pack must be mastered instead.
 	Prerequisites []string           `yaml:"prerequisites"`
 	Mastery       ProgressionMastery `yaml:"mastery"`
 }
@@ -263,7 +522,7 @@ type ProgressionMastery struct {
 	MaxResets int `yaml:"max_resets"`
 }
 
-type TeachingExtension struct {
+type TeachingSpec struct {
 	Concepts   []string `yaml:"concepts"`
 	ReviewDays []int    `yaml:"review_days"`
 }
@@ -275,8 +534,8 @@ func (p Pack) Validate() error {
 	if p.SchemaVersion == 0 {
 		return fmt.Errorf("schema_version is required")
 	}
-	if p.SchemaVersion > SupportedSchemaVersion {
-		return fmt.Errorf("unsupported pack schema_version %d (max supported %d)", p.SchemaVersion, SupportedSchemaVersion)
+	if p.SchemaVersion > PackSchemaVersion {
+		return fmt.Errorf("unsupported pack schema_version %d (max supported %d)", p.SchemaVersion, PackSchemaVersion)
 	}
 	if !idPattern.MatchString(p.PackID) {
 		return fmt.Errorf("invalid pack_id %q", p.PackID)
@@ -300,6 +559,29 @@ func (p Pack) Validate() error {
 		}
 		seen[l.LevelID] = struct{}{}
 	}
+	required := map[string]struct{}{}
+	for _, r := range p.Requires {
+		if !idPattern.MatchString(r.PackID) {
+			return fmt.Errorf("requires: invalid pack_id %q", r.PackID)
+		}
+		if r.PackID == p.PackID {
+			return fmt.Errorf("requires: pack %q cannot require itself", r.PackID)
+		}
+		if _, ok := required[r.PackID]; ok {
+			return fmt.Errorf("requires: duplicate pack_id %q", r.PackID)
+		}
+		required[r.PackID] = struct{}{}
+		if r.VersionConstraint != "" {
+			if _, err := ParseConstraint(r.VersionConstraint); err != nil {
+				return fmt.Errorf("requires %s: %w", r.PackID, err)
+			}
+		}
+	}
+	for _, locale := range p.Locales {
+		if !localePattern.MatchString(locale) {
+			return fmt.Errorf("locales: invalid locale %q (want e.g. de or pt_BR)", locale)
+		}
+	}
 	return nil
 }
 
@@ -310,8 +592,8 @@ func (l Level) Validate() error {
 	if l.SchemaVersion == 0 {
 		return fmt.Errorf("schema_version is required")
 	}
-	if l.SchemaVersion > SupportedSchemaVersion {
-		return fmt.Errorf("unsupported level schema_version %d (max supported %d)", l.SchemaVersion, SupportedSchemaVersion)
+	if l.SchemaVersion > LevelSchemaVersion {
+		return fmt.Errorf("unsupported level schema_version %d (max supported %d)", l.SchemaVersion, LevelSchemaVersion)
 	}
 	if !idPattern.MatchString(l.LevelID) {
 		return fmt.Errorf("invalid level_id %q", l.LevelID)
@@ -340,11 +622,43 @@ func (l Level) Validate() error {
 	if l.Filesystem.Dataset.MountPoint[0] != '/' || l.Filesystem.Work.MountPoint[0] != '/' {
 		return fmt.Errorf("filesystem mount points must start with /")
 	}
-	if len(l.Objective.Bullets) == 0 {
+	layout := l.Filesystem.Work.InitialLayout
+	for _, dir := range layout.Mkdirs {
+		if !workRelative(dir) {
+			return fmt.Errorf("initial_layout.mkdirs %q must stay inside the workdir", dir)
+		}
+	}
+	for _, cp := range layout.CopyFromDataset {
+		if !workRelative(cp.To) {
+			return fmt.Errorf("initial_layout.copy_from_dataset to %q must stay inside the workdir", cp.To)
+		}
+	}
+	if err := layout.validateFixtures(); err != nil {
+		return fmt.Errorf("initial_layout.%w", err)
+	}
+	for _, o := range layout.Overrides {
+		if err := o.validate(); err != nil {
+			return fmt.Errorf("initial_layout.overrides %q: %w", o.Path, err)
+		}
+	}
+	if err := l.validateServices(); err != nil {
+		return err
+	}
+	if err := l.Setup.validate(); err != nil {
+		return fmt.Errorf("setup.%w", err)
+	}
+	if err := l.Teardown.validate(); err != nil {
+		return fmt.Errorf("teardown.%w", err)
+	}
+	if len(l.Stages) > 0 {
+		if err := l.validateStages(); err != nil {
+			return err
+		}
+	} else if len(l.Objective.Bullets) == 0 {
 		return fmt.Errorf("objective.bullets must contain at least one item")
 	}
 	seenHints := map[string]struct{}{}
-	for _, h := range l.Hints {
+	for _, h := range l.AllHints() {
 		if h.HintID == "" {
 			return fmt.Errorf("hints[].hint_id is required")
 		}
@@ -355,7 +669,7 @@ func (l Level) Validate() error {
 	}
 	seenChecks := map[string]struct{}{}
 	requiredCount := 0
-	for _, c := range l.Checks {
+	for _, c := range l.AllChecks() {
 		if c.ID == "" {
 			return fmt.Errorf("checks[].id is required")
 		}
@@ -373,25 +687,81 @@ func (l Level) Validate() error {
 		if c.CompareToPath != "" && c.CompareToPath[0] != '/' {
 			return fmt.Errorf("check %q compare_to_path must start with /", c.ID)
 		}
+		if err := c.validateStructured(); err != nil {
+			return fmt.Errorf("check %q: %w", c.ID, err)
+		}
+		if err := c.validateFileMeta(); err != nil {
+			return fmt.Errorf("check %q: %w", c.ID, err)
+		}
+		if c.Service != "" {
+			if c.Type != "command_output_equals_file" {
+				return fmt.Errorf("check %q: service is only supported by command_output_equals_file checks", c.ID)
+			}
+			if !l.hasService(c.Service) {
+				return fmt.Errorf("check %q targets unknown service %q", c.ID, c.Service)
+			}
+		}
 	}
 	if requiredCount == 0 {
 		return fmt.Errorf("level must have at least one required check")
 	}
-	switch l.XAutoCheck.Mode {
+	for _, b := range l.Scoring.CmdlogBonuses {
+		seenChecks[b.ID] = struct{}{}
+	}
+	seenTests := map[string]struct{}{}
+	for _, t := range l.Tests {
+		if t.TestID == "" {
+			return fmt.Errorf("tests[].test_id is required")
+		}
+		if _, ok := seenTests[t.TestID]; ok {
+			return fmt.Errorf("duplicate tests test_id %q", t.TestID)
+		}
+		seenTests[t.TestID] = struct{}{}
+		if t.ScriptSH == "" {
+			return fmt.Errorf("test %q script_sh is required", t.TestID)
+		}
+		if len(t.ExpectFailedChecks) == 0 {
+			return fmt.Errorf("test %q expect_failed_checks must list at least one check id", t.TestID)
+		}
+		for _, id := range t.ExpectFailedChecks {
+			if _, ok := seenChecks[id]; !ok {
+				return fmt.Errorf("test %q expects unknown check %q to fail", t.TestID, id)
+			}
+		}
+	}
+	switch l.AutoCheck.Mode {
 	case "", "off", "command_debounce", "command_and_fs_debounce":
 	default:
-		return fmt.Errorf("invalid x-autocheck.mode %q", l.XAutoCheck.Mode)
+		return fmt.Errorf("invalid autocheck.mode %q", l.AutoCheck.Mode)
+	}
+	if l.AutoCheck.DebounceMS < 0 {
+		return fmt.Errorf("autocheck.debounce_ms must be >= 0")
 	}
-	if l.XAutoCheck.DebounceMS < 0 {
-		return fmt.Errorf("x-autocheck.debounce_ms must be >= 0")
+	if l.Progression.Tier < 0 {
+		return fmt.Errorf("progression.tier must be >= 0")
 	}
-	if l.XProgression.Tier < 0 {
-		return fmt.Errorf("x-progression.tier must be >= 0")
+	if err := l.validateI18n(); err != nil {
+		return err
 	}
-	for _, day := range l.XTeaching.ReviewDays {
+	for _, prereq := range l.Progression.Prerequisites {
+		if _, err := ParseLevelRef(prereq, ""); err != nil {
+			return fmt.Errorf("progression.prerequisites: %w", err)
+		}
+	}
+	for _, day := range l.Teaching.ReviewDays {
 		if day <= 0 {
-			return fmt.Errorf("x-teaching.review_days entries must be > 0")
+			return fmt.Errorf("teaching.review_days entries must be > 0")
+		}
+	}
+	seenVariants := map[string]struct{}{}
+	for _, v := range l.Variants {
+		if !idPattern.MatchString(v.VariantID) {
+			return fmt.Errorf("invalid variants[].variant_id %q", v.VariantID)
+		}
+		if _, ok := seenVariants[v.VariantID]; ok {
+			return fmt.Errorf("duplicate variant_id %q", v.VariantID)
 		}
+		seenVariants[v.VariantID] = struct{}{}
 	}
 	return nil
 }
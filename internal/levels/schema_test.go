@@ -5,7 +5,7 @@ import "testing"
 func TestPackValidateRejectsUnsupportedSchemaVersion(t *testing.T) {
 	p := Pack{
 		Kind:          PackKind,
-		SchemaVersion: SupportedSchemaVersion + 1,
+		SchemaVersion: PackSchemaVersion + 1,
 		PackID:        "builtin-core",
 		Name:          "x",
 		Version:       "0.1.0",
@@ -79,7 +79,7 @@ func TestLevelValidateRejectsInvalidAutoCheckMode(t *testing.T) {
 		Checks: []CheckSpec{
 			{ID: "c1", Type: "file_exists", Description: "desc", Required: &required, Path: "/work/out.txt"},
 		},
-		XAutoCheck: AutoCheckExtension{Mode: "boom"},
+		AutoCheck: AutoCheckSpec{Mode: "boom"},
 	}
 	if err := l.Validate(); err == nil {
 		t.Fatalf("expected validation error")
@@ -103,9 +103,127 @@ func TestLevelValidateRejectsInvalidReviewDay(t *testing.T) {
 		Checks: []CheckSpec{
 			{ID: "c1", Type: "file_exists", Description: "desc", Required: &required, Path: "/work/out.txt"},
 		},
-		XTeaching: TeachingExtension{ReviewDays: []int{1, 0}},
+		Teaching: TeachingSpec{ReviewDays: []int{1, 0}},
 	}
 	if err := l.Validate(); err == nil {
 		t.Fatalf("expected validation error")
 	}
 }
+
+func TestLevelValidateRejectsTestWithUnknownExpectedCheck(t *testing.T) {
+	required := true
+	l := Level{
+		Kind:             LevelKind,
+		SchemaVersion:    1,
+		LevelID:          "level-rst",
+		Title:            "x",
+		Difficulty:       1,
+		EstimatedMinutes: 1,
+		Filesystem: FilesystemSpec{
+			Dataset: DatasetSpec{Source: "dir", Path: "dataset", MountPoint: "/levels/current"},
+			Work:    WorkSpec{MountPoint: "/work"},
+		},
+		Objective: ObjectiveSpec{Bullets: []string{"do thing"}},
+		Checks: []CheckSpec{
+			{ID: "c1", Type: "file_exists", Description: "desc", Required: &required, Path: "/work/out.txt"},
+		},
+		Tests: []LevelTest{
+			{TestID: "empty", ScriptSH: "true", ExpectFailedChecks: []string{"c1"}},
+		},
+	}
+	if err := l.Validate(); err != nil {
+		t.Fatalf("expected valid tests block, got %v", err)
+	}
+	l.Tests[0].ExpectFailedChecks = []string{"c2"}
+	if err := l.Validate(); err == nil {
+		t.Fatalf("expected validation error")
+	}
+}
+
+func TestLevelValidateStructuredChecks(t *testing.T) {
+	required, table := true, "a\n1\n"
+	l := Level{
+		Kind:             LevelKind,
+		SchemaVersion:    1,
+		LevelID:          "level-opq",
+		Title:            "x",
+		Difficulty:       1,
+		EstimatedMinutes: 1,
+		Filesystem: FilesystemSpec{
+			Dataset: DatasetSpec{Source: "dir", Path: "dataset", MountPoint: "/levels/current"},
+			Work:    WorkSpec{MountPoint: "/work"},
+		},
+		Objective: ObjectiveSpec{Bullets: []string{"do thing"}},
+		Checks: []CheckSpec{
+			{ID: "c1", Type: "file_json_path", Description: "desc", Required: &required, Path: "/work/out.json", Query: ".a", ValueType: "array"},
+			{ID: "c2", Type: "file_csv_equals", Description: "desc", Required: &required, Path: "/work/out.csv", Expected: &table},
+		},
+	}
+	if err := l.Validate(); err != nil {
+		t.Fatalf("expected valid checks, got %v", err)
+	}
+	// An empty expected value is still an assertion.
+	empty := l
+	empty.Checks = []CheckSpec{l.Checks[0]}
+	empty.Checks[0].ValueType, empty.Checks[0].Expected = "", new(string)
+	if err := empty.Validate(); err != nil {
+		t.Fatalf("expected an empty expected value to be valid, got %v", err)
+	}
+	for name, broken := range map[string]func(*Level){
+		"no assertion":   func(l *Level) { l.Checks[0].ValueType = "" },
+		"bad value_type": func(l *Level) { l.Checks[0].ValueType = "list" },
+		"bad format":     func(l *Level) { l.Checks[0].Format = "toml" },
+		"two sources":    func(l *Level) { l.Checks[1].CompareToPath = "/work/want.csv" },
+		"long delimiter": func(l *Level) { l.Checks[1].CSV.Delimiter = ";;" },
+	} {
+		lv := l
+		lv.Checks = append([]CheckSpec(nil), l.Checks...)
+		broken(&lv)
+		if err := lv.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestLevelValidateFileMetadataChecks(t *testing.T) {
+	required, target := true, "key"
+	l := Level{
+		Kind:             LevelKind,
+		SchemaVersion:    1,
+		LevelID:          "level-lmn",
+		Title:            "x",
+		Difficulty:       1,
+		EstimatedMinutes: 1,
+		Filesystem: FilesystemSpec{
+			Dataset: DatasetSpec{Source: "dir", Path: "dataset", MountPoint: "/levels/current"},
+			Work:    WorkSpec{MountPoint: "/work"},
+		},
+		Objective: ObjectiveSpec{Bullets: []string{"do thing"}},
+		Checks: []CheckSpec{
+			{ID: "c1", Type: "file_mode", Description: "desc", Required: &required, Path: "/work/key", PermSet: "0600", PermUnset: "0077"},
+			{ID: "c2", Type: "file_mtime", Description: "desc", Required: &required, Path: "/work/key", MtimeAfter: "2024-01-01", MtimeBefore: "2024-01-01T12:00:00Z"},
+			{ID: "c3", Type: "symlink_target", Description: "desc", Required: &required, Path: "/work/link", Expected: &target},
+		},
+	}
+	if err := l.Validate(); err != nil {
+		t.Fatalf("expected valid checks, got %v", err)
+	}
+	for name, broken := range map[string]func(*Level){
+		"not octal":        func(l *Level) { l.Checks[0].PermSet = "0800" },
+		"perm and set":     func(l *Level) { l.Checks[0].Perm = "0600" },
+		"overlapping bits": func(l *Level) { l.Checks[0].PermUnset = "0700" },
+		"bad time":         func(l *Level) { l.Checks[1].MtimeAfter = "yesterday" },
+		"empty range":      func(l *Level) { l.Checks[1].MtimeBefore = "2023-12-31" },
+		"two targets":      func(l *Level) { l.Checks[2].Pattern = "^key$" },
+		"bad file_type": func(l *Level) {
+			l.Checks[2] = CheckSpec{ID: "c3", Type: "file_type", Path: "/work/link", FileType: "link"}
+		},
+	} {
+		lv := l
+		lv.Checks = append([]CheckSpec(nil), l.Checks...)
+		broken(&lv)
+		if err := lv.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package levels
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed semantic version. Build metadata is dropped.
+type Version struct {
+	Major, Minor, Patch int
+	Pre                 string
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// ParseVersion parses MAJOR.MINOR.PATCH[-PRE][+BUILD]; a leading "v" and
+// missing minor/patch components are accepted.
+func ParseVersion(s string) (Version, error) {
+	v, _, err := parseVersionParts(s)
+	return v, err
+}
+
+// parseVersionParts also returns how many numeric components were given, which
+// tilde and caret ranges need.
+func parseVersionParts(s string) (Version, int, error) {
+	raw := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(raw, '+'); i >= 0 {
+		raw = raw[:i]
+	}
+	var v Version
+	if i := strings.IndexByte(raw, '-'); i >= 0 {
+		v.Pre = raw[i+1:]
+		raw = raw[:i]
+		if v.Pre == "" {
+			return Version{}, 0, fmt.Errorf("invalid version %q", s)
+		}
+	}
+	fields := strings.Split(raw, ".")
+	if len(fields) == 0 || len(fields) > 3 {
+		return Version{}, 0, fmt.Errorf("invalid version %q", s)
+	}
+	nums := [3]int{}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return Version{}, 0, fmt.Errorf("invalid version %q", s)
+		}
+		nums[i] = n
+	}
+	v.Major, v.Minor, v.Patch = nums[0], nums[1], nums[2]
+	return v, len(fields), nil
+}
+
+// Compare returns -1, 0 or 1. A pre-release sorts before its release.
+func (v Version) Compare(o Version) int {
+	for _, d := range [][2]int{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if d[0] != d[1] {
+			if d[0] < d[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	}
+	return comparePrerelease(v.Pre, o.Pre)
+}
+
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				if an < bn {
+					return -1
+				}
+				return 1
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+// Constraint is a version range such as ">=1.2.0, <2.0.0", "^1.4", "~0.3.1"
+// or "1.x || >=3". Comparators separated by commas or spaces must all match;
+// "||" separates alternatives.
+type Constraint struct {
+	raw  string
+	sets [][]comparator
+}
+
+type comparator struct {
+	op string
+	v  Version
+}
+
+func (c Constraint) String() string { return c.raw }
+
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{raw: strings.TrimSpace(s)}
+	if c.raw == "" {
+		return Constraint{}, fmt.Errorf("empty version constraint")
+	}
+	for _, alt := range strings.Split(c.raw, "||") {
+		set := []comparator{}
+		terms := strings.FieldsFunc(alt, func(r rune) bool { return r == ',' || r == ' ' })
+		for i := 0; i < len(terms); i++ {
+			term := terms[i]
+			// Allow a space between an operator and its version (">= 1.2").
+			if strings.Trim(term, "<>=!^~") == "" && i+1 < len(terms) {
+				i++
+				term += terms[i]
+			}
+			cmps, err := parseComparator(term)
+			if err != nil {
+				return Constraint{}, fmt.Errorf("invalid version constraint %q: %w", s, err)
+			}
+			set = append(set, cmps...)
+		}
+		if len(set) == 0 {
+			return Constraint{}, fmt.Errorf("invalid version constraint %q: empty alternative", s)
+		}
+		c.sets = append(c.sets, set)
+	}
+	return c, nil
+}
+
+// Check reports whether v satisfies the constraint. Pre-release versions only
+// match comparators that name a pre-release of the same major.minor.patch.
+func (c Constraint) Check(v Version) bool {
+	for _, set := range c.sets {
+		ok := true
+		preAllowed := v.Pre == ""
+		for _, cmp := range set {
+			if !cmp.match(v) {
+				ok = false
+				break
+			}
+			if cmp.v.Pre != "" && cmp.v.Major == v.Major && cmp.v.Minor == v.Minor && cmp.v.Patch == v.Patch {
+				preAllowed = true
+			}
+		}
+		if ok && preAllowed {
+			return true
+		}
+	}
+	return false
+}
+
+func (cmp comparator) match(v Version) bool {
+	c := v.Compare(cmp.v)
+	switch cmp.op {
+	case "*":
+		return true
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+	return false
+}
+
+func parseComparator(term string) ([]comparator, error) {
+	if term == "*" || term == "x" || term == "X" {
+		return []comparator{{op: "*"}}, nil
+	}
+	for _, op := range []string{">=", "<=", "!=", ">", "<", "=", "^", "~"} {
+		if !strings.HasPrefix(term, op) {
+			continue
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(term, op))
+		if strings.ContainsAny(rest, "xX*") {
+			return nil, fmt.Errorf("wildcards cannot be combined with %q", op)
+		}
+		v, n, err := parseVersionParts(rest)
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case "^":
+			return caretRange(v, n), nil
+		case "~":
+			return tildeRange(v, n), nil
+		}
+		return []comparator{{op: op, v: v}}, nil
+	}
+	return wildcardRange(term)
+}
+
+// caretRange allows changes that do not modify the left-most non-zero part.
+func caretRange(v Version, n int) []comparator {
+	upper := Version{Major: v.Major + 1}
+	switch {
+	case v.Major == 0 && n == 1:
+		upper = Version{Major: 1}
+	case v.Major == 0 && v.Minor == 0 && n == 3:
+		upper = Version{Minor: 0, Patch: v.Patch + 1}
+	case v.Major == 0:
+		upper = Version{Minor: v.Minor + 1}
+	}
+	return []comparator{{op: ">=", v: v}, {op: "<", v: upper}}
+}
+
+// tildeRange allows patch-level changes, or minor-level when only the major
+// version is given.
+func tildeRange(v Version, n int) []comparator {
+	upper := Version{Major: v.Major, Minor: v.Minor + 1}
+	if n == 1 {
+		upper = Version{Major: v.Major + 1}
+	}
+	return []comparator{{op: ">=", v: v}, {op: "<", v: upper}}
+}
+
+// wildcardRange handles bare versions, where missing or x components match
+// anything ("1", "1.2.x").
+func wildcardRange(term string) ([]comparator, error) {
+	raw := term
+	if i := strings.IndexAny(raw, "xX*"); i >= 0 {
+		if !strings.HasSuffix(raw[:i], ".") || strings.Trim(raw[i:], "xX*.") != "" {
+			return nil, fmt.Errorf("invalid version %q", term)
+		}
+		raw = strings.TrimSuffix(raw[:i], ".")
+	}
+	v, n, err := parseVersionParts(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch n {
+	case 3:
+		return []comparator{{op: "=", v: v}}, nil
+	case 2:
+		return []comparator{{op: ">=", v: v}, {op: "<", v: Version{Major: v.Major, Minor: v.Minor + 1}}}, nil
+	}
+	return []comparator{{op: ">=", v: v}, {op: "<", v: Version{Major: v.Major + 1}}}, nil
+}
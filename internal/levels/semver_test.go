@@ -0,0 +1,45 @@
+package levels
+
+import "testing"
+
+func TestConstraintCheck(t *testing.T) {
+	cases := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{">=0.1.0, <1.0.0", "0.1.0", true},
+		{">=0.1.0, <1.0.0", "1.0.0", false},
+		{">= 0.2", "0.1.9", false},
+		{"^1.4", "1.9.0", true},
+		{"^1.4", "2.0.0", false},
+		{"^0.3.1", "0.3.9", true},
+		{"^0.3.1", "0.4.0", false},
+		{"~1.2.3", "1.2.9", true},
+		{"~1.2.3", "1.3.0", false},
+		{"1.x || >=3", "2.5.0", false},
+		{"1.x || >=3", "3.1.0", true},
+		{"1.2.3", "1.2.3", true},
+		{"*", "7.0.0", true},
+		{">=1.0.0", "1.1.0-beta.1", false},
+		{">=1.1.0-beta.1", "1.1.0-beta.2", true},
+	}
+	for _, tc := range cases {
+		c, err := ParseConstraint(tc.constraint)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.constraint, err)
+		}
+		v, err := ParseVersion(tc.version)
+		if err != nil {
+			t.Fatalf("parse version %q: %v", tc.version, err)
+		}
+		if got := c.Check(v); got != tc.want {
+			t.Errorf("%q.Check(%s) = %v, want %v", tc.constraint, tc.version, got, tc.want)
+		}
+	}
+	for _, bad := range []string{"", ">=", "^x", "1.2.3.4", "=>1"} {
+		if _, err := ParseConstraint(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package levels
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Default health check pacing for services that set no interval or retries.
+const (
+	DefaultHealthInterval = time.Second
+	DefaultHealthRetries  = 30
+)
+
+// ServiceRun is a service ready to start. Its fields mirror
+// sandbox.ServiceSpec so callers can convert between the two.
+type ServiceRun struct {
+	Name           string
+	Image          string
+	Command        []string
+	Hostname       string
+	Env            map[string]string
+	CapAdd         []string
+	HealthCommand  string
+	HealthInterval time.Duration
+	HealthRetries  int
+}
+
+var (
+	serviceNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]{0,30}$`)
+	capabilityPattern  = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+)
+
+// ServiceRuns returns the level's services with defaults applied.
+func (l Level) ServiceRuns() []ServiceRun {
+	out := make([]ServiceRun, 0, len(l.Services))
+	for _, s := range l.Services {
+		run := ServiceRun{
+			Name:     s.Name,
+			Image:    s.Image,
+			Command:  s.Command,
+			Hostname: cmp.Or(s.Hostname, s.Name),
+			Env:      s.Env,
+			CapAdd:   s.CapAdd,
+		}
+		if hc := s.Healthcheck; hc != nil {
+			run.HealthCommand = hc.Command
+			run.HealthInterval = DefaultHealthInterval
+			if hc.IntervalSeconds > 0 {
+				run.HealthInterval = time.Duration(hc.IntervalSeconds) * time.Second
+			}
+			run.HealthRetries = cmp.Or(hc.Retries, DefaultHealthRetries)
+		}
+		out = append(out, run)
+	}
+	return out
+}
+
+func (l Level) hasService(name string) bool {
+	for _, s := range l.Services {
+		if s.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (l Level) validateServices() error {
+	seen := map[string]struct{}{}
+	for _, s := range l.Services {
+		if !serviceNamePattern.MatchString(s.Name) {
+			return fmt.Errorf("services: invalid name %q (want a lower-case DNS label)", s.Name)
+		}
+		host := cmp.Or(s.Hostname, s.Name)
+		if !serviceNamePattern.MatchString(host) {
+			return fmt.Errorf("service %q: invalid hostname %q", s.Name, host)
+		}
+		// The level container has the alias "dojo" on the service network.
+		if host == "dojo" {
+			return fmt.Errorf("service %q: hostname dojo is reserved", s.Name)
+		}
+		// Names and hostnames are both network aliases.
+		aliases := []string{s.Name}
+		if host != s.Name {
+			aliases = append(aliases, host)
+		}
+		for _, alias := range aliases {
+			if _, ok := seen[alias]; ok {
+				return fmt.Errorf("services: %q is used by two services", alias)
+			}
+			seen[alias] = struct{}{}
+		}
+		if strings.TrimSpace(s.Image) == "" {
+			return fmt.Errorf("service %q image is required", s.Name)
+		}
+		for _, c := range s.CapAdd {
+			if !capabilityPattern.MatchString(c) || strings.EqualFold(c, "ALL") {
+				return fmt.Errorf("service %q: invalid cap_add %q (want a capability name such as NET_BIND_SERVICE)", s.Name, c)
+			}
+		}
+		if hc := s.Healthcheck; hc != nil {
+			if strings.TrimSpace(hc.Command) == "" {
+				return fmt.Errorf("service %q healthcheck.command is required", s.Name)
+			}
+			if hc.IntervalSeconds < 0 || hc.Retries < 0 {
+				return fmt.Errorf("service %q healthcheck interval_seconds and retries must be >= 0", s.Name)
+			}
+		}
+	}
+	return nil
+}
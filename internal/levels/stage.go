@@ -0,0 +1,254 @@
+package levels
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stagedModeBits are the mode bits staging carries over from the dataset.
+const stagedModeBits = fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky
+
+// stager copies dataset paths into a workdir the way they are on disk:
+// modes, mtimes, symlinks (dangling ones too), empty directories and
+// hardlinks between copied files.
+type stager struct {
+	// copied indexes staged regular files by size to find hardlinks.
+	copied map[int64][]stagedFile
+}
+
+type stagedFile struct {
+	info fs.FileInfo
+	dst  string
+}
+
+func newStager() *stager {
+	return &stager{copied: map[int64][]stagedFile{}}
+}
+
+func (s *stager) copyPath(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return s.copyEntry(src, dst, info)
+	}
+	dirs := []stagedFile{}
+	err = filepath.WalkDir(src, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return s.copyEntry(path, target, info)
+		}
+		if existing, err := os.Lstat(target); err == nil && !existing.IsDir() {
+			if err := os.Remove(target); err != nil {
+				return err
+			}
+		}
+		if err := os.MkdirAll(target, 0o755); err != nil {
+			return err
+		}
+		dirs = append(dirs, stagedFile{info: info, dst: target})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Directory modes and mtimes go on last, deepest first: read-only
+	// directories can still be filled, and filling them would bump mtimes.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := restoreMeta(dirs[i].dst, dirs[i].info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *stager) copyEntry(src, dst string, info fs.FileInfo) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	case info.Mode().IsRegular():
+		for _, prev := range s.copied[info.Size()] {
+			if os.SameFile(prev.info, info) {
+				return os.Link(prev.dst, dst)
+			}
+		}
+		if err := copyFileContent(src, dst); err != nil {
+			return err
+		}
+		s.copied[info.Size()] = append(s.copied[info.Size()], stagedFile{info: info, dst: dst})
+		return restoreMeta(dst, info)
+	default:
+		// Sockets, devices and FIFOs have no place in a workdir.
+		return nil
+	}
+}
+
+func copyFileContent(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func restoreMeta(path string, info fs.FileInfo) error {
+	if err := os.Chmod(path, info.Mode()&stagedModeBits); err != nil {
+		return err
+	}
+	return os.Chtimes(path, info.ModTime(), info.ModTime())
+}
+
+// applyOverride applies one initial_layout override inside workdir.
+func applyOverride(workdir string, o PathOverride) error {
+	target, err := stagePath(workdir, o.Path)
+	if err != nil {
+		return err
+	}
+	if o.SymlinkTo != "" || o.Content != nil {
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if o.SymlinkTo != "" {
+			return os.Symlink(o.SymlinkTo, target)
+		}
+		if err := os.WriteFile(target, []byte(*o.Content), 0o644); err != nil {
+			return err
+		}
+	}
+	info, err := os.Lstat(target)
+	if err != nil {
+		return err
+	}
+	// Chmod and Chtimes follow symlinks, which could point out of the
+	// workdir on the host.
+	if info.Mode()&fs.ModeSymlink != 0 && (o.Mode != "" || o.Mtime != "") {
+		return fmt.Errorf("mode and mtime cannot be set on a symlink")
+	}
+	if o.Mode != "" {
+		mode, err := ParseFileMode(o.Mode)
+		if err != nil {
+			return err
+		}
+		if err := os.Chmod(target, mode); err != nil {
+			return err
+		}
+	}
+	if o.Mtime != "" {
+		mtime, err := ParseMtime(o.Mtime)
+		if err != nil {
+			return err
+		}
+		if err := os.Chtimes(target, mtime, mtime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stagePath resolves rel inside the workdir root. Staging runs on the host,
+// so paths that leave root, lexically or through a symlink staged earlier,
+// are rejected.
+func stagePath(root, rel string) (string, error) {
+	if !workRelative(rel) {
+		return "", fmt.Errorf("path %q must stay inside the workdir", rel)
+	}
+	target := filepath.Join(root, filepath.FromSlash(rel))
+	for dir := filepath.Dir(target); len(dir) > len(root); dir = filepath.Dir(dir) {
+		if info, err := os.Lstat(dir); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			return "", fmt.Errorf("path %q is below a symlink", rel)
+		}
+	}
+	return target, nil
+}
+
+// workRelative reports whether rel names a path inside the work mount.
+func workRelative(rel string) bool {
+	clean := filepath.Clean(filepath.FromSlash(rel))
+	return !filepath.IsAbs(clean) && clean != ".." && !strings.HasPrefix(clean, ".."+string(filepath.Separator))
+}
+
+// RemoveWorkdir deletes a staged workdir. Levels may stage read-only
+// directories, which os.RemoveAll cannot empty, so those are made writable
+// first.
+func RemoveWorkdir(dir string) error {
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			if info, err := d.Info(); err == nil && info.Mode().Perm()&0o700 != 0o700 {
+				_ = os.Chmod(path, info.Mode().Perm()|0o700)
+			}
+		}
+		return nil
+	})
+	return os.RemoveAll(dir)
+}
+
+// ParseFileMode parses an octal initial_layout mode such as "0755", "755",
+// "0o4755" or "1777".
+func ParseFileMode(s string) (fs.FileMode, error) {
+	n, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(s), "0o"), 8, 32)
+	if err != nil || n > 0o7777 {
+		return 0, fmt.Errorf("mode %q is not an octal mode like 0755", s)
+	}
+	mode := fs.FileMode(n) & fs.ModePerm
+	if n&0o4000 != 0 {
+		mode |= fs.ModeSetuid
+	}
+	if n&0o2000 != 0 {
+		mode |= fs.ModeSetgid
+	}
+	if n&0o1000 != 0 {
+		mode |= fs.ModeSticky
+	}
+	return mode, nil
+}
+
+// ParseMtime parses an initial_layout mtime: RFC 3339 or a plain date (UTC).
+func ParseMtime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("mtime %q is not RFC 3339 (2024-01-02T15:04:05Z) or a date (2024-01-02)", s)
+}
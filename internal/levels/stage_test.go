@@ -0,0 +1,182 @@
+package levels
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestStageWorkdirPreservesDatasetMetadata(t *testing.T) {
+	dataset := t.TempDir()
+	tree := filepath.Join(dataset, "tree")
+	old := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	write := func(rel string, mode fs.FileMode) {
+		p := filepath.Join(tree, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(rel+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(p, mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("bin/run.sh", 0o755)
+	write("notes/readonly.txt", 0o444)
+	write("-rf", 0o600)
+	write("two\nlines", 0o644)
+	if err := os.Link(filepath.Join(tree, "bin/run.sh"), filepath.Join(tree, "bin/alias.sh")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("notes/readonly.txt", filepath.Join(tree, "latest")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("missing.txt", filepath.Join(tree, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(tree, "empty"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{"empty", "notes"} {
+		if err := os.Chtimes(filepath.Join(tree, dir), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(filepath.Join(tree, "notes"), 0o555); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(filepath.Join(tree, "notes"), 0o755)
+
+	level := Level{DatasetHostPath: dataset}
+	level.Filesystem.Work.InitialLayout.CopyFromDataset = []CopyMapping{{From: "tree", To: "tree"}}
+	work := filepath.Join(t.TempDir(), "work")
+	loader := NewLoader()
+	// Staging twice also proves a read-only staged dir can be cleared.
+	for i := 0; i < 2; i++ {
+		if err := loader.StageWorkdir(level, work); err != nil {
+			t.Fatalf("stage %d: %v", i, err)
+		}
+	}
+	staged := filepath.Join(work, "tree")
+	defer RemoveWorkdir(work)
+
+	for rel, mode := range map[string]fs.FileMode{"bin/run.sh": 0o755, "notes/readonly.txt": 0o444, "-rf": 0o600, "two\nlines": 0o644, "notes": fs.ModeDir | 0o555, "empty": fs.ModeDir | 0o755} {
+		info, err := os.Lstat(filepath.Join(staged, rel))
+		if err != nil {
+			t.Fatalf("%q not staged: %v", rel, err)
+		}
+		if info.Mode() != mode {
+			t.Fatalf("%q mode = %v, want %v", rel, info.Mode(), mode)
+		}
+		if !info.ModTime().Equal(old) {
+			t.Fatalf("%q mtime = %v, want %v", rel, info.ModTime(), old)
+		}
+	}
+	for link, target := range map[string]string{"latest": "notes/readonly.txt", "dangling": "missing.txt"} {
+		got, err := os.Readlink(filepath.Join(staged, link))
+		if err != nil || got != target {
+			t.Fatalf("%s -> %q (%v), want %q", link, got, err, target)
+		}
+	}
+	a, _ := os.Stat(filepath.Join(staged, "bin/run.sh"))
+	b, _ := os.Stat(filepath.Join(staged, "bin/alias.sh"))
+	if a == nil || b == nil || !os.SameFile(a, b) {
+		t.Fatal("hardlinked dataset files were not staged as hardlinks")
+	}
+}
+
+func TestStageWorkdirAppliesOverrides(t *testing.T) {
+	var level Level
+	src := `
+filesystem:
+  work:
+    initial_layout:
+      mkdirs: ["logs"]
+      overrides:
+        - path: "logs/app.log"
+          content: "boot\n"
+          mode: 0640
+          mtime: "2021-06-01"
+        - path: "-n"
+          content: ""
+        - path: "new\nline.txt"
+          content: "x"
+        - path: "current"
+          symlink_to: "logs/app.log"
+        - path: "logs"
+          mode: "0o1777"
+`
+	if err := yaml.Unmarshal([]byte(src), &level); err != nil {
+		t.Fatal(err)
+	}
+	work := filepath.Join(t.TempDir(), "work")
+	if err := NewLoader().StageWorkdir(level, work); err != nil {
+		t.Fatalf("stage: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(work, "logs", "app.log"))
+	if err != nil || info.Mode() != 0o640 || !info.ModTime().Equal(time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("app.log override not applied: %v %v", info, err)
+	}
+	if info, err := os.Stat(filepath.Join(work, "logs")); err != nil || info.Mode() != fs.ModeDir|fs.ModeSticky|0o777 {
+		t.Fatalf("logs mode = %v (%v)", info.Mode(), err)
+	}
+	for _, name := range []string{"-n", "new\nline.txt"} {
+		if _, err := os.Stat(filepath.Join(work, name)); err != nil {
+			t.Fatalf("%q not staged: %v", name, err)
+		}
+	}
+	if got, _ := os.Readlink(filepath.Join(work, "current")); got != "logs/app.log" {
+		t.Fatalf("current -> %q", got)
+	}
+
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(work, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	empty := ""
+	for _, o := range []PathOverride{
+		{Path: "../x", Content: &empty},
+		{Path: "escape/x", Content: &empty},
+		{Path: "escape", Mode: "0700"},
+	} {
+		if err := applyOverride(work, o); err == nil {
+			t.Fatalf("override %+v should be rejected", o)
+		}
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+		t.Fatalf("staging wrote outside the workdir: %v", entries)
+	}
+}
+
+func TestPathOverrideValidation(t *testing.T) {
+	empty := ""
+	for _, tc := range []struct {
+		o    PathOverride
+		want string
+	}{
+		{PathOverride{Content: &empty}, "path is required"},
+		{PathOverride{Path: "../etc", Content: &empty}, "inside the workdir"},
+		{PathOverride{Path: "a", Content: &empty, SymlinkTo: "b"}, "exclusive"},
+		{PathOverride{Path: "a", SymlinkTo: "b", Mode: "0755"}, "symlink"},
+		{PathOverride{Path: "a", Mode: "rwx"}, "octal"},
+		{PathOverride{Path: "a", Mode: "17777"}, "octal"},
+		{PathOverride{Path: "a", Mtime: "yesterday"}, "RFC 3339"},
+	} {
+		err := tc.o.validate()
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%+v: err = %v, want %q", tc.o, err, tc.want)
+		}
+	}
+	if err := (PathOverride{Path: "bin/run.sh", Mode: "4755", Mtime: "2024-01-02T15:04:05Z"}).validate(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,185 @@
+package levels
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// VariantSpec is one parameter set of a level. Its params replace
+// {{name}} placeholders in the variant scopes of level.yaml.
+type VariantSpec struct {
+	VariantID string            `yaml:"variant_id"`
+	Params    map[string]string `yaml:"params"`
+}
+
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_]+)\s*\}\}`)
+
+// variantScopes are the level.yaml subtrees where placeholders are expanded.
+var variantScopes = [][]string{
+	{"objective"},
+	{"hints"},
+	{"checks"},
+	{"stages"},
+	{"reference_solutions"},
+	{"tests"},
+	{"filesystem", "dataset", "generator"},
+	{"i18n"},
+}
+
+// VariantIDs lists the level's variant IDs in declaration order.
+func (l Level) VariantIDs() []string {
+	ids := make([]string, 0, len(l.Variants))
+	for _, v := range l.Variants {
+		ids = append(ids, v.VariantID)
+	}
+	return ids
+}
+
+func (l Level) variant(id string) (VariantSpec, bool) {
+	if id == "" && len(l.Variants) > 0 {
+		return l.Variants[0], true
+	}
+	for _, v := range l.Variants {
+		if v.VariantID == id {
+			return v, true
+		}
+	}
+	return VariantSpec{}, false
+}
+
+// WithVariant returns the level with the params of variant id expanded; an
+// empty id selects the first variant. The result is not hydrated, so loaders
+// should go through FSLoader.PrepareVariant.
+func (l Level) WithVariant(id string) (Level, error) {
+	if len(l.Variants) == 0 {
+		if id != "" {
+			return l, fmt.Errorf("level %s has no variants (requested %q)", l.LevelID, id)
+		}
+		return l, nil
+	}
+	v, ok := l.variant(id)
+	if !ok {
+		return l, fmt.Errorf("level %s has no variant %q", l.LevelID, id)
+	}
+	if l.source == nil {
+		return l, fmt.Errorf("level %s: variant source not loaded", l.LevelID)
+	}
+	doc := cloneNode(l.source)
+	if missing := expandVariant(doc, v.Params); len(missing) > 0 {
+		return l, fmt.Errorf("level %s variant %q: undefined params %s", l.LevelID, v.VariantID, strings.Join(missing, ", "))
+	}
+	var out Level
+	if err := doc.Decode(&out); err != nil {
+		return l, fmt.Errorf("level %s variant %q: %w", l.LevelID, v.VariantID, err)
+	}
+	out.Path = l.Path
+	out.DatasetHostPath = l.DatasetHostPath
+	out.Variant = v.VariantID
+	out.source = l.source
+	return out, nil
+}
+
+// expandVariant substitutes params into the variant scopes of a level
+// document and returns the placeholders that have no value, sorted.
+func expandVariant(doc *yaml.Node, params map[string]string) []string {
+	missing := map[string]struct{}{}
+	for _, scope := range variantScopes {
+		walkScalars(scopeNode(doc, scope), func(n *yaml.Node) {
+			if !strings.Contains(n.Value, "{{") {
+				return
+			}
+			trimmed := strings.TrimSpace(n.Value)
+			loc := placeholderPattern.FindStringIndex(trimmed)
+			whole := loc != nil && loc[0] == 0 && loc[1] == len(trimmed)
+			n.Value = placeholderPattern.ReplaceAllStringFunc(n.Value, func(m string) string {
+				name := placeholderPattern.FindStringSubmatch(m)[1]
+				v, ok := params[name]
+				if !ok {
+					missing[name] = struct{}{}
+					return m
+				}
+				return v
+			})
+			// A scalar that is exactly one placeholder takes the type of its
+			// value, so `equals: "{{top_n}}"` decodes as an integer.
+			if whole {
+				n.Value = strings.TrimSpace(n.Value)
+				n.Tag = ""
+				n.Style = 0
+				n.Tag = n.ShortTag()
+			}
+		})
+	}
+	out := make([]string, 0, len(missing))
+	for name := range missing {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// placeholdersIn lists the params referenced in the variant scopes of a
+// level document, each with the first node that uses it.
+func placeholdersIn(doc *yaml.Node) map[string]*yaml.Node {
+	out := map[string]*yaml.Node{}
+	for _, scope := range variantScopes {
+		walkScalars(scopeNode(doc, scope), func(n *yaml.Node) {
+			for _, m := range placeholderPattern.FindAllStringSubmatch(n.Value, -1) {
+				if _, ok := out[m[1]]; !ok {
+					out[m[1]] = n
+				}
+			}
+		})
+	}
+	return out
+}
+
+func scopeNode(doc *yaml.Node, keys []string) *yaml.Node {
+	cur := docRoot(doc)
+	for _, key := range keys {
+		if cur = lookupSchemaPath(cur, key); cur == nil {
+			return nil
+		}
+	}
+	return cur
+}
+
+// walkScalars calls fn for every scalar value below n; mapping keys are
+// skipped.
+func walkScalars(n *yaml.Node, fn func(*yaml.Node)) {
+	if n == nil {
+		return
+	}
+	switch n.Kind {
+	case yaml.ScalarNode:
+		fn(n)
+	case yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			walkScalars(n.Content[i], fn)
+		}
+	case yaml.SequenceNode, yaml.DocumentNode:
+		for _, c := range n.Content {
+			walkScalars(c, fn)
+		}
+	}
+}
+
+func cloneNode(n *yaml.Node) *yaml.Node {
+	if n == nil {
+		return nil
+	}
+	out := *n
+	if n.Content != nil {
+		out.Content = make([]*yaml.Node, len(n.Content))
+		for i, c := range n.Content {
+			out.Content[i] = cloneNode(c)
+		}
+	}
+	// Aliases keep pointing into the original tree; variant scopes do not
+	// expand through them.
+	return &out
+}
@@ -0,0 +1,87 @@
+package levels
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVariantsExpandIntoObjectiveChecksAndSolutions(t *testing.T) {
+	path := filepath.Join("..", "..", "packs", "builtin-core", "levels", "level-003-top-ips", "level.yaml")
+	level, err := loadLevelFile(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if level.Variant != "top5" || level.Checks[1].Equals != 5 {
+		t.Fatalf("expected default variant top5 with equals 5, got %q equals %d", level.Variant, level.Checks[1].Equals)
+	}
+
+	top3, err := level.WithVariant("top3")
+	if err != nil {
+		t.Fatalf("WithVariant: %v", err)
+	}
+	if top3.Variant != "top3" || top3.Checks[1].Equals != 3 {
+		t.Fatalf("expected equals 3, got %+v", top3.Checks[1])
+	}
+	if !strings.Contains(top3.Objective.Bullets[1], "top 3 client IPs") {
+		t.Fatalf("objective not expanded: %q", top3.Objective.Bullets[1])
+	}
+	for _, s := range []string{top3.Checks[4].Command, top3.ReferenceSolutions[0].ScriptSH} {
+		if !strings.Contains(s, "head -n 3") || strings.Contains(s, "{{") {
+			t.Fatalf("script not expanded: %q", s)
+		}
+	}
+	if _, err := level.WithVariant("top99"); err == nil {
+		t.Fatalf("expected error for unknown variant")
+	}
+}
+
+func TestLoadLevelFileRejectsVariantWithMissingParam(t *testing.T) {
+	root := t.TempDir()
+	writeTestPack(t, root, "variant-pack", "Variants")
+	levelYAML := filepath.Join(root, "variant-pack", "levels", "level-one", "level.yaml")
+	b := mustRead(t, levelYAML)
+	src := strings.Replace(string(b), `bullets: ["do it"]`, `bullets: ["write {{count}} lines"]`, 1) +
+		"variants:\n  - { variant_id: two, params: { count: 2 } }\n  - { variant_id: three, params: {} }\n"
+	if err := os.WriteFile(levelYAML, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadLevelFile(levelYAML); err == nil || !strings.Contains(err.Error(), `variant "three": undefined params count`) {
+		t.Fatalf("expected undefined param error, got %v", err)
+	}
+
+	diags, err := Lint(filepath.Join(root, "variant-pack"), LintOptions{})
+	if err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	found := false
+	for _, d := range diags {
+		if d.Rule == "variant-params" && strings.Contains(d.Message, `variant "three" does not set "count"`) {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected variant-params diagnostic, got %v", diags)
+	}
+}
+
+func TestPrepareVariantHydratesLevel(t *testing.T) {
+	loader := NewLoader()
+	packs, err := loader.LoadPacks(context.Background(), filepath.Join("..", "..", "packs"))
+	if err != nil {
+		t.Fatalf("load packs: %v", err)
+	}
+	pack, level, err := loader.FindLevel(packs, "builtin-core", "level-003-top-ips")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lv, err := loader.PrepareVariant(context.Background(), pack, level, "top4")
+	if err != nil {
+		t.Fatalf("PrepareVariant: %v", err)
+	}
+	if lv.Variant != "top4" || lv.Checks[1].Equals != 4 || lv.DatasetHostPath != level.DatasetHostPath || lv.Scoring.BasePoints == 0 || *lv.Checks[1].Required != true {
+		t.Fatalf("variant not hydrated: %+v", lv)
+	}
+}
@@ -0,0 +1,27 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// ExecScript runs a non-interactive bash script inside a started level
+// container and returns its combined output.
+func (m *Manager) ExecScript(ctx context.Context, h Handle, cwd, script string) ([]byte, error) {
+	if h.IsMock() {
+		return nil, fmt.Errorf("cannot exec scripts in a mock sandbox")
+	}
+	if cwd == "" {
+		cwd = "/work"
+	}
+	args := []string{"exec", "-i", "-w", cwd, h.ContainerName(), "bash", "-c", script}
+	out, err := exec.CommandContext(ctx, m.engine, args...).CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			return out, fmt.Errorf("script timed out: %w", ctx.Err())
+		}
+		return out, fmt.Errorf("script failed: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,96 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// GeneratorSpec describes one dataset generator run. It has the same fields
+// as levels.GeneratorJob.
+type GeneratorSpec struct {
+	SessionID string
+	PackID    string
+	LevelID   string
+	Image     string
+	LevelDir  string
+	OutputDir string
+	Command   string
+	Args      []string
+	Env       map[string]string
+}
+
+const (
+	generatorLevelMount  = "/level"
+	generatorOutputMount = "/dataset"
+)
+
+// RunGenerator runs a dataset generator to completion in a throwaway
+// container with no network. The level dir is mounted read-only as the
+// working directory and only the output dir is writable.
+func (m *Manager) RunGenerator(ctx context.Context, spec GeneratorSpec) error {
+	engine := m.engine
+	if m.mode == "mock" || engine == "" || engine == "mock" {
+		return errors.New("dataset generators need docker or podman")
+	}
+	args := buildGeneratorArgs(engine, spec, os.Getuid(), os.Getgid())
+	out, err := exec.CommandContext(ctx, engine, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s run failed: %s", engine, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// ImageID resolves a generator image to its ID with the detected engine.
+func (m *Manager) ImageID(ctx context.Context, image string) (string, error) {
+	engine := m.engine
+	if m.mode == "mock" || engine == "" || engine == "mock" {
+		return "", errors.New("dataset generators need docker or podman")
+	}
+	return ImageID(ctx, engine, image)
+}
+
+func buildGeneratorArgs(engine string, spec GeneratorSpec, uid, gid int) []string {
+	capDrop := "ALL"
+	if engine == "podman" {
+		capDrop = "all"
+	}
+	args := []string{
+		"run", "--rm",
+		"--network", "none",
+		"--cap-drop", capDrop,
+		"--security-opt", "no-new-privileges",
+		"--pids-limit", "256",
+		"--memory", "768m",
+		"--label", "clidojo.session=" + spec.SessionID,
+		"--label", "clidojo.level=" + spec.LevelID,
+		"--label", "clidojo.pack=" + spec.PackID,
+		"-e", "LANG=C.UTF-8",
+		"-e", "LC_ALL=C",
+		"-w", generatorLevelMount,
+	}
+	if engine == "docker" {
+		// Rootless podman maps container root to the invoking user already.
+		args = append(args, "--user", fmt.Sprintf("%d:%d", uid, gid),
+			"--mount", fmt.Sprintf("type=bind,src=%s,dst=%s,readonly", spec.LevelDir, generatorLevelMount),
+			"--mount", fmt.Sprintf("type=bind,src=%s,dst=%s", spec.OutputDir, generatorOutputMount))
+	} else {
+		args = append(args,
+			"-v", fmt.Sprintf("%s:%s:ro", spec.LevelDir, generatorLevelMount),
+			"-v", fmt.Sprintf("%s:%s:rw", spec.OutputDir, generatorOutputMount))
+	}
+	keys := make([]string, 0, len(spec.Env))
+	for k := range spec.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "-e", fmt.Sprintf("%s=%s", k, spec.Env[k]))
+	}
+	args = append(args, spec.Image, spec.Command)
+	return append(args, spec.Args...)
+}
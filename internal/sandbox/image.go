@@ -0,0 +1,78 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type ImageBuild struct {
+	Ref        string
+	ContextDir string
+	Dockerfile string
+	Target     string
+	Args       map[string]string
+}
+
+func ImageExists(ctx context.Context, engine, image string) (bool, error) {
+	out, err := exec.CommandContext(ctx, engine, "image", "inspect", image).CombinedOutput()
+	if err != nil {
+		msg := strings.ToLower(strings.TrimSpace(string(out)))
+		// Normal "missing image" output from docker/podman.
+		if strings.Contains(msg, "no such image") || strings.Contains(msg, "not found") || strings.Contains(msg, "unable to find") {
+			return false, nil
+		}
+		return false, fmt.Errorf("%s image inspect failed for %s: %s", engine, image, strings.TrimSpace(string(out)))
+	}
+	return len(out) > 0, nil
+}
+
+// ImageID returns the ID of a local image. It changes whenever the image is
+// rebuilt or pulled again under the same ref.
+func ImageID(ctx context.Context, engine, image string) (string, error) {
+	out, err := exec.CommandContext(ctx, engine, "image", "inspect", "--format", "{{.Id}}", image).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s image inspect failed for %s: %s", engine, image, strings.TrimSpace(string(out)))
+	}
+	id := strings.TrimSpace(string(out))
+	if id == "" {
+		return "", fmt.Errorf("%s image inspect returned no ID for %s", engine, image)
+	}
+	return id, nil
+}
+
+func PullImage(ctx context.Context, engine, image string) error {
+	out, err := exec.CommandContext(ctx, engine, "pull", image).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s pull failed for %s: %s", engine, image, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func BuildImage(ctx context.Context, engine string, build ImageBuild) error {
+	dockerfile := strings.TrimSpace(build.Dockerfile)
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	args := []string{"build", "-t", build.Ref, "-f", filepath.Join(build.ContextDir, dockerfile)}
+	if strings.TrimSpace(build.Target) != "" {
+		args = append(args, "--target", strings.TrimSpace(build.Target))
+	}
+	keys := make([]string, 0, len(build.Args))
+	for k := range build.Args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "--build-arg", fmt.Sprintf("%s=%s", k, build.Args[k]))
+	}
+	args = append(args, build.ContextDir)
+	out, err := exec.CommandContext(ctx, engine, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s build failed for %s: %s", engine, build.Ref, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
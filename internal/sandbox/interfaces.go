@@ -16,4 +16,6 @@ type Handle interface {
 	Cwd() string
 	Env() []string
 	IsMock() bool
+	// Services maps the level's service names to their container names.
+	Services() map[string]string
 }
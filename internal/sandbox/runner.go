@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"os/exec"
 	"strings"
 )
@@ -86,9 +87,10 @@ func (m *Manager) StartLevel(ctx context.Context, spec StartSpec) (Handle, error
 	}
 
 	h := &containerHandle{
-		engine: engine,
-		name:   spec.ContainerName,
-		work:   spec.WorkDir,
+		engine:   engine,
+		name:     spec.ContainerName,
+		work:     spec.WorkDir,
+		services: map[string]string{},
 	}
 	if engine == "mock" {
 		h.shell = nil
@@ -101,8 +103,21 @@ func (m *Manager) StartLevel(ctx context.Context, spec StartSpec) (Handle, error
 		return h, nil
 	}
 
+	if len(spec.Services) > 0 {
+		if err := startServices(ctx, engine, spec, h); err != nil {
+			_ = h.Stop(context.Background())
+			return nil, err
+		}
+		spec.Network = h.network
+	}
+
 	args := buildRunArgs(engine, spec)
 	out, err := exec.CommandContext(ctx, engine, args...).CombinedOutput()
+	if err != nil && len(spec.Services) > 0 {
+		// The services live on this engine, so there is no fallback.
+		_ = h.Stop(context.Background())
+		return nil, fmt.Errorf("%s run failed: %s", engine, strings.TrimSpace(string(out)))
+	}
 	if err != nil {
 		if m.mode == "auto" && engine == "podman" {
 			if errValidate := validateEngine(ctx, "docker"); errValidate == nil {
@@ -243,7 +258,13 @@ func buildRunArgs(engine string, spec StartSpec) []string {
 		args[7] = "all"
 	}
 	if network != "inherit" {
-		args = append(args[:4], append([]string{"--network", network}, args[4:]...)...)
+		netArgs := []string{"--network", network}
+		if len(spec.Services) > 0 && network == serviceNetworkName(spec) {
+			// --hostname is not resolvable by other containers; the alias
+			// lets services reach the level container as dojo.
+			netArgs = append(netArgs, "--network-alias", "dojo")
+		}
+		args = append(args[:4], append(netArgs, args[4:]...)...)
 	}
 	if spec.ReadOnlyRoot {
 		args = append(args, "--read-only")
@@ -301,7 +322,7 @@ func (m *Manager) CleanupOrphans(ctx context.Context, activeSession string) erro
 		}
 		_ = exec.CommandContext(ctx, engine, "rm", "-f", id).Run()
 	}
-	return nil
+	return cleanupOrphanNetworks(ctx, engine, activeSession)
 }
 
 type containerHandle struct {
@@ -312,6 +333,10 @@ type containerHandle struct {
 	env    []string
 	shell  []string
 	mock   bool
+	// services maps service names to container names; network is their
+	// private network.
+	services map[string]string
+	network  string
 }
 
 func (h *containerHandle) ShellCommand() []string {
@@ -322,10 +347,20 @@ func (h *containerHandle) Stop(ctx context.Context) error {
 	if h.engine == "mock" || h.name == "" {
 		return nil
 	}
-	out, err := exec.CommandContext(ctx, h.engine, "rm", "-f", h.name).CombinedOutput()
+	names := []string{h.name}
+	for _, name := range h.services {
+		names = append(names, name)
+	}
+	out, err := exec.CommandContext(ctx, h.engine, append([]string{"rm", "-f"}, names...)...).CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("container cleanup failed: %s", strings.TrimSpace(string(out)))
 	}
+	if h.network != "" {
+		out, err := exec.CommandContext(ctx, h.engine, "network", "rm", h.network).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("network cleanup failed: %s", strings.TrimSpace(string(out)))
+		}
+	}
 	return nil
 }
 
@@ -337,8 +372,9 @@ func (h *containerHandle) Cwd() string {
 	}
 	return ""
 }
-func (h *containerHandle) Env() []string { return append([]string(nil), h.env...) }
-func (h *containerHandle) IsMock() bool  { return h.mock }
+func (h *containerHandle) Env() []string               { return append([]string(nil), h.env...) }
+func (h *containerHandle) IsMock() bool                { return h.mock }
+func (h *containerHandle) Services() map[string]string { return maps.Clone(h.services) }
 
 func contains(list []string, target string) bool {
 	for _, s := range list {
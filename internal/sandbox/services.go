@@ -0,0 +1,142 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ServiceContainerName is the container name of a level container's service.
+func ServiceContainerName(levelContainer, service string) string {
+	return levelContainer + "-" + service
+}
+
+func serviceNetworkName(spec StartSpec) string {
+	return spec.ContainerName + "-net"
+}
+
+// startServices creates the level's private network, starts its services on
+// it and waits for their health checks. The network is internal, so neither
+// the services nor the level container can reach the internet. Everything is
+// recorded on h before it is created, so h.Stop cleans up after a failure.
+func startServices(ctx context.Context, engine string, spec StartSpec, h *containerHandle) error {
+	network := serviceNetworkName(spec)
+	h.network = network
+	out, err := exec.CommandContext(ctx, engine, buildNetworkArgs(spec, network)...).CombinedOutput()
+	if err != nil {
+		h.network = ""
+		return fmt.Errorf("%s network create failed: %s", engine, strings.TrimSpace(string(out)))
+	}
+	for _, svc := range spec.Services {
+		h.services[svc.Name] = ServiceContainerName(spec.ContainerName, svc.Name)
+		out, err := exec.CommandContext(ctx, engine, buildServiceArgs(engine, spec, network, svc)...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("service %s: %s run failed: %s", svc.Name, engine, strings.TrimSpace(string(out)))
+		}
+	}
+	for _, svc := range spec.Services {
+		if err := waitHealthy(ctx, engine, h.services[svc.Name], svc); err != nil {
+			return fmt.Errorf("service %s: %w", svc.Name, err)
+		}
+	}
+	return nil
+}
+
+func buildNetworkArgs(spec StartSpec, network string) []string {
+	return []string{
+		"network", "create", "--internal",
+		"--label", "clidojo.session=" + spec.SessionID,
+		"--label", "clidojo.level=" + spec.LevelID,
+		"--label", "clidojo.pack=" + spec.PackID,
+		network,
+	}
+}
+
+func buildServiceArgs(engine string, spec StartSpec, network string, svc ServiceSpec) []string {
+	hostname := svc.Hostname
+	if hostname == "" {
+		hostname = svc.Name
+	}
+	args := []string{
+		"run", "-d", "--name", ServiceContainerName(spec.ContainerName, svc.Name),
+		"--network", network,
+		"--network-alias", svc.Name,
+		"--hostname", hostname,
+		"--cap-drop", "ALL",
+		"--security-opt", "no-new-privileges",
+		"--pids-limit", "256",
+		"--memory", "256m",
+		"--label", "clidojo.session=" + spec.SessionID,
+		"--label", "clidojo.level=" + spec.LevelID,
+		"--label", "clidojo.pack=" + spec.PackID,
+		"--label", "clidojo.service=" + svc.Name,
+	}
+	if engine == "podman" {
+		// podman docs use lower-case "all", as in buildRunArgs.
+		args[11] = "all"
+	}
+	if hostname != svc.Name {
+		args = append(args, "--network-alias", hostname)
+	}
+	for _, c := range svc.CapAdd {
+		args = append(args, "--cap-add", c)
+	}
+	keys := make([]string, 0, len(svc.Env))
+	for k := range svc.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "-e", fmt.Sprintf("%s=%s", k, svc.Env[k]))
+	}
+	args = append(args, svc.Image)
+	return append(args, svc.Command...)
+}
+
+// waitHealthy runs the service's health command until it succeeds or its
+// retries are used up. Services without one count as healthy once started.
+func waitHealthy(ctx context.Context, engine, container string, svc ServiceSpec) error {
+	if svc.HealthCommand == "" {
+		return nil
+	}
+	retries := max(1, svc.HealthRetries)
+	var out []byte
+	for attempt := 1; ; attempt++ {
+		var err error
+		out, err = exec.CommandContext(ctx, engine, "exec", container, "sh", "-c", svc.HealthCommand).CombinedOutput()
+		if err == nil {
+			return nil
+		}
+		if attempt >= retries {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("health check interrupted: %w", ctx.Err())
+		case <-time.After(svc.HealthInterval):
+		}
+	}
+	return fmt.Errorf("not healthy after %d checks: %s", retries, strings.TrimSpace(string(out)))
+}
+
+// cleanupOrphanNetworks removes service networks of sessions other than the
+// active one. Their containers must be gone already.
+func cleanupOrphanNetworks(ctx context.Context, engine, activeSession string) error {
+	out, err := exec.CommandContext(ctx, engine, "network", "ls", "--filter", "label=clidojo.session", "--format", "{{.Name}}").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("list networks: %s", strings.TrimSpace(string(out)))
+	}
+	for _, name := range strings.Fields(string(out)) {
+		if activeSession != "" {
+			labelOut, err := exec.CommandContext(ctx, engine, "network", "inspect", "--format", "{{ index .Labels \"clidojo.session\" }}", name).CombinedOutput()
+			if err == nil && strings.TrimSpace(string(labelOut)) == activeSession {
+				continue
+			}
+		}
+		_ = exec.CommandContext(ctx, engine, "network", "rm", name).Run()
+	}
+	return nil
+}
@@ -1,5 +1,7 @@
 package sandbox
 
+import "time"
+
 type EngineInfo struct {
 	Name    string
 	Version string
@@ -30,6 +32,24 @@ type StartSpec struct {
 	MemoryMB     int
 	PidsLimit    int
 	Tmpfs        []TmpfsMount
+
+	// Services start before the level container, which then joins their
+	// private network instead of Network.
+	Services []ServiceSpec
+}
+
+// ServiceSpec describes one sidecar container. It has the same fields as
+// levels.ServiceRun.
+type ServiceSpec struct {
+	Name           string
+	Image          string
+	Command        []string
+	Hostname       string
+	Env            map[string]string
+	CapAdd         []string
+	HealthCommand  string
+	HealthInterval time.Duration
+	HealthRetries  int
 }
 
 type TmpfsMount struct {
@@ -0,0 +1,11 @@
+package selftest
+
+import (
+	"context"
+
+	"clidojo/internal/levels"
+)
+
+type Harness interface {
+	Run(ctx context.Context, packs []levels.Pack) (Report, error)
+}
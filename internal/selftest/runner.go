@@ -0,0 +1,436 @@
+package selftest
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"clidojo/internal/grading"
+	"clidojo/internal/levels"
+	"clidojo/internal/sandbox"
+
+	"github.com/google/uuid"
+)
+
+var unsafeNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+type Runner struct {
+	opts    Options
+	loader  *levels.FSLoader
+	sandbox *sandbox.Manager
+	grader  *grading.DefaultGrader
+
+	sessionID string
+	ensured   map[string]bool
+}
+
+type testCase struct {
+	kind   string
+	id     string
+	script string
+	expect []string
+}
+
+func NewRunner(opts Options) *Runner {
+	if opts.SandboxMode == "" {
+		opts.SandboxMode = "auto"
+	}
+	if opts.ScriptTimeout <= 0 {
+		opts.ScriptTimeout = 30 * time.Second
+	}
+	r := &Runner{
+		opts:      opts,
+		loader:    levels.NewLoader(),
+		sandbox:   sandbox.NewManager(opts.SandboxMode),
+		grader:    grading.NewGrader(),
+		sessionID: uuid.NewString(),
+		ensured:   map[string]bool{},
+	}
+	r.loader.RunGenerator = func(ctx context.Context, job levels.GeneratorJob) error {
+		job.SessionID = r.sessionID
+		return r.sandbox.RunGenerator(ctx, sandbox.GeneratorSpec(job))
+	}
+	r.loader.ImageID = r.sandbox.ImageID
+	return r
+}
+
+// Run stages every selected level once per variant, reference solution and
+// negative fixture, executes the script in a fresh sandbox and grades the result.
+func (r *Runner) Run(ctx context.Context, packs []levels.Pack) (Report, error) {
+	if r.opts.SandboxMode == "mock" {
+		return Report{}, fmt.Errorf("pack test needs docker or podman; the mock sandbox cannot run scripts")
+	}
+	engine, err := r.sandbox.Detect(ctx, r.opts.EngineOverride)
+	if err != nil {
+		return Report{}, err
+	}
+
+	workRoot := r.opts.WorkRoot
+	if workRoot == "" {
+		dir, err := os.MkdirTemp("", "clidojo-pack-test-")
+		if err != nil {
+			return Report{}, err
+		}
+		workRoot = dir
+		if !r.opts.KeepWorkdirs {
+			defer levels.RemoveWorkdir(dir)
+		}
+	}
+
+	report := Report{Engine: engine.Name, Cases: []CaseResult{}}
+	for _, pack := range packs {
+		for _, level := range pack.LoadedLevels {
+			if !r.selected(level.LevelID) {
+				continue
+			}
+			variants := level.VariantIDs()
+			if len(variants) == 0 {
+				variants = []string{""}
+			}
+			for _, variant := range variants {
+				lv, err := r.loader.PrepareVariant(ctx, pack, level, variant)
+				if err == nil && lv.PerRunSeed() {
+					// Reference solutions must pass for any seed; the report
+					// records the one used so failures can be replayed.
+					lv = lv.WithRunSeed(rand.Int63(), filepath.Join(workRoot, pack.PackID, level.LevelID, variant, "dataset"))
+				}
+				for _, tc := range levelCases(lv) {
+					if err := ctx.Err(); err != nil {
+						return report, err
+					}
+					var res CaseResult
+					if err != nil {
+						res = CaseResult{PackID: pack.PackID, LevelID: level.LevelID, Variant: variant, Kind: tc.kind, CaseID: tc.id, Message: err.Error()}
+					} else {
+						workDir := filepath.Join(workRoot, pack.PackID, level.LevelID, variant, tc.kind+"-"+tc.id)
+						res = r.runCase(ctx, pack, lv, tc, workDir)
+					}
+					if res.Passed {
+						report.Passed++
+					} else {
+						report.Failed++
+					}
+					report.Cases = append(report.Cases, res)
+					if r.opts.OnCase != nil {
+						r.opts.OnCase(res)
+					}
+				}
+			}
+		}
+	}
+	return report, nil
+}
+
+func (r *Runner) selected(levelID string) bool {
+	if len(r.opts.LevelIDs) == 0 {
+		return true
+	}
+	for _, id := range r.opts.LevelIDs {
+		if id == levelID {
+			return true
+		}
+	}
+	return false
+}
+
+func levelCases(level levels.Level) []testCase {
+	out := make([]testCase, 0, len(level.ReferenceSolutions)+len(level.Tests))
+	for _, sol := range level.ReferenceSolutions {
+		out = append(out, testCase{kind: CaseReference, id: sol.SolutionID, script: sol.ScriptSH})
+	}
+	for _, t := range level.Tests {
+		out = append(out, testCase{kind: CaseNegative, id: t.TestID, script: t.ScriptSH, expect: t.ExpectFailedChecks})
+	}
+	return out
+}
+
+func (r *Runner) runCase(ctx context.Context, pack levels.Pack, level levels.Level, tc testCase, workDir string) (res CaseResult) {
+	started := time.Now()
+	res = CaseResult{PackID: pack.PackID, LevelID: level.LevelID, Variant: level.Variant, DatasetSeed: level.RunSeed, Kind: tc.kind, CaseID: tc.id}
+	defer func() { res.DurationMS = time.Since(started).Milliseconds() }()
+
+	image, err := r.ensureImage(ctx, pack, level)
+	if err != nil {
+		res.Message = err.Error()
+		return res
+	}
+	level, err = r.loader.PrepareDataset(ctx, pack, level, nil)
+	if err != nil {
+		res.Message = err.Error()
+		return res
+	}
+	if err := r.loader.StageWorkdir(level, workDir); err != nil {
+		res.Message = "stage workdir: " + err.Error()
+		return res
+	}
+	handle, err := r.sandbox.StartLevel(ctx, startSpec(r.sessionID, pack, level, image, workDir, r.containerName(level.LevelID, tc)))
+	if err != nil {
+		res.Message = err.Error()
+		return res
+	}
+	defer func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_ = handle.Stop(stopCtx)
+	}()
+
+	hookExec := func(ctx context.Context, script string) ([]byte, error) {
+		return r.sandbox.ExecScript(ctx, handle, level.Shell.CWD, script)
+	}
+	if err := level.Setup.RunHooks(ctx, "setup", hookExec, nil); err != nil {
+		res.Message = err.Error()
+		return res
+	}
+
+	scriptCtx, cancel := context.WithTimeout(ctx, r.opts.ScriptTimeout)
+	out, scriptErr := r.sandbox.ExecScript(scriptCtx, handle, level.Shell.CWD, tc.script)
+	cancel()
+	res.Output = strings.TrimSpace(string(out))
+	if err := appendCmdlog(workDir, tc.script, time.Now()); err != nil {
+		res.Message = "write cmdlog: " + err.Error()
+		return res
+	}
+
+	result, err := r.grader.Grade(ctx, grading.Request{
+		AppVersion:  "0.1.0",
+		PackID:      pack.PackID,
+		PackVersion: pack.Version,
+		LevelID:     level.LevelID,
+		RunID:       fmt.Sprintf("%s-%s-%s", r.sessionID, tc.kind, tc.id),
+		Attempt:     1,
+		StartedAt:   started,
+		FinishedAt:  time.Now(),
+		Engine:      r.sandbox.CurrentEngine(),
+		Container:   handle.ContainerName(),
+		ImageRef:    image,
+		WorkDir:     workDir,
+		Services:    handle.Services(),
+		Restricted:  pack.Trust.Restricted,
+		Checks:      grading.ChecksForLevel(level),
+		BasePoints:  level.Scoring.BasePoints,
+	})
+	if err != nil {
+		res.Message = "grade: " + err.Error()
+		return res
+	}
+	res.FailedChecks = failedChecks(result)
+	if tc.kind == CaseNegative {
+		res.Passed, res.Message = judgeNegative(tc.expect, result)
+	} else {
+		res.Passed, res.Message = judgeReference(result, scriptErr)
+	}
+	if err := level.Teardown.RunHooks(ctx, "teardown", hookExec, nil); err != nil && res.Passed {
+		res.Passed, res.Message = false, err.Error()
+	}
+	return res
+}
+
+func (r *Runner) ensureImage(ctx context.Context, pack levels.Pack, level levels.Level) (string, error) {
+	image := level.ImageRef(pack)
+	if r.ensured[image] {
+		return image, nil
+	}
+	if err := EnsureImage(ctx, r.sandbox.CurrentEngine(), pack, image); err != nil {
+		return "", err
+	}
+	r.ensured[image] = true
+	return image, nil
+}
+
+// EnsureImage makes image available to engine: it is built from the pack's
+// image.build, pulled when image.pull is set, or must already exist.
+func EnsureImage(ctx context.Context, engine string, pack levels.Pack, image string) error {
+	exists, err := sandbox.ImageExists(ctx, engine, image)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		switch {
+		case image == pack.Image.Ref && pack.Image.Build != nil && !pack.Image.Pull:
+			build := pack.Image.Build
+			err = sandbox.BuildImage(ctx, engine, sandbox.ImageBuild{
+				Ref:        pack.Image.Ref,
+				ContextDir: filepath.Join(pack.Path, build.ContextDir),
+				Dockerfile: build.Dockerfile,
+				Target:     build.Target,
+				Args:       build.Args,
+			})
+		case pack.Image.Pull:
+			err = sandbox.PullImage(ctx, engine, image)
+		default:
+			err = fmt.Errorf("image %q not found locally (pack %s); set image.pull=true, run make image, or configure image.build", image, pack.PackID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Runner) containerName(levelID string, tc testCase) string {
+	short := r.sessionID
+	if len(short) > 8 {
+		short = short[:8]
+	}
+	return "clidojo_test_" + short + "_" + unsafeNameChars.ReplaceAllString(levelID+"_"+tc.kind+"_"+tc.id, "_")
+}
+
+func startSpec(sessionID string, pack levels.Pack, level levels.Level, image, workDir, name string) sandbox.StartSpec {
+	readOnly := true
+	if level.Sandbox.ReadOnlyRoot != nil {
+		readOnly = *level.Sandbox.ReadOnlyRoot
+	}
+	tmpfs := make([]sandbox.TmpfsMount, 0, len(level.Sandbox.Tmpfs))
+	for _, tm := range level.Sandbox.Tmpfs {
+		tmpfs = append(tmpfs, sandbox.TmpfsMount{Mount: tm.Mount, Options: tm.Options})
+	}
+	return sandbox.StartSpec{
+		SessionID:     sessionID,
+		PackID:        pack.PackID,
+		LevelID:       level.LevelID,
+		ContainerName: name,
+		Image:         image,
+		DatasetDir:    level.DatasetHostPath,
+		DatasetMount:  level.Filesystem.Dataset.MountPoint,
+		WorkDir:       workDir,
+		WorkMount:     level.Filesystem.Work.MountPoint,
+		ShellProgram:  level.Shell.Program,
+		ShellArgs:     level.Shell.Args,
+		ShellCWD:      level.Shell.CWD,
+		ShellEnv:      level.Shell.Env,
+		Network:       level.Sandbox.Network,
+		ReadOnlyRoot:  readOnly,
+		CPU:           level.Sandbox.CPU,
+		MemoryMB:      level.Sandbox.MemoryMB,
+		PidsLimit:     level.Sandbox.PidsLimit,
+		Tmpfs:         tmpfs,
+		Services:      serviceSpecs(level),
+	}
+}
+
+// serviceSpecs converts the level's services for sandbox.StartSpec.
+func serviceSpecs(level levels.Level) []sandbox.ServiceSpec {
+	runs := level.ServiceRuns()
+	out := make([]sandbox.ServiceSpec, 0, len(runs))
+	for _, run := range runs {
+		out = append(out, sandbox.ServiceSpec(run))
+	}
+	return out
+}
+
+// appendCmdlog records the script in .dojo_cmdlog the way the interactive
+// bashrc would, so cmdlog checks and bonuses see the solution's commands.
+func appendCmdlog(workDir, script string, now time.Time) error {
+	f, err := os.OpenFile(filepath.Join(workDir, ".dojo_cmdlog"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	for _, entry := range cmdlogEntries(script) {
+		if _, err := fmt.Fprintf(f, "%d\t%s\n", now.Unix(), entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cmdlogEntries splits a script into history-style entries: one per command
+// line, with backslash continuations joined and blank/comment lines dropped.
+func cmdlogEntries(script string) []string {
+	entries := []string{}
+	var cur strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasSuffix(trimmed, `\`) {
+			cur.WriteString(strings.TrimSpace(strings.TrimSuffix(trimmed, `\`)))
+			cur.WriteString(" ")
+			continue
+		}
+		cur.WriteString(trimmed)
+		entry := strings.TrimSpace(cur.String())
+		cur.Reset()
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if rest := strings.TrimSpace(cur.String()); rest != "" {
+		entries = append(entries, rest)
+	}
+	return entries
+}
+
+func failedChecks(result grading.Result) []string {
+	out := []string{}
+	for _, c := range result.Checks {
+		if !c.Passed {
+			out = append(out, c.ID)
+		}
+	}
+	return out
+}
+
+func judgeReference(result grading.Result, scriptErr error) (bool, string) {
+	if result.Passed {
+		return true, ""
+	}
+	failed := []string{}
+	for _, c := range result.Checks {
+		if !c.Passed && c.Required {
+			failed = append(failed, fmt.Sprintf("%s (%s)", c.ID, firstNonEmpty(c.Message, c.Summary)))
+		}
+	}
+	msg := "required checks failed: " + strings.Join(failed, ", ")
+	if scriptErr != nil {
+		msg += "; " + scriptErr.Error()
+	}
+	return false, msg
+}
+
+// judgeNegative passes when every expected check failed and no other
+// required check did.
+func judgeNegative(expect []string, result grading.Result) (bool, string) {
+	failed := map[string]bool{}
+	required := map[string]bool{}
+	for _, c := range result.Checks {
+		failed[c.ID] = !c.Passed
+		required[c.ID] = c.Required
+	}
+	expected := map[string]bool{}
+	missing := []string{}
+	for _, id := range expect {
+		expected[id] = true
+		if !failed[id] {
+			missing = append(missing, id)
+		}
+	}
+	unexpected := []string{}
+	for _, c := range result.Checks {
+		if failed[c.ID] && required[c.ID] && !expected[c.ID] {
+			unexpected = append(unexpected, c.ID)
+		}
+	}
+	problems := []string{}
+	if len(missing) > 0 {
+		problems = append(problems, "expected to fail but passed: "+strings.Join(missing, ", "))
+	}
+	if len(unexpected) > 0 {
+		problems = append(problems, "unexpected failures: "+strings.Join(unexpected, ", "))
+	}
+	if len(problems) > 0 {
+		return false, strings.Join(problems, "; ")
+	}
+	return true, ""
+}
+
+func firstNonEmpty(a, b string) string {
+	if strings.TrimSpace(a) != "" {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,90 @@
+package selftest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"clidojo/internal/grading"
+	"clidojo/internal/levels"
+)
+
+func TestCmdlogEntriesJoinsContinuations(t *testing.T) {
+	script := "# setup\nsort /levels/current/animals.txt \\\n  | uniq -c \\\n  | sort -nr > /work/out.txt\n\necho done\n"
+	got := cmdlogEntries(script)
+	want := []string{
+		"sort /levels/current/animals.txt | uniq -c | sort -nr > /work/out.txt",
+		"echo done",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected entries: %#v", got)
+	}
+}
+
+func TestAppendCmdlogWritesTabSeparatedEntries(t *testing.T) {
+	dir := t.TempDir()
+	if err := appendCmdlog(dir, "ls | wc -l\n", time.Unix(1700000000, 0)); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, ".dojo_cmdlog"))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(b) != "1700000000\tls | wc -l\n" {
+		t.Fatalf("unexpected cmdlog %q", string(b))
+	}
+}
+
+func TestJudgeNegative(t *testing.T) {
+	result := grading.Result{Checks: []grading.CheckResult{
+		{ID: "exists", Required: true, Passed: true},
+		{ID: "content", Required: true, Passed: false},
+		{ID: "bonus", Required: false, Passed: false},
+	}}
+	if ok, msg := judgeNegative([]string{"content"}, result); !ok {
+		t.Fatalf("expected pass, got %q", msg)
+	}
+	if ok, msg := judgeNegative([]string{"content", "bonus"}, result); !ok {
+		t.Fatalf("expected pass with optional check, got %q", msg)
+	}
+	ok, msg := judgeNegative([]string{"exists"}, result)
+	if ok || !strings.Contains(msg, "passed: exists") || !strings.Contains(msg, "unexpected failures: content") {
+		t.Fatalf("expected missing and unexpected failures, got %v %q", ok, msg)
+	}
+}
+
+func TestJudgeReferenceReportsRequiredFailures(t *testing.T) {
+	result := grading.Result{Passed: false, Checks: []grading.CheckResult{
+		{ID: "content", Required: true, Passed: false, Message: "output differs"},
+		{ID: "bonus", Required: false, Passed: false},
+	}}
+	ok, msg := judgeReference(result, nil)
+	if ok || msg != "required checks failed: content (output differs)" {
+		t.Fatalf("unexpected judgement %v %q", ok, msg)
+	}
+	if ok, _ := judgeReference(grading.Result{Passed: true}, nil); !ok {
+		t.Fatalf("expected passing result to pass")
+	}
+}
+
+func TestLevelCasesOrdersReferenceBeforeNegative(t *testing.T) {
+	level := levels.Level{
+		ReferenceSolutions: []levels.ReferenceSolution{{SolutionID: "sol1", ScriptSH: "true"}},
+		Tests:              []levels.LevelTest{{TestID: "empty", ScriptSH: ":", ExpectFailedChecks: []string{"c1"}}},
+	}
+	cases := levelCases(level)
+	if len(cases) != 2 || cases[0].kind != CaseReference || cases[1].kind != CaseNegative || cases[1].expect[0] != "c1" {
+		t.Fatalf("unexpected cases: %#v", cases)
+	}
+}
+
+func TestRunRejectsMockSandbox(t *testing.T) {
+	_, err := NewRunner(Options{SandboxMode: "mock"}).Run(context.Background(), nil)
+	if err == nil {
+		t.Fatalf("expected mock sandbox to be rejected")
+	}
+}
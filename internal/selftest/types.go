@@ -0,0 +1,46 @@
+package selftest
+
+import "time"
+
+const (
+	CaseReference = "reference"
+	CaseNegative  = "negative"
+)
+
+type Options struct {
+	SandboxMode    string
+	EngineOverride string
+
+	// WorkRoot holds the staged workdirs. A temporary directory is used (and
+	// removed afterwards unless KeepWorkdirs is set) when empty.
+	WorkRoot     string
+	KeepWorkdirs bool
+
+	// LevelIDs limits the run to the listed levels when non-empty.
+	LevelIDs      []string
+	ScriptTimeout time.Duration
+
+	// OnCase is called after every finished case, e.g. for progress output.
+	OnCase func(CaseResult)
+}
+
+type CaseResult struct {
+	PackID       string   `json:"pack_id"`
+	LevelID      string   `json:"level_id"`
+	Variant      string   `json:"variant,omitempty"`
+	DatasetSeed  *int64   `json:"dataset_seed,omitempty"`
+	Kind         string   `json:"kind"`
+	CaseID       string   `json:"case_id"`
+	Passed       bool     `json:"passed"`
+	Message      string   `json:"message,omitempty"`
+	FailedChecks []string `json:"failed_checks,omitempty"`
+	Output       string   `json:"output,omitempty"`
+	DurationMS   int64    `json:"duration_ms"`
+}
+
+type Report struct {
+	Engine string       `json:"engine"`
+	Cases  []CaseResult `json:"cases"`
+	Passed int          `json:"passed"`
+	Failed int          `json:"failed"`
+}
@@ -12,6 +12,8 @@ type Store interface {
 	RecordCheckAttempt(ctx context.Context, runID int64, passed bool) error
 	UpsertLevelProgress(ctx context.Context, update LevelProgressUpdate) error
 	GetLevelProgressMap(ctx context.Context) (map[string]LevelProgress, error)
+	GetPassedLevels(ctx context.Context) (map[string]int, error)
+	GetVariantRuns(ctx context.Context, packID, levelID string) (map[string]int, error)
 	UpsertDailyDrill(ctx context.Context, drill DailyDrill) error
 	GetDailyDrill(ctx context.Context, day string) (*DailyDrill, error)
 	SaveSettings(ctx context.Context, values map[string]string) error
@@ -28,7 +30,10 @@ type LevelRun struct {
 	PackID    string
 	LevelID   string
 	Mode      string
-	StartTS   time.Time
+	Variant   string
+	// DatasetSeed is the generator seed of seed: per_run levels.
+	DatasetSeed *int64
+	StartTS     time.Time
 }
 
 type Summary struct {
@@ -39,16 +44,19 @@ type Summary struct {
 }
 
 type LastRun struct {
-	PackID     string
-	LevelID    string
-	Mode       string
-	StartTS    time.Time
-	LastPassed bool
-	Attempts   int
-	Resets     int
+	PackID      string
+	LevelID     string
+	Mode        string
+	Variant     string
+	DatasetSeed *int64
+	StartTS     time.Time
+	LastPassed  bool
+	Attempts    int
+	Resets      int
 }
 
 type LevelProgress struct {
+	PackID       string
 	LevelID      string
 	PassedCount  int
 	BestScore    int
@@ -58,6 +66,7 @@ type LevelProgress struct {
 }
 
 type LevelProgressUpdate struct {
+	PackID       string
 	LevelID      string
 	Passed       bool
 	Score        int
@@ -35,6 +35,8 @@ func (s *SQLiteStore) EnsureSchema(ctx context.Context) error {
 			pack_id TEXT NOT NULL,
 			level_id TEXT NOT NULL,
 			mode TEXT NOT NULL DEFAULT 'free',
+			variant TEXT NOT NULL DEFAULT '',
+			dataset_seed INTEGER,
 			start_ts TEXT NOT NULL,
 			resets INTEGER NOT NULL DEFAULT 0,
 			attempts INTEGER NOT NULL DEFAULT 0,
@@ -56,14 +58,7 @@ func (s *SQLiteStore) EnsureSchema(ctx context.Context) error {
 			created_ts TEXT NOT NULL DEFAULT (datetime('now')),
 			UNIQUE(concept, source_level_id, due_date)
 		);`,
-		`CREATE TABLE IF NOT EXISTS level_progress (
-			level_id TEXT PRIMARY KEY,
-			passed_count INTEGER NOT NULL DEFAULT 0,
-			best_score INTEGER NOT NULL DEFAULT 0,
-			best_time_ms INTEGER NOT NULL DEFAULT 0,
-			last_played_ts TEXT NOT NULL DEFAULT '',
-			last_passed_ts TEXT NOT NULL DEFAULT ''
-		);`,
+		levelProgressTable,
 		`CREATE TABLE IF NOT EXISTS daily_drill (
 			day TEXT PRIMARY KEY,
 			playlist_json TEXT NOT NULL,
@@ -80,23 +75,76 @@ func (s *SQLiteStore) EnsureSchema(ctx context.Context) error {
 			return fmt.Errorf("ensure schema: %w", err)
 		}
 	}
-	// Backfill older schemas that predate level_runs.mode.
-	if _, err := s.db.ExecContext(ctx, `ALTER TABLE level_runs ADD COLUMN mode TEXT NOT NULL DEFAULT 'free'`); err != nil {
-		msg := strings.ToLower(err.Error())
-		if !strings.Contains(msg, "duplicate column name") {
-			return fmt.Errorf("ensure schema alter level_runs.mode: %w", err)
+	if err := s.migrateLevelProgress(ctx); err != nil {
+		return fmt.Errorf("ensure schema migrate level_progress: %w", err)
+	}
+	// Backfill older schemas that predate level_runs.mode, .variant and
+	// .dataset_seed.
+	for _, col := range []string{"mode TEXT NOT NULL DEFAULT 'free'", "variant TEXT NOT NULL DEFAULT ''", "dataset_seed INTEGER"} {
+		if _, err := s.db.ExecContext(ctx, `ALTER TABLE level_runs ADD COLUMN `+col); err != nil {
+			msg := strings.ToLower(err.Error())
+			if !strings.Contains(msg, "duplicate column name") {
+				return fmt.Errorf("ensure schema alter level_runs.%s: %w", strings.Fields(col)[0], err)
+			}
 		}
 	}
 	return nil
 }
 
+const levelProgressTable = `CREATE TABLE IF NOT EXISTS level_progress (
+	pack_id TEXT NOT NULL DEFAULT '',
+	level_id TEXT NOT NULL,
+	passed_count INTEGER NOT NULL DEFAULT 0,
+	best_score INTEGER NOT NULL DEFAULT 0,
+	best_time_ms INTEGER NOT NULL DEFAULT 0,
+	last_played_ts TEXT NOT NULL DEFAULT '',
+	last_passed_ts TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY(pack_id, level_id)
+);`
+
+// migrateLevelProgress rekeys a level_progress table from before pack_id by
+// pack and level. Each old row goes to the pack with the most runs of that
+// level_id, or to pack "" when it has none.
+func (s *SQLiteStore) migrateLevelProgress(ctx context.Context) error {
+	var n int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pragma_table_info('level_progress') WHERE name = 'pack_id'`).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	for _, stmt := range []string{
+		`ALTER TABLE level_progress RENAME TO level_progress_old`,
+		levelProgressTable,
+		`INSERT INTO level_progress(pack_id, level_id, passed_count, best_score, best_time_ms, last_played_ts, last_passed_ts)
+		SELECT COALESCE((
+			SELECT r.pack_id FROM level_runs r WHERE r.level_id = o.level_id
+			GROUP BY r.pack_id ORDER BY COUNT(*) DESC, r.pack_id LIMIT 1
+		), ''), o.level_id, o.passed_count, o.best_score, o.best_time_ms, o.last_played_ts, o.last_passed_ts
+		FROM level_progress_old o`,
+		`DROP TABLE level_progress_old`,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
 func (s *SQLiteStore) StartLevelRun(ctx context.Context, run LevelRun) (int64, error) {
 	res, err := s.db.ExecContext(ctx,
-		`INSERT INTO level_runs(session_id, pack_id, level_id, mode, start_ts) VALUES(?,?,?,?,?)`,
+		`INSERT INTO level_runs(session_id, pack_id, level_id, mode, variant, dataset_seed, start_ts) VALUES(?,?,?,?,?,?,?)`,
 		run.SessionID,
 		run.PackID,
 		run.LevelID,
 		strings.TrimSpace(run.Mode),
+		strings.TrimSpace(run.Variant),
+		run.DatasetSeed,
 		run.StartTS.UTC().Format(timeLayout),
 	)
 	if err != nil {
@@ -138,9 +186,9 @@ func (s *SQLiteStore) UpsertLevelProgress(ctx context.Context, update LevelProgr
 		passTS = playTS.UTC().Format(timeLayout)
 	}
 	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO level_progress(level_id, passed_count, best_score, best_time_ms, last_played_ts, last_passed_ts)
-		VALUES(?, ?, ?, ?, ?, ?)
-		ON CONFLICT(level_id) DO UPDATE SET
+		INSERT INTO level_progress(pack_id, level_id, passed_count, best_score, best_time_ms, last_played_ts, last_passed_ts)
+		VALUES(?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(pack_id, level_id) DO UPDATE SET
 			passed_count = level_progress.passed_count + excluded.passed_count,
 			best_score = CASE
 				WHEN excluded.best_score > 0 AND excluded.best_score > level_progress.best_score THEN excluded.best_score
@@ -156,6 +204,7 @@ func (s *SQLiteStore) UpsertLevelProgress(ctx context.Context, update LevelProgr
 				ELSE level_progress.last_passed_ts
 			END
 	`,
+		strings.TrimSpace(update.PackID),
 		levelID,
 		ifThen(update.Passed, 1, 0),
 		max(0, update.Score),
@@ -166,9 +215,11 @@ func (s *SQLiteStore) UpsertLevelProgress(ctx context.Context, update LevelProgr
 	return err
 }
 
+// GetLevelProgressMap returns progress per level, keyed by
+// "pack_id/level_id".
 func (s *SQLiteStore) GetLevelProgressMap(ctx context.Context) (map[string]LevelProgress, error) {
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT level_id, passed_count, best_score, best_time_ms, last_played_ts, last_passed_ts
+		SELECT pack_id, level_id, passed_count, best_score, best_time_ms, last_played_ts, last_passed_ts
 		FROM level_progress
 	`)
 	if err != nil {
@@ -178,6 +229,7 @@ func (s *SQLiteStore) GetLevelProgressMap(ctx context.Context) (map[string]Level
 	out := map[string]LevelProgress{}
 	for rows.Next() {
 		var (
+			packID       string
 			levelID      string
 			passedCount  int
 			bestScore    int
@@ -187,7 +239,7 @@ func (s *SQLiteStore) GetLevelProgressMap(ctx context.Context) (map[string]Level
 			lastPlayedTS time.Time
 			lastPassedTS time.Time
 		)
-		if err := rows.Scan(&levelID, &passedCount, &bestScore, &bestTimeMS, &lastPlayed, &lastPassed); err != nil {
+		if err := rows.Scan(&packID, &levelID, &passedCount, &bestScore, &bestTimeMS, &lastPlayed, &lastPassed); err != nil {
 			return nil, err
 		}
 		if t, err := time.Parse(timeLayout, lastPlayed); err == nil {
@@ -196,7 +248,8 @@ func (s *SQLiteStore) GetLevelProgressMap(ctx context.Context) (map[string]Level
 		if t, err := time.Parse(timeLayout, lastPassed); err == nil {
 			lastPassedTS = t
 		}
-		out[levelID] = LevelProgress{
+		out[packID+"/"+levelID] = LevelProgress{
+			PackID:       packID,
 			LevelID:      levelID,
 			PassedCount:  passedCount,
 			BestScore:    bestScore,
@@ -211,6 +264,63 @@ func (s *SQLiteStore) GetLevelProgressMap(ctx context.Context) (map[string]Level
 	return out, nil
 }
 
+// GetPassedLevels counts passed runs per level, keyed by "pack_id/level_id".
+func (s *SQLiteStore) GetPassedLevels(ctx context.Context) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT r.pack_id, r.level_id, COUNT(DISTINCT r.id)
+		FROM level_runs r
+		JOIN check_attempts c ON c.run_id = r.id AND c.passed = 1
+		GROUP BY r.pack_id, r.level_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]int{}
+	for rows.Next() {
+		var (
+			packID  string
+			levelID string
+			passed  int
+		)
+		if err := rows.Scan(&packID, &levelID, &passed); err != nil {
+			return nil, err
+		}
+		out[packID+"/"+levelID] = passed
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetVariantRuns counts runs of a level per variant_id. Runs recorded before
+// the level had variants are keyed by "".
+func (s *SQLiteStore) GetVariantRuns(ctx context.Context, packID, levelID string) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT variant, COUNT(*)
+		FROM level_runs
+		WHERE pack_id = ? AND level_id = ?
+		GROUP BY variant
+	`, packID, levelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]int{}
+	for rows.Next() {
+		var (
+			variant string
+			runs    int
+		)
+		if err := rows.Scan(&variant, &runs); err != nil {
+			return nil, err
+		}
+		out[variant] = runs
+	}
+	return out, rows.Err()
+}
+
 func (s *SQLiteStore) UpsertDailyDrill(ctx context.Context, drill DailyDrill) error {
 	day := strings.TrimSpace(drill.Day)
 	if day == "" {
@@ -386,7 +496,7 @@ func (s *SQLiteStore) GetSummary(ctx context.Context) (Summary, error) {
 
 func (s *SQLiteStore) GetLastRun(ctx context.Context) (*LastRun, error) {
 	row := s.db.QueryRowContext(ctx, `
-		SELECT pack_id, level_id, mode, start_ts, last_passed, attempts, resets
+		SELECT pack_id, level_id, mode, variant, dataset_seed, start_ts, last_passed, attempts, resets
 		FROM level_runs
 		ORDER BY id DESC
 		LIMIT 1
@@ -395,12 +505,14 @@ func (s *SQLiteStore) GetLastRun(ctx context.Context) (*LastRun, error) {
 		packID     string
 		levelID    string
 		mode       string
+		variant    string
+		seed       sql.NullInt64
 		startTSRaw string
 		lastPassed int
 		attempts   int
 		resets     int
 	)
-	if err := row.Scan(&packID, &levelID, &mode, &startTSRaw, &lastPassed, &attempts, &resets); err != nil {
+	if err := row.Scan(&packID, &levelID, &mode, &variant, &seed, &startTSRaw, &lastPassed, &attempts, &resets); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -410,15 +522,20 @@ func (s *SQLiteStore) GetLastRun(ctx context.Context) (*LastRun, error) {
 	if err != nil {
 		startTS = time.Time{}
 	}
-	return &LastRun{
+	out := &LastRun{
 		PackID:     packID,
 		LevelID:    levelID,
 		Mode:       mode,
+		Variant:    variant,
 		StartTS:    startTS,
 		LastPassed: lastPassed == 1,
 		Attempts:   attempts,
 		Resets:     resets,
-	}, nil
+	}
+	if seed.Valid {
+		out.DatasetSeed = &seed.Int64
+	}
+	return out, nil
 }
 
 func (s *SQLiteStore) Close() error {
@@ -119,3 +119,163 @@ func TestDailyDrillUpsertAndGet(t *testing.T) {
 		t.Fatalf("expected completed_count=2, got %d", got.CompletedCount)
 	}
 }
+
+func TestGetPassedLevelsKeysByPack(t *testing.T) {
+	store, err := NewSQLite(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("new sqlite: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	ctx := context.Background()
+	if err := store.EnsureSchema(ctx); err != nil {
+		t.Fatalf("ensure schema: %v", err)
+	}
+
+	start := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	for _, run := range []struct {
+		pack, level string
+		passed      bool
+	}{
+		{"builtin-core", "level-001", true},
+		{"builtin-core", "level-001", true},
+		{"incident-response", "level-001", false},
+	} {
+		id, err := store.StartLevelRun(ctx, LevelRun{SessionID: "s", PackID: run.pack, LevelID: run.level, Mode: "free", StartTS: start})
+		if err != nil {
+			t.Fatalf("start run: %v", err)
+		}
+		if err := store.RecordCheckAttempt(ctx, id, run.passed); err != nil {
+			t.Fatalf("record attempt: %v", err)
+		}
+	}
+
+	passed, err := store.GetPassedLevels(ctx)
+	if err != nil {
+		t.Fatalf("get passed levels: %v", err)
+	}
+	if passed["builtin-core/level-001"] != 2 || passed["incident-response/level-001"] != 0 {
+		t.Fatalf("unexpected passed map %v", passed)
+	}
+}
+
+func TestLevelProgressKeysByPack(t *testing.T) {
+	store, err := NewSQLite(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("new sqlite: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	ctx := context.Background()
+	if err := store.EnsureSchema(ctx); err != nil {
+		t.Fatalf("ensure schema: %v", err)
+	}
+	for _, u := range []LevelProgressUpdate{
+		{PackID: "core", LevelID: "intro", Passed: true, Score: 900},
+		{PackID: "extra", LevelID: "intro", Passed: false, Score: 0},
+	} {
+		if err := store.UpsertLevelProgress(ctx, u); err != nil {
+			t.Fatalf("upsert progress: %v", err)
+		}
+	}
+	progress, err := store.GetLevelProgressMap(ctx)
+	if err != nil {
+		t.Fatalf("get progress: %v", err)
+	}
+	if p := progress["core/intro"]; p.PassedCount != 1 || p.BestScore != 900 || p.PackID != "core" {
+		t.Fatalf("unexpected core progress %+v", p)
+	}
+	if p := progress["extra/intro"]; p.PassedCount != 0 || p.BestScore != 0 {
+		t.Fatalf("extra/intro should not share core's progress: %+v", p)
+	}
+}
+
+func TestEnsureSchemaMigratesLevelProgressToPacks(t *testing.T) {
+	store, err := NewSQLite(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("new sqlite: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	ctx := context.Background()
+	for _, stmt := range []string{
+		`CREATE TABLE level_progress (level_id TEXT PRIMARY KEY, passed_count INTEGER NOT NULL DEFAULT 0, best_score INTEGER NOT NULL DEFAULT 0,
+			best_time_ms INTEGER NOT NULL DEFAULT 0, last_played_ts TEXT NOT NULL DEFAULT '', last_passed_ts TEXT NOT NULL DEFAULT '')`,
+		`INSERT INTO level_progress(level_id, passed_count, best_score) VALUES('intro', 2, 700), ('orphan', 1, 100)`,
+		`CREATE TABLE level_runs (id INTEGER PRIMARY KEY AUTOINCREMENT, session_id TEXT NOT NULL, pack_id TEXT NOT NULL, level_id TEXT NOT NULL,
+			start_ts TEXT NOT NULL, resets INTEGER NOT NULL DEFAULT 0, attempts INTEGER NOT NULL DEFAULT 0, last_passed INTEGER NOT NULL DEFAULT 0)`,
+		`INSERT INTO level_runs(session_id, pack_id, level_id, start_ts) VALUES('s', 'core', 'intro', ''), ('s', 'core', 'intro', ''), ('s', 'extra', 'intro', '')`,
+	} {
+		if _, err := store.db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("old schema: %v", err)
+		}
+	}
+	if err := store.EnsureSchema(ctx); err != nil {
+		t.Fatalf("ensure schema: %v", err)
+	}
+	progress, err := store.GetLevelProgressMap(ctx)
+	if err != nil {
+		t.Fatalf("get progress: %v", err)
+	}
+	if len(progress) != 2 || progress["core/intro"].BestScore != 700 || progress["/orphan"].PassedCount != 1 {
+		t.Fatalf("unexpected migrated progress %v", progress)
+	}
+	if err := store.EnsureSchema(ctx); err != nil {
+		t.Fatalf("ensure schema is not idempotent: %v", err)
+	}
+}
+
+func TestGetVariantRunsCountsPerVariant(t *testing.T) {
+	store, err := NewSQLite(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("new sqlite: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	ctx := context.Background()
+	if err := store.EnsureSchema(ctx); err != nil {
+		t.Fatalf("ensure schema: %v", err)
+	}
+
+	start := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	for _, variant := range []string{"", "top5", "top3", "top3"} {
+		if _, err := store.StartLevelRun(ctx, LevelRun{SessionID: "s", PackID: "builtin-core", LevelID: "level-003", Mode: "free", Variant: variant, StartTS: start}); err != nil {
+			t.Fatalf("start run: %v", err)
+		}
+	}
+	runs, err := store.GetVariantRuns(ctx, "builtin-core", "level-003")
+	if err != nil {
+		t.Fatalf("get variant runs: %v", err)
+	}
+	if runs[""] != 1 || runs["top5"] != 1 || runs["top3"] != 2 {
+		t.Fatalf("unexpected variant runs %v", runs)
+	}
+	last, err := store.GetLastRun(ctx)
+	if err != nil || last == nil || last.Variant != "top3" {
+		t.Fatalf("expected last run variant top3, got %+v (%v)", last, err)
+	}
+}
+
+func TestLastRunKeepsDatasetSeed(t *testing.T) {
+	store, err := NewSQLite(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("new sqlite: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	ctx := context.Background()
+	if err := store.EnsureSchema(ctx); err != nil {
+		t.Fatalf("ensure schema: %v", err)
+	}
+
+	start := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := store.StartLevelRun(ctx, LevelRun{SessionID: "s", PackID: "p", LevelID: "fixed", StartTS: start}); err != nil {
+		t.Fatalf("start run: %v", err)
+	}
+	if last, err := store.GetLastRun(ctx); err != nil || last == nil || last.DatasetSeed != nil {
+		t.Fatalf("expected no seed for a fixed-seed run, got %+v (%v)", last, err)
+	}
+	seed := int64(-4242)
+	if _, err := store.StartLevelRun(ctx, LevelRun{SessionID: "s", PackID: "p", LevelID: "per-run", DatasetSeed: &seed, StartTS: start}); err != nil {
+		t.Fatalf("start run: %v", err)
+	}
+	last, err := store.GetLastRun(ctx)
+	if err != nil || last == nil || last.DatasetSeed == nil || *last.DatasetSeed != seed {
+		t.Fatalf("expected seed %d, got %+v (%v)", seed, last, err)
+	}
+}
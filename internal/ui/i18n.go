@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DefaultLocale is the language of the built-in UI text and the fallback for
+// keys a catalog does not translate.
+const DefaultLocale = "en"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// catalogs maps a language to its UI messages, loaded from locales/<lang>.json.
+var catalogs = loadCatalogs()
+
+func loadCatalogs() map[string]map[string]string {
+	out := map[string]map[string]string{}
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	for _, e := range entries {
+		b, err := localeFiles.ReadFile(path.Join("locales", e.Name()))
+		if err != nil {
+			panic(err)
+		}
+		msgs := map[string]string{}
+		if err := json.Unmarshal(b, &msgs); err != nil {
+			panic(fmt.Sprintf("locales/%s: %v", e.Name(), err))
+		}
+		out[strings.TrimSuffix(e.Name(), ".json")] = msgs
+	}
+	return out
+}
+
+// Locales lists the languages with a UI message catalog, sorted.
+func Locales() []string {
+	out := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		out = append(out, lang)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// NormalizeLocale turns values such as "de_DE.UTF-8" or "pt-BR" into the
+// "de_DE" form used for level i18n keys. "C" and "POSIX" have no language and
+// normalize to "".
+func NormalizeLocale(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, ".@"); i >= 0 {
+		s = s[:i]
+	}
+	if s == "" || s == "C" || s == "POSIX" {
+		return ""
+	}
+	lang, region, found := strings.Cut(strings.ReplaceAll(s, "-", "_"), "_")
+	lang = strings.ToLower(lang)
+	if !found {
+		return lang
+	}
+	return lang + "_" + strings.ToUpper(region)
+}
+
+// ResolveLocale returns the locale for a settings value: the value itself, or
+// for "auto" and "" the first of LC_ALL, LC_MESSAGES and LANG that names a
+// language, else DefaultLocale.
+func ResolveLocale(setting string) string {
+	if setting = strings.TrimSpace(setting); setting != "" && setting != "auto" {
+		return NormalizeLocale(setting)
+	}
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale := NormalizeLocale(os.Getenv(env)); locale != "" {
+			return locale
+		}
+	}
+	return DefaultLocale
+}
+
+// messagesFor returns the catalog for locale's language, falling back to the
+// default catalog.
+func messagesFor(locale string) map[string]string {
+	lang, _, _ := strings.Cut(NormalizeLocale(locale), "_")
+	if msgs, ok := catalogs[lang]; ok {
+		return msgs
+	}
+	return catalogs[DefaultLocale]
+}
+
+// t looks up a UI message in the active catalog, then the default one, and
+// formats it with args. Unknown keys render as the key itself.
+func (r *Root) t(key string, args ...any) string {
+	msg, ok := r.msgs[key]
+	if !ok {
+		if msg, ok = catalogs[DefaultLocale][key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
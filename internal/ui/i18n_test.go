@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"sort"
+	"testing"
+
+	"clidojo/internal/term"
+)
+
+func TestLocaleCatalogsHaveSameKeys(t *testing.T) {
+	base := catalogs[DefaultLocale]
+	for _, lang := range Locales() {
+		for key := range base {
+			if _, ok := catalogs[lang][key]; !ok {
+				t.Errorf("locales/%s.json is missing %q", lang, key)
+			}
+		}
+		extra := []string{}
+		for key := range catalogs[lang] {
+			if _, ok := base[key]; !ok {
+				extra = append(extra, key)
+			}
+		}
+		sort.Strings(extra)
+		if len(extra) > 0 {
+			t.Errorf("locales/%s.json has keys missing from %s.json: %v", lang, DefaultLocale, extra)
+		}
+	}
+}
+
+func TestResolveLocale(t *testing.T) {
+	for in, want := range map[string]string{"de_DE.UTF-8": "de_DE", "pt-br": "pt_BR", "C.UTF-8": "", "POSIX": "", "fr": "fr"} {
+		if got := NormalizeLocale(in); got != want {
+			t.Fatalf("NormalizeLocale(%q) = %q, want %q", in, got, want)
+		}
+	}
+	t.Setenv("LC_ALL", "C")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "de_AT.UTF-8")
+	if got := ResolveLocale("auto"); got != "de_AT" {
+		t.Fatalf("expected LANG to be used, got %q", got)
+	}
+	if got := ResolveLocale("en"); got != "en" {
+		t.Fatalf("explicit setting should win, got %q", got)
+	}
+	t.Setenv("LANG", "")
+	if got := ResolveLocale(""); got != DefaultLocale {
+		t.Fatalf("expected the default locale, got %q", got)
+	}
+}
+
+func TestSetLocaleTranslatesUI(t *testing.T) {
+	v := New(Options{TermPane: term.NewTerminalPane(nil), Locale: "de_DE"})
+	if got := v.mainMenuItems()[0].Label; got != "Fortsetzen" {
+		t.Fatalf("expected a German menu label, got %q", got)
+	}
+	if got := v.t("no.such.key"); got != "no.such.key" {
+		t.Fatalf("unknown keys should render as themselves, got %q", got)
+	}
+	v.SetLocale("fr")
+	if got := v.mainMenuItems()[0].Label; got != catalogs[DefaultLocale]["main.continue"] {
+		t.Fatalf("languages without a catalog should fall back to %s, got %q", DefaultLocale, got)
+	}
+}
@@ -30,6 +30,11 @@ type Controller interface {
 	OnOpenDiff()
 	OnJournalExplainAI()
 	OnApplySettings(update SettingsState)
+	OnRestageLevel()
+	OnOpenTools()
+	// OnStartToolPlaylist starts a playlist of the tool's unplayed and
+	// unmastered levels.
+	OnStartToolPlaylist(toolID string)
 }
 
 type View interface {
@@ -39,6 +44,7 @@ type View interface {
 	SetScreen(screen Screen)
 	SetMainMenuState(state MainMenuState)
 	SetCatalog(packs []PackSummary)
+	SetTools(tools []ToolSummary)
 	SetLevelSelection(packID, levelID string)
 	SetPlayingState(PlayingState)
 	SetTooSmall(cols, rows int)
@@ -53,17 +59,27 @@ type View interface {
 	SetReferenceText(text string, open bool)
 	SetDiffText(text string, open bool)
 	SetInfo(title, text string, open bool)
+	// SetLocale switches the UI message catalog.
+	SetLocale(locale string)
+	// SetLoading opens the level loading overlay at step, with progress from
+	// 0 to 1. SetInfo closes it.
+	SetLoading(step string, progress float64)
 	SetSettings(state SettingsState, open bool)
 	SetChecking(checking bool)
 	FlashStatus(msg string)
 }
 
+// InfoTitleLevelChanged titles the --dev prompt shown when the active level
+// changes on disk; pressing r in it restages the level.
+const InfoTitleLevelChanged = "Level Changed"
+
 type Screen int
 
 const (
 	ScreenMainMenu Screen = iota
 	ScreenLevelSelect
 	ScreenPlaying
+	ScreenTools
 )
 
 type LayoutMode int
@@ -95,6 +111,10 @@ type PlayingState struct {
 	Streak       int
 	Badges       []string
 	SessionGoals []string
+	// Stage and StageCount are set for multi-stage levels; Stage is 1-based.
+	Stage      int
+	StageCount int
+	StageTitle string
 }
 
 type HintRow struct {
@@ -161,11 +181,20 @@ type SettingsState struct {
 	StyleVariant        string
 	MotionLevel         string
 	MouseScope          string
+	// Locale is "auto" (from the environment) or a UI language.
+	Locale string
 }
 
 type PackSummary struct {
-	PackID string
-	Name   string
+	PackID     string
+	Name       string
+	Source     string
+	SourcePath string
+	Trust      string
+	TrustKeyID string
+	Restricted bool
+	// Error is set when the pack failed to load; it then has no levels.
+	Error  string
 	Levels []LevelSummary
 }
 
@@ -182,6 +211,29 @@ type LevelSummary struct {
 	Prerequisites    []string
 	Locked           bool
 	LockReason       string
-	PassedCount      int
-	BestScore        int
+	// Broken levels failed to load and are always locked.
+	Broken      bool
+	PassedCount int
+	BestScore   int
+}
+
+// ToolSummary is a tool page: a tool from a pack's catalog, or a tool_focus
+// entry no pack describes, with the levels that focus on it.
+type ToolSummary struct {
+	ToolID    string
+	Name      string
+	SummaryMD string
+	Levels    []ToolLevel
+	Passed    int
+	Mastered  int
+}
+
+type ToolLevel struct {
+	PackID     string
+	LevelID    string
+	Title      string
+	Difficulty int
+	Passed     bool
+	Mastered   bool
+	Locked     bool
 }
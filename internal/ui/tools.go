@@ -0,0 +1,185 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/list"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+func (r *Root) refreshToolList() {
+	items := make([]list.Item, 0, len(r.tools))
+	for _, tool := range r.tools {
+		items = append(items, uiListItem{
+			title:       r.t("tools.count", tool.Name, tool.Mastered, len(tool.Levels)),
+			description: tool.ToolID,
+			filterValue: strings.ToLower(tool.ToolID + " " + tool.Name),
+		})
+	}
+	r.toolList.SetItems(items)
+	if len(items) == 0 {
+		r.toolIndex = 0
+		return
+	}
+	r.toolIndex = wrapIndex(r.toolIndex, len(items))
+	r.toolList.Select(r.toolIndex)
+}
+
+func (r *Root) selectedTool() *ToolSummary {
+	if len(r.tools) == 0 {
+		return nil
+	}
+	return &r.tools[wrapIndex(r.toolIndex, len(r.tools))]
+}
+
+// toolTrainingLevels counts the levels a playlist for tool would include.
+func toolTrainingLevels(tool ToolSummary) int {
+	n := 0
+	for _, lv := range tool.Levels {
+		if !lv.Mastered && !lv.Locked {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *Root) renderTools() string {
+	w, h := r.cols, r.rows
+	r.refreshToolList()
+	headerTxt := "CLI Dojo - " + r.t("tools.title") + " | " + r.t("tools.header")
+	header := r.theme.Header.Width(max(1, w)).Render(trimForWidth(headerTxt, max(1, w-1)))
+
+	leftW := min(40, max(24, w/3))
+	bodyH := max(8, h-2)
+	if len(r.toolList.Items()) > 0 {
+		r.toolList.SetWidth(max(8, leftW-4))
+		r.toolList.SetHeight(max(3, bodyH-4))
+		r.toolList.Select(wrapIndex(r.toolIndex, len(r.toolList.Items())))
+	}
+	leftView := strings.TrimRight(r.toolList.View(), "\n")
+	leftLines := []string{r.t("tools.none")}
+	if strings.TrimSpace(leftView) != "" {
+		leftLines = strings.Split(leftView, "\n")
+	}
+	left := r.drawPanel(r.t("panel.tools"), leftLines, leftW, bodyH)
+
+	rightW := max(22, w-lipgloss.Width(left))
+	r.updateDetailViewport(max(8, rightW-4), max(3, bodyH-4), r.toolDetailText(max(8, rightW-4)))
+	detailView := strings.TrimRight(r.detailVP.View(), "\n")
+	detailLines := []string{r.t("tools.none")}
+	if strings.TrimSpace(detailView) != "" {
+		detailLines = strings.Split(detailView, "\n")
+	}
+	right := r.drawPanel(r.t("panel.details"), detailLines, rightW, bodyH)
+
+	return header + "\n" + lipgloss.JoinHorizontal(lipgloss.Top, left, right)
+}
+
+func (r *Root) toolDetailText(width int) string {
+	tool := r.selectedTool()
+	if tool == nil {
+		return r.t("tools.none") + "\n\n" + r.t("detail.back")
+	}
+	total := len(tool.Levels)
+	ratio := func(n int) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(n) / float64(total)
+	}
+	bar := r.mastery
+	bar.SetWidth(max(8, min(30, width-20)))
+
+	var b strings.Builder
+	b.WriteString(tool.Name + "\n")
+	b.WriteString(r.t("detail.id", tool.ToolID) + "\n\n")
+	b.WriteString(r.t("tools.completed", tool.Passed, total) + "  " + bar.ViewAs(ratio(tool.Passed)) + "\n")
+	b.WriteString(r.t("tools.mastered", tool.Mastered, total) + "  " + bar.ViewAs(ratio(tool.Mastered)) + "\n")
+	if summary := strings.TrimSpace(tool.SummaryMD); summary != "" {
+		if r.markdown != nil {
+			if rendered, err := r.markdown.Render(summary); err == nil {
+				summary = strings.TrimSpace(rendered)
+			}
+		}
+		b.WriteString("\n" + summary + "\n")
+	}
+	b.WriteString("\n" + r.t("tools.levels") + "\n")
+	if total == 0 {
+		b.WriteString(r.t("tools.no_levels") + "\n")
+		b.WriteString("\n" + r.t("detail.back"))
+		return b.String()
+	}
+	for _, lv := range tool.Levels {
+		state := r.t("state.new")
+		switch {
+		case lv.Locked:
+			state = r.t("state.locked")
+		case lv.Mastered:
+			state = r.t("state.mastered")
+		case lv.Passed:
+			state = r.t("state.done")
+		}
+		b.WriteString(fmt.Sprintf("- %s [d:%d] %s/%s · %s\n", lv.Title, lv.Difficulty, lv.PackID, lv.LevelID, state))
+	}
+	if n := toolTrainingLevels(*tool); n > 0 {
+		b.WriteString("\n" + r.t("tools.train", tool.Name, n))
+	} else {
+		b.WriteString("\n" + r.t("tools.all_mastered"))
+	}
+	b.WriteString("    " + r.t("detail.back"))
+	return b.String()
+}
+
+func (r *Root) handleToolsKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	r.refreshToolList()
+	switch msg.Code {
+	case tea.KeyEsc:
+		r.dispatchController(func(c Controller) { c.OnBackToMainMenu() })
+		return r, nil
+	case tea.KeyEnter:
+		r.startSelectedTool()
+		return r, nil
+	case tea.KeyUp, tea.KeyDown, tea.KeyHome, tea.KeyEnd, tea.KeyPgUp, tea.KeyPgDown:
+		if len(r.tools) == 0 {
+			return r, nil
+		}
+		var cmd tea.Cmd
+		r.toolList, cmd = r.toolList.Update(msg)
+		r.toolIndex = wrapIndex(r.toolList.Index(), len(r.tools))
+		return r, cmd
+	}
+	return r, nil
+}
+
+func (r *Root) handleToolsMouseClick(x, y int) (tea.Model, tea.Cmd) {
+	leftW := min(40, max(24, r.cols/3))
+	if y < 2 || x < 1 || x >= leftW-1 || len(r.tools) == 0 {
+		return r, nil
+	}
+	idx := y - 2
+	if idx >= len(r.tools) {
+		return r, nil
+	}
+	if idx == r.toolIndex {
+		r.startSelectedTool()
+		return r, nil
+	}
+	r.toolIndex = idx
+	r.toolList.Select(idx)
+	return r, nil
+}
+
+func (r *Root) startSelectedTool() {
+	tool := r.selectedTool()
+	if tool == nil {
+		return
+	}
+	if len(tool.Levels) == 0 {
+		r.statusFlash = r.t("tools.no_levels")
+		return
+	}
+	toolID := tool.ToolID
+	r.dispatchController(func(c Controller) { c.OnStartToolPlaylist(toolID) })
+}
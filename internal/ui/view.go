@@ -86,6 +86,8 @@ type Root struct {
 	styleVariant string
 	motionLevel  string
 	mouseScope   string
+	// msgs is the UI message catalog of the active locale; see t.
+	msgs map[string]string
 
 	mu      sync.Mutex
 	program *tea.Program
@@ -103,6 +105,7 @@ type Root struct {
 	state         PlayingState
 	mainMenu      MainMenuState
 	catalog       []PackSummary
+	tools         []ToolSummary
 	selectedPack  string
 	selectedLevel string
 	result        ResultState
@@ -116,6 +119,9 @@ type Root struct {
 	diffText       string
 	infoTitle      string
 	infoText       string
+	// loadingOpen adds a progress bar at loadingProgress to the info overlay.
+	loadingOpen     bool
+	loadingProgress float64
 
 	menuOpen      bool
 	hintsOpen     bool
@@ -132,6 +138,7 @@ type Root struct {
 	packIndex     int
 	levelIndex    int
 	catalogFocus  int
+	toolIndex     int
 	levelSearch   string
 	levelDiffBand int
 	menuIndex     int
@@ -145,6 +152,7 @@ type Root struct {
 	mainList  list.Model
 	packList  list.Model
 	levelList list.Model
+	toolList  list.Model
 	detailVP  viewport.Model
 	detailMD  string
 
@@ -184,6 +192,8 @@ type Options struct {
 	StyleVariant string
 	MotionLevel  string
 	MouseScope   string
+	// Locale selects the UI message catalog; empty means DefaultLocale.
+	Locale string
 }
 
 func New(opts Options) *Root {
@@ -244,6 +254,7 @@ func New(opts Options) *Root {
 		styleVariant: styleVariant,
 		motionLevel:  motionLevel,
 		mouseScope:   mouseScope,
+		msgs:         messagesFor(opts.Locale),
 		screen:       ScreenMainMenu,
 		layout:       LayoutWide,
 		cols:         120,
@@ -252,6 +263,7 @@ func New(opts Options) *Root {
 		mainList:     newList(),
 		packList:     newList(),
 		levelList:    newList(),
+		toolList:     newList(),
 		detailVP:     viewport.New(viewport.WithWidth(1), viewport.WithHeight(1)),
 		mastery:      mastery,
 		checkSpin:    checkSpin,
@@ -430,6 +442,8 @@ func (r *Root) View() (view string) {
 		base = r.renderMainMenu()
 	case ScreenLevelSelect:
 		base = r.renderLevelSelect()
+	case ScreenTools:
+		base = r.renderTools()
 	default:
 		base = r.renderPlaying()
 	}
@@ -522,6 +536,13 @@ func (r *Root) SetCatalog(packs []PackSummary) {
 	})
 }
 
+func (r *Root) SetTools(tools []ToolSummary) {
+	r.apply(func(m *Root) {
+		m.tools = append([]ToolSummary(nil), tools...)
+		m.refreshToolList()
+	})
+}
+
 func (r *Root) SetLevelSelection(packID, levelID string) {
 	r.apply(func(m *Root) {
 		m.selectedPack = packID
@@ -643,6 +664,26 @@ func (r *Root) SetInfo(title, text string, open bool) {
 		m.infoTitle = title
 		m.infoText = text
 		m.infoOpen = open
+		m.loadingOpen = false
+	})
+}
+
+func (r *Root) SetLocale(locale string) {
+	r.apply(func(m *Root) {
+		m.msgs = messagesFor(locale)
+	})
+}
+
+func (r *Root) SetLoading(step string, progress float64) {
+	r.apply(func(m *Root) {
+		m.infoTitle = m.t("loading.title")
+		m.infoText = step
+		m.infoOpen = true
+		m.loadingOpen = true
+		m.loadingProgress = maxFloat(progress, 0)
+		if m.loadingProgress > 1 {
+			m.loadingProgress = 1
+		}
 	})
 }
 
@@ -655,6 +696,7 @@ func (r *Root) SetSettings(state SettingsState, open bool) {
 		state.StyleVariant = normalizeStyleVariant(state.StyleVariant)
 		state.MotionLevel = normalizeMotionLevel(state.MotionLevel)
 		state.MouseScope = normalizeMouseScope(state.MouseScope)
+		state.Locale = normalizeLocaleSetting(state.Locale)
 
 		m.settings = state
 		m.settingsOpen = open
@@ -776,6 +818,8 @@ func (r *Root) handleKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 		return r.handleMainMenuKey(msg)
 	case ScreenLevelSelect:
 		return r.handleLevelSelectKey(msg)
+	case ScreenTools:
+		return r.handleToolsKey(msg)
 	default:
 		return r.handlePlayingKey(msg)
 	}
@@ -829,6 +873,8 @@ func (r *Root) handleMouseClick(msg tea.MouseClickMsg) (tea.Model, tea.Cmd) {
 		return r.handleMainMenuMouseClick(m.X, m.Y)
 	case ScreenLevelSelect:
 		return r.handleLevelSelectMouseClick(m.X, m.Y)
+	case ScreenTools:
+		return r.handleToolsMouseClick(m.X, m.Y)
 	}
 	return r, nil
 }
@@ -917,7 +963,7 @@ func (r *Root) handleLevelSelectMouseClick(x, y int) (tea.Model, tea.Cmd) {
 		if level.Locked {
 			reason := strings.TrimSpace(level.LockReason)
 			if reason == "" {
-				reason = "Level is locked."
+				reason = r.t("level.locked_short")
 			}
 			r.statusFlash = reason
 			return r, nil
@@ -1015,7 +1061,7 @@ func (r *Root) handleOverlayKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 		if strings.TrimSpace(text) == "" {
 			return r, nil
 		}
-		r.statusFlash = "Copied overlay text"
+		r.statusFlash = r.t("status.copied_overlay")
 		return r, tea.SetClipboard(text)
 	}
 	if msg.Mod == 0 && (msg.Code == 'y' || msg.Code == 'Y') {
@@ -1025,9 +1071,9 @@ func (r *Root) handleOverlayKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 			return r, nil
 		}
 		if full {
-			r.statusFlash = "Copied overlay text"
+			r.statusFlash = r.t("status.copied_overlay")
 		} else {
-			r.statusFlash = "Copied selection"
+			r.statusFlash = r.t("status.copied_selection")
 		}
 		return r, tea.SetClipboard(text)
 	}
@@ -1120,6 +1166,12 @@ func (r *Root) handleOverlayKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 			msg.Mod&tea.ModCtrl == 0 && msg.Mod&tea.ModAlt == 0 {
 			r.dispatchController(func(c Controller) { c.OnOpenStats() })
 		}
+		if strings.TrimSpace(r.infoTitle) == InfoTitleLevelChanged &&
+			(msg.Code == 'r' || msg.Code == 'R') &&
+			msg.Mod&tea.ModCtrl == 0 && msg.Mod&tea.ModAlt == 0 {
+			r.closeTopOverlay()
+			r.dispatchController(func(c Controller) { c.OnRestageLevel() })
+		}
 	case "briefing":
 		switch msg.Code {
 		case tea.KeyEnter:
@@ -1169,7 +1221,7 @@ func (r *Root) handleMainMenuKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 	r.refreshMainMenuList()
 	switch msg.Code {
 	case tea.KeyEsc:
-		r.statusFlash = "Select Quit from the menu to exit."
+		r.statusFlash = r.t("status.quit_hint")
 		return r, nil
 	case tea.KeyEnter:
 		r.mainMenuIndex = wrapIndex(r.mainList.Index(), len(items))
@@ -1281,7 +1333,7 @@ func (r *Root) handleLevelSelectKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 		if level.Locked {
 			reason := strings.TrimSpace(level.LockReason)
 			if reason == "" {
-				reason = "Level is locked."
+				reason = r.t("level.locked_short")
 			}
 			r.statusFlash = reason
 			return r, nil
@@ -1526,12 +1578,12 @@ func (r *Root) renderMainMenu() string {
 	if strings.TrimSpace(menuView) != "" {
 		menuLines = strings.Split(menuView, "\n")
 	}
-	left := r.drawPanel("Main Menu", menuLines, leftW, bodyH)
+	left := r.drawPanel(r.t("panel.main_menu"), menuLines, leftW, bodyH)
 	rightText := r.mainMenuInfoText(items)
-	right := r.drawPanel("Overview", strings.Split(strings.TrimSuffix(rightText, "\n"), "\n"), max(20, w-lipgloss.Width(left)), bodyH)
+	right := r.drawPanel(r.t("panel.overview"), strings.Split(strings.TrimSuffix(rightText, "\n"), "\n"), max(20, w-lipgloss.Width(left)), bodyH)
 	body := lipgloss.JoinHorizontal(lipgloss.Top, left, right)
 	if r.setupMsg != "" {
-		setup := r.drawPanel("Setup", strings.Split(strings.TrimSpace(r.setupMsg+"\n\n"+r.setupDetails), "\n"), min(100, w), 10)
+		setup := r.drawPanel(r.t("panel.setup"), strings.Split(strings.TrimSpace(r.setupMsg+"\n\n"+r.setupDetails), "\n"), min(100, w), 10)
 		body = body + "\n" + setup
 	}
 	return header + "\n" + body
@@ -1542,11 +1594,11 @@ func (r *Root) renderLevelSelect() string {
 	r.refreshLevelSelectLists()
 	search := strings.TrimSpace(r.levelSearch)
 	filter := r.levelDiffBandLabel()
-	headerTxt := "CLI Dojo - Level Select"
+	headerTxt := "CLI Dojo - " + r.t("select.title")
 	if search != "" || filter != "all" {
-		headerTxt = fmt.Sprintf("%s | Search: %q | Filter: %s", headerTxt, search, filter)
+		headerTxt = headerTxt + " | " + r.t("select.search", search, filter)
 	} else {
-		headerTxt = fmt.Sprintf("%s | / type to search  Alt+F difficulty filter", headerTxt)
+		headerTxt = headerTxt + " | " + r.t("select.search_hint")
 	}
 	header := r.theme.Header.Width(max(1, w)).Render(trimForWidth(headerTxt, max(1, w-1)))
 
@@ -1558,11 +1610,11 @@ func (r *Root) renderLevelSelect() string {
 		r.packList.Select(wrapIndex(r.packIndex, len(r.packList.Items())))
 	}
 	leftView := strings.TrimRight(r.packList.View(), "\n")
-	leftLines := []string{"No packs loaded."}
+	leftLines := []string{r.t("select.no_packs")}
 	if strings.TrimSpace(leftView) != "" {
 		leftLines = strings.Split(leftView, "\n")
 	}
-	left := r.drawPanel("Packs", leftLines, leftW, bodyH)
+	left := r.drawPanel(r.t("panel.packs"), leftLines, leftW, bodyH)
 
 	middleW := min(46, max(28, w/3))
 	if len(r.levelList.Items()) > 0 {
@@ -1571,20 +1623,20 @@ func (r *Root) renderLevelSelect() string {
 		r.levelList.Select(wrapIndex(r.levelIndex, len(r.levelList.Items())))
 	}
 	middleView := strings.TrimRight(r.levelList.View(), "\n")
-	levelLines := []string{"No levels match current search/filter."}
+	levelLines := []string{r.t("select.no_match")}
 	if strings.TrimSpace(middleView) != "" {
 		levelLines = strings.Split(middleView, "\n")
 	}
-	middle := r.drawPanel("Levels", levelLines, middleW, bodyH)
+	middle := r.drawPanel(r.t("panel.levels"), levelLines, middleW, bodyH)
 
 	rightW := max(22, w-lipgloss.Width(left)-lipgloss.Width(middle))
-	r.updateDetailViewport(max(8, rightW-4), max(3, bodyH-4))
+	r.updateDetailViewport(max(8, rightW-4), max(3, bodyH-4), r.levelDetailText())
 	detailView := strings.TrimRight(r.detailVP.View(), "\n")
-	detailLines := []string{"No details available."}
+	detailLines := []string{r.t("select.no_details")}
 	if strings.TrimSpace(detailView) != "" {
 		detailLines = strings.Split(detailView, "\n")
 	}
-	right := r.drawPanel("Details", detailLines, rightW, bodyH)
+	right := r.drawPanel(r.t("panel.details"), detailLines, rightW, bodyH)
 
 	return header + "\n" + lipgloss.JoinHorizontal(lipgloss.Top, left, middle, right)
 }
@@ -1610,12 +1662,12 @@ func (r *Root) renderPlaying() string {
 			rows = r.tooSmallRows
 		}
 		msg := []string{
-			"Terminal too small",
-			fmt.Sprintf("Current: %dx%d", cols, rows),
-			"Minimum: 80x24",
-			"Resize the terminal to continue.",
+			r.t("too_small.title"),
+			r.t("too_small.current", cols, rows),
+			r.t("too_small.minimum"),
+			r.t("too_small.resize"),
 		}
-		panel := r.drawPanel("Resize Required", msg, min(60, w), min(12, h))
+		panel := r.drawPanel(r.t("too_small.panel"), msg, min(60, w), min(12, h))
 		return lipgloss.Place(w, h, lipgloss.Center, lipgloss.Center, panel)
 	}
 
@@ -1694,7 +1746,7 @@ func (r *Root) renderTerminalPanel(width, height int, originX, originY int) stri
 			}
 		}
 	} else {
-		lines[0] = "No terminal session"
+		lines[0] = r.t("terminal.none")
 	}
 	for i := range lines {
 		if lines[i] == "" {
@@ -1703,7 +1755,7 @@ func (r *Root) renderTerminalPanel(width, height int, originX, originY int) stri
 			lines[i] = padANSI(lines[i], innerW)
 		}
 	}
-	return r.drawTerminalPanel("Terminal", lines, width, height)
+	return r.drawTerminalPanel(r.t("panel.terminal"), lines, width, height)
 }
 
 func renderTermFrameRows(frame term.Frame, width, height int, ascii bool) []string {
@@ -1845,8 +1897,8 @@ func (r *Root) renderGoalDrawer(bodyHeight int) string {
 		return ""
 	}
 	lines := strings.Split(strings.TrimSuffix(r.hudText(), "\n"), "\n")
-	lines = append(lines, "", "Esc closes drawer")
-	return r.drawPanel("HUD Drawer", lines, drawW, bodyHeight)
+	lines = append(lines, "", r.t("hud.drawer_close"))
+	return r.drawPanel(r.t("hud.drawer"), lines, drawW, bodyHeight)
 }
 
 func (r *Root) renderOverlay() string {
@@ -1895,7 +1947,7 @@ func (r *Root) overlaySpec(top string) (overlaySpec, bool) {
 	var lines []string
 	switch top {
 	case "menu":
-		title = "Menu"
+		title = r.t("overlay.menu")
 		minW = 28
 		maxWCap = min(maxModalW, 44)
 		minH = 8
@@ -1908,28 +1960,28 @@ func (r *Root) overlaySpec(top string) (overlaySpec, bool) {
 			lines = append(lines, "  "+item.Label)
 		}
 	case "hints":
-		title = "Hints"
+		title = r.t("overlay.hints")
 		minW = 56
 		maxWCap = min(maxModalW, 90)
 		minH = 12
 		lines = strings.Split(strings.TrimSuffix(r.hintsText(), "\n"), "\n")
-		lines = append(lines, "", "Enter: Reveal hint", "y: Copy hints", "Esc: Close")
+		lines = append(lines, "", r.t("hints.reveal"), r.t("hints.copy"), r.t("common.esc_close"))
 	case "journal":
-		title = "Journal"
+		title = r.t("overlay.journal")
 		minW = 58
 		maxWCap = min(maxModalW, 92)
 		minH = 12
 		lines = strings.Split(strings.TrimSuffix(r.journalText(), "\n"), "\n")
-		lines = append(lines, "", "Enter: AI Explain", "y: Copy current  Y/Ctrl+C: Copy all", "Esc: Close")
+		lines = append(lines, "", r.t("journal.explain"), r.t("journal.copy"), r.t("common.esc_close"))
 	case "result":
-		title = "Results"
+		title = r.t("overlay.results")
 		minW = 60
 		maxWCap = min(maxModalW, 110)
 		minH = 12
 		lines = strings.Split(strings.TrimSuffix(r.resultText(), "\n"), "\n")
 		buttons := r.resultButtons()
 		if len(buttons) > 0 {
-			lines = append(lines, "", "Actions:")
+			lines = append(lines, "", r.t("results.actions"))
 			for i, b := range buttons {
 				if i == r.resultIndex {
 					lines = append(lines, r.theme.Accent.Render("> "+b))
@@ -1938,14 +1990,14 @@ func (r *Root) overlaySpec(top string) (overlaySpec, bool) {
 				lines = append(lines, "  "+b)
 			}
 		}
-		lines = append(lines, "", "Ctrl+C: Copy results")
+		lines = append(lines, "", r.t("results.copy"))
 	case "reset":
-		title = "Confirm Reset"
+		title = r.t("overlay.reset")
 		minW = 44
 		maxWCap = min(maxModalW, 64)
 		minH = 8
-		lines = []string{"Reset will destroy current /work state. Continue?", ""}
-		labels := []string{"Cancel", "Reset"}
+		lines = []string{r.t("reset.confirm"), ""}
+		labels := []string{r.t("reset.cancel"), r.t("reset.reset")}
 		for i, label := range labels {
 			if i == r.resetIndex {
 				lines = append(lines, r.theme.Accent.Render("> "+label))
@@ -1954,39 +2006,45 @@ func (r *Root) overlaySpec(top string) (overlaySpec, bool) {
 			lines = append(lines, "  "+label)
 		}
 	case "settings":
-		title = "Settings"
+		title = r.t("overlay.settings")
 		minW = 56
 		maxWCap = min(maxModalW, 84)
 		minH = 12
 		lines = r.renderSettingsLines()
 	case "briefing":
-		title = "Level Briefing"
+		title = r.t("overlay.briefing")
 		minW = 66
 		maxWCap = min(maxModalW, 112)
 		minH = 14
 		lines = strings.Split(strings.TrimSuffix(r.briefingText(), "\n"), "\n")
-		lines = append(lines, "", "Enter: Start level", "Esc: Back")
+		lines = append(lines, "", r.t("briefing.start"), r.t("briefing.back"))
 	case "reference":
-		title = "Reference Solutions"
+		title = r.t("overlay.reference")
 		minW = 64
 		maxWCap = maxModalW
 		minH = 12
 		lines = strings.Split(strings.TrimSuffix(r.referenceText, "\n"), "\n")
-		lines = append(lines, "", "Ctrl+C: Copy text", "Esc/q: Close")
+		lines = append(lines, "", r.t("common.copy_text"), r.t("common.esc_q_close"))
 	case "diff":
-		title = "Artifact Diff"
+		title = r.t("overlay.diff")
 		minW = 64
 		maxWCap = maxModalW
 		minH = 12
 		lines = strings.Split(strings.TrimSuffix(r.diffText, "\n"), "\n")
-		lines = append(lines, "", "Ctrl+C: Copy text", "Esc/q: Close")
+		lines = append(lines, "", r.t("common.copy_text"), r.t("common.esc_q_close"))
 	case "info":
-		title = firstNonEmptyStr(r.infoTitle, "Info")
+		title = firstNonEmptyStr(r.infoTitle, r.t("overlay.info"))
 		minW = 50
 		maxWCap = min(maxModalW, 100)
 		minH = 10
 		lines = strings.Split(strings.TrimSuffix(r.infoText, "\n"), "\n")
-		lines = append(lines, "", "Ctrl+C: Copy text", "Esc/q: Close")
+		if r.loadingOpen {
+			bar := r.mastery
+			bar.SetWidth(40)
+			lines = append(lines, "", bar.ViewAs(r.loadingProgress)+fmt.Sprintf(" %3d%%", int(r.loadingProgress*100)))
+			break
+		}
+		lines = append(lines, "", r.t("common.copy_text"), r.t("common.esc_q_close"))
 	default:
 		return overlaySpec{}, false
 	}
@@ -2168,17 +2226,20 @@ func (r *Root) statusText() string {
 
 func (r *Root) hudText() string {
 	var b strings.Builder
-	b.WriteString("Objective\n")
+	b.WriteString(r.objectiveTitle() + "\n")
+	if r.state.StageTitle != "" {
+		b.WriteString(r.state.StageTitle + "\n")
+	}
 	for _, obj := range r.state.Objective {
 		b.WriteString("- " + obj + "\n")
 	}
 	if len(r.state.SessionGoals) > 0 {
-		b.WriteString("\nSession Goals\n")
+		b.WriteString("\n" + r.t("hud.session_goals") + "\n")
 		for _, goal := range r.state.SessionGoals {
 			b.WriteString("- " + goal + "\n")
 		}
 	}
-	b.WriteString("\nChecks\n")
+	b.WriteString("\n" + r.t("hud.checks") + "\n")
 	for _, c := range r.state.Checks {
 		icon := "o"
 		if c.Status == "pass" {
@@ -2198,28 +2259,28 @@ func (r *Root) hudText() string {
 		}
 		b.WriteString(fmt.Sprintf("%s %s\n", icon, c.Description))
 	}
-	b.WriteString("\nHints\n")
+	b.WriteString("\n" + r.t("hud.hints") + "\n")
 	for i, h := range r.state.Hints {
 		text := h.Text
-		status := "available"
+		status := r.t("hint.available")
 		if h.Locked {
-			text = "(hidden)"
-			status = "locked"
+			text = r.t("hint.hidden")
+			status = r.t("hint.locked")
 			if h.LockReason != "" {
 				status += " (" + h.LockReason + ")"
 			}
 		}
 		if h.Revealed {
-			status = "revealed"
+			status = r.t("hint.revealed")
 		}
 		b.WriteString(fmt.Sprintf("%d. [%s] %s\n", i+1, status, text))
 	}
-	b.WriteString("\nScore\n")
-	b.WriteString(fmt.Sprintf("Current: %d\nHints: %d  Resets: %d\nStreak: %d\n", r.state.Score, r.state.HintsUsed, r.state.Resets, r.state.Streak))
-	b.WriteString("\nMastery\n")
+	b.WriteString("\n" + r.t("hud.score") + "\n")
+	b.WriteString(r.t("score.current", r.state.Score) + "\n" + r.t("score.hints", r.state.HintsUsed) + "  " + r.t("score.resets", r.state.Resets) + "\n" + r.t("score.streak", r.state.Streak) + "\n")
+	b.WriteString("\n" + r.t("hud.mastery") + "\n")
 	b.WriteString(r.masteryBar(24) + "\n")
 	if len(r.state.Badges) > 0 {
-		b.WriteString("\nBadges\n")
+		b.WriteString("\n" + r.t("hud.badges") + "\n")
 		for _, badge := range r.state.Badges {
 			b.WriteString("- " + badge + "\n")
 		}
@@ -2238,15 +2299,15 @@ func (r *Root) renderHUDColumn(width, height int) string {
 	}
 
 	cards := []cardSpec{
-		{title: "Objective", lines: r.objectiveCardLines(), desired: max(5, min(10, len(r.state.Objective)+3))},
-		{title: "Checks", lines: r.checkCardLines(), desired: max(5, min(12, len(r.state.Checks)+3))},
-		{title: "Hints", lines: r.hintCardLines(), desired: max(5, min(10, len(r.state.Hints)+3))},
-		{title: "Score", lines: r.scoreCardLines(), desired: 6},
-		{title: "Mastery", lines: r.masteryCardLines(), desired: 5},
+		{title: r.objectiveTitle(), lines: r.objectiveCardLines(), desired: max(5, min(10, len(r.state.Objective)+3))},
+		{title: r.t("hud.checks"), lines: r.checkCardLines(), desired: max(5, min(12, len(r.state.Checks)+3))},
+		{title: r.t("hud.hints"), lines: r.hintCardLines(), desired: max(5, min(10, len(r.state.Hints)+3))},
+		{title: r.t("hud.score"), lines: r.scoreCardLines(), desired: 6},
+		{title: r.t("hud.mastery"), lines: r.masteryCardLines(), desired: 5},
 	}
 	if len(r.state.Badges) > 0 {
 		cards = append(cards, cardSpec{
-			title:   "Badges",
+			title:   r.t("hud.badges"),
 			lines:   r.badgesCardLines(),
 			desired: max(4, min(8, len(r.state.Badges)+3)),
 		})
@@ -2266,23 +2327,35 @@ func (r *Root) renderHUDColumn(width, height int) string {
 		remaining -= cardH
 	}
 	if len(rendered) == 0 {
-		return r.drawPanel("HUD", []string{"No HUD data"}, width, height)
+		return r.drawPanel("HUD", []string{r.t("hud.empty")}, width, height)
 	}
 	out := strings.Join(rendered, "\n")
 	lines := normalizeScreenLines(out, height, width)
 	return strings.Join(lines, "\n")
 }
 
+// objectiveTitle names the objective card, with the stage of a multi-stage
+// level.
+func (r *Root) objectiveTitle() string {
+	if r.state.StageCount > 0 {
+		return r.t("hud.stage", r.state.Stage, r.state.StageCount)
+	}
+	return r.t("hud.objective")
+}
+
 func (r *Root) objectiveCardLines() []string {
-	lines := make([]string, 0, len(r.state.Objective)+len(r.state.SessionGoals)+2)
+	lines := make([]string, 0, len(r.state.Objective)+len(r.state.SessionGoals)+3)
+	if r.state.StageTitle != "" {
+		lines = append(lines, r.state.StageTitle)
+	}
 	for _, obj := range r.state.Objective {
 		lines = append(lines, "• "+obj)
 	}
 	if len(lines) == 0 {
-		lines = append(lines, "No objective loaded.")
+		lines = append(lines, r.t("hud.no_objective"))
 	}
 	if len(r.state.SessionGoals) > 0 {
-		lines = append(lines, "", "Session Goals")
+		lines = append(lines, "", r.t("hud.session_goals"))
 		for _, goal := range r.state.SessionGoals {
 			lines = append(lines, "◦ "+goal)
 		}
@@ -2311,7 +2384,7 @@ func (r *Root) checkCardLines() []string {
 		lines = append(lines, icon+" "+c.Description)
 	}
 	if len(lines) == 0 {
-		lines = append(lines, "No checks loaded.")
+		lines = append(lines, r.t("hud.no_checks"))
 	}
 	return lines
 }
@@ -2319,38 +2392,38 @@ func (r *Root) checkCardLines() []string {
 func (r *Root) hintCardLines() []string {
 	lines := make([]string, 0, len(r.state.Hints))
 	for i, h := range r.state.Hints {
-		status := r.theme.Info.Render("available")
+		status := r.theme.Info.Render(r.t("hint.available"))
 		text := h.Text
 		if h.Locked && !h.Revealed {
-			status = r.theme.Muted.Render("locked")
-			text = "(hidden)"
+			status = r.theme.Muted.Render(r.t("hint.locked"))
+			text = r.t("hint.hidden")
 			if h.LockReason != "" {
-				status = r.theme.Muted.Render("locked: " + h.LockReason)
+				status = r.theme.Muted.Render(r.t("hint.locked") + ": " + h.LockReason)
 			}
 		} else if h.Revealed {
-			status = r.theme.Pass.Render("revealed")
+			status = r.theme.Pass.Render(r.t("hint.revealed"))
 		}
 		lines = append(lines, fmt.Sprintf("%d. %s %s", i+1, status, text))
 	}
 	if len(lines) == 0 {
-		lines = append(lines, "No hints configured.")
+		lines = append(lines, r.t("hints.none"))
 	}
 	return lines
 }
 
 func (r *Root) scoreCardLines() []string {
 	return []string{
-		fmt.Sprintf("Current: %d", r.state.Score),
-		fmt.Sprintf("Hints: %d", r.state.HintsUsed),
-		fmt.Sprintf("Resets: %d", r.state.Resets),
-		fmt.Sprintf("Streak: %d", r.state.Streak),
+		r.t("score.current", r.state.Score),
+		r.t("score.hints", r.state.HintsUsed),
+		r.t("score.resets", r.state.Resets),
+		r.t("score.streak", r.state.Streak),
 	}
 }
 
 func (r *Root) masteryCardLines() []string {
 	return []string{
 		r.masteryBar(24),
-		fmt.Sprintf("Progress: %d%%", int(r.masteryPercent()*100)),
+		r.t("mastery.progress", int(r.masteryPercent()*100)),
 	}
 }
 
@@ -2365,29 +2438,29 @@ func (r *Root) badgesCardLines() []string {
 func (r *Root) hintsText() string {
 	var b strings.Builder
 	for i, h := range r.state.Hints {
-		status := "available"
+		status := r.t("hint.available")
 		text := h.Text
 		if h.Locked {
-			status = "locked"
-			text = "(hidden)"
+			status = r.t("hint.locked")
+			text = r.t("hint.hidden")
 			if h.LockReason != "" {
 				status += " (" + h.LockReason + ")"
 			}
 		}
 		if h.Revealed {
-			status = "revealed"
+			status = r.t("hint.revealed")
 		}
 		b.WriteString(fmt.Sprintf("%d. %s %s\n", i+1, status, text))
 	}
 	if b.Len() == 0 {
-		return "No hints configured."
+		return r.t("hints.none")
 	}
 	return b.String()
 }
 
 func (r *Root) journalText() string {
 	if len(r.journalEntries) == 0 {
-		return "No commands logged yet."
+		return r.t("journal.empty")
 	}
 	start := r.journalIndex
 	if start < 0 {
@@ -2442,80 +2515,52 @@ func (r *Root) resultText() string {
 
 func (r *Root) mainMenuItems() []menuItem {
 	return []menuItem{
-		{Label: "Continue", Action: "continue"},
-		{Label: "Daily Drill", Action: "daily"},
-		{Label: "Level Select", Action: "select"},
-		{Label: "Campaign", Action: "campaign"},
-		{Label: "Practice", Action: "practice"},
-		{Label: "Settings", Action: "settings"},
-		{Label: "Stats", Action: "stats"},
-		{Label: "Quit", Action: "quit"},
+		{Label: r.t("main.continue"), Action: "continue"},
+		{Label: r.t("main.daily"), Action: "daily"},
+		{Label: r.t("main.select"), Action: "select"},
+		{Label: r.t("main.campaign"), Action: "campaign"},
+		{Label: r.t("main.practice"), Action: "practice"},
+		{Label: r.t("main.tools"), Action: "tools"},
+		{Label: r.t("main.settings"), Action: "settings"},
+		{Label: r.t("main.stats"), Action: "stats"},
+		{Label: r.t("main.quit"), Action: "quit"},
 	}
 }
 
 func (r *Root) mainMenuInfoText(items []menuItem) string {
 	idx := wrapIndex(r.mainMenuIndex, len(items))
-	action := "Use Enter to select an option."
+	action := r.t("main.select_hint")
 	if len(items) > 0 {
-		switch items[idx].Action {
-		case "continue":
-			action = "Continue your most recent level run."
-		case "daily":
-			action = "Start today's deterministic daily challenge."
-		case "campaign":
-			action = "Play structured progression with prerequisites."
-		case "practice":
-			action = "Browse packs and choose any level."
-		case "select":
-			action = "Open level browser directly."
-		case "settings":
-			action = "Inspect runtime configuration."
-		case "stats":
-			action = "Review local progress summary."
-		case "quit":
-			action = "Exit CLI Dojo."
-		}
+		action = r.t("main." + items[idx].Action + ".action")
 	}
 	var b strings.Builder
 	b.WriteString("CLI Dojo\n\n")
 	if strings.TrimSpace(r.mainMenu.ModeLabel) != "" {
-		b.WriteString(fmt.Sprintf("Mode: %s\n", r.mainMenu.ModeLabel))
+		b.WriteString(r.t("overview.mode", r.mainMenu.ModeLabel) + "\n")
 	}
-	b.WriteString(fmt.Sprintf("Engine: %s\n", firstNonEmptyStr(r.mainMenu.EngineName, "unknown")))
-	b.WriteString(fmt.Sprintf("Packs: %d  Levels: %d\n", r.mainMenu.PackCount, r.mainMenu.LevelCount))
-	b.WriteString(fmt.Sprintf("Due Reviews: %d\n", r.mainMenu.DueReviews))
+	b.WriteString(r.t("overview.engine", firstNonEmptyStr(r.mainMenu.EngineName, r.t("common.unknown"))) + "\n")
+	b.WriteString(r.t("overview.packs", r.mainMenu.PackCount, r.mainMenu.LevelCount) + "\n")
+	b.WriteString(r.t("overview.due_reviews", r.mainMenu.DueReviews) + "\n")
 	if r.mainMenu.LastPackID != "" && r.mainMenu.LastLevelID != "" {
-		b.WriteString(fmt.Sprintf("Last Played: %s / %s\n", r.mainMenu.LastPackID, r.mainMenu.LastLevelID))
+		b.WriteString(r.t("overview.last_played", r.mainMenu.LastPackID, r.mainMenu.LastLevelID) + "\n")
 	}
-	b.WriteString(fmt.Sprintf("Runs: %d  Passes: %d  Attempts: %d  Resets: %d\n", r.mainMenu.LevelRuns, r.mainMenu.Passes, r.mainMenu.Attempts, r.mainMenu.Resets))
-	b.WriteString(fmt.Sprintf("Streak: %d\n", r.mainMenu.Streak))
+	b.WriteString(r.t("overview.runs", r.mainMenu.LevelRuns, r.mainMenu.Passes, r.mainMenu.Attempts, r.mainMenu.Resets) + "\n")
+	b.WriteString(r.t("overview.streak", r.mainMenu.Streak) + "\n")
 	if strings.TrimSpace(r.mainMenu.Tip) != "" {
-		b.WriteString("\nTip:\n")
+		b.WriteString("\n" + r.t("overview.tip") + "\n")
 		b.WriteString(r.mainMenu.Tip)
 		b.WriteString("\n")
 	}
-	b.WriteString("\nAction:\n" + action + "\n")
+	b.WriteString("\n" + r.t("overview.action") + "\n" + action + "\n")
 	return b.String()
 }
 
 func (r *Root) mainMenuDescription(action string) string {
 	switch action {
-	case "continue":
-		return "Resume your latest run"
-	case "daily":
-		return "Deterministic daily set"
-	case "campaign":
-		return "Progressive guided track"
-	case "practice":
-		return "Free play practice mode"
-	case "select":
-		return "Browse all levels"
-	case "settings":
-		return "Configure UI and checks"
-	case "stats":
-		return "Review performance"
+	case "continue", "daily", "campaign", "practice", "tools", "select", "settings", "stats":
+		return r.t("main." + action + ".desc")
 	default:
-		return "Exit CLI Dojo"
+		return r.t("main.quit.desc")
 	}
 }
 
@@ -2538,12 +2583,56 @@ func (r *Root) refreshMainMenuList() {
 	r.mainList.Select(r.mainMenuIndex)
 }
 
+// trustBadge summarizes a pack's signature state for the pack list. Built-in
+// packs get no badge.
+func trustBadge(p PackSummary, ascii bool) string {
+	var badge string
+	switch p.Trust {
+	case "trusted":
+		badge = "✓ signed"
+		if ascii {
+			badge = "[signed]"
+		}
+	case "unsigned":
+		badge = "⚠ unsigned"
+		if ascii {
+			badge = "[unsigned]"
+		}
+	case "unknown_key":
+		badge = "? unknown signer"
+		if ascii {
+			badge = "[unknown signer]"
+		}
+	case "invalid":
+		badge = "✗ bad signature"
+		if ascii {
+			badge = "[bad signature]"
+		}
+	default:
+		return ""
+	}
+	if p.Restricted {
+		badge += " (sandbox-only)"
+	}
+	return badge
+}
+
 func (r *Root) refreshLevelSelectLists() {
 	packItems := make([]list.Item, 0, len(r.catalog))
 	for _, p := range r.catalog {
+		description := r.t("select.pack_levels", len(p.Levels))
+		if p.Error != "" {
+			description = r.t("state.broken")
+		}
+		if p.Source != "" {
+			description += " · " + p.Source
+		}
+		if badge := trustBadge(p, r.ascii); badge != "" {
+			description += " · " + badge
+		}
 		packItems = append(packItems, uiListItem{
 			title:       p.Name,
-			description: fmt.Sprintf("%d levels", len(p.Levels)),
+			description: description,
 			filterValue: strings.ToLower(p.PackID + " " + p.Name),
 		})
 	}
@@ -2558,13 +2647,18 @@ func (r *Root) refreshLevelSelectLists() {
 	levels := r.selectedPackLevels()
 	levelItems := make([]list.Item, 0, len(levels))
 	for _, lv := range levels {
-		state := "new"
-		if lv.Locked {
-			state = "locked"
+		state := r.t("state.new")
+		if lv.Broken {
+			state = r.t("state.broken")
+		} else if lv.Locked {
+			state = r.t("state.locked")
 		} else if lv.PassedCount > 0 {
-			state = "done"
+			state = r.t("state.done")
 		}
 		title := fmt.Sprintf("%s [d:%d ~%dm]", lv.Title, lv.Difficulty, lv.EstimatedMinutes)
+		if lv.Broken {
+			title = lv.Title
+		}
 		levelItems = append(levelItems, uiListItem{
 			title:       title,
 			description: state,
@@ -2580,12 +2674,11 @@ func (r *Root) refreshLevelSelectLists() {
 	}
 }
 
-func (r *Root) updateDetailViewport(width, height int) {
+func (r *Root) updateDetailViewport(width, height int, content string) {
 	innerW := max(1, width)
 	innerH := max(1, height)
 	r.detailVP.SetWidth(innerW)
 	r.detailVP.SetHeight(innerH)
-	content := r.levelDetailText()
 	if content != r.detailMD {
 		r.detailMD = content
 		r.detailVP.SetContent(content)
@@ -2595,13 +2688,14 @@ func (r *Root) updateDetailViewport(width, height int) {
 
 func (r *Root) settingsMenuItems() []menuItem {
 	return []menuItem{
-		{Label: "Auto-check mode", Action: "auto_check_mode"},
-		{Label: "Auto-check debounce", Action: "auto_check_debounce"},
-		{Label: "Style", Action: "style"},
-		{Label: "Motion", Action: "motion"},
-		{Label: "Mouse scope", Action: "mouse"},
-		{Label: "Apply", Action: "apply"},
-		{Label: "Cancel", Action: "cancel"},
+		{Label: r.t("settings.auto_check_mode"), Action: "auto_check_mode"},
+		{Label: r.t("settings.auto_check_debounce"), Action: "auto_check_debounce"},
+		{Label: r.t("settings.style"), Action: "style"},
+		{Label: r.t("settings.motion"), Action: "motion"},
+		{Label: r.t("settings.mouse"), Action: "mouse"},
+		{Label: r.t("settings.language"), Action: "language"},
+		{Label: r.t("settings.apply"), Action: "apply"},
+		{Label: r.t("settings.cancel"), Action: "cancel"},
 	}
 }
 
@@ -2621,6 +2715,8 @@ func (r *Root) renderSettingsLines() []string {
 			label = fmt.Sprintf("%s: %s", label, normalizeMotionLevel(r.settings.MotionLevel))
 		case "mouse":
 			label = fmt.Sprintf("%s: %s", label, normalizeMouseScope(r.settings.MouseScope))
+		case "language":
+			label = fmt.Sprintf("%s: %s", label, normalizeLocaleSetting(r.settings.Locale))
 		}
 		if i == r.settingsIndex {
 			lines = append(lines, r.theme.Accent.Render("> "+label))
@@ -2628,7 +2724,7 @@ func (r *Root) renderSettingsLines() []string {
 		}
 		lines = append(lines, "  "+label)
 	}
-	lines = append(lines, "", "Left/Right/Enter: change value  Up/Down: move", "Esc: close")
+	lines = append(lines, "", r.t("settings.help"), r.t("settings.close"))
 	return lines
 }
 
@@ -2669,49 +2765,77 @@ func (r *Root) stepSetting(action string, forward bool) {
 		next := cycleString(opts, normalizeMouseScope(r.settings.MouseScope), forward)
 		r.settings.MouseScope = next
 		r.mouseScope = next
+	case "language":
+		opts := append([]string{"auto"}, Locales()...)
+		next := cycleString(opts, normalizeLocaleSetting(r.settings.Locale), forward)
+		r.settings.Locale = next
+		r.msgs = messagesFor(ResolveLocale(next))
 	}
 }
 
 func (r *Root) levelDetailText() string {
 	pack := r.selectedPackSummary()
 	if pack == nil {
-		return "No levels available in this pack."
+		return r.t("detail.no_levels")
+	}
+	if pack.Error != "" {
+		return fmt.Sprintf("%s\n%s\n%s\n\n%s\n\n%s\n\n%s", pack.Name, r.t("detail.status_broken"), r.t("detail.pack_failed"), pack.Error, r.t("detail.pack", pack.SourcePath), r.t("detail.back"))
 	}
 	levels := r.filteredLevels(pack.Levels)
 	if len(levels) == 0 {
-		return "No levels match current search/filter.\n\nType to search, Backspace to edit, Ctrl+U to clear.\nUse Alt+F to cycle difficulty filters."
+		return r.t("select.no_match") + "\n\n" + r.t("detail.search_help")
 	}
 	idx := wrapIndex(r.levelIndex, len(levels))
 	lv := levels[idx]
 	var b strings.Builder
 	b.WriteString(fmt.Sprintf("%s\n", lv.Title))
-	b.WriteString(fmt.Sprintf("ID: %s\nDifficulty: %d\nEstimated: %d min\n", lv.LevelID, lv.Difficulty, lv.EstimatedMinutes))
+	if lv.Broken {
+		b.WriteString(r.t("detail.id", lv.LevelID) + "\n")
+	} else {
+		b.WriteString(r.t("detail.id", lv.LevelID) + "\n" + r.t("detail.difficulty", lv.Difficulty) + "\n" + r.t("detail.estimated", lv.EstimatedMinutes) + "\n")
+	}
 	if lv.Tier > 0 {
-		b.WriteString(fmt.Sprintf("Tier: %d\n", lv.Tier))
+		b.WriteString(r.t("detail.tier", lv.Tier) + "\n")
 	}
 	if lv.PassedCount > 0 {
-		b.WriteString(fmt.Sprintf("Completed: %d run(s)", lv.PassedCount))
+		b.WriteString(r.t("detail.completed", lv.PassedCount))
 		if lv.BestScore > 0 {
-			b.WriteString(fmt.Sprintf("  Best score: %d", lv.BestScore))
+			b.WriteString("  " + r.t("detail.best_score", lv.BestScore))
 		}
 		b.WriteString("\n")
 	}
-	if lv.Locked {
+	if lv.Broken {
+		b.WriteString(r.t("detail.status_broken") + "\n" + r.t("detail.level_failed") + "\n")
+		b.WriteString(strings.TrimSpace(lv.LockReason) + "\n")
+	} else if lv.Locked {
 		lockReason := strings.TrimSpace(lv.LockReason)
 		if lockReason == "" {
-			lockReason = "This level is locked."
+			lockReason = r.t("detail.locked_default")
 		}
-		b.WriteString("Status: LOCKED\n")
+		b.WriteString(r.t("detail.status_locked") + "\n")
 		b.WriteString(lockReason + "\n")
 	}
 	if len(lv.Prerequisites) > 0 {
-		b.WriteString("Prerequisites: " + strings.Join(lv.Prerequisites, ", ") + "\n")
+		b.WriteString(r.t("detail.prerequisites", strings.Join(lv.Prerequisites, ", ")) + "\n")
 	}
 	if len(lv.ToolFocus) > 0 {
-		b.WriteString("Tools: " + strings.Join(lv.ToolFocus, ", ") + "\n")
+		b.WriteString(r.t("detail.tools", strings.Join(lv.ToolFocus, ", ")) + "\n")
+	}
+	if pack.SourcePath != "" {
+		b.WriteString(r.t("detail.pack", pack.SourcePath) + " (" + firstNonEmptyStr(pack.Source, "local") + ")\n")
+	}
+	if pack.Trust != "" && pack.Trust != "builtin" {
+		trust := "Trust: " + pack.Trust
+		if pack.TrustKeyID != "" {
+			trust += " (key " + pack.TrustKeyID + ")"
+		}
+		if pack.Restricted {
+			trust += ", sandbox-only"
+		}
+		b.WriteString(trust + "\n")
 	}
 	if len(lv.Concepts) > 0 {
-		b.WriteString("Concepts: " + strings.Join(lv.Concepts, ", ") + "\n")
+		b.WriteString(r.t("detail.concepts", strings.Join(lv.Concepts, ", ")) + "\n")
 	}
 	if strings.TrimSpace(lv.SummaryMD) != "" {
 		summary := strings.TrimSpace(lv.SummaryMD)
@@ -2723,15 +2847,15 @@ func (r *Root) levelDetailText() string {
 		b.WriteString("\n" + summary + "\n")
 	}
 	if len(lv.ObjectiveBullets) > 0 {
-		b.WriteString("\nObjectives:\n")
+		b.WriteString("\n" + r.t("detail.objectives") + "\n")
 		for _, obj := range lv.ObjectiveBullets {
 			b.WriteString("- " + obj + "\n")
 		}
 	}
 	if lv.Locked {
-		b.WriteString("\nEnter: Locked    Esc: Back to main menu")
+		b.WriteString("\n" + r.t("detail.enter_locked") + "    " + r.t("detail.back"))
 	} else {
-		b.WriteString("\nEnter: Start level    Esc: Back to main menu")
+		b.WriteString("\n" + r.t("briefing.start") + "    " + r.t("detail.back"))
 	}
 	return b.String()
 }
@@ -2739,26 +2863,26 @@ func (r *Root) levelDetailText() string {
 func (r *Root) briefingText() string {
 	levels := r.selectedPackLevels()
 	if len(levels) == 0 {
-		return "No selectable level."
+		return r.t("briefing.none")
 	}
 	lv := levels[wrapIndex(r.levelIndex, len(levels))]
 	var b strings.Builder
 	b.WriteString(fmt.Sprintf("%s\n", lv.Title))
-	b.WriteString(fmt.Sprintf("Level: %s\nDifficulty: %d\nEstimated: %d min\n", lv.LevelID, lv.Difficulty, lv.EstimatedMinutes))
+	b.WriteString(r.t("briefing.level", lv.LevelID) + "\n" + r.t("detail.difficulty", lv.Difficulty) + "\n" + r.t("detail.estimated", lv.EstimatedMinutes) + "\n")
 	if len(lv.ToolFocus) > 0 {
-		b.WriteString("Tools: " + strings.Join(lv.ToolFocus, ", ") + "\n")
+		b.WriteString(r.t("detail.tools", strings.Join(lv.ToolFocus, ", ")) + "\n")
 	}
 	if len(lv.Concepts) > 0 {
-		b.WriteString("Concepts: " + strings.Join(lv.Concepts, ", ") + "\n")
+		b.WriteString(r.t("detail.concepts", strings.Join(lv.Concepts, ", ")) + "\n")
 	}
 	if len(lv.ObjectiveBullets) > 0 {
-		b.WriteString("\nObjectives:\n")
+		b.WriteString("\n" + r.t("detail.objectives") + "\n")
 		for _, obj := range lv.ObjectiveBullets {
 			b.WriteString("- " + obj + "\n")
 		}
 	}
 	if strings.TrimSpace(lv.SummaryMD) != "" {
-		b.WriteString("\nSummary:\n")
+		b.WriteString("\n" + r.t("briefing.summary") + "\n")
 		b.WriteString(strings.TrimSpace(lv.SummaryMD))
 		b.WriteString("\n")
 	}
@@ -2772,13 +2896,13 @@ type menuItem struct {
 
 func (r *Root) menuItems() []menuItem {
 	return []menuItem{
-		{Label: "Continue", Action: "continue"},
-		{Label: "Restart level", Action: "restart"},
-		{Label: "Level select", Action: "level_select"},
-		{Label: "Main menu", Action: "main_menu"},
-		{Label: "Settings", Action: "settings"},
-		{Label: "Stats", Action: "stats"},
-		{Label: "Quit", Action: "quit"},
+		{Label: r.t("menu.continue"), Action: "continue"},
+		{Label: r.t("menu.restart"), Action: "restart"},
+		{Label: r.t("menu.level_select"), Action: "level_select"},
+		{Label: r.t("menu.main_menu"), Action: "main_menu"},
+		{Label: r.t("menu.settings"), Action: "settings"},
+		{Label: r.t("menu.stats"), Action: "stats"},
+		{Label: r.t("menu.quit"), Action: "quit"},
 	}
 }
 
@@ -2797,6 +2921,8 @@ func (r *Root) activateMainMenuSelection() {
 		r.dispatchController(func(c Controller) { c.OnStartCampaign() })
 	case "practice":
 		r.dispatchController(func(c Controller) { c.OnStartPractice() })
+	case "tools":
+		r.dispatchController(func(c Controller) { c.OnOpenTools() })
 	case "select":
 		r.dispatchController(func(c Controller) { c.OnOpenLevelSelect() })
 	case "settings":
@@ -2845,7 +2971,7 @@ func (r *Root) startSelectedLevel() {
 	if lv.Locked {
 		reason := strings.TrimSpace(lv.LockReason)
 		if reason == "" {
-			reason = "Level is locked."
+			reason = r.t("level.locked_short")
 		}
 		r.statusFlash = reason
 		return
@@ -3061,6 +3187,7 @@ func (r *Root) closeTopOverlay() {
 		r.infoOpen = false
 		r.infoText = ""
 		r.infoTitle = ""
+		r.loadingOpen = false
 	case "reset":
 		r.resetOpen = false
 	case "result":
@@ -3592,6 +3719,16 @@ func normalizeAutoCheckMode(v string) string {
 	}
 }
 
+// normalizeLocaleSetting maps a settings locale to "auto" or a language with
+// a UI catalog.
+func normalizeLocaleSetting(v string) string {
+	lang, _, _ := strings.Cut(NormalizeLocale(v), "_")
+	if _, ok := catalogs[lang]; ok {
+		return lang
+	}
+	return "auto"
+}
+
 func normalizeMouseScope(v string) string {
 	switch strings.TrimSpace(v) {
 	case "off", "scoped", "full":
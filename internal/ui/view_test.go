@@ -25,6 +25,8 @@ type mockController struct {
 	hintsCalls    int
 	journalCalls  int
 	statsCalls    int
+	restageCalls  int
+	toolRuns      []string
 	inputs        [][]byte
 	settings      []SettingsState
 }
@@ -110,6 +112,31 @@ func (m *mockController) OnApplySettings(s SettingsState) {
 	m.settings = append(m.settings, s)
 }
 
+func (m *mockController) OnRestageLevel() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.restageCalls++
+}
+
+func (m *mockController) OnOpenTools() {}
+func (m *mockController) OnStartToolPlaylist(toolID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.toolRuns = append(m.toolRuns, toolID)
+}
+
+func (m *mockController) ToolRuns() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.toolRuns...)
+}
+
+func (m *mockController) RestageCalls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.restageCalls
+}
+
 func (m *mockController) ContinueCalls() int {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -1086,6 +1113,34 @@ func TestLevelSelectSearchFiltersLevels(t *testing.T) {
 	}
 }
 
+func TestLevelSelectShowsBrokenEntries(t *testing.T) {
+	pane := term.NewTerminalPane(nil)
+	v := New(Options{TermPane: pane})
+	v.SetScreen(ScreenLevelSelect)
+	v.SetCatalog([]PackSummary{
+		{
+			PackID: "core",
+			Name:   "Core",
+			Levels: []LevelSummary{
+				{LevelID: "level-two", Title: "level-two", Broken: true, Locked: true, LockReason: "yaml: line 2: did not find expected node content"},
+			},
+		},
+		{PackID: "bad", Name: "bad", Error: "parse pack.yaml: yaml: line 1"},
+	})
+
+	if item := v.levelList.Items()[0].(uiListItem); item.description != "broken" {
+		t.Fatalf("expected broken state, got %q", item.description)
+	}
+	if text := v.levelDetailText(); !strings.Contains(text, "Status: BROKEN") || !strings.Contains(text, "did not find expected node") {
+		t.Fatalf("expected the validation message in the detail, got %q", text)
+	}
+	v.packIndex = 1
+	v.refreshLevelSelectLists()
+	if text := v.levelDetailText(); !strings.Contains(text, "This pack failed to load") || !strings.Contains(text, "line 1") {
+		t.Fatalf("expected the pack error in the detail, got %q", text)
+	}
+}
+
 func TestLevelSelectDifficultyFilterCycles(t *testing.T) {
 	pane := term.NewTerminalPane(nil)
 	v := New(Options{TermPane: pane})
@@ -1196,7 +1251,7 @@ func TestSettingsOverlayAppliesUpdate(t *testing.T) {
 
 	// Toggle auto-check mode once (off -> manual), then jump to Apply.
 	press(v, tea.KeyRight, 0, "")
-	for i := 0; i < 5; i++ {
+	for i := 0; i < 6; i++ {
 		press(v, tea.KeyDown, 0, "")
 	}
 	press(v, tea.KeyEnter, 0, "")
@@ -1232,6 +1287,76 @@ func TestStatsInfoOverlayRefreshHotkey(t *testing.T) {
 	}
 }
 
+func TestLevelChangedOverlayRestageHotkey(t *testing.T) {
+	pane := term.NewTerminalPane(nil)
+	v := New(Options{TermPane: pane, DevMode: true})
+	ctrl := &mockController{}
+	v.SetController(ctrl)
+	v.SetScreen(ScreenPlaying)
+	v.SetInfo(InfoTitleLevelChanged, "level-001 changed on disk.", true)
+
+	press(v, 'r', 0, "r")
+	waitForCondition(t, 300*time.Millisecond, func() bool {
+		return ctrl.RestageCalls() == 1
+	})
+	if got := ctrl.RestageCalls(); got != 1 {
+		t.Fatalf("expected restage to dispatch once, got %d", got)
+	}
+	if v.infoOpen {
+		t.Fatalf("expected the prompt to close")
+	}
+}
+
+func TestLoadingOverlayShowsProgress(t *testing.T) {
+	pane := term.NewTerminalPane(nil)
+	v := New(Options{TermPane: pane})
+	v.SetLoading("Generating dataset...", 0.5)
+
+	spec, ok := v.overlaySpec(v.topOverlay())
+	if !ok || spec.title != "Loading level" || !strings.Contains(strings.Join(spec.lines, "\n"), " 50%") {
+		t.Fatalf("expected a loading overlay at 50%%, got %q %v", spec.title, spec.lines)
+	}
+	v.SetInfo("", "", false)
+	if v.infoOpen || v.loadingOpen {
+		t.Fatalf("expected SetInfo to close the loading overlay")
+	}
+}
+
+func TestToolsScreenShowsMasteryAndStartsPlaylist(t *testing.T) {
+	pane := term.NewTerminalPane(nil)
+	v := New(Options{TermPane: pane})
+	ctrl := &mockController{}
+	v.SetController(ctrl)
+	v.SetScreen(ScreenTools)
+	v.SetTools([]ToolSummary{
+		{ToolID: "pipes", Name: "Pipes"},
+		{ToolID: "awk", Name: "awk", SummaryMD: "Text processing.", Passed: 1, Mastered: 1, Levels: []ToolLevel{
+			{PackID: "core", LevelID: "l1", Title: "Top IPs", Difficulty: 2, Passed: true, Mastered: true},
+			{PackID: "core", LevelID: "l2", Title: "Columns", Difficulty: 3},
+		}},
+	})
+
+	press(v, tea.KeyEnter, 0, "")
+	if !strings.Contains(v.statusFlash, "No level focuses") {
+		t.Fatalf("a tool without levels should not start a playlist, got %q", v.statusFlash)
+	}
+	press(v, tea.KeyDown, 0, "")
+	text := v.toolDetailText(60)
+	for _, want := range []string{"Completed 1/2", "Mastered  1/2", "Top IPs [d:2] core/l1 · mastered", "Columns [d:3] core/l2 · new", "Train awk (1 level(s) to go)"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("tool detail is missing %q:\n%s", want, text)
+		}
+	}
+	if out := v.View(); !strings.Contains(out, "awk  1/2 mastered") {
+		t.Fatalf("expected the tool list to show mastery")
+	}
+	press(v, tea.KeyEnter, 0, "")
+	waitForCondition(t, time.Second, func() bool { return len(ctrl.ToolRuns()) == 1 })
+	if runs := ctrl.ToolRuns(); len(runs) != 1 || runs[0] != "awk" {
+		t.Fatalf("expected an awk playlist, got %v", runs)
+	}
+}
+
 func waitForCondition(t *testing.T, timeout time.Duration, fn func() bool) {
 	t.Helper()
 	deadline := time.Now().Add(timeout)
@@ -1242,3 +1367,15 @@ func waitForCondition(t *testing.T, timeout time.Duration, fn func() bool) {
 		time.Sleep(5 * time.Millisecond)
 	}
 }
+
+func TestTrustBadgeMarksUnsignedSandboxOnlyPacks(t *testing.T) {
+	if got := trustBadge(PackSummary{Trust: "builtin"}, false); got != "" {
+		t.Fatalf("expected no badge for built-in packs, got %q", got)
+	}
+	if got := trustBadge(PackSummary{Trust: "trusted"}, true); got != "[signed]" {
+		t.Fatalf("unexpected ascii badge %q", got)
+	}
+	if got := trustBadge(PackSummary{Trust: "unsigned", Restricted: true}, false); got != "⚠ unsigned (sandbox-only)" {
+		t.Fatalf("unexpected badge %q", got)
+	}
+}